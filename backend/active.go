@@ -0,0 +1,70 @@
+package main
+
+// backend/active.go
+//
+// One active job per greenhouse. POST /simulate?unique_active=true requires a
+// greenhouse_id and takes the lock active_job:<greenhouse_id> (SET NX, value =
+// job id) before enqueuing; while the lock is held by a queued or running job
+// further unique submissions get 409. The lock is released (only by its
+// holder) when the job finishes in the worker, is cancelled, is failed by the
+// stale-job sweeper or is deleted. Only submissions made with unique_active
+// take the lock.
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisActiveJobPrefix keys active_job:<greenhouse_id> -> id of the job holding the lock.
+const RedisActiveJobPrefix = "active_job:"
+
+// releaseActiveScript deletes the lock only if jobID still holds it.
+var releaseActiveScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// acquireActiveLock takes the greenhouse lock for jobID, expiring after ttl as
+// a last resort. When another job holds it, ok is false and holder is that
+// job. A lock left behind by a job that already finished or no longer exists
+// is cleared and taken over.
+func acquireActiveLock(ctx context.Context, greenhouseID, jobID string, ttl time.Duration) (holder string, ok bool, err error) {
+	key := activeJobKey(greenhouseID)
+	for attempt := 0; attempt < 2; attempt++ {
+		ok, err = rdb.SetNX(ctx, key, jobID, ttl).Result()
+		if err != nil || ok {
+			return "", ok, err
+		}
+		holder, err = rdb.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue // released between SETNX and GET
+		} else if err != nil {
+			return "", false, err
+		}
+		meta, err := redisMetaStore{}.GetMeta(ctx, holder)
+		if err != nil && !errors.Is(err, ErrMetaNotFound) {
+			return "", false, err
+		}
+		if err == nil && !isTerminalStatus(meta.Status) {
+			return holder, false, nil
+		}
+		if err := releaseActiveLock(ctx, greenhouseID, holder); err != nil {
+			return "", false, err
+		}
+	}
+	return holder, false, nil
+}
+
+// releaseActiveLock frees the greenhouse lock if jobID holds it. It is a no-op
+// for jobs without a greenhouse id or that never took the lock.
+func releaseActiveLock(ctx context.Context, greenhouseID, jobID string) error {
+	if greenhouseID == "" {
+		return nil
+	}
+	return releaseActiveScript.Run(ctx, rdb, []string{activeJobKey(greenhouseID)}, jobID).Err()
+}
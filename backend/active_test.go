@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const uniqueSubmit = "/simulate?unique_active=true"
+
+func TestUniqueActiveLock(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	// lock free: the job is enqueued and holds the lock
+	code, first := submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-1"}`))
+	require.Equal(t, http.StatusAccepted, code, first)
+	firstID := first["job_id"].(string)
+	assert.Equal(t, firstID, rdb.Get(ctx, RedisActiveJobPrefix+"gh-1").Val())
+	assert.Positive(t, rdb.TTL(ctx, RedisActiveJobPrefix+"gh-1").Val())
+
+	// lock held: refused without enqueuing anything
+	code, refused := submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-1", "T_init": 10}`))
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, firstID, refused["active_job_id"])
+	assert.Equal(t, "gh-1", refused["greenhouse_id"])
+	assert.Equal(t, int64(1), rdb.LLen(ctx, RedisJobsList).Val())
+	assert.Equal(t, int64(1), rdb.LLen(ctx, RedisRecentJobsList).Val())
+
+	// another greenhouse is independent
+	code, _ = submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-2"}`))
+	assert.Equal(t, http.StatusAccepted, code)
+
+	// without the flag nothing is checked or locked
+	code, _ = submitReuse(t, router, "/simulate", withSite(`{"greenhouse_id": "gh-1"}`))
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, firstID, rdb.Get(ctx, RedisActiveJobPrefix+"gh-1").Val())
+}
+
+func TestUniqueActiveLockReleased(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	t.Run("on cancel", func(t *testing.T) {
+		code, first := submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-cancel"}`))
+		require.Equal(t, http.StatusAccepted, code)
+		code, _ = submitReuse(t, router, "/jobs/"+first["job_id"].(string)+"/cancel", ``)
+		require.Equal(t, http.StatusOK, code)
+		assert.Zero(t, rdb.Exists(ctx, RedisActiveJobPrefix+"gh-cancel").Val())
+
+		code, _ = submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-cancel"}`))
+		assert.Equal(t, http.StatusAccepted, code)
+	})
+
+	t.Run("holder finished without releasing", func(t *testing.T) {
+		code, first := submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-done"}`))
+		require.Equal(t, http.StatusAccepted, code)
+		markDone(t, ctx, first["job_id"].(string))
+
+		code, second := submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-done"}`))
+		require.Equal(t, http.StatusAccepted, code)
+		assert.Equal(t, second["job_id"], rdb.Get(ctx, RedisActiveJobPrefix+"gh-done").Val())
+	})
+
+	t.Run("holder no longer exists", func(t *testing.T) {
+		require.NoError(t, rdb.Set(ctx, RedisActiveJobPrefix+"gh-gone", "deleted-job", time.Hour).Err())
+		code, _ := submitReuse(t, router, uniqueSubmit, withSite(`{"greenhouse_id": "gh-gone"}`))
+		assert.Equal(t, http.StatusAccepted, code)
+	})
+}
+
+func TestUniqueActiveRequiresGreenhouseID(t *testing.T) {
+	router := setupRouter()
+	code, resp := submitReuse(t, router, uniqueSubmit, withSite(`{}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "greenhouse_id", resp["fields"].([]interface{})[0].(map[string]interface{})["field"])
+
+	code, resp = submitReuse(t, router, "/simulate", withSite(`{"greenhouse_id": "not valid"}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "greenhouse_id", resp["fields"].([]interface{})[0].(map[string]interface{})["field"])
+}
+
+func TestReleaseActiveLockOnlyByHolder(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	require.NoError(t, rdb.Set(ctx, RedisActiveJobPrefix+"gh", "holder", time.Hour).Err())
+	require.NoError(t, releaseActiveLock(ctx, "gh", "someone-else"))
+	assert.Equal(t, "holder", rdb.Get(ctx, RedisActiveJobPrefix+"gh").Val())
+	require.NoError(t, releaseActiveLock(ctx, "gh", "holder"))
+	assert.Zero(t, rdb.Exists(ctx, RedisActiveJobPrefix+"gh").Val())
+	assert.NoError(t, releaseActiveLock(ctx, "", "holder"))
+}
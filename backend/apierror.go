@@ -0,0 +1,113 @@
+package main
+
+// backend/apierror.go
+//
+// Error responses. Every handler reports failures through respondError (or
+// the variants below), which writes one envelope:
+//
+//	{"error": {"code": "INVALID_PARAM", "message": "...", "field": "limit"}, "request_id": "..."}
+//
+// Clients branch on code, one of the ErrorCode constants; message is for
+// people and may change. field names the offending input when there is a
+// single one. Context about the failure (the job's current status, the list
+// of invalid fields, limits) stays next to "error" at the top level.
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is the machine-readable kind of an error response.
+type ErrorCode string
+
+// Error codes
+const (
+	CodeInvalidRequest  ErrorCode = "INVALID_REQUEST"        // malformed body or request
+	CodeInvalidParam    ErrorCode = "INVALID_PARAM"          // a param or query value is out of range or malformed
+	CodeUnauthorized    ErrorCode = "UNAUTHORIZED"           // missing or wrong credentials
+	CodeForbidden       ErrorCode = "FORBIDDEN"              // credentials lack the permission
+	CodeNotFound        ErrorCode = "NOT_FOUND"              // job, scenario or link does not exist
+	CodeExpired         ErrorCode = "EXPIRED"                // the job existed but its data has been purged
+	CodeConflict        ErrorCode = "CONFLICT"               // the job's state does not allow the operation
+	CodeNotAcceptable   ErrorCode = "NOT_ACCEPTABLE"         // no representation matches Accept
+	CodePayloadTooLarge ErrorCode = "PAYLOAD_TOO_LARGE"      // body over the configured limit
+	CodeUnsupportedType ErrorCode = "UNSUPPORTED_MEDIA_TYPE" // body in a Content-Type the route does not read
+	CodeRateLimited     ErrorCode = "RATE_LIMITED"           // rate limit or active job quota hit
+	CodeInternal        ErrorCode = "INTERNAL"               // Redis or other backend failure
+	CodeUpstream        ErrorCode = "UPSTREAM_ERROR"         // an external service (weather API) failed
+	CodeUnavailable     ErrorCode = "UNAVAILABLE"            // feature disabled or dependency down
+	CodeTimeout         ErrorCode = "TIMEOUT"                // the request exceeded REQUEST_TIMEOUT
+)
+
+// errorCodes lists every ErrorCode, for the OpenAPI document.
+var errorCodes = []ErrorCode{
+	CodeInvalidRequest, CodeInvalidParam, CodeUnauthorized, CodeForbidden, CodeNotFound, CodeExpired,
+	CodeConflict, CodeNotAcceptable, CodePayloadTooLarge, CodeUnsupportedType, CodeRateLimited, CodeInternal,
+	CodeUpstream, CodeUnavailable, CodeTimeout,
+}
+
+// apiError is the value of "error" in an error response.
+type apiError struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Field   string    `json:"field,omitempty"`
+}
+
+// codeForStatus is the default code for an HTTP status.
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusGone:
+		return CodeExpired
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		return CodeConflict
+	case http.StatusNotAcceptable:
+		return CodeNotAcceptable
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedType
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusBadGateway:
+		return CodeUpstream
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	}
+	return CodeInternal
+}
+
+// respondAPIError aborts with err in the error envelope, including the
+// request id; extra fields are merged into the top level of the body.
+func respondAPIError(c *gin.Context, status int, err apiError, extra ...gin.H) {
+	body := gin.H{"error": err}
+	for _, e := range extra {
+		for k, v := range e {
+			body[k] = v
+		}
+	}
+	if reqID := c.GetString(ctxRequestID); reqID != "" {
+		body["request_id"] = reqID
+	}
+	c.AbortWithStatusJSON(status, body)
+}
+
+// respondError aborts with msg under the default code for status.
+func respondError(c *gin.Context, status int, msg string, extra ...gin.H) {
+	respondAPIError(c, status, apiError{Code: codeForStatus(status), Message: msg}, extra...)
+}
+
+// respondInvalidParam aborts with 400 INVALID_PARAM for the named input.
+func respondInvalidParam(c *gin.Context, field, msg string, extra ...gin.H) {
+	respondAPIError(c, http.StatusBadRequest, apiError{Code: CodeInvalidParam, Message: msg, Field: field}, extra...)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorEnvelope decodes an error response body.
+type errorEnvelope struct {
+	Error     apiError     `json:"error"`
+	RequestID string       `json:"request_id"`
+	Fields    []FieldError `json:"fields"`
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorEnvelope {
+	t.Helper()
+	var env errorEnvelope
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &env), w.Body.String())
+	return env
+}
+
+func TestErrorEnvelopeValidation(t *testing.T) {
+	router := setupRouter()
+	req, _ := http.NewRequest("POST", "/simulate?dry_run=true", bytes.NewBufferString(withSite(`{"ACH": -5}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	env := decodeError(t, w)
+	assert.Equal(t, apiError{Code: CodeInvalidParam, Message: "invalid parameters", Field: "ACH"}, env.Error)
+	assert.NotEmpty(t, env.RequestID)
+	require.Len(t, env.Fields, 1)
+
+	// query parameters name the field too
+	req, _ = http.NewRequest("GET", "/jobs?limit=0", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, apiError{Code: CodeInvalidParam, Message: "limit must be a positive integer", Field: "limit"}, decodeError(t, w).Error)
+
+	// a body that is not JSON at all is a request error, not a param error
+	req, _ = http.NewRequest("POST", "/simulate", bytes.NewBufferString(`{`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeInvalidRequest, decodeError(t, w).Error.Code)
+}
+
+func TestErrorEnvelopeNotFound(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	req, _ := http.NewRequest("GET", "/jobs/no-such-job", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+	assert.Equal(t, map[string]interface{}{"code": "NOT_FOUND", "message": "job not found"}, raw["error"])
+	assert.Contains(t, raw, "request_id")
+}
+
+func TestCodeForStatus(t *testing.T) {
+	assert.Equal(t, CodeInvalidRequest, codeForStatus(http.StatusBadRequest))
+	assert.Equal(t, CodeConflict, codeForStatus(http.StatusConflict))
+	assert.Equal(t, CodeRateLimited, codeForStatus(http.StatusTooManyRequests))
+	assert.Equal(t, CodeInternal, codeForStatus(http.StatusInternalServerError))
+	assert.Equal(t, CodeInternal, codeForStatus(http.StatusTeapot))
+}
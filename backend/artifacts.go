@@ -0,0 +1,156 @@
+package main
+
+// backend/artifacts.go
+//
+// Named result artifacts. Besides the main result document, the worker can
+// store further outputs of a run (e.g. "summary", "temperature", "energy")
+// under job_artifact:<id>:<name>, recording each name in the set
+// job_artifacts:<id>. Values are JSON, gzip-compressed like results.
+// GET /jobs/:job_id/artifacts lists them and GET /jobs/:job_id/artifacts/:name
+// returns one. The main result (job_result:<id>) is the "default" artifact, so
+// jobs stored before artifacts existed list and serve it too.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisArtifactPrefix keys job_artifact:<jobID>:<name> -> the artifact.
+const RedisArtifactPrefix = "job_artifact:"
+
+// RedisArtifactIndexPrefix keys job_artifacts:<jobID> -> set of artifact names.
+const RedisArtifactIndexPrefix = "job_artifacts:"
+
+// DefaultArtifact names the job's main result document.
+const DefaultArtifact = "default"
+
+// artifactNamePattern bounds names to something safe in a key and a URL.
+var artifactNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// artifactInfo describes one stored artifact.
+type artifactInfo struct {
+	Name      string `json:"name"`
+	SizeBytes int64  `json:"size_bytes"` // as stored, i.e. compressed
+}
+
+// artifactKeys returns every artifact key (index included) of each job, for deletion.
+func artifactKeys(ctx context.Context, jobIDs []string) ([]string, error) {
+	cmds := make([]*redis.StringSliceCmd, len(jobIDs))
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range jobIDs {
+			cmds[i] = pipe.SMembers(ctx, artifactIndexKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for i, id := range jobIDs {
+		keys = append(keys, artifactIndexKey(id))
+		for _, name := range cmds[i].Val() {
+			keys = append(keys, artifactKey(id, name))
+		}
+	}
+	return keys, nil
+}
+
+// listArtifacts returns jobID's stored artifacts sorted by name. Names left in
+// the index after their artifact expired are skipped.
+func listArtifacts(ctx context.Context, jobID string) ([]artifactInfo, error) {
+	names, err := rdb.SMembers(ctx, artifactIndexKey(jobID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	names = append(names, DefaultArtifact)
+	sizes := make([]*redis.IntCmd, len(names))
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, name := range names {
+			sizes[i] = pipe.StrLen(ctx, artifactKeyFor(jobID, name))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	artifacts := []artifactInfo{}
+	for i, name := range names {
+		if n := sizes[i].Val(); n > 0 {
+			artifacts = append(artifacts, artifactInfo{Name: name, SizeBytes: n})
+		}
+	}
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].Name < artifacts[j].Name })
+	return artifacts, nil
+}
+
+// artifactKeyFor maps DefaultArtifact to the job's result key.
+func artifactKeyFor(jobID, name string) string {
+	if name == DefaultArtifact {
+		return jobResultKey(jobID)
+	}
+	return artifactKey(jobID, name)
+}
+
+func listArtifactsHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	if _, err := (redisMetaStore{}).GetMeta(ctx, jobID); errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	artifacts, err := listArtifacts(ctx, jobID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job_id": jobID, "artifacts": artifacts})
+}
+
+func getArtifactHandler(c *gin.Context) {
+	jobID, name := c.Param("job_id"), c.Param("name")
+	if !artifactNamePattern.MatchString(name) {
+		respondInvalidParam(c, "name", "artifact names are up to 64 letters, digits or . _ - (starting with a letter or digit)")
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	if name == DefaultArtifact {
+		// same answers as GET /results/:job_id for a job without a result
+		res, ok := loadStoredResult(c, ctx, jobID)
+		if ok {
+			c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(res))
+		}
+		return
+	}
+	stored, err := rdb.Get(ctx, artifactKey(jobID, name)).Result()
+	if errors.Is(err, redis.Nil) {
+		respondError(c, http.StatusNotFound, "artifact not found", gin.H{"job_id": jobID, "name": name})
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	res, err := decodeResult(stored)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	contentType := "application/json; charset=utf-8"
+	if !json.Valid([]byte(res)) {
+		contentType = "application/octet-stream"
+	}
+	c.Data(http.StatusOK, contentType, []byte(res))
+}
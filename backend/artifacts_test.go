@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedArtifact stores a gzip-compressed artifact and indexes its name.
+func seedArtifact(t *testing.T, ctx context.Context, jobID, name, doc string) {
+	t.Helper()
+	b, err := encodeResult([]byte(doc))
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, artifactKey(jobID, name), b, DefaultResultTTL).Err())
+	require.NoError(t, rdb.SAdd(ctx, artifactIndexKey(jobID), name).Err())
+}
+
+func getArtifactPath(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestListArtifacts(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "art-job", StatusDone)
+	require.NoError(t, rdb.Set(ctx, jobResultKey("art-job"), sampleResult, DefaultResultTTL).Err())
+	seedArtifact(t, ctx, "art-job", "summary", `{"Tin_min": 10.5}`)
+	seedArtifact(t, ctx, "art-job", "energy", `{"data": [{"datetime": "2025-11-01T00:00:00", "Q_heater": 0}]}`)
+	// indexed but expired
+	require.NoError(t, rdb.SAdd(ctx, artifactIndexKey("art-job"), "temperature").Err())
+
+	w := getArtifactPath(router, "/jobs/art-job/artifacts")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Artifacts []artifactInfo `json:"artifacts"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	names := []string{}
+	for _, a := range resp.Artifacts {
+		names = append(names, a.Name)
+		assert.Positive(t, a.SizeBytes, a.Name)
+	}
+	assert.Equal(t, []string{DefaultArtifact, "energy", "summary"}, names)
+
+	// a job from before artifacts still lists its result as the default one
+	seedJobMeta(t, ctx, "legacy-job", StatusDone)
+	require.NoError(t, rdb.Set(ctx, jobResultKey("legacy-job"), sampleResult, DefaultResultTTL).Err())
+	w = getArtifactPath(router, "/jobs/legacy-job/artifacts")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Artifacts, 1)
+	assert.Equal(t, DefaultArtifact, resp.Artifacts[0].Name)
+
+	// queued: nothing stored yet
+	seedJobMeta(t, ctx, "queued-job", StatusQueued)
+	w = getArtifactPath(router, "/jobs/queued-job/artifacts")
+	assert.JSONEq(t, `{"job_id": "queued-job", "artifacts": []}`, w.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, getArtifactPath(router, "/jobs/no-such-job/artifacts").Code)
+}
+
+func TestGetArtifact(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "art-job", StatusDone)
+	require.NoError(t, rdb.Set(ctx, jobResultKey("art-job"), sampleResult, DefaultResultTTL).Err())
+	seedArtifact(t, ctx, "art-job", "summary", `{"Tin_min": 10.5}`)
+
+	w := getArtifactPath(router, "/jobs/art-job/artifacts/summary")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.JSONEq(t, `{"Tin_min": 10.5}`, w.Body.String())
+
+	w = getArtifactPath(router, "/jobs/art-job/artifacts/"+DefaultArtifact)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, sampleResult, w.Body.String())
+
+	w = getArtifactPath(router, "/jobs/art-job/artifacts/energy")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = getArtifactPath(router, "/jobs/art-job/artifacts/..bad")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// the default artifact of an unfinished job answers like GET /results/:job_id
+	seedJobMeta(t, ctx, "queued-job", StatusQueued)
+	w = getArtifactPath(router, "/jobs/queued-job/artifacts/"+DefaultArtifact)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestDeleteJobRemovesArtifacts(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "art-job", StatusDone)
+	seedArtifact(t, ctx, "art-job", "summary", `{"Tin_min": 10.5}`)
+
+	req, _ := http.NewRequest("DELETE", "/jobs/art-job", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), rdb.Exists(ctx, artifactKey("art-job", "summary"), artifactIndexKey("art-job")).Val())
+}
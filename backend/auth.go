@@ -0,0 +1,96 @@
+package main
+
+// backend/auth.go
+//
+// API key authentication. Valid keys come from the API_KEYS env var
+// (comma-separated) and/or the Redis set api_keys, so keys can be rotated
+// without a restart. Keys in ADMIN_API_KEYS are also valid and additionally
+// unlock admin-only routes such as the bulk purge.
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedisAPIKeysSet holds API keys that are valid in addition to API_KEYS.
+const RedisAPIKeysSet = "api_keys"
+
+// APIKeyHeader is the request header carrying the client's key.
+const APIKeyHeader = "X-API-Key"
+
+// ctxAPIKey is the gin context key holding the authenticated API key.
+const ctxAPIKey = "api_key"
+
+var (
+	// authEnabled can be switched off (AUTH_DISABLED=true, or directly in tests)
+	authEnabled = true
+	apiKeys     = map[string]bool{}
+	adminKeys   = map[string]bool{}
+)
+
+func loadAuthConfig() {
+	authEnabled = os.Getenv("AUTH_DISABLED") != "true"
+	apiKeys = parseKeyList(os.Getenv("API_KEYS"))
+	adminKeys = parseKeyList(os.Getenv("ADMIN_API_KEYS"))
+}
+
+// parseKeyList splits a comma-separated list of keys, dropping blanks.
+func parseKeyList(v string) map[string]bool {
+	keys := map[string]bool{}
+	for _, k := range strings.Split(v, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys[k] = true
+		}
+	}
+	return keys
+}
+
+// apiKeyAuth rejects requests without a valid X-API-Key header with 401.
+func apiKeyAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authEnabled {
+			c.Next()
+			return
+		}
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			respondError(c, http.StatusUnauthorized, "missing "+APIKeyHeader+" header")
+			return
+		}
+		if !validAPIKey(c.Request.Context(), key) {
+			respondError(c, http.StatusUnauthorized, "invalid API key")
+			return
+		}
+		c.Set(ctxAPIKey, key)
+		c.Next()
+	}
+}
+
+// adminOnly rejects keys not listed in ADMIN_API_KEYS with 403. It must run
+// after apiKeyAuth; with auth disabled every caller is let through.
+func adminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authEnabled && !adminKeys[c.GetString(ctxAPIKey)] {
+			respondError(c, http.StatusForbidden, "admin API key required")
+			return
+		}
+		c.Next()
+	}
+}
+
+func validAPIKey(ctx context.Context, key string) bool {
+	if apiKeys[key] || adminKeys[key] {
+		return true
+	}
+	if rdb == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(ctx, RedisOpTimeout)
+	defer cancel()
+	ok, err := rdb.SIsMember(ctx, redisKey(RedisAPIKeysSet), key).Result()
+	return err == nil && ok
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withAuth enables the API key middleware with the given static keys for one test.
+func withAuth(t *testing.T, keys ...string) {
+	t.Helper()
+	authEnabled = true
+	apiKeys = map[string]bool{}
+	for _, k := range keys {
+		apiKeys[k] = true
+	}
+	t.Cleanup(func() {
+		authEnabled = false
+		apiKeys = map[string]bool{}
+	})
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	router := setupRouter()
+	withAuth(t, "good-key")
+
+	tests := []struct {
+		name string
+		key  string
+		want int
+	}{
+		{"valid key", "good-key", http.StatusOK},
+		{"invalid key", "bad-key", http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// invalid query short-circuits before Redis, so this runs without it
+			req, _ := http.NewRequest("GET", "/jobs?limit=0", nil)
+			if tt.key != "" {
+				req.Header.Set(APIKeyHeader, tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if tt.want == http.StatusOK {
+				assert.NotEqual(t, http.StatusUnauthorized, w.Code)
+				return
+			}
+			assert.Equal(t, tt.want, w.Code)
+			assert.Contains(t, w.Body.String(), "error")
+		})
+	}
+}
+
+func TestAPIKeyAuthFromRedisSet(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	withAuth(t)
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.SAdd(ctx, RedisAPIKeysSet, "redis-key")
+
+	req, _ := http.NewRequest("GET", "/jobs", nil)
+	req.Header.Set(APIKeyHeader, "redis-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHealthBypassesAuth(t *testing.T) {
+	router := setupRouter()
+	withAuth(t, "good-key")
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLoadAuthConfig(t *testing.T) {
+	t.Setenv("API_KEYS", "a, b,,c")
+	t.Setenv("ADMIN_API_KEYS", "root")
+	t.Setenv("AUTH_DISABLED", "")
+	loadAuthConfig()
+	defer func() {
+		authEnabled = false
+		apiKeys = map[string]bool{}
+		adminKeys = map[string]bool{}
+	}()
+
+	assert.True(t, authEnabled)
+	assert.Equal(t, map[string]bool{"a": true, "b": true, "c": true}, apiKeys)
+	assert.Equal(t, map[string]bool{"root": true}, adminKeys)
+	assert.True(t, validAPIKey(context.Background(), "root"), "admin keys are valid API keys")
+}
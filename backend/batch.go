@@ -0,0 +1,139 @@
+package main
+
+// backend/batch.go
+//
+// POST /simulate/batch: submit many parameter sets in one call. Each set gets
+// defaults and validation like /simulate; valid ones are enqueued together in
+// one pipeline. With ?atomic=true a single invalid set rejects the whole batch.
+// With ?dry_run=true the resolved sets are returned and nothing is enqueued.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// batchItemError reports why the param set at Index was not enqueued.
+type batchItemError struct {
+	Index  int          `json:"index"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// batchItem is an accepted param set and the job created for it.
+type batchItem struct {
+	Index    int      `json:"index"`
+	JobID    string   `json:"job_id"`
+	Status   string   `json:"status"`
+	Warnings []string `json:"warnings,omitempty"` // from applyDefaults and validateParams
+}
+
+// dryRunItem is a param set as it would be enqueued, returned by ?dry_run=true.
+type dryRunItem struct {
+	Index    int              `json:"index"`
+	Params   SimulationParams `json:"params"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+func newBatchItemError(index int, err error) batchItemError {
+	item := batchItemError{Index: index, Error: err.Error()}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		item.Error = "invalid parameters"
+		item.Fields = verr.Fields
+	}
+	return item
+}
+
+func submitBatchHandler(c *gin.Context) {
+	var batch []SimulationParams
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, maxBatchBodyBytes)
+			return
+		}
+		respondError(c, http.StatusBadRequest, "invalid JSON: expected an array of simulation params: "+err.Error())
+		return
+	}
+	if len(batch) == 0 {
+		respondError(c, http.StatusBadRequest, "batch is empty")
+		return
+	}
+	atomic := c.Query("atomic") == "true"
+
+	batchID := uuid.NewString()
+	now := time.Now().UTC()
+	metas := make([]JobMeta, 0, len(batch))
+	indexes := make([]int, 0, len(batch))
+	warnings := make([][]string, 0, len(batch))
+	itemErrors := []batchItemError{}
+	allowExtreme := c.Query("allow_extreme") == "true"
+	for i := range batch {
+		params := batch[i]
+		units, err := toSI(&params)
+		if err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		if err := applyPreset(&params); err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		w := applyDefaults(&params)
+		implausible, err := validateParams(&params, allowExtreme)
+		if err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		w = append(w, implausible...)
+		meta := newJobMeta(params, now)
+		meta.BatchID = batchID
+		meta.Units = units
+		metas = append(metas, meta)
+		indexes = append(indexes, i)
+		warnings = append(warnings, w)
+	}
+
+	if len(metas) == 0 || (atomic && len(itemErrors) > 0) {
+		respondAPIError(c, http.StatusBadRequest, apiError{Code: CodeInvalidParam, Message: "batch rejected: invalid parameter sets"}, gin.H{"errors": itemErrors})
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		resolved := make([]dryRunItem, len(metas))
+		for i, meta := range metas {
+			resolved[i] = dryRunItem{Index: indexes[i], Params: meta.Params, Warnings: warnings[i]}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run": true,
+			"jobs":    resolved,
+			"errors":  itemErrors,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	if !claimJobQuota(c, ctx, metas) {
+		return
+	}
+	if err := enqueueJobs(ctx, metas); err != nil {
+		releaseJobQuota(c, ctx, metas)
+		respondError(c, http.StatusInternalServerError, "failed to enqueue batch: "+err.Error())
+		return
+	}
+
+	items := make([]batchItem, len(metas))
+	for i, meta := range metas {
+		items[i] = batchItem{Index: indexes[i], JobID: meta.JobID, Status: meta.Status, Warnings: warnings[i]}
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"batch_id": batchID,
+		"jobs":     items,
+		"errors":   itemErrors,
+	})
+}
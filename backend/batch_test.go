@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var mixedBatch = "[" + strings.Join([]string{
+	withSite(`{"setpoint": 10}`),
+	`{"ACH": -1}`,
+	withSite(`{"setpoint": 14}`),
+	`{"tau_glass": 3}`,
+}, ", ") + "]"
+
+type batchResponse struct {
+	BatchID string           `json:"batch_id"`
+	Jobs    []batchItem      `json:"jobs"`
+	Errors  []batchItemError `json:"errors"`
+}
+
+func postBatch(t *testing.T, router *gin.Engine, query, body string) (int, batchResponse) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/simulate/batch"+query, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp batchResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestBatchEnqueuesValidItems(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, resp := postBatch(t, router, "", mixedBatch)
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.NotEmpty(t, resp.BatchID)
+
+	require.Len(t, resp.Jobs, 2)
+	assert.Equal(t, 0, resp.Jobs[0].Index)
+	assert.Equal(t, 2, resp.Jobs[1].Index)
+	require.Len(t, resp.Errors, 2)
+	assert.Equal(t, 1, resp.Errors[0].Index)
+	assert.Equal(t, "ACH", resp.Errors[0].Fields[0].Field)
+	assert.Equal(t, 3, resp.Errors[1].Index)
+
+	n, err := rdb.LLen(ctx, RedisJobsList).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	metaStr, err := rdb.Get(ctx, RedisJobMetaPrefix+resp.Jobs[1].JobID).Result()
+	require.NoError(t, err)
+	var meta JobMeta
+	require.NoError(t, json.Unmarshal([]byte(metaStr), &meta))
+	assert.Equal(t, resp.BatchID, meta.BatchID)
+	assert.Equal(t, 14.0, *meta.Params.Setpoint)
+}
+
+func TestBatchAtomicRejectsWholeBatch(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, resp := postBatch(t, router, "?atomic=true", mixedBatch)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Empty(t, resp.Jobs)
+	assert.Len(t, resp.Errors, 2)
+
+	n, err := rdb.LLen(ctx, RedisJobsList).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestBatchAtomicAcceptsAllValid(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	code, resp := postBatch(t, router, "?atomic=true", "["+withSite(`{"setpoint": 10}`)+", "+withSite(`{"setpoint": 12}`)+"]")
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Len(t, resp.Jobs, 2)
+	assert.Empty(t, resp.Errors)
+}
+
+func TestBatchRejectsEmptyAndNonArray(t *testing.T) {
+	router := setupRouter()
+
+	for _, body := range []string{`[]`, `{"setpoint": 10}`} {
+		req, _ := http.NewRequest("POST", "/simulate/batch", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
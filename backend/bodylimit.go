@@ -0,0 +1,54 @@
+package main
+
+// backend/bodylimit.go
+//
+// Request body size limits for the submission endpoints, so an oversized POST is
+// rejected with 413 before it can be buffered and decoded.
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Body size defaults (bytes)
+const (
+	DefaultMaxBodyBytes      = 64 << 10 // POST /simulate
+	DefaultMaxBatchBodyBytes = 1 << 20  // POST /simulate/batch
+)
+
+var (
+	maxBodyBytes      int64 = DefaultMaxBodyBytes
+	maxBatchBodyBytes int64 = DefaultMaxBatchBodyBytes
+)
+
+func loadBodyLimitConfig() {
+	maxBodyBytes = int64(envInt("MAX_BODY_BYTES", DefaultMaxBodyBytes))
+	maxBatchBodyBytes = int64(envInt("MAX_BATCH_BODY_BYTES", DefaultMaxBatchBodyBytes))
+}
+
+// limitBody caps the request body at *limit bytes (read per request so config
+// and tests can change it). A declared Content-Length over the limit is rejected
+// up front; otherwise the body is wrapped so reads past the limit fail and the
+// handler reports it via isBodyTooLarge.
+func limitBody(limit *int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > *limit {
+			respondBodyTooLarge(c, *limit)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, *limit)
+		c.Next()
+	}
+}
+
+// isBodyTooLarge reports whether err came from reading past limitBody's cap.
+func isBodyTooLarge(err error) bool {
+	var mbe *http.MaxBytesError
+	return errors.As(err, &mbe)
+}
+
+func respondBodyTooLarge(c *gin.Context, limit int64) {
+	respondError(c, http.StatusRequestEntityTooLarge, "request body too large", gin.H{"max_bytes": limit})
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitRejectsOversizedBody(t *testing.T) {
+	router := setupRouter()
+	orig, origBatch := maxBodyBytes, maxBatchBodyBytes
+	maxBodyBytes, maxBatchBodyBytes = 64, 128
+	defer func() { maxBodyBytes, maxBatchBodyBytes = orig, origBatch }()
+
+	// valid JSON padded with whitespace past the limit
+	single := `{"A_glass": 50` + strings.Repeat(" ", 100) + `}`
+	batch := `[{}` + strings.Repeat(" ", 200) + `]`
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		chunked bool
+		max     int64
+	}{
+		{"single with content-length", "/simulate", single, false, 64},
+		{"single chunked", "/simulate", single, true, 64},
+		{"batch with content-length", "/simulate/batch", batch, false, 128},
+		{"batch chunked", "/simulate/batch", batch, true, 128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.chunked {
+				// unknown length: the limit is only hit while decoding
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			var response struct {
+				Error    apiError `json:"error"`
+				MaxBytes int64    `json:"max_bytes"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, apiError{Code: CodePayloadTooLarge, Message: "request body too large"}, response.Error)
+			assert.Equal(t, tt.max, response.MaxBytes)
+		})
+	}
+}
+
+func TestSubmitAcceptsBodyWithinLimit(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+	orig := maxBodyBytes
+	maxBodyBytes = 64
+	defer func() { maxBodyBytes = orig }()
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{"A_glass": 50}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+}
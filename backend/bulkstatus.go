@@ -0,0 +1,71 @@
+package main
+
+// backend/bulkstatus.go
+//
+// Bulk status lookup for dashboards. GET /jobs/status?ids=id1,id2,... returns
+// the status of every listed job from a single MGET instead of one
+// GET /jobs/:job_id per job. Ids with no meta (never existed, deleted or
+// expired) come back with "found": false and are listed under "missing";
+// they do not fail the request.
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxStatusJobIDs caps the ids accepted by one GET /jobs/status call.
+const MaxStatusJobIDs = 200
+
+// jobStatusEntry is one job in the GET /jobs/status response.
+type jobStatusEntry struct {
+	JobID     string     `json:"job_id"`
+	Found     bool       `json:"found"`
+	Status    string     `json:"status,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func bulkJobStatusHandler(c *gin.Context) {
+	ids := parseCompareIDs(c.Query("ids"))
+	if len(ids) == 0 {
+		respondInvalidParam(c, "ids", "ids must list at least one job id")
+		return
+	}
+	if len(ids) > MaxStatusJobIDs {
+		respondInvalidParam(c, "ids", "too many job ids", gin.H{"max_ids": MaxStatusJobIDs})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = jobMetaKey(id)
+	}
+	vals, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+
+	jobs := make([]jobStatusEntry, len(ids))
+	missing := []string{}
+	for i, v := range vals {
+		jobs[i].JobID = ids[i]
+		var meta JobMeta
+		s, ok := v.(string)
+		if !ok || json.Unmarshal([]byte(s), &meta) != nil {
+			missing = append(missing, ids[i])
+			continue
+		}
+		jobs[i].Found = true
+		jobs[i].Status = meta.Status
+		jobs[i].Error = meta.Error
+		jobs[i].UpdatedAt = &meta.UpdatedAt
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs, "missing": missing})
+}
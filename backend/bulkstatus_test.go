@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkJobStatus(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "job-a", StatusDone)
+	seedJobMeta(t, ctx, "job-b", StatusRunning)
+	rdb.Set(ctx, RedisJobMetaPrefix+"job-garbled", "{not json", DefaultResultTTL)
+
+	req, _ := http.NewRequest("GET", "/jobs/status?ids=job-b,missing-1,job-a,job-garbled,job-b", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp struct {
+		Jobs    []jobStatusEntry `json:"jobs"`
+		Missing []string         `json:"missing"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Jobs, 4, "duplicates are collapsed")
+	assert.Equal(t, "job-b", resp.Jobs[0].JobID)
+	assert.True(t, resp.Jobs[0].Found)
+	assert.Equal(t, StatusRunning, resp.Jobs[0].Status)
+	assert.NotNil(t, resp.Jobs[0].UpdatedAt)
+	assert.Equal(t, jobStatusEntry{JobID: "missing-1"}, resp.Jobs[1])
+	assert.Equal(t, StatusDone, resp.Jobs[2].Status)
+	assert.False(t, resp.Jobs[3].Found)
+	assert.Equal(t, []string{"missing-1", "job-garbled"}, resp.Missing)
+}
+
+func TestBulkJobStatusRejectsBadIDs(t *testing.T) {
+	router := setupRouter()
+	ids := make([]string, MaxStatusJobIDs+1)
+	for i := range ids {
+		ids[i] = "job-" + strconv.Itoa(i)
+	}
+	for _, query := range []string{"", "?ids=", "?ids=" + strings.Join(ids, ",")} {
+		req, _ := http.NewRequest("GET", "/jobs/status"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
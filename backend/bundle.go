@@ -0,0 +1,109 @@
+package main
+
+// backend/bundle.go
+//
+// GET /jobs/:job_id/bundle: a finished job as one zip file for archiving. It
+// holds params.json (the resolved params the job ran with), result.json (the
+// stored result as the worker wrote it), summary.json (as GET
+// /results/:job_id/summary) and logs.txt (the worker's log lines, when any
+// are left). Jobs without a result yet are answered with 409 and their status,
+// and a stored result that does not parse with 502, as GET /results/:job_id.
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MIMEZip is the content type of the bundle.
+const MIMEZip = "application/zip"
+
+// bundleEntry is one file in the bundle.
+type bundleEntry struct {
+	name string
+	data []byte
+}
+
+// jobBundleEntries collects the files of the bundle for meta's job, its stored
+// result res and the parsed r, in archive order.
+func jobBundleEntries(ctx context.Context, meta JobMeta, res string, r *SimulationResult) ([]bundleEntry, error) {
+	stats := summarizeResult(r)
+	stats.JobID = meta.JobID
+
+	params, err := json.MarshalIndent(meta.Params, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	summary, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	entries := []bundleEntry{
+		{"params.json", params},
+		{"result.json", []byte(res)},
+		{"summary.json", summary},
+	}
+
+	lines, err := rdb.LRange(ctx, jobLogsKey(meta.JobID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis error: %w", err)
+	}
+	if len(lines) > 0 {
+		entries = append(entries, bundleEntry{"logs.txt", []byte(strings.Join(lines, "\n") + "\n")})
+	}
+	return entries, nil
+}
+
+func getJobBundleHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	meta, err := metaStore.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondJobMissing(c, ctx, jobID, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	res, ok := loadStoredResult(c, ctx, jobID)
+	if !ok {
+		return
+	}
+	r, err := parseSimulationResult(res)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": jobID})
+		return
+	}
+	entries, err := jobBundleEntries(ctx, meta, res, r)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to build bundle: "+err.Error())
+		return
+	}
+
+	// everything is loaded; from here on the zip is streamed and errors can only be logged
+	c.Header("Content-Type", MIMEZip)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+".zip"))
+	c.Status(http.StatusOK)
+	zw := zip.NewWriter(c.Writer)
+	for _, e := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: meta.UpdatedAt})
+		if err == nil {
+			_, err = w.Write(e.data)
+		}
+		if err != nil {
+			loggerFrom(c).Warn("failed to write bundle", "job_id", jobID, "entry", e.name, "error", err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		loggerFrom(c).Warn("failed to write bundle", "job_id", jobID, "error", err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getBundle(t *testing.T, jobID string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/jobs/"+jobID+"/bundle", nil)
+	w := httptest.NewRecorder()
+	setupRouter().ServeHTTP(w, req)
+	return w
+}
+
+// zipEntries reads every file of a zip archive by name, in archive order.
+func zipEntries(t *testing.T, data []byte) ([]string, map[string]string) {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	var names []string
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		names = append(names, f.Name)
+		files[f.Name] = string(b)
+	}
+	return names, files
+}
+
+func TestGetJobBundle(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	now := time.Now().UTC()
+	meta := JobMeta{JobID: "bundle-job", Status: StatusDone, CreatedAt: now, UpdatedAt: now,
+		Params: SimulationParams{Setpoint: floatPtr(12), A_glass: floatPtr(80)}}
+	metaBytes, err := json.Marshal(meta)
+	require.NoError(t, err)
+	rdb.Set(ctx, jobMetaKey("bundle-job"), metaBytes, DefaultResultTTL)
+	rdb.Set(ctx, jobResultKey("bundle-job"), sampleResult, DefaultResultTTL)
+	rdb.RPush(ctx, jobLogsKey("bundle-job"), "2025-11-01T00:00:00Z INFO Processing job", "2025-11-01T00:00:01Z INFO Job complete")
+
+	w := getBundle(t, "bundle-job")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, MIMEZip, w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="bundle-job.zip"`, w.Header().Get("Content-Disposition"))
+
+	names, files := zipEntries(t, w.Body.Bytes())
+	assert.Equal(t, []string{"params.json", "result.json", "summary.json", "logs.txt"}, names)
+
+	var params SimulationParams
+	require.NoError(t, json.Unmarshal([]byte(files["params.json"]), &params))
+	assert.Equal(t, 80.0, *params.A_glass)
+	assert.JSONEq(t, sampleResult, files["result.json"])
+	var stats resultStats
+	require.NoError(t, json.Unmarshal([]byte(files["summary.json"]), &stats))
+	assert.Equal(t, "bundle-job", stats.JobID)
+	assert.Equal(t, 3, stats.Points)
+	assert.Equal(t, "2025-11-01T00:00:00Z INFO Processing job\n2025-11-01T00:00:01Z INFO Job complete\n", files["logs.txt"])
+
+	// without logs the entry is left out
+	rdb.Del(ctx, jobLogsKey("bundle-job"))
+	names, _ = zipEntries(t, getBundle(t, "bundle-job").Body.Bytes())
+	assert.Equal(t, []string{"params.json", "result.json", "summary.json"}, names)
+}
+
+func TestGetJobBundleNotReady(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "bundle-running", StatusRunning)
+
+	w := getBundle(t, "bundle-running")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"running"`)
+	assert.Equal(t, http.StatusNotFound, getBundle(t, "bundle-unknown").Code)
+}
+
+func TestGetJobBundleMalformedResult(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "bundle-bad", StatusDone)
+
+	for name, stored := range map[string]string{
+		"schema":   `{"data": [{"datetime": "2025-11-01T00:00:00", "Tin": "warm"}]}`,
+		"bad gzip": string(gzipMagic) + "not gzip",
+	} {
+		require.NoError(t, rdb.Set(ctx, jobResultKey("bundle-bad"), stored, DefaultResultTTL).Err())
+		w := getBundle(t, "bundle-bad")
+		assert.Equal(t, http.StatusBadGateway, w.Code, name)
+		assert.Contains(t, w.Body.String(), "malformed result from worker", name)
+		assert.NotEqual(t, MIMEZip, w.Header().Get("Content-Type"), name)
+	}
+}
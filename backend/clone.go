@@ -0,0 +1,99 @@
+package main
+
+// backend/clone.go
+//
+// POST /jobs/:job_id/clone: "adjust and compare". The body is a partial
+// SimulationParams patch merged over the original job's stored params; the
+// result goes through unit conversion, defaults and validation like a normal
+// submission and is enqueued as a new job pointing back via cloned_from.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func cloneJobHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	var patch SimulationParams
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, maxBodyBytes)
+			return
+		}
+		respondError(c, http.StatusBadRequest, "invalid JSON: expected a params patch: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	orig, err := metaStore.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+
+	// the stored params are SI, so only the patch needs converting
+	units := orig.Units
+	if patch.Units != "" {
+		if units, err = toSI(&patch); err != nil {
+			respondValidationError(c, err)
+			return
+		}
+	}
+	// a relative window in the patch replaces the original's concrete dates
+	if patch.RelativeStart != "" || patch.RelativeEnd != "" {
+		orig.Params.StartDate, orig.Params.EndDate = "", ""
+	}
+	params, err := overlayParams(orig.Params, patch)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to merge params: "+err.Error())
+		return
+	}
+	if err := applyPreset(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	warnings := applyDefaults(&params)
+	implausible, err := validateParams(&params, c.Query("allow_extreme") == "true")
+	if err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	warnings = append(warnings, implausible...)
+
+	meta := newJobMeta(params, time.Now().UTC())
+	meta.ClonedFrom = jobID
+	meta.Units = units
+	if patch.Tags == nil {
+		meta.Tags = orig.Tags
+	}
+	if !claimJobQuota(c, ctx, []JobMeta{meta}) {
+		return
+	}
+	if err := enqueueJobs(ctx, []JobMeta{meta}); err != nil {
+		releaseJobQuota(c, ctx, []JobMeta{meta})
+		respondError(c, http.StatusInternalServerError, "failed to enqueue job: "+err.Error())
+		return
+	}
+
+	links := jobLinks(meta.JobID)
+	c.Header("Location", links["result"])
+	resp := gin.H{
+		"job_id":      meta.JobID,
+		"status":      StatusQueued,
+		"cloned_from": jobID,
+		"links":       links,
+	}
+	if len(warnings) > 0 {
+		resp["warnings"] = warnings
+	}
+	c.JSON(http.StatusAccepted, resp)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneJobOverridesOneField(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, submitted := submitReuse(t, router, "/simulate",
+		`{"A_glass": 80, "setpoint": 12, "lat": 52.1, "lon": 5.2, "tags": ["trial"], "priority": "high"}`)
+	require.Equal(t, http.StatusAccepted, code)
+	origID := submitted["job_id"].(string)
+
+	code, cloned := submitReuse(t, router, "/jobs/"+origID+"/clone", `{"setpoint": 18}`)
+	require.Equal(t, http.StatusAccepted, code, cloned)
+	assert.Equal(t, origID, cloned["cloned_from"])
+	assert.Equal(t, StatusQueued, cloned["status"])
+	cloneID := cloned["job_id"].(string)
+	assert.NotEqual(t, origID, cloneID)
+
+	orig, err := redisMetaStore{}.GetMeta(ctx, origID)
+	require.NoError(t, err)
+	clone, err := redisMetaStore{}.GetMeta(ctx, cloneID)
+	require.NoError(t, err)
+
+	assert.Equal(t, origID, clone.ClonedFrom)
+	assert.Equal(t, 18.0, *clone.Params.Setpoint)
+	assert.Equal(t, orig.Tags, clone.Tags)
+	assert.Equal(t, orig.Priority, clone.Priority)
+	// apart from the override the params are identical
+	want := orig.Params
+	want.Setpoint = floatPtr(18)
+	assert.Equal(t, want, clone.Params)
+
+	n, err := rdb.LLen(ctx, queueFor(PriorityHigh)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestCloneJobImperialPatch(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, submitted := submitReuse(t, router, "/simulate", withSite(`{"V": 200}`))
+	require.Equal(t, http.StatusAccepted, code)
+
+	code, cloned := submitReuse(t, router, "/jobs/"+submitted["job_id"].(string)+"/clone", `{"units": "imperial", "setpoint": 64.4}`)
+	require.Equal(t, http.StatusAccepted, code, cloned)
+	clone, err := redisMetaStore{}.GetMeta(ctx, cloned["job_id"].(string))
+	require.NoError(t, err)
+	assert.InDelta(t, 18.0, *clone.Params.Setpoint, 1e-9)
+	assert.Equal(t, 200.0, *clone.Params.Volume, "stored SI values are not converted again")
+	assert.Equal(t, UnitsImperial, clone.Units)
+}
+
+func TestCloneJobRejects(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, submitted := submitReuse(t, router, "/simulate", withSite(`{}`))
+	require.Equal(t, http.StatusAccepted, code)
+	path := "/jobs/" + submitted["job_id"].(string) + "/clone"
+
+	code, resp := submitReuse(t, router, path, `{"tau_glass": 1.5}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "tau_glass", resp["fields"].([]interface{})[0].(map[string]interface{})["field"])
+
+	code, _ = submitReuse(t, router, path, `[1, 2]`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = submitReuse(t, router, "/jobs/no-such-job/clone", `{"setpoint": 18}`)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// only the original job was ever queued
+	n, err := rdb.LLen(ctx, RedisJobsList).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
@@ -0,0 +1,83 @@
+package main
+
+// backend/coerce.go
+//
+// Lenient decoding of numeric params. Form inputs in the frontend send numbers
+// as strings ("ACH": "0.5"), so SimulationParams accepts a JSON string for any
+// of its float fields as long as it parses as a finite number; an empty string
+// counts as not set. Anything else (e.g. "ACH": "high") fails decoding, naming
+// the field, so submit handlers answer 400 before validation runs.
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// numericParamFields holds the lowercased JSON names of SimulationParams'
+// *float64 fields (encoding/json matches keys case-insensitively, so we do too).
+var numericParamFields = func() map[string]bool {
+	fields := map[string]bool{}
+	floatPtr := reflect.TypeOf((*float64)(nil))
+	t := reflect.TypeOf(SimulationParams{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type != floatPtr {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		fields[strings.ToLower(name)] = true
+	}
+	return fields
+}()
+
+// coerceNumber converts a string-encoded number into its JSON number form;
+// "" becomes null.
+func coerceNumber(field, s string) (json.RawMessage, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return json.RawMessage("null"), nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("%s must be a number, got %q", field, s)
+	}
+	return json.RawMessage(strconv.FormatFloat(v, 'g', -1, 64)), nil
+}
+
+// UnmarshalJSON decodes p, coercing string-encoded numbers in its float fields.
+func (p *SimulationParams) UnmarshalJSON(data []byte) error {
+	// plain has the same fields but no UnmarshalJSON, so decoding it cannot recurse
+	type plain SimulationParams
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		// not an object (or null): let the plain decode report it
+		return json.Unmarshal(data, (*plain)(p))
+	}
+	coerced := false
+	for name, raw := range fields {
+		if !numericParamFields[strings.ToLower(name)] || len(raw) == 0 || raw[0] != '"' {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		num, err := coerceNumber(name, s)
+		if err != nil {
+			return err
+		}
+		fields[name] = num
+		coerced = true
+	}
+	if coerced {
+		var err error
+		if data, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(data, (*plain)(p))
+}
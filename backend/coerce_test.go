@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulationParamsCoercesNumericStrings(t *testing.T) {
+	var p SimulationParams
+	err := json.Unmarshal([]byte(`{"ACH": "0.5", "V": " 120 ", "setpoint": 18, "tau_glass": "", "start_date": "2025-11-01"}`), &p)
+	require.NoError(t, err)
+	require.NotNil(t, p.ACH)
+	assert.Equal(t, 0.5, *p.ACH)
+	assert.Equal(t, 120.0, *p.Volume)
+	assert.Equal(t, 18.0, *p.Setpoint)
+	assert.Nil(t, p.TauGlass, "an empty string leaves the field unset")
+	assert.Equal(t, "2025-11-01", p.StartDate, "string fields are untouched")
+
+	for _, body := range []string{`{"ACH": "high"}`, `{"ACH": "NaN"}`, `{"setpoint": "1e999"}`} {
+		err = json.Unmarshal([]byte(body), &SimulationParams{})
+		assert.ErrorContains(t, err, "must be a number", body)
+	}
+
+	// string fields keep rejecting numbers
+	err = json.Unmarshal([]byte(`{"model": 3}`), &SimulationParams{})
+	assert.Error(t, err)
+}
+
+func TestSubmitJobNumericStrings(t *testing.T) {
+	router := setupRouter()
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/simulate?dry_run=true", withSite(`{"ACH": "0.5", "setpoint": "16"}`))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Params SimulationParams `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 0.5, *resp.Params.ACH)
+	assert.Equal(t, 16.0, *resp.Params.Setpoint)
+
+	w = post("/simulate?dry_run=true", `{"ACH": "lots"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `ACH must be a number, got \"lots\"`)
+
+	// out-of-range values are still caught by validation after coercion
+	w = post("/simulate?dry_run=true", `{"ACH": "-5"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"ACH"`)
+}
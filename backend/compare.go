@@ -0,0 +1,246 @@
+package main
+
+// backend/compare.go
+//
+// GET /compare diffs finished jobs against the first one listed (the baseline).
+// Time series are aligned on their datetime stamps; only timesteps present in
+// every job are compared.
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// comparePoint is the subset of a result timestep used by /compare.
+type comparePoint struct {
+	Datetime string   `json:"datetime"`
+	Tin      *float64 `json:"Tin"`
+	QHeater  *float64 `json:"Q_heater"`
+}
+
+// compareRun is the series of one job and the timestep it ran at, in seconds.
+type compareRun struct {
+	points   []comparePoint
+	timestep float64
+}
+
+// compareStep holds one aligned timestep; deltas are keyed by job id and
+// measured against the baseline.
+type compareStep struct {
+	Datetime     string              `json:"datetime"`
+	Tin          map[string]*float64 `json:"Tin"`
+	TinDelta     map[string]*float64 `json:"Tin_delta"`
+	QHeaterDelta map[string]*float64 `json:"Q_heater_delta"`
+}
+
+// compareSummary aggregates one job's deltas from the baseline.
+type compareSummary struct {
+	TinDeltaMin       *float64 `json:"Tin_delta_min"`
+	TinDeltaMax       *float64 `json:"Tin_delta_max"`
+	TinDeltaMean      *float64 `json:"Tin_delta_mean"`
+	HeaterTotalDeltaJ float64  `json:"Heater_total_delta_J"`
+}
+
+// parseCompareIDs splits ?jobs= into distinct ids, preserving order.
+func parseCompareIDs(v string) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, id := range strings.Split(v, ",") {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// compareSeries aligns the runs of ids (baseline first) and computes the
+// per-timestep deltas and per-job summaries. Heater deltas are integrated into
+// joules over the spacing of the aligned steps: the coarser of the two jobs'
+// timesteps.
+func compareSeries(ids []string, runs map[string]compareRun) ([]compareStep, map[string]compareSummary) {
+	byTime := make(map[string]map[string]comparePoint, len(ids))
+	for _, id := range ids {
+		m := make(map[string]comparePoint, len(runs[id].points))
+		for _, p := range runs[id].points {
+			m[p.Datetime] = p
+		}
+		byTime[id] = m
+	}
+
+	base := ids[0]
+	steps := []compareStep{}
+	type acc struct {
+		min, max, sum float64
+		n             int
+		heater        float64
+	}
+	accs := make(map[string]*acc, len(ids)-1)
+	for _, id := range ids[1:] {
+		accs[id] = &acc{}
+	}
+
+	for _, bp := range runs[base].points {
+		points := make(map[string]comparePoint, len(ids))
+		aligned := true
+		for _, id := range ids {
+			p, ok := byTime[id][bp.Datetime]
+			if !ok {
+				aligned = false
+				break
+			}
+			points[id] = p
+		}
+		if !aligned {
+			continue
+		}
+
+		step := compareStep{
+			Datetime:     bp.Datetime,
+			Tin:          map[string]*float64{},
+			TinDelta:     map[string]*float64{},
+			QHeaterDelta: map[string]*float64{},
+		}
+		step.Tin[base] = bp.Tin
+		for _, id := range ids[1:] {
+			p := points[id]
+			a := accs[id]
+			step.Tin[id] = p.Tin
+			step.TinDelta[id] = diff(p.Tin, bp.Tin)
+			step.QHeaterDelta[id] = diff(p.QHeater, bp.QHeater)
+			if d := step.TinDelta[id]; d != nil {
+				if a.n == 0 || *d < a.min {
+					a.min = *d
+				}
+				if a.n == 0 || *d > a.max {
+					a.max = *d
+				}
+				a.sum += *d
+				a.n++
+			}
+			if d := step.QHeaterDelta[id]; d != nil {
+				a.heater += *d * max(runs[id].timestep, runs[base].timestep)
+			}
+		}
+		steps = append(steps, step)
+	}
+
+	summary := make(map[string]compareSummary, len(accs))
+	for id, a := range accs {
+		s := compareSummary{HeaterTotalDeltaJ: a.heater}
+		if a.n > 0 {
+			mean := a.sum / float64(a.n)
+			s.TinDeltaMin, s.TinDeltaMax, s.TinDeltaMean = &a.min, &a.max, &mean
+		}
+		summary[id] = s
+	}
+	return steps, summary
+}
+
+// diff returns a-b, or nil when either side is missing.
+func diff(a, b *float64) *float64 {
+	if a == nil || b == nil {
+		return nil
+	}
+	d := *a - *b
+	return &d
+}
+
+// decodeCompareRun reads the series of a stored result and the timestep from
+// the params the worker echoed into it.
+func decodeCompareRun(stored string) (compareRun, error) {
+	var res struct {
+		Data   []comparePoint `json:"data"`
+		Params struct {
+			TimestepSeconds *float64 `json:"timestep_seconds"`
+		} `json:"params"`
+	}
+	doc, err := decodeResult(stored)
+	if err != nil {
+		return compareRun{}, err
+	}
+	if err := json.Unmarshal([]byte(doc), &res); err != nil {
+		return compareRun{}, err
+	}
+	return compareRun{points: res.Data, timestep: timestepSeconds(res.Params.TimestepSeconds)}, nil
+}
+
+// compareJobsHandler serves GET /compare?jobs=id1,id2,... The first id is the
+// baseline. Every job must be done; otherwise 409 lists the ones that are not.
+func compareJobsHandler(c *gin.Context) {
+	ids := parseCompareIDs(c.Query("jobs"))
+	if len(ids) < 2 {
+		respondInvalidParam(c, "jobs", "jobs must list at least two distinct job ids")
+		return
+	}
+	if len(ids) > maxCompareJobs {
+		respondInvalidParam(c, "jobs", "too many jobs to compare", gin.H{"max_jobs": maxCompareJobs})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = jobResultKey(id)
+	}
+	vals, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+
+	var missing []string
+	for i, v := range vals {
+		if _, ok := v.(string); !ok {
+			missing = append(missing, ids[i])
+		}
+	}
+	if len(missing) > 0 {
+		metas, err := loadMetas(ctx, missing)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+			return
+		}
+		statuses := make(map[string]string, len(metas))
+		for _, m := range metas {
+			statuses[m.JobID] = m.Status
+		}
+		notReady := make([]gin.H, 0, len(missing))
+		for _, id := range missing {
+			status, ok := statuses[id]
+			if !ok {
+				status = "not_found"
+			}
+			notReady = append(notReady, gin.H{"job_id": id, "status": status})
+		}
+		respondError(c, http.StatusConflict, "not all jobs are done", gin.H{"not_ready": notReady})
+		return
+	}
+
+	runs := make(map[string]compareRun, len(ids))
+	for i, v := range vals {
+		run, err := decodeCompareRun(v.(string))
+		if err != nil {
+			respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": ids[i]})
+			return
+		}
+		runs[ids[i]] = run
+	}
+
+	steps, summary := compareSeries(ids, runs)
+	c.JSON(http.StatusOK, gin.H{
+		"baseline":  ids[0],
+		"jobs":      ids,
+		"timesteps": len(steps),
+		"summary":   summary,
+		"series":    steps,
+	})
+}
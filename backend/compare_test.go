@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	baselineResult = `{"data": [
+		{"datetime": "2025-11-01T00:00:00", "Tin": 12.0, "Q_heater": 1000},
+		{"datetime": "2025-11-01T01:00:00", "Tin": 11.0, "Q_heater": 2000},
+		{"datetime": "2025-11-01T02:00:00", "Tin": 10.0, "Q_heater": 3000}
+	]}`
+	// insulated variant: warmer and cheaper, and missing the first timestep
+	modifiedResult = `{"data": [
+		{"datetime": "2025-11-01T01:00:00", "Tin": 13.5, "Q_heater": 1500},
+		{"datetime": "2025-11-01T02:00:00", "Tin": 10.5, "Q_heater": 2000}
+	]}`
+)
+
+// compareResponse mirrors the /compare body.
+type compareResponse struct {
+	Baseline  string                    `json:"baseline"`
+	Timesteps int                       `json:"timesteps"`
+	Summary   map[string]compareSummary `json:"summary"`
+	Series    []compareStep             `json:"series"`
+	NotReady  []map[string]string       `json:"not_ready"`
+}
+
+func getCompare(t *testing.T, router http.Handler, query string) (int, compareResponse) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/compare"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response compareResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestCompareJobs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"base", baselineResult, DefaultResultTTL)
+	rdb.Set(ctx, RedisResultsPrefix+"mod", gzipString(t, modifiedResult), DefaultResultTTL)
+
+	code, response := getCompare(t, router, "?jobs=base,mod")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "base", response.Baseline)
+	assert.Equal(t, 2, response.Timesteps)
+	require.Len(t, response.Series, 2)
+
+	step := response.Series[0]
+	assert.Equal(t, "2025-11-01T01:00:00", step.Datetime)
+	assert.InDelta(t, 11.0, *step.Tin["base"], 1e-9)
+	assert.InDelta(t, 2.5, *step.TinDelta["mod"], 1e-9)
+	assert.InDelta(t, -500, *step.QHeaterDelta["mod"], 1e-9)
+	assert.InDelta(t, 0.5, *response.Series[1].TinDelta["mod"], 1e-9)
+
+	s := response.Summary["mod"]
+	assert.InDelta(t, 0.5, *s.TinDeltaMin, 1e-9)
+	assert.InDelta(t, 2.5, *s.TinDeltaMax, 1e-9)
+	assert.InDelta(t, 1.5, *s.TinDeltaMean, 1e-9)
+	assert.InDelta(t, -1500*3600, s.HeaterTotalDeltaJ, 1e-9, "watts over hourly steps")
+	assert.NotContains(t, response.Summary, "base")
+}
+
+func TestCompareJobsNotReady(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"base", baselineResult, DefaultResultTTL)
+	seedJobMeta(t, ctx, "pending", StatusRunning)
+
+	code, response := getCompare(t, router, "?jobs=base,pending,ghost")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, []map[string]string{
+		{"job_id": "pending", "status": StatusRunning},
+		{"job_id": "ghost", "status": "not_found"},
+	}, response.NotReady)
+}
+
+func TestCompareJobsMalformedResult(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"base", baselineResult, DefaultResultTTL)
+	rdb.Set(ctx, RedisResultsPrefix+"bad", "not json", DefaultResultTTL)
+
+	req, _ := http.NewRequest("GET", "/compare?jobs=base,bad", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response["error"].(map[string]interface{})["message"], "malformed result from worker")
+	assert.Equal(t, "bad", response["job_id"])
+}
+
+func TestCompareJobsRejectsBadQuery(t *testing.T) {
+	router := setupRouter()
+	orig := maxCompareJobs
+	maxCompareJobs = 3
+	defer func() { maxCompareJobs = orig }()
+
+	for _, query := range []string{"", "?jobs=only-one", "?jobs=a,a", "?jobs=a,b,c,d"} {
+		code, _ := getCompare(t, router, query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestCompareSeriesHeaterEnergy(t *testing.T) {
+	base, err := decodeCompareRun(`{"params": {"timestep_seconds": 900}, "data": [
+		{"datetime": "2025-11-01T00:00:00", "Tin": 12.0, "Q_heater": 1000},
+		{"datetime": "2025-11-01T00:15:00", "Tin": 12.0, "Q_heater": 1000}
+	]}`)
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, base.timestep)
+	mod, err := decodeCompareRun(`{"params": {"timestep_seconds": 900}, "data": [
+		{"datetime": "2025-11-01T00:00:00", "Tin": 12.0, "Q_heater": 1200},
+		{"datetime": "2025-11-01T00:15:00", "Tin": 12.0, "Q_heater": 1400}
+	]}`)
+	require.NoError(t, err)
+
+	_, summary := compareSeries([]string{"base", "mod"}, map[string]compareRun{"base": base, "mod": mod})
+	assert.InDelta(t, (200+400)*900, summary["mod"].HeaterTotalDeltaJ, 1e-9)
+
+	// against an hourly baseline the aligned steps are an hour apart
+	hourly, err := decodeCompareRun(baselineResult)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultTimestepSeconds, hourly.timestep)
+	_, summary = compareSeries([]string{"hourly", "mod"}, map[string]compareRun{"hourly": hourly, "mod": mod})
+	assert.InDelta(t, 200*3600, summary["mod"].HeaterTotalDeltaJ, 1e-9)
+}
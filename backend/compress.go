@@ -0,0 +1,38 @@
+package main
+
+// backend/compress.go
+//
+// gzip response compression for the endpoints that return large JSON (results
+// and job listings). Clients opt in with Accept-Encoding: gzip. The SSE stream
+// is never compressed: the gzip writer would buffer events instead of
+// flushing them as they happen. Nor is the zip bundle, already compressed.
+
+import (
+	"strings"
+
+	"github.com/gin-contrib/gzip"
+	"github.com/gin-gonic/gin"
+)
+
+// compressedRoutePrefixes select routes (by their registered pattern) to compress.
+var compressedRoutePrefixes = []string{"/results", "/jobs"}
+
+// uncompressedRoutes are excluded even when they match a prefix.
+var uncompressedRoutes = map[string]bool{"/jobs/:job_id/events": true, "/jobs/:job_id/bundle": true}
+
+// compressResponses gzips responses of the routes selected above.
+func compressResponses() gin.HandlerFunc {
+	gz := gzip.Gzip(gzip.DefaultCompression)
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if uncompressedRoutes[route] {
+			return
+		}
+		for _, prefix := range compressedRoutePrefixes {
+			if strings.HasPrefix(route, prefix) {
+				gz(c)
+				return
+			}
+		}
+	}
+}
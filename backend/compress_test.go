@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getWithEncoding(router *gin.Engine, path, encoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if encoding != "" {
+		req.Header.Set("Accept-Encoding", encoding)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	require.NoError(t, err)
+	out, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return string(out)
+}
+
+func TestResponseCompression(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "gz-job", StatusDone)
+	require.NoError(t, rdb.Set(ctx, RedisResultsPrefix+"gz-job", sampleResult, 0).Err())
+
+	for _, path := range []string{"/results/gz-job", "/results/gz-job/csv", "/jobs", "/jobs/gz-job"} {
+		t.Run(path, func(t *testing.T) {
+			plain := getWithEncoding(router, path, "")
+			require.Equal(t, http.StatusOK, plain.Code)
+			assert.Empty(t, plain.Header().Get("Content-Encoding"))
+
+			compressed := getWithEncoding(router, path, "gzip, deflate")
+			require.Equal(t, http.StatusOK, compressed.Code)
+			assert.Equal(t, "gzip", compressed.Header().Get("Content-Encoding"))
+			assert.Contains(t, compressed.Header().Get("Vary"), "Accept-Encoding")
+			assert.Equal(t, plain.Body.String(), gunzip(t, compressed.Body.Bytes()))
+		})
+	}
+}
+
+func TestResponseCompressionSkipsEventsAndOtherRoutes(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "gz-events", StatusDone)
+
+	// the job is already terminal, so the stream ends after its first event
+	w := getWithEncoding(router, "/jobs/gz-events/events", "gzip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), "event:")
+
+	w = getWithEncoding(router, "/presets", "gzip")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
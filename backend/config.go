@@ -0,0 +1,113 @@
+package main
+
+// backend/config.go
+//
+// Runtime settings read from the environment at startup. Package-level values
+// hold the defaults so tests can use (or override) them without calling loadConfig.
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Configuration defaults
+const (
+	DefaultMaxSimulationDays = 366                 // longest start_date..end_date span accepted
+	DefaultShutdownGrace     = 15 * time.Second    // time given to in-flight requests on SIGTERM
+	DefaultMaxResultTTL      = 90 * 24 * time.Hour // upper bound for a job's result_ttl_seconds
+	DefaultMaxCompareJobs    = 10                  // most jobs accepted by one /compare call
+	DefaultMaxPollInterval   = 30 * time.Second    // cap on the Retry-After hint for pending results
+	DefaultTimestepSeconds   = 3600.0              // worker step when timestep_seconds is omitted
+	DefaultMaxTimesteps      = 10000               // most steps a start_date..end_date span may imply
+)
+
+var (
+	maxSimulationDays = DefaultMaxSimulationDays
+	shutdownGrace     = DefaultShutdownGrace
+	maxResultTTL      = DefaultMaxResultTTL
+	maxCompareJobs    = DefaultMaxCompareJobs
+	maxPollInterval   = DefaultMaxPollInterval
+	maxTimesteps      = DefaultMaxTimesteps
+	// apiBaseURL prefixes links returned to clients (e.g. when behind a reverse proxy)
+	apiBaseURL = ""
+)
+
+// loadConfig reads optional overrides from the environment.
+func loadConfig() {
+	maxSimulationDays = envInt("MAX_SIMULATION_DAYS", DefaultMaxSimulationDays)
+	maxTimesteps = envInt("MAX_TIMESTEPS", DefaultMaxTimesteps)
+	apiBaseURL = strings.TrimRight(os.Getenv("API_BASE_URL"), "/")
+	shutdownGrace = envSeconds("SHUTDOWN_GRACE_SECONDS", DefaultShutdownGrace)
+	idempotencyTTL = envSeconds("IDEMPOTENCY_TTL_SECONDS", DefaultIdempotencyTTL)
+	maxResultTTL = envSeconds("MAX_RESULT_TTL_SECONDS", DefaultMaxResultTTL)
+	maxCompareJobs = envInt("COMPARE_MAX_JOBS", DefaultMaxCompareJobs)
+	maxPollInterval = envSeconds("POLL_MAX_INTERVAL_SECONDS", DefaultMaxPollInterval)
+	maxSweepCombinations = envInt("SWEEP_MAX_COMBINATIONS", DefaultMaxSweepCombinations)
+	maxSearchScan = envInt("SEARCH_MAX_SCAN", DefaultMaxSearchScan)
+	loadAuthConfig()
+	loadRateLimitConfig()
+	loadBodyLimitConfig()
+	loadRedisConfig()
+	loadStaleJobConfig()
+	loadQuotaConfig()
+	loadWaitConfig()
+	loadUploadConfig()
+	loadShareConfig()
+	loadInternalConfig()
+	loadTemperatureBandConfig()
+	loadWeatherConfig()
+	loadRequestTimeoutConfig()
+	loadKeyPrefixConfig()
+	loadSchemaConfig()
+	loadDedupConfig()
+	loadLoggingConfig()
+	loadQueueConfig()
+	loadExpiryConfig()
+	loadRecentJobsConfig()
+}
+
+// envInt returns the integer value of name, or def if unset or malformed.
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		slog.Warn("ignoring invalid environment value", "name", name, "value", v, "error", err)
+		return def
+	}
+	return n
+}
+
+// envFloat returns the numeric value of name, or def if unset or malformed.
+func envFloat(name string, def float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		slog.Warn("ignoring invalid environment value", "name", name, "value", v, "error", err)
+		return def
+	}
+	return f
+}
+
+// envSeconds reads name as a whole number of seconds, or returns def.
+func envSeconds(name string, def time.Duration) time.Duration {
+	return time.Duration(envInt(name, int(def/time.Second))) * time.Second
+}
+
+// envMillis reads name as a whole number of milliseconds, or returns def.
+func envMillis(name string, def time.Duration) time.Duration {
+	return time.Duration(envInt(name, int(def/time.Millisecond))) * time.Millisecond
+}
+
+// apiURL returns path prefixed with the configured API_BASE_URL.
+func apiURL(path string) string {
+	return apiBaseURL + path
+}
@@ -0,0 +1,56 @@
+package main
+
+// backend/cors.go
+//
+// CORS settings. Allowed origins come from CORS_ALLOWED_ORIGINS
+// (comma-separated) and default to the local frontend dev server. "*" allows
+// any origin; credentials are then disabled, since browsers reject a wildcard
+// Access-Control-Allow-Origin on credentialed requests.
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+)
+
+// DefaultCORSOrigins are used when CORS_ALLOWED_ORIGINS is unset.
+var DefaultCORSOrigins = []string{"http://localhost:3000", "http://127.0.0.1:3000"}
+
+// parseCORSOrigins splits a comma-separated origin list, dropping blanks and
+// trailing slashes. An empty list yields DefaultCORSOrigins.
+func parseCORSOrigins(raw string) []string {
+	var origins []string
+	for _, o := range strings.Split(raw, ",") {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	if len(origins) == 0 {
+		return DefaultCORSOrigins
+	}
+	return origins
+}
+
+// corsConfig builds the middleware config for raw (the CORS_ALLOWED_ORIGINS
+// value). It returns an error for origins without an http(s) scheme rather
+// than letting cors.New panic.
+func corsConfig(raw string) (cors.Config, error) {
+	cfg := cors.Config{
+		AllowMethods:     []string{"GET", "POST", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "If-None-Match", APIKeyHeader, RequestIDHeader, IdempotencyKeyHeader, "traceparent", "tracestate"},
+		ExposeHeaders:    []string{RequestIDHeader, "ETag"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}
+	origins := parseCORSOrigins(raw)
+	for _, o := range origins {
+		if o == "*" {
+			cfg.AllowAllOrigins = true
+			cfg.AllowCredentials = false
+			return cfg, cfg.Validate()
+		}
+	}
+	cfg.AllowOrigins = origins
+	return cfg, cfg.Validate()
+}
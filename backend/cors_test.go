@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// corsRouter serves /health behind the CORS middleware configured from the environment.
+func corsRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg, err := corsConfig(os.Getenv("CORS_ALLOWED_ORIGINS"))
+	require.NoError(t, err)
+	router := gin.New()
+	router.Use(cors.New(cfg))
+	router.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	return router
+}
+
+func getWithOrigin(router http.Handler, origin string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/health", nil)
+	req.Header.Set("Origin", origin)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSConfiguredOrigins(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://greensim.example.com, https://staging.example.com/")
+	router := corsRouter(t)
+
+	w := getWithOrigin(router, "https://greensim.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://greensim.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = getWithOrigin(router, "https://staging.example.com")
+	assert.Equal(t, "https://staging.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = getWithOrigin(router, "http://localhost:3000")
+	assert.Equal(t, http.StatusForbidden, w.Code, "defaults are replaced, not extended")
+}
+
+func TestCORSDefaultsAndWildcard(t *testing.T) {
+	t.Run("unset", func(t *testing.T) {
+		t.Setenv("CORS_ALLOWED_ORIGINS", "")
+		w := getWithOrigin(corsRouter(t), "http://localhost:3000")
+		assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+		w := getWithOrigin(corsRouter(t), "https://anywhere.example.org")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+
+	t.Run("invalid origin", func(t *testing.T) {
+		_, err := corsConfig("greensim.example.com")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+// backend/dedup.go
+//
+// Accidental resubmission guard. A submission takes dedup:<hash> (SET NX,
+// value = job id) for dedupWindow, where the hash covers the client and the
+// params as submitted. While the key lives, the same client sending the same
+// params gets the first job back with 200 and "deduplicated": true instead of
+// a second job: a double-clicked button or a reloaded GET /simulate link runs
+// once. Unlike ?reuse=true this needs no finished job and no opt-in; it is
+// skipped with ?dedup=false, with an Idempotency-Key (which already answers
+// retries), and when DEDUP_WINDOW_SECONDS is 0.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisDedupPrefix keys dedup:<hash> -> id of the job submitted with those params.
+const RedisDedupPrefix = "dedup:"
+
+// DefaultDedupWindow is how long an identical submission is folded into the first.
+const DefaultDedupWindow = 30 * time.Second
+
+var dedupWindow = DefaultDedupWindow
+
+func loadDedupConfig() {
+	dedupWindow = envSeconds("DEDUP_WINDOW_SECONDS", DefaultDedupWindow)
+}
+
+// dedupHash identifies a submission by its client and params. Params are
+// hashed before a seed is picked, so omitting the seed twice still matches.
+func dedupHash(c *gin.Context, p *SimulationParams) string {
+	sum := sha256.Sum256([]byte(rateLimitClient(c) + "\n" + paramsHash(p)))
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupEnabled reports whether the submission in c is checked for duplicates.
+func dedupEnabled(c *gin.Context) bool {
+	return dedupWindow > 0 && c.Query("dedup") != "false" && c.GetHeader(IdempotencyKeyHeader) == ""
+}
+
+// claimDedup records jobID under hash for dedupWindow. When another job holds
+// it, ok is false and holder is that job. A key left by a job that no longer
+// exists is taken over.
+func claimDedup(ctx context.Context, hash, jobID string) (holder string, ok bool, err error) {
+	key := dedupKey(hash)
+	for attempt := 0; attempt < 2; attempt++ {
+		ok, err = rdb.SetNX(ctx, key, jobID, dedupWindow).Result()
+		if err != nil || ok {
+			return "", ok, err
+		}
+		holder, err = rdb.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue // expired between SETNX and GET
+		} else if err != nil {
+			return "", false, err
+		}
+		n, err := rdb.Exists(ctx, jobMetaKey(holder)).Result()
+		if err != nil {
+			return "", false, err
+		}
+		if n > 0 {
+			return holder, false, nil
+		}
+		if err := releaseDedup(ctx, hash, holder); err != nil {
+			return "", false, err
+		}
+	}
+	return holder, false, nil
+}
+
+// releaseDedup frees hash if jobID holds it, for submissions that fail after
+// claiming it.
+func releaseDedup(ctx context.Context, hash, jobID string) error {
+	// the same compare-and-delete as the greenhouse lock
+	return releaseActiveScript.Run(ctx, rdb, []string{dedupKey(hash)}, jobID).Err()
+}
+
+// respondDeduplicated answers a duplicate submission with the job it repeats.
+func respondDeduplicated(c *gin.Context, ctx context.Context, jobID string) {
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	if err != nil && !errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	status := meta.Status
+	if status == "" {
+		status = StatusQueued // deleted since; report it as submitted
+	}
+	links := jobLinks(jobID)
+	c.Header("Location", links["result"])
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":       jobID,
+		"status":       status,
+		"links":        links,
+		"deduplicated": true,
+	})
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dedupBody = `{"A_glass": 80, "setpoint": 14, "lat": 52.1, "lon": 5.2, "start_date": "2025-11-01", "end_date": "2025-11-03"}`
+
+func submitDedup(t *testing.T, router http.Handler, key, query string) (int, map[string]interface{}) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/simulate"+query, bytes.NewBufferString(dedupBody))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestSubmitDeduplicatesRapidResubmission(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	dedupWindow = 30 * time.Second
+	defer func() { dedupWindow = 0 }()
+
+	code, first := submitDedup(t, router, "", "")
+	require.Equal(t, http.StatusAccepted, code, first)
+	assert.Nil(t, first["deduplicated"])
+
+	code, second := submitDedup(t, router, "", "")
+	require.Equal(t, http.StatusOK, code, second)
+	assert.Equal(t, true, second["deduplicated"])
+	assert.Equal(t, first["job_id"], second["job_id"])
+	assert.Equal(t, StatusQueued, second["status"])
+	assert.Equal(t, int64(1), rdb.LLen(ctx, queueFor(PriorityNormal)).Val(), "only one job was queued")
+
+	// opting out, or another client, gets a job of its own
+	code, _ = submitDedup(t, router, "", "?dedup=false")
+	assert.Equal(t, http.StatusAccepted, code)
+	withAuth(t, "key-a", "key-b")
+	code, a := submitDedup(t, router, "key-a", "")
+	assert.Equal(t, http.StatusAccepted, code)
+	code, b := submitDedup(t, router, "key-b", "")
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.NotEqual(t, a["job_id"], b["job_id"])
+}
+
+func TestSubmitDedupWindowExpires(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	dedupWindow = time.Second
+	defer func() { dedupWindow = 0 }()
+
+	code, first := submitDedup(t, router, "", "")
+	require.Equal(t, http.StatusAccepted, code, first)
+
+	time.Sleep(1100 * time.Millisecond)
+	code, second := submitDedup(t, router, "", "")
+	require.Equal(t, http.StatusAccepted, code, second)
+	assert.NotEqual(t, first["job_id"], second["job_id"])
+	assert.Equal(t, int64(2), rdb.LLen(ctx, queueFor(PriorityNormal)).Val())
+}
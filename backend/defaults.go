@@ -0,0 +1,100 @@
+package main
+
+// backend/defaults.go
+//
+// Values applyDefaults fills in for missing physics fields. A deployment can
+// override any of them with a JSON file named by DEFAULTS_CONFIG, e.g.
+// {"A_glass": 120, "setpoint": 16}; fields the file leaves out keep the
+// built-in values below.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ParamDefaults uses the SimulationParams JSON names for each defaulted field.
+type ParamDefaults struct {
+	A_glass          float64 `json:"A_glass"`
+	TauGlass         float64 `json:"tau_glass"`
+	U_day            float64 `json:"U_day"`
+	U_night          float64 `json:"U_night"`
+	Volume           float64 `json:"V"`
+	ACH              float64 `json:"ACH"`
+	CpMass           float64 `json:"cp_mass"`
+	C                float64 `json:"C"` // used when none of C, thermal_mass and thermal_mass_kg is given
+	T_init           float64 `json:"T_init"`
+	Setpoint         float64 `json:"setpoint"`
+	HeaterMaxW       float64 `json:"heater_max_w"`
+	FractionSolarAir float64 `json:"fraction_solar_to_air"`
+	Model            string  `json:"model"`
+	HeaterControl    string  `json:"heater_control"`
+	HeaterDeadband   float64 `json:"heater_deadband"` // used when heater_control is onoff
+}
+
+// builtinDefaults match the worker model's own defaults.
+var builtinDefaults = ParamDefaults{
+	A_glass:          50,
+	TauGlass:         0.85,
+	U_day:            3.0,
+	U_night:          0.6,
+	Volume:           100,
+	ACH:              0.5,
+	CpMass:           4186,
+	C:                2e7,
+	T_init:           15,
+	Setpoint:         12,
+	HeaterMaxW:       5000,
+	FractionSolarAir: 0.5,
+	Model:            ModelLumped,
+	HeaterControl:    HeaterOnOff,
+	HeaterDeadband:   1,
+}
+
+// paramDefaults is what applyDefaults uses; see loadParamDefaults.
+var paramDefaults = builtinDefaults
+
+// loadParamDefaults reads a DEFAULTS_CONFIG file over builtinDefaults. Unknown
+// keys and values validateParams would reject are errors, so a typo in the
+// file stops startup instead of silently keeping a built-in value.
+func loadParamDefaults(path string) (ParamDefaults, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return ParamDefaults{}, err
+	}
+	d := builtinDefaults
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return ParamDefaults{}, fmt.Errorf("%s: %w", path, err)
+	}
+	p := d.params()
+	// the file sets no site; each job brings its own and is checked on submit
+	p.Lat, p.Lon = new(float64), new(float64)
+	if _, err := validateParams(&p, false); err != nil {
+		return ParamDefaults{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// params returns d as a fully populated SimulationParams.
+func (d ParamDefaults) params() SimulationParams {
+	return SimulationParams{
+		A_glass:          &d.A_glass,
+		TauGlass:         &d.TauGlass,
+		U_day:            &d.U_day,
+		U_night:          &d.U_night,
+		Volume:           &d.Volume,
+		ACH:              &d.ACH,
+		CpMass:           &d.CpMass,
+		C:                &d.C,
+		T_init:           &d.T_init,
+		Setpoint:         &d.Setpoint,
+		HeaterMaxW:       &d.HeaterMaxW,
+		FractionSolarAir: &d.FractionSolarAir,
+		Model:            d.Model,
+		HeaterControl:    d.HeaterControl,
+		HeaterDeadband:   &d.HeaterDeadband,
+	}
+}
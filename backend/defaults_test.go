@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeDefaultsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "defaults.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestApplyDefaultsFromFile(t *testing.T) {
+	path := writeDefaultsFile(t, `{"A_glass": 120, "V": 400, "setpoint": 16, "heater_max_w": 20000}`)
+	d, err := loadParamDefaults(path)
+	require.NoError(t, err)
+
+	defer func(prev ParamDefaults) { paramDefaults = prev }(paramDefaults)
+	paramDefaults = d
+
+	params := SimulationParams{HeaterMaxW: floatPtr(8000)}
+	applyDefaults(&params)
+
+	// from the file
+	assert.Equal(t, 120.0, *params.A_glass)
+	assert.Equal(t, 400.0, *params.Volume)
+	assert.Equal(t, 16.0, *params.Setpoint)
+	// explicit fields still win over the file
+	assert.Equal(t, 8000.0, *params.HeaterMaxW)
+	// fields the file leaves out keep the built-in values
+	assert.Equal(t, builtinDefaults.TauGlass, *params.TauGlass)
+	assert.Equal(t, builtinDefaults.U_night, *params.U_night)
+	assert.Equal(t, builtinDefaults.C, *params.C)
+}
+
+func TestLoadParamDefaultsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"malformed", `{"A_glass": `, "unexpected EOF"},
+		{"unknown field", `{"A_glas": 120}`, `unknown field "A_glas"`},
+		{"invalid value", `{"tau_glass": 1.5}`, "tau_glass=1.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadParamDefaults(writeDefaultsFile(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+
+	_, err := loadParamDefaults(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestBuiltinDefaultsUnchanged(t *testing.T) {
+	params := SimulationParams{}
+	applyDefaults(&params)
+	assert.Equal(t, builtinDefaults.params(), params)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertNothingStored checks that no job meta, result or queue entry exists.
+func assertNothingStored(t *testing.T, ctx context.Context) {
+	t.Helper()
+	for _, pattern := range []string{RedisJobMetaPrefix + "*", RedisResultsPrefix + "*", RedisParamsHashPrefix + "*"} {
+		keys, err := rdb.Keys(ctx, pattern).Result()
+		require.NoError(t, err)
+		assert.Empty(t, keys, pattern)
+	}
+	for _, q := range jobQueues {
+		assert.Zero(t, rdb.Exists(ctx, q.Key()).Val(), q.Key())
+	}
+	assert.Zero(t, rdb.Exists(ctx, RedisRecentJobsList).Val())
+}
+
+func TestSubmitDryRun(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	req, _ := http.NewRequest("POST", "/simulate?dry_run=true", bytes.NewBufferString(withSite(`{"setpoint": 16, "ACH": 2, "ventilation_rate": 0.1}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		DryRun   bool             `json:"dry_run"`
+		JobID    string           `json:"job_id"`
+		Params   SimulationParams `json:"params"`
+		Warnings []string         `json:"warnings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.DryRun)
+	assert.Empty(t, response.JobID)
+	assert.Equal(t, 16.0, *response.Params.Setpoint)
+	require.NotNil(t, response.Params.C, "defaults are resolved")
+	assert.InDelta(t, 3.6, *response.Params.ACH, 1e-9)
+	assert.Len(t, response.Warnings, 1)
+
+	assertNothingStored(t, ctx)
+
+	// validation errors surface exactly as without dry_run
+	req, _ = http.NewRequest("POST", "/simulate?dry_run=true", bytes.NewBufferString(`{"tau_glass": 2}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "tau_glass")
+}
+
+func TestBatchDryRun(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	req, _ := http.NewRequest("POST", "/simulate/batch?dry_run=true", bytes.NewBufferString(mixedBatch))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		DryRun bool             `json:"dry_run"`
+		Jobs   []dryRunItem     `json:"jobs"`
+		Errors []batchItemError `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.True(t, response.DryRun)
+	require.Len(t, response.Jobs, 2)
+	assert.Equal(t, 2, response.Jobs[1].Index)
+	assert.Equal(t, 14.0, *response.Jobs[1].Params.Setpoint)
+	require.Len(t, response.Errors, 2)
+	assert.Equal(t, "ACH", response.Errors[0].Fields[0].Field)
+
+	assertNothingStored(t, ctx)
+
+	// atomic rejection applies to dry runs too
+	code, _ := postBatch(t, router, "?dry_run=true&atomic=true", mixedBatch)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,165 @@
+package main
+
+// backend/estimate.go
+//
+// Runtime estimates. POST /simulate/estimate takes the same body as POST
+// /simulate, resolves and validates it the same way, and answers with the
+// number of timesteps the worker would run and the expected runtime, without
+// storing or enqueuing anything. The runtime is timesteps times a per-step
+// cost for the model. That cost starts at a built-in guess and is calibrated
+// from finished jobs: each done job's running time
+// (started_at to its final updated_at) per step into an exponentially
+// weighted average kept in the step_cost hash, shared by every replica.
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStepCostKey is a hash of model -> calibrated seconds per timestep, and
+// <model>:samples -> number of jobs it was calibrated from.
+const RedisStepCostKey = "step_cost"
+
+// RedisStepCostSeenPrefix marks step_cost_seen:<job_id> once a job has been
+// folded into the average, so several replicas count it once.
+const RedisStepCostSeenPrefix = "step_cost_seen:"
+
+// StepCostSmoothing is the weight of each new observation in the average.
+const StepCostSmoothing = 0.2
+
+// Dates the worker simulates when a job gives none.
+const (
+	WorkerDefaultStartDate = "2025-10-01"
+	WorkerDefaultEndDate   = "2025-10-02"
+)
+
+// defaultStepCost is the seconds per timestep assumed before any job of the
+// model has finished.
+var defaultStepCost = map[string]float64{
+	ModelLumped:    0.002,
+	ModelMultinode: 0.006,
+}
+
+// recordStepCostScript folds ARGV[2] seconds per step into the average for
+// model ARGV[1], unless the job (KEYS[2]) was already counted.
+var recordStepCostScript = redis.NewScript(`
+if not redis.call('SET', KEYS[2], '1', 'NX', 'EX', 86400) then
+  return 0
+end
+local v = tonumber(ARGV[2])
+local cur = tonumber(redis.call('HGET', KEYS[1], ARGV[1]))
+if cur then
+  v = cur + tonumber(ARGV[3]) * (v - cur)
+end
+redis.call('HSET', KEYS[1], ARGV[1], tostring(v))
+redis.call('HINCRBY', KEYS[1], ARGV[1] .. ':samples', 1)
+return 1
+`)
+
+// jobModel is the thermal model p runs with.
+func jobModel(p SimulationParams) string {
+	if p.Model == "" {
+		return ModelLumped
+	}
+	return p.Model
+}
+
+// jobSteps is the number of timesteps the worker runs for p, using its
+// default window when p has no dates.
+func jobSteps(p SimulationParams) (int, error) {
+	startDate, endDate := p.StartDate, p.EndDate
+	if startDate == "" && endDate == "" {
+		startDate, endDate = WorkerDefaultStartDate, WorkerDefaultEndDate
+	}
+	start, err := time.Parse(DateLayout, startDate)
+	if err != nil {
+		return 0, err
+	}
+	end, err := time.Parse(DateLayout, endDate)
+	if err != nil {
+		return 0, err
+	}
+	return simulationSteps(start, end, p.TimestepSeconds), nil
+}
+
+// recordStepCost calibrates the per-step cost from a finished job. Jobs that
+// did not finish successfully or never reported running are skipped.
+func recordStepCost(ctx context.Context, meta JobMeta) error {
+	if meta.Status != StatusDone || meta.StartedAt == nil {
+		return nil
+	}
+	steps, err := jobSteps(meta.Params)
+	if err != nil || steps <= 0 {
+		return nil
+	}
+	runtime := meta.UpdatedAt.Sub(*meta.StartedAt).Seconds()
+	if runtime <= 0 {
+		return nil
+	}
+	perStep := strconv.FormatFloat(runtime/float64(steps), 'g', -1, 64)
+	smoothing := strconv.FormatFloat(StepCostSmoothing, 'g', -1, 64)
+	return recordStepCostScript.Run(ctx, rdb,
+		[]string{redisKey(RedisStepCostKey), redisKey(RedisStepCostSeenPrefix + meta.JobID)},
+		jobModel(meta.Params), perStep, smoothing).Err()
+}
+
+// stepCost returns the seconds per timestep for model and how many finished
+// jobs it is calibrated from (0 for the built-in guess).
+func stepCost(ctx context.Context, model string) (float64, int, error) {
+	vals, err := rdb.HMGet(ctx, redisKey(RedisStepCostKey), model, model+":samples").Result()
+	if err != nil {
+		return 0, 0, err
+	}
+	cost, _ := vals[0].(string)
+	samples, _ := vals[1].(string)
+	c, err := strconv.ParseFloat(cost, 64)
+	if err != nil || c <= 0 {
+		return defaultStepCost[model], 0, nil
+	}
+	n, _ := strconv.Atoi(samples)
+	return c, n, nil
+}
+
+func estimateHandler(c *gin.Context) {
+	params, ok := bindParamsBody(c)
+	if !ok {
+		return
+	}
+	units, warnings, ok := resolveParams(c, &params)
+	if !ok {
+		return
+	}
+
+	steps, err := jobSteps(params)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "counting timesteps: "+err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	model := jobModel(params)
+	cost, samples, err := stepCost(ctx, model)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+
+	resp := gin.H{
+		"units":                     units,
+		"params":                    params,
+		"model":                     model,
+		"timesteps":                 steps,
+		"seconds_per_step":          cost,
+		"estimated_runtime_seconds": cost * float64(steps),
+		"calibration_samples":       samples,
+	}
+	if len(warnings) > 0 {
+		resp["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, resp)
+}
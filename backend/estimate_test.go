@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postEstimate(t *testing.T, router http.Handler, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/simulate/estimate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestEstimateScalesWithSpan(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, day := postEstimate(t, router, withSite(`{"start_date": "2025-11-01", "end_date": "2025-11-01"}`))
+	require.Equal(t, http.StatusOK, code, day)
+	code, tenDays := postEstimate(t, router, withSite(`{"start_date": "2025-11-01", "end_date": "2025-11-10"}`))
+	require.Equal(t, http.StatusOK, code, tenDays)
+
+	assert.Equal(t, 24.0, day["timesteps"])
+	assert.Equal(t, 240.0, tenDays["timesteps"])
+	assert.InDelta(t, 10*day["estimated_runtime_seconds"].(float64), tenDays["estimated_runtime_seconds"], 1e-9)
+	assert.Equal(t, defaultStepCost[ModelLumped], day["seconds_per_step"])
+	assert.Equal(t, 0.0, day["calibration_samples"])
+
+	// a finer step means more of them; multinode costs more per step
+	_, fine := postEstimate(t, router, withSite(`{"start_date": "2025-11-01", "end_date": "2025-11-01", "timestep_seconds": 900}`))
+	assert.Equal(t, 96.0, fine["timesteps"])
+	_, multi := postEstimate(t, router, withSite(`{"start_date": "2025-11-01", "end_date": "2025-11-01", "model": "multinode"}`))
+	assert.Greater(t, multi["estimated_runtime_seconds"], day["estimated_runtime_seconds"])
+
+	// no dates: the worker's default window
+	_, undated := postEstimate(t, router, withSite(`{}`))
+	assert.Equal(t, 48.0, undated["timesteps"])
+
+	// nothing was queued or stored
+	assert.Zero(t, rdb.Exists(ctx, RedisRecentJobsList).Val())
+	assert.Zero(t, rdb.LLen(ctx, queueFor(PriorityNormal)).Val())
+}
+
+func TestEstimateRejectsInvalidParams(t *testing.T) {
+	router := setupRouter()
+	code, resp := postEstimate(t, router, withSite(`{"start_date": "2025-11-03", "end_date": "2025-11-01"}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "end_date", resp["error"].(map[string]interface{})["field"])
+}
+
+func TestEstimateCalibratesFromFinishedJobs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	finished := func(jobID string, runtime time.Duration) JobMeta {
+		started := time.Now().UTC().Add(-time.Hour)
+		return JobMeta{
+			JobID:     jobID,
+			Status:    StatusDone,
+			StartedAt: &started,
+			UpdatedAt: started.Add(runtime),
+			Params:    SimulationParams{StartDate: "2025-11-01", EndDate: "2025-11-01"}, // 24 steps
+		}
+	}
+	require.NoError(t, recordStepCost(ctx, finished("job-a", 24*time.Second)))
+	cost, samples, err := stepCost(ctx, ModelLumped)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, cost, "the first job sets the cost")
+	assert.Equal(t, 1, samples)
+
+	// counted once however many replicas see it
+	require.NoError(t, recordStepCost(ctx, finished("job-a", 24*time.Second)))
+	require.NoError(t, recordStepCost(ctx, finished("job-b", 48*time.Second)))
+	cost, samples, err = stepCost(ctx, ModelLumped)
+	require.NoError(t, err)
+	assert.InDelta(t, 1+StepCostSmoothing*(2-1), cost, 1e-9)
+	assert.Equal(t, 2, samples)
+
+	// failed and never-started jobs say nothing about the cost
+	failed := finished("job-c", time.Hour)
+	failed.Status = StatusError
+	require.NoError(t, recordStepCost(ctx, failed))
+	require.NoError(t, recordStepCost(ctx, JobMeta{JobID: "job-d", Status: StatusDone}))
+	_, samples, _ = stepCost(ctx, ModelLumped)
+	assert.Equal(t, 2, samples)
+
+	code, resp := postEstimate(t, router, withSite(`{"start_date": "2025-11-01", "end_date": "2025-11-02"}`))
+	require.Equal(t, http.StatusOK, code)
+	assert.InDelta(t, 48*cost, resp["estimated_runtime_seconds"], 1e-9)
+	assert.Equal(t, 2.0, resp["calibration_samples"])
+}
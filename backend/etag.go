@@ -0,0 +1,52 @@
+package main
+
+// backend/etag.go
+//
+// ETags and conditional GETs for endpoints that clients poll. The tag is a hash
+// of the response body, so it changes exactly when the payload does and a
+// matching If-None-Match gets 304 Not Modified with no body.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagFor returns a strong ETag for body.
+func etagFor(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// Weak comparison is used, as RFC 9110 requires for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// respondJSONWithETag writes obj as a 200 JSON response tagged with its ETag,
+// or 304 if the client already holds that version.
+func respondJSONWithETag(c *gin.Context, obj interface{}) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to encode response: "+err.Error())
+		return
+	}
+	etag := etagFor(body)
+	c.Header("ETag", etag)
+	if inm := c.GetHeader("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEtagMatches(t *testing.T) {
+	etag := `"abc"`
+	assert.True(t, etagMatches(`"abc"`, etag))
+	assert.True(t, etagMatches(`W/"abc"`, etag))
+	assert.True(t, etagMatches(`"xyz", "abc"`, etag))
+	assert.True(t, etagMatches(`*`, etag))
+	assert.False(t, etagMatches(`"xyz"`, etag))
+	assert.False(t, etagMatches(`abc`, etag))
+}
+
+// conditionalGet requests path with an optional If-None-Match header.
+func conditionalGet(router http.Handler, path, ifNoneMatch string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConditionalGet(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "etag-job", StatusDone)
+	rdb.Set(ctx, RedisResultsPrefix+"etag-job", sampleResult, DefaultResultTTL)
+
+	for _, path := range []string{"/results/etag-job", "/jobs/etag-job"} {
+		t.Run(path, func(t *testing.T) {
+			w := conditionalGet(router, path, "")
+			require.Equal(t, http.StatusOK, w.Code)
+			etag := w.Header().Get("ETag")
+			require.NotEmpty(t, etag)
+
+			// unchanged: same tag, and a matching If-None-Match gets an empty 304
+			again := conditionalGet(router, path, "")
+			assert.Equal(t, etag, again.Header().Get("ETag"))
+			w = conditionalGet(router, path, etag)
+			assert.Equal(t, http.StatusNotModified, w.Code)
+			assert.Empty(t, w.Body.String())
+			assert.Equal(t, etag, w.Header().Get("ETag"))
+
+			w = conditionalGet(router, path, `"stale"`)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.NotEmpty(t, w.Body.String())
+		})
+	}
+}
+
+func TestConditionalGetChangesWithStatus(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "polled", StatusQueued)
+
+	w := conditionalGet(router, "/results/polled", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+
+	seedJobMeta(t, ctx, "polled", StatusRunning)
+	w = conditionalGet(router, "/results/polled", etag)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), StatusRunning)
+}
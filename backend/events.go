@@ -0,0 +1,119 @@
+package main
+
+// backend/events.go
+//
+// Server-Sent Events stream of a job's status transitions. The worker (and the
+// cancel handler) publish the updated meta on job_events:<id>; if nothing arrives
+// for a while the stream falls back to polling the meta key, so a missed publish
+// only delays an update instead of losing it.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedisJobEventsPrefix is the pub/sub channel prefix: job_events:<jobID> -> JobMeta JSON.
+const RedisJobEventsPrefix = "job_events:"
+
+// eventsPollInterval is how long the stream waits for a published event before
+// reading the meta key directly.
+var eventsPollInterval = 5 * time.Second
+
+// publishJobEvent announces a meta change to /jobs/:job_id/events subscribers.
+func publishJobEvent(ctx context.Context, meta JobMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return rdb.Publish(ctx, jobEventsChannel(meta.JobID), b).Err()
+}
+
+// jobEventsHandler streams the job's current status followed by every
+// transition as SSE events named after the status. The stream ends after a
+// terminal event (done, error or cancelled) or when the client disconnects.
+func jobEventsHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	reqCtx := c.Request.Context()
+
+	// subscribe before reading the meta so a transition in between is not missed
+	sub := rdb.Subscribe(reqCtx, jobEventsChannel(jobID))
+	defer sub.Close()
+
+	ctx, cancel := context.WithTimeout(reqCtx, RedisOpTimeout)
+	if _, err := sub.Receive(ctx); err != nil {
+		cancel()
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	// always read Redis: it holds the live status the worker writes
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	cancel()
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // keep nginx from buffering the stream
+	c.Status(http.StatusOK)
+
+	last := ""
+	// send emits meta if its status changed and reports whether the stream is over.
+	send := func(m JobMeta) bool {
+		if m.Status != last {
+			last = m.Status
+			c.SSEvent(m.Status, m)
+			c.Writer.Flush()
+		}
+		return isTerminalStatus(m.Status)
+	}
+	if send(meta) {
+		return
+	}
+
+	msgs := sub.Channel()
+	poll := time.NewTimer(eventsPollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-reqCtx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			var m JobMeta
+			if json.Unmarshal([]byte(msg.Payload), &m) != nil {
+				continue
+			}
+			if send(m) {
+				return
+			}
+			poll.Reset(eventsPollInterval)
+		case <-poll.C:
+			ctx, cancel := context.WithTimeout(reqCtx, RedisOpTimeout)
+			m, err := redisMetaStore{}.GetMeta(ctx, jobID)
+			cancel()
+			if errors.Is(err, ErrMetaNotFound) {
+				// deleted or expired while we were watching
+				c.SSEvent(StatusError, gin.H{"job_id": jobID, "error": "job not found"})
+				c.Writer.Flush()
+				return
+			}
+			if err == nil && send(m) {
+				return
+			}
+			poll.Reset(eventsPollInterval)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent is one decoded event from a text/event-stream body.
+type sseEvent struct {
+	Name string
+	Data string
+}
+
+// readEvents opens /jobs/:job_id/events and sends each event on the returned
+// channel, closing it when the server ends the stream.
+func readEvents(t *testing.T, srv *httptest.Server, jobID string) <-chan sseEvent {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/jobs/" + jobID + "/events")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+		var ev sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				ev.Name = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				ev.Data = strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			case line == "" && ev.Name != "":
+				events <- ev
+				ev = sseEvent{}
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		require.True(t, ok, "stream closed early")
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return sseEvent{}
+	}
+}
+
+func assertStreamClosed(t *testing.T, events <-chan sseEvent) {
+	t.Helper()
+	select {
+	case _, ok := <-events:
+		assert.False(t, ok, "expected stream to end")
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not closed")
+	}
+}
+
+func TestJobEventsStreamsPublishedTransitions(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	srv := httptest.NewServer(setupRouter())
+	defer srv.Close()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	meta := seedJobMeta(t, ctx, "evt-job", StatusQueued)
+	events := readEvents(t, srv, "evt-job")
+
+	ev := nextEvent(t, events)
+	assert.Equal(t, StatusQueued, ev.Name)
+	assert.Contains(t, ev.Data, `"job_id":"evt-job"`)
+
+	meta.Status = StatusRunning
+	require.NoError(t, publishJobEvent(ctx, meta))
+	assert.Equal(t, StatusRunning, nextEvent(t, events).Name)
+
+	meta.Status = StatusDone
+	require.NoError(t, publishJobEvent(ctx, meta))
+	assert.Equal(t, StatusDone, nextEvent(t, events).Name)
+	assertStreamClosed(t, events)
+}
+
+func TestJobEventsFallsBackToPolling(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	srv := httptest.NewServer(setupRouter())
+	defer srv.Close()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	orig := eventsPollInterval
+	eventsPollInterval = 50 * time.Millisecond
+	defer func() { eventsPollInterval = orig }()
+
+	seedJobMeta(t, ctx, "quiet-job", StatusRunning)
+	events := readEvents(t, srv, "quiet-job")
+	assert.Equal(t, StatusRunning, nextEvent(t, events).Name)
+
+	// the meta changes without a publish
+	seedJobMeta(t, ctx, "quiet-job", StatusError)
+	assert.Equal(t, StatusError, nextEvent(t, events).Name)
+	assertStreamClosed(t, events)
+}
+
+func TestJobEventsTerminalJobClosesImmediately(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	srv := httptest.NewServer(setupRouter())
+	defer srv.Close()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	seedJobMeta(t, ctx, "finished", StatusDone)
+	events := readEvents(t, srv, "finished")
+	assert.Equal(t, StatusDone, nextEvent(t, events).Name)
+	assertStreamClosed(t, events)
+}
+
+func TestJobEventsNotFound(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("GET", "/jobs/nonexistent/events", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
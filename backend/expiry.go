@@ -0,0 +1,82 @@
+package main
+
+// backend/expiry.go
+//
+// Job expiry. A job's meta and result are deleted by Redis once its TTL runs
+// out; expires_at on the meta says when. It starts as created_at + TTL and
+// moves with the TTL, which every meta write (worker status updates included)
+// renews. Alongside, job_expiry:<id> holds expires_at and outlives the job by
+// EXPIRED_GRACE_SECONDS, so for that long GET /jobs/:job_id and
+// /results/:job_id answer 410 with status "expired" instead of a bare 404.
+// Deleting or purging a job removes the marker too: it was not expired.
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisJobExpiryPrefix keys job_expiry:<job_id> -> expires_at (RFC 3339).
+const RedisJobExpiryPrefix = "job_expiry:"
+
+// StatusExpired is reported for a job whose data was purged within the grace period.
+const StatusExpired = "expired"
+
+// DefaultExpiredGrace is how long an expired job is still reported as expired.
+const DefaultExpiredGrace = 7 * 24 * time.Hour
+
+var expiredGrace = DefaultExpiredGrace
+
+func loadExpiryConfig() {
+	expiredGrace = envSeconds("EXPIRED_GRACE_SECONDS", DefaultExpiredGrace)
+}
+
+// setExpiry sets meta.ExpiresAt to ttl from now.
+func setExpiry(meta *JobMeta, now time.Time, ttl time.Duration) {
+	expires := now.Add(ttl)
+	meta.ExpiresAt = &expires
+}
+
+// setExpiryMarker queues the write of meta's job_expiry marker on pipe.
+func setExpiryMarker(ctx context.Context, pipe redis.Pipeliner, meta JobMeta) {
+	if meta.ExpiresAt == nil {
+		return
+	}
+	ttl := time.Until(*meta.ExpiresAt) + expiredGrace
+	pipe.Set(ctx, jobExpiryKey(meta.JobID), meta.ExpiresAt.Format(time.RFC3339Nano), ttl)
+}
+
+// expiredAt returns when jobID expired if it is within the grace period;
+// ok is false for a job that never existed, was deleted or expired long ago.
+func expiredAt(ctx context.Context, jobID string) (t time.Time, ok bool, err error) {
+	v, err := rdb.Get(ctx, jobExpiryKey(jobID)).Result()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	} else if err != nil {
+		return time.Time{}, false, err
+	}
+	t, err = time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, false, nil
+	}
+	return t, true, nil
+}
+
+// respondJobMissing answers for a job with no meta: 410 with status expired
+// when it expired recently, otherwise 404 with msg.
+func respondJobMissing(c *gin.Context, ctx context.Context, jobID, msg string) {
+	expires, ok, err := expiredAt(ctx, jobID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	if !ok {
+		respondError(c, http.StatusNotFound, msg)
+		return
+	}
+	respondError(c, http.StatusGone, "job data has expired",
+		gin.H{"job_id": jobID, "status": StatusExpired, "expires_at": expires})
+}
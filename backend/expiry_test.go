@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitRecordsExpiry(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	w := postSimulate(router, withSite(`{"A_glass": 80, "result_ttl_seconds": 3600}`))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	jobID := resp["job_id"].(string)
+
+	meta, err := metaStore.GetMeta(ctx, jobID)
+	require.NoError(t, err)
+	require.NotNil(t, meta.ExpiresAt)
+	assert.WithinDuration(t, meta.CreatedAt.Add(time.Hour), *meta.ExpiresAt, time.Second)
+
+	marker, err := rdb.Get(ctx, jobExpiryKey(jobID)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, meta.ExpiresAt.Format(time.RFC3339Nano), marker)
+	assert.Greater(t, rdb.TTL(ctx, jobExpiryKey(jobID)).Val(), time.Hour, "the marker outlives the job")
+
+	// the worker's status report renews the TTL and moves expires_at along
+	withInternalSecret(t, "s3cret")
+	time.Sleep(10 * time.Millisecond)
+	w = reportStatus(t, router, "s3cret", jobID, gin.H{"status": StatusRunning})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	renewed, err := metaStore.GetMeta(ctx, jobID)
+	require.NoError(t, err)
+	assert.True(t, renewed.ExpiresAt.After(*meta.ExpiresAt))
+	assert.Equal(t, renewed.ExpiresAt.Format(time.RFC3339Nano), rdb.Get(ctx, jobExpiryKey(jobID)).Val())
+}
+
+func TestResultReportsExpiry(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "exp-done", StatusDone)
+	require.NoError(t, rdb.Set(ctx, jobResultKey("exp-done"), sampleResult, time.Hour).Err())
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/results/exp-done", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.WithinDuration(t, time.Now().Add(time.Hour), resp.ExpiresAt, 2*time.Second)
+}
+
+func TestExpiredJobAnswersGone(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// meta and result are gone; the marker is what is left of an expired job
+	expires := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	require.NoError(t, rdb.Set(ctx, jobExpiryKey("exp-old"), expires.Format(time.RFC3339Nano), time.Hour).Err())
+	for _, path := range []string{"/jobs/exp-old", "/results/exp-old", "/results/exp-old/csv"} {
+		w := get(path)
+		require.Equal(t, http.StatusGone, w.Code, path)
+		assert.Equal(t, CodeExpired, decodeError(t, w).Error.Code, path)
+		var body struct {
+			JobID     string    `json:"job_id"`
+			Status    string    `json:"status"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "exp-old", body.JobID)
+		assert.Equal(t, StatusExpired, body.Status)
+		assert.True(t, expires.Equal(body.ExpiresAt), path)
+	}
+
+	// never existed
+	w := get("/jobs/exp-unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, CodeNotFound, decodeError(t, w).Error.Code)
+
+	// a deleted job did not expire
+	w = postSimulate(router, withSite(`{"A_glass": 80}`))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	jobID := resp["job_id"].(string)
+	del := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/jobs/"+jobID, nil)
+	router.ServeHTTP(del, req)
+	require.Equal(t, http.StatusOK, del.Code, del.Body.String())
+	assert.Zero(t, rdb.Exists(ctx, jobExpiryKey(jobID)).Val())
+	assert.Equal(t, http.StatusNotFound, get("/jobs/"+jobID).Code)
+}
@@ -0,0 +1,117 @@
+package main
+
+// backend/health.go
+//
+// Readiness probe. /health stays a pure liveness check; /ready additionally
+// verifies that Redis answers so traffic can be gated until it is reachable.
+// /health/workers reports the workers whose heartbeat has not expired, so an
+// API that accepts jobs nobody will run can be alerted on.
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadyPingTimeout bounds the Redis ping done by /ready.
+const ReadyPingTimeout = 1 * time.Second
+
+// RedisWorkerHeartbeatPrefix keys worker_heartbeat:<workerID> -> the time of
+// the worker's last beat. Workers refresh it every few seconds with a TTL, so
+// only live workers have one.
+const RedisWorkerHeartbeatPrefix = "worker_heartbeat:"
+
+// workerLiveness is one live worker in GET /health/workers.
+type workerLiveness struct {
+	WorkerID      string    `json:"worker_id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+	AgeSeconds    float64   `json:"age_seconds"`
+}
+
+// liveWorkers returns the workers with a heartbeat key, oldest beat first.
+// A heartbeat that does not parse still counts the worker as alive.
+func liveWorkers(ctx context.Context, now time.Time) ([]workerLiveness, error) {
+	prefix := workerHeartbeatKey("")
+	var keys []string
+	iter := rdb.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	workers := []workerLiveness{}
+	if len(keys) == 0 {
+		return workers, nil
+	}
+	vals, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue // expired between SCAN and MGET
+		}
+		w := workerLiveness{WorkerID: strings.TrimPrefix(keys[i], prefix)}
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			w.LastHeartbeat = t.UTC()
+			w.AgeSeconds = now.Sub(t).Seconds()
+		}
+		workers = append(workers, w)
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		if workers[i].AgeSeconds != workers[j].AgeSeconds {
+			return workers[i].AgeSeconds > workers[j].AgeSeconds
+		}
+		return workers[i].WorkerID < workers[j].WorkerID
+	})
+	return workers, nil
+}
+
+func readyHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), ReadyPingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := rdb.Ping(ctx).Err()
+	latency := time.Since(start)
+
+	body := gin.H{
+		"redis_addr":       rdbAddr,
+		"redis_latency_ms": float64(latency.Microseconds()) / 1000,
+	}
+	if err != nil {
+		body["status"] = "unavailable"
+		body["error"] = "redis ping failed: " + err.Error()
+		c.JSON(http.StatusServiceUnavailable, body)
+		return
+	}
+	body["status"] = "ready"
+	c.JSON(http.StatusOK, body)
+}
+
+func workersHealthHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	workers, err := liveWorkers(ctx, time.Now())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "error": "redis error: " + err.Error()})
+		return
+	}
+	body := gin.H{"workers_alive": len(workers), "oldest_heartbeat_age_seconds": nil, "workers": workers}
+	if len(workers) == 0 {
+		body["status"] = "unavailable"
+		body["error"] = "no live workers"
+		c.JSON(http.StatusServiceUnavailable, body)
+		return
+	}
+	body["status"] = "ok"
+	body["oldest_heartbeat_age_seconds"] = workers[0].AgeSeconds
+	c.JSON(http.StatusOK, body)
+}
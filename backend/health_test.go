@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadyWithRedis(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdbAddr = testRedisAddr
+
+	req, _ := http.NewRequest("GET", "/ready", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "ready", response["status"])
+	assert.Equal(t, testRedisAddr, response["redis_addr"])
+	assert.Contains(t, response, "redis_latency_ms")
+}
+
+func TestReadyWithUnreachableRedis(t *testing.T) {
+	router := setupRouter()
+	rdbAddr = "localhost:1"
+	rdb = redis.NewClient(&redis.Options{Addr: rdbAddr, MaxRetries: -1})
+
+	req, _ := http.NewRequest("GET", "/ready", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "unavailable", response["status"])
+	assert.Equal(t, "localhost:1", response["redis_addr"])
+
+	// /health is liveness only and stays ok
+	req, _ = http.NewRequest("GET", "/health", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWorkersHealth(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	get := func() (int, map[string]interface{}) {
+		req, _ := http.NewRequest("GET", "/health/workers", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, body := get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unavailable", body["status"])
+	assert.Equal(t, 0.0, body["workers_alive"])
+	assert.Nil(t, body["oldest_heartbeat_age_seconds"])
+
+	now := time.Now().UTC()
+	// the worker writes Python's isoformat(), e.g. 2025-11-01T10:00:00.123456+00:00
+	beats := map[string]time.Time{"worker-a": now.Add(-2 * time.Second), "worker-b": now.Add(-20 * time.Second)}
+	for id, at := range beats {
+		require.NoError(t, rdb.Set(ctx, workerHeartbeatKey(id), at.Format("2006-01-02T15:04:05.000000-07:00"), 30*time.Second).Err())
+	}
+	// not a heartbeat key
+	require.NoError(t, rdb.Set(ctx, jobMetaKey("worker-c"), "{}", 0).Err())
+
+	code, body = get()
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Equal(t, "ok", body["status"])
+	assert.Equal(t, 2.0, body["workers_alive"])
+	assert.InDelta(t, 20, body["oldest_heartbeat_age_seconds"], 2)
+	workers := body["workers"].([]interface{})
+	require.Len(t, workers, 2)
+	assert.Equal(t, "worker-b", workers[0].(map[string]interface{})["worker_id"], "oldest first")
+}
+
+func TestVersion(t *testing.T) {
+	router := setupRouter()
+	req, _ := http.NewRequest("GET", "/version", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]string{
+		"commit":     "dev",
+		"build_time": "unknown",
+		"go_version": runtime.Version(),
+	}, body)
+
+	orig := Commit
+	Commit = "abc123"
+	t.Cleanup(func() { Commit = orig })
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), `"commit":"abc123"`)
+}
@@ -0,0 +1,96 @@
+package main
+
+// backend/idempotency.go
+//
+// Idempotency-Key support for /simulate. The first request with a key claims
+// idempotency:<client>:<key> (SETNX) for the new job; retries from the same
+// client with the same key and params get the original job back instead of
+// enqueuing a duplicate. Keys are scoped to the client (see rateLimitClient),
+// so another client reusing a key neither sees nor collides with the job.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyKeyHeader is the request header clients set to make submission retry-safe.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RedisIdempotencyPrefix keys idempotency:<client>:<key> -> idempotencyRecord JSON
+const RedisIdempotencyPrefix = "idempotency:"
+
+// DefaultIdempotencyTTL is how long a key is remembered.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+var idempotencyTTL = DefaultIdempotencyTTL
+
+type idempotencyRecord struct {
+	JobID      string `json:"job_id"`
+	ParamsHash string `json:"params_hash"`
+}
+
+// paramsHash returns a stable hash of the resolved params.
+func paramsHash(p *SimulationParams) string {
+	b, _ := json.Marshal(p)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// reserveIdempotencyKey claims client's key for rec. If another request already
+// claimed it, the stored record is returned with claimed=false.
+func reserveIdempotencyKey(ctx context.Context, client, key string, rec idempotencyRecord) (idempotencyRecord, bool, error) {
+	recBytes, _ := json.Marshal(rec)
+	claimed, err := rdb.SetNX(ctx, idempotencyKey(client, key), recBytes, idempotencyTTL).Result()
+	if err != nil || claimed {
+		return rec, claimed, err
+	}
+	stored, err := rdb.Get(ctx, idempotencyKey(client, key)).Result()
+	if err != nil {
+		return idempotencyRecord{}, false, err
+	}
+	var existing idempotencyRecord
+	if err := json.Unmarshal([]byte(stored), &existing); err != nil {
+		return idempotencyRecord{}, false, err
+	}
+	return existing, false, nil
+}
+
+// releaseIdempotencyKey drops a claim whose job could not be enqueued.
+func releaseIdempotencyKey(ctx context.Context, client, key string) {
+	rdb.Del(ctx, idempotencyKey(client, key))
+}
+
+// respondIdempotentReplay answers a repeated submission: 422 if the key was used
+// with different params, otherwise the original job and its current status.
+func respondIdempotentReplay(c *gin.Context, ctx context.Context, existing idempotencyRecord, hash string) {
+	if existing.ParamsHash != hash {
+		respondError(c, http.StatusUnprocessableEntity, IdempotencyKeyHeader+" was already used with different parameters",
+			gin.H{"job_id": existing.JobID})
+		return
+	}
+	status := StatusQueued
+	if metaStr, err := rdb.Get(ctx, jobMetaKey(existing.JobID)).Result(); err == nil {
+		var meta JobMeta
+		if json.Unmarshal([]byte(metaStr), &meta) == nil {
+			status = meta.Status
+		}
+	} else if err != redis.Nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	links := jobLinks(existing.JobID)
+	c.Header("Location", links["result"])
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":            existing.JobID,
+		"status":            status,
+		"links":             links,
+		"idempotent_replay": true,
+	})
+}
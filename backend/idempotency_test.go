@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func submitIdempotent(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotentReplayReturnsOriginalJob(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	first := submitIdempotent(router, "retry-1", withSite(`{"setpoint": 14}`))
+	require.Equal(t, http.StatusAccepted, first.Code)
+	var firstResp map[string]interface{}
+	require.NoError(t, json.Unmarshal(first.Body.Bytes(), &firstResp))
+
+	second := submitIdempotent(router, "retry-1", withSite(`{"setpoint": 14}`))
+	assert.Equal(t, http.StatusOK, second.Code)
+	var secondResp map[string]interface{}
+	require.NoError(t, json.Unmarshal(second.Body.Bytes(), &secondResp))
+	assert.Equal(t, firstResp["job_id"], secondResp["job_id"])
+	assert.Equal(t, StatusQueued, secondResp["status"])
+	assert.Equal(t, true, secondResp["idempotent_replay"])
+
+	// only one job was enqueued
+	n, err := rdb.LLen(ctx, RedisJobsList).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestIdempotencyKeyWithDifferentParams(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	require.Equal(t, http.StatusAccepted, submitIdempotent(router, "retry-2", withSite(`{"setpoint": 14}`)).Code)
+	w := submitIdempotent(router, "retry-2", withSite(`{"setpoint": 16}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	n, err := rdb.LLen(ctx, RedisJobsList).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestDistinctIdempotencyKeysCreateDistinctJobs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	a := submitIdempotent(router, "key-a", withSite(`{}`))
+	b := submitIdempotent(router, "key-b", withSite(`{}`))
+	require.Equal(t, http.StatusAccepted, a.Code)
+	require.Equal(t, http.StatusAccepted, b.Code)
+
+	var ra, rb map[string]interface{}
+	require.NoError(t, json.Unmarshal(a.Body.Bytes(), &ra))
+	require.NoError(t, json.Unmarshal(b.Body.Bytes(), &rb))
+	assert.NotEqual(t, ra["job_id"], rb["job_id"])
+}
+
+func TestIdempotencyKeysAreScopedToClient(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withAuth(t, "client-a", "client-b")
+
+	submit := func(apiKey, body string) (*httptest.ResponseRecorder, string) {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(IdempotencyKeyHeader, "shared-key")
+		req.Header.Set(APIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		id, _ := resp["job_id"].(string)
+		return w, id
+	}
+
+	wa, idA := submit("client-a", withSite(`{"setpoint": 14}`))
+	require.Equal(t, http.StatusAccepted, wa.Code, wa.Body.String())
+
+	// the same key from another client is a new submission, with the same or other params
+	wb, idB := submit("client-b", withSite(`{"setpoint": 14}`))
+	require.Equal(t, http.StatusAccepted, wb.Code, wb.Body.String())
+	assert.NotEqual(t, idA, idB)
+	wb, idB2 := submit("client-b", withSite(`{"setpoint": 16}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, wb.Code)
+	assert.Equal(t, idB, idB2, "a conflict names the client's own job")
+	assert.NotContains(t, wb.Body.String(), idA)
+
+	// each client still replays its own job
+	wa, replay := submit("client-a", withSite(`{"setpoint": 14}`))
+	assert.Equal(t, http.StatusOK, wa.Code)
+	assert.Equal(t, idA, replay)
+}
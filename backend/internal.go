@@ -0,0 +1,214 @@
+package main
+
+// backend/internal.go
+//
+// Worker-facing API. POST /internal/jobs/:job_id/status lets a worker report
+// running (optionally with its progress), done (with its result) or error
+// over HTTP instead of writing the Redis keys itself; the backend then
+// updates the meta, stores the result, publishes the job event and frees the
+// greenhouse lock exactly as the Python worker does. These routes sit outside the API key group and are guarded by
+// a shared secret in X-Internal-Secret; without INTERNAL_API_SECRET they
+// refuse every call.
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// InternalSecretHeader carries the shared secret on internal routes.
+const InternalSecretHeader = "X-Internal-Secret"
+
+// internalSecret is empty unless INTERNAL_API_SECRET is set, which disables the internal API.
+var internalSecret = ""
+
+func loadInternalConfig() {
+	internalSecret = os.Getenv("INTERNAL_API_SECRET")
+}
+
+// internalAuth rejects requests without the shared secret with 401, and every
+// request with 503 when no secret is configured.
+func internalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if internalSecret == "" {
+			respondError(c, http.StatusServiceUnavailable, "internal API disabled: INTERNAL_API_SECRET is not set")
+			return
+		}
+		got := c.GetHeader(InternalSecretHeader)
+		if got == "" {
+			respondError(c, http.StatusUnauthorized, "missing "+InternalSecretHeader+" header")
+			return
+		}
+		if !hmac.Equal([]byte(got), []byte(internalSecret)) {
+			respondError(c, http.StatusUnauthorized, "invalid internal secret")
+			return
+		}
+		c.Next()
+	}
+}
+
+// workerStatusRequest is the body of POST /internal/jobs/:job_id/status.
+type workerStatusRequest struct {
+	Status   string          `json:"status" binding:"required"`
+	Error    string          `json:"error,omitempty"`    // required with status error
+	Result   json.RawMessage `json:"result,omitempty"`   // required with status done
+	Progress *float64        `json:"progress,omitempty"` // percent done, only with status running
+}
+
+// workerStatuses are the statuses a worker may report.
+var workerStatuses = map[string]bool{
+	StatusRunning: true,
+	StatusDone:    true,
+	StatusError:   true,
+}
+
+// errJobFinished is returned by applyWorkerStatus for jobs already in a
+// terminal state, e.g. cancelled or failed by the stale sweeper.
+var errJobFinished = errors.New("job already finished")
+
+// check validates the report on its own, before the job is looked at.
+func (r workerStatusRequest) check() error {
+	if !workerStatuses[r.Status] {
+		return fmt.Errorf("status must be one of %s, %s, %s", StatusRunning, StatusDone, StatusError)
+	}
+	hasResult := len(r.Result) > 0 && string(r.Result) != "null"
+	switch {
+	case r.Status == StatusDone && !hasResult:
+		return errors.New("result is required with status done")
+	case r.Status != StatusDone && hasResult:
+		return errors.New("result is only accepted with status done")
+	case r.Status == StatusError && r.Error == "":
+		return errors.New("error is required with status error")
+	case r.Status != StatusError && r.Error != "":
+		return errors.New("error is only accepted with status error")
+	case r.Progress != nil && r.Status != StatusRunning:
+		return errors.New("progress is only accepted with status running")
+	case r.Progress != nil && (*r.Progress < 0 || *r.Progress > 100):
+		return errors.New("progress must be between 0 and 100")
+	}
+	if hasResult {
+		if _, err := parseSimulationResult(string(r.Result)); err != nil {
+			return fmt.Errorf("invalid result: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyWorkerStatus moves jobID to the reported status, storing the result
+// alongside. The meta is watched so a cancel or stale sweep in between wins;
+// reports for a job that is already terminal fail with errJobFinished.
+func applyWorkerStatus(ctx context.Context, jobID string, r workerStatusRequest, now time.Time) (JobMeta, error) {
+	key := jobMetaKey(jobID)
+	var result []byte
+	if r.Status == StatusDone {
+		var err error
+		if result, err = encodeResult(r.Result); err != nil {
+			return JobMeta{}, err
+		}
+	}
+	var meta JobMeta
+	var started bool
+	err := rdb.Watch(ctx, func(tx *redis.Tx) error {
+		raw, err := tx.Get(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(raw), &meta); err != nil {
+			return err
+		}
+		if isTerminalStatus(meta.Status) {
+			return errJobFinished
+		}
+		started = r.Status == StatusRunning && meta.StartedAt == nil
+		if started {
+			// queue wait is measured from created_at to the first running report
+			meta.StartedAt = &now
+		}
+		meta.Status = r.Status
+		meta.Error = r.Error
+		meta.UpdatedAt = now
+		ttl := metaTTL(meta)
+		setExpiry(&meta, now, ttl)
+		b, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if result != nil {
+				pipe.Set(ctx, jobResultKey(jobID), result, ttl)
+			}
+			if r.Progress != nil {
+				pipe.Set(ctx, jobProgressKey(jobID), strconv.FormatFloat(*r.Progress, 'f', -1, 64), ttl)
+			}
+			pipe.Set(ctx, key, b, ttl)
+			setExpiryMarker(ctx, pipe, meta)
+			return nil
+		})
+		return err
+	}, key)
+	if errors.Is(err, redis.Nil) {
+		return JobMeta{}, ErrMetaNotFound
+	} else if err != nil {
+		return JobMeta{}, err
+	}
+
+	if started {
+		observeJobStarted(ctx, meta)
+	}
+	if isTerminalStatus(meta.Status) {
+		observeJobFinished(ctx, meta)
+	}
+	if err := publishJobEvent(ctx, meta); err != nil {
+		slog.Warn("failed to publish job event", "job_id", jobID, "error", err)
+	}
+	if isTerminalStatus(meta.Status) {
+		if err := releaseActiveLock(ctx, meta.Params.GreenhouseID, jobID); err != nil {
+			slog.Warn("failed to release greenhouse lock", "job_id", jobID, "error", err)
+		}
+	}
+	return meta, nil
+}
+
+func workerStatusHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	var req workerStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if err := req.check(); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	meta, err := applyWorkerStatus(ctx, jobID, req, time.Now().UTC())
+	switch {
+	case errors.Is(err, ErrMetaNotFound):
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	case errors.Is(err, errJobFinished):
+		current, _ := redisMetaStore{}.GetMeta(ctx, jobID)
+		respondError(c, http.StatusConflict, err.Error(), gin.H{"job_id": jobID, "status": current.Status})
+		return
+	case errors.Is(err, redis.TxFailedErr):
+		respondError(c, http.StatusConflict, "job was updated concurrently, retry", gin.H{"job_id": jobID})
+		return
+	case err != nil:
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	loggerFrom(c).Info("worker reported job status", "job_id", jobID, "status", meta.Status)
+	c.JSON(http.StatusOK, meta)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withInternalSecret configures secret for the duration of the test.
+func withInternalSecret(t *testing.T, secret string) {
+	t.Helper()
+	orig := internalSecret
+	internalSecret = secret
+	t.Cleanup(func() { internalSecret = orig })
+}
+
+func reportStatus(t *testing.T, router *gin.Engine, secret, jobID string, body gin.H) *httptest.ResponseRecorder {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, _ := http.NewRequest("POST", "/internal/jobs/"+jobID+"/status", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(InternalSecretHeader, secret)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestWorkerStatusRequiresSecret(t *testing.T) {
+	router := setupRouter()
+	running := gin.H{"status": StatusRunning}
+
+	withInternalSecret(t, "")
+	w := reportStatus(t, router, "anything", "job-1", running)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "disabled without INTERNAL_API_SECRET")
+
+	withInternalSecret(t, "s3cret")
+	w = reportStatus(t, router, "", "job-1", running)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), InternalSecretHeader)
+
+	w = reportStatus(t, router, "wrong", "job-1", running)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestWorkerStatusValidation(t *testing.T) {
+	router := setupRouter()
+	withInternalSecret(t, "s3cret")
+
+	for name, body := range map[string]gin.H{
+		"unknown status":      {"status": StatusCancelled},
+		"done without result": {"status": StatusDone},
+		"error without error": {"status": StatusError},
+		"result with running": {"status": StatusRunning, "result": json.RawMessage(sampleResult)},
+		"malformed result":    {"status": StatusDone, "result": gin.H{"data": "nope"}},
+	} {
+		w := reportStatus(t, router, "s3cret", "job-1", body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+}
+
+func TestWorkerStatusTransitions(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withInternalSecret(t, "s3cret")
+
+	meta := seedJobMeta(t, ctx, "worker-job", StatusQueued)
+	meta.Params.GreenhouseID = "gh-1"
+	b, _ := json.Marshal(meta)
+	rdb.Set(ctx, RedisJobMetaPrefix+"worker-job", b, DefaultResultTTL)
+	rdb.Set(ctx, RedisActiveJobPrefix+"gh-1", "worker-job", DefaultResultTTL)
+
+	sub := rdb.Subscribe(ctx, RedisJobEventsPrefix+"worker-job")
+	defer sub.Close()
+	_, err := sub.Receive(ctx)
+	require.NoError(t, err)
+
+	w := reportStatus(t, router, "s3cret", "worker-job", gin.H{"status": StatusRunning})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var got JobMeta
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, StatusRunning, got.Status)
+	require.NotNil(t, got.StartedAt)
+	msg, err := sub.ReceiveMessage(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, msg.Payload, `"status":"running"`)
+
+	// a second running report is a heartbeat and keeps started_at
+	w = reportStatus(t, router, "s3cret", "worker-job", gin.H{"status": StatusRunning})
+	require.Equal(t, http.StatusOK, w.Code)
+	var again JobMeta
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.Equal(t, got.StartedAt.UnixNano(), again.StartedAt.UnixNano())
+
+	w = reportStatus(t, router, "s3cret", "worker-job", gin.H{"status": StatusDone, "result": json.RawMessage(sampleResult)})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	stored, err := rdb.Get(ctx, RedisResultsPrefix+"worker-job").Result()
+	require.NoError(t, err)
+	decoded, err := decodeResult(stored)
+	require.NoError(t, err)
+	assert.JSONEq(t, sampleResult, decoded)
+	assert.Positive(t, rdb.TTL(ctx, RedisResultsPrefix+"worker-job").Val())
+	assert.Zero(t, rdb.Exists(ctx, RedisActiveJobPrefix+"gh-1").Val(), "greenhouse lock released")
+
+	stored2, err := redisMetaStore{}.GetMeta(ctx, "worker-job")
+	require.NoError(t, err)
+	assert.Equal(t, StatusDone, stored2.Status)
+
+	// a finished job cannot be moved again
+	w = reportStatus(t, router, "s3cret", "worker-job", gin.H{"status": StatusError, "error": "late"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"done"`)
+
+	w = reportStatus(t, router, "s3cret", "missing-job", gin.H{"status": StatusRunning})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestWorkerStatusError(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withInternalSecret(t, "s3cret")
+	seedJobMeta(t, ctx, "failing-job", StatusRunning)
+
+	w := reportStatus(t, router, "s3cret", "failing-job", gin.H{"status": StatusError, "error": "weather API unreachable"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	meta, err := redisMetaStore{}.GetMeta(ctx, "failing-job")
+	require.NoError(t, err)
+	assert.Equal(t, StatusError, meta.Status)
+	assert.Equal(t, "weather API unreachable", meta.Error)
+	assert.Zero(t, rdb.Exists(ctx, RedisResultsPrefix+"failing-job").Val())
+
+	// cancelled jobs are terminal too; the worker learns it should stop
+	seedJobMeta(t, ctx, "cancelled-job", StatusCancelled)
+	w = reportStatus(t, router, "s3cret", "cancelled-job", gin.H{"status": StatusRunning})
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -0,0 +1,196 @@
+package main
+
+// backend/jobpatch.go
+//
+// PATCH /jobs/:job_id: fix the params of a job that is still queued, keeping
+// its id. The body is a partial SimulationParams merged over the stored
+// params, as for POST /jobs/:job_id/clone, and the result is converted,
+// defaulted and validated like a new submission. The job's payload is then
+// swapped in the jobs list at the same position, and its meta rewritten, in
+// one script: a worker either pops the old payload first (409, nothing
+// changes) or gets the new one with meta to match. A patch that changes
+// priority or queue moves the payload to the back of the new list.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// replacePayloadScript swaps payload ARGV[1] in list KEYS[1] for ARGV[2],
+// pushed onto KEYS[2] when that is another list, and stores meta ARGV[3] in
+// KEYS[3] for ARGV[4] ms. It returns 0 without writing when ARGV[1] is gone.
+var replacePayloadScript = redis.NewScript(`
+if KEYS[1] == KEYS[2] then
+  if redis.call('LINSERT', KEYS[1], 'BEFORE', ARGV[1], ARGV[2]) <= 0 then
+    return 0
+  end
+  redis.call('LREM', KEYS[1], 1, ARGV[1])
+else
+  if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+    return 0
+  end
+  redis.call('RPUSH', KEYS[2], ARGV[2])
+end
+redis.call('SET', KEYS[3], ARGV[3], 'PX', ARGV[4])
+return 1
+`)
+
+func patchJobHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	var patch SimulationParams
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, maxBodyBytes)
+			return
+		}
+		respondError(c, http.StatusBadRequest, "invalid JSON: expected a params patch: "+err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	if meta.Status != StatusQueued {
+		respondError(c, http.StatusConflict, "only queued jobs can be updated", gin.H{"job_id": jobID, "status": meta.Status})
+		return
+	}
+
+	// the same merge as clone: the stored params are SI, only the patch needs converting
+	units := meta.Units
+	if patch.Units != "" {
+		if units, err = toSI(&patch); err != nil {
+			respondValidationError(c, err)
+			return
+		}
+	}
+	base := meta.Params
+	if patch.RelativeStart != "" || patch.RelativeEnd != "" {
+		base.StartDate, base.EndDate = "", ""
+	}
+	params, err := overlayParams(base, patch)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to merge params: "+err.Error())
+		return
+	}
+	if err := applyPreset(&params); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	warnings := applyDefaults(&params)
+	implausible, err := validateParams(&params, c.Query("allow_extreme") == "true")
+	if err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	warnings = append(warnings, implausible...)
+
+	orig := meta
+	now := time.Now().UTC()
+	if patch.Tags != nil {
+		meta.Tags = normalizeTags(params.Tags)
+	}
+	params.Tags = nil
+	meta.Params = params
+	meta.Units = units
+	meta.Priority = params.Priority
+	if meta.Priority == "" {
+		meta.Priority = PriorityNormal
+	}
+	meta.Queue = params.Queue
+	meta.ResultTTLSeconds = int64(resultTTLFor(&params) / time.Second)
+	meta.UpdatedAt = now
+	setExpiry(&meta, now, metaTTL(meta))
+
+	raw, key, found, err := findQueuedPayload(ctx, orig.Queue, jobID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	replaced := false
+	if found {
+		if replaced, err = replaceQueuedJob(ctx, raw, key, meta); err != nil {
+			respondError(c, http.StatusInternalServerError, "failed to update job: "+err.Error())
+			return
+		}
+	}
+	if !replaced {
+		// a worker popped the payload between our reads; it is effectively running
+		respondError(c, http.StatusConflict, "job was already picked up by a worker", gin.H{"job_id": jobID, "status": StatusRunning})
+		return
+	}
+
+	if err := reindexPatchedJob(ctx, orig, meta); err != nil {
+		loggerFrom(c).Warn("failed to update job indexes", "job_id", jobID, "error", err)
+	}
+	if err := publishJobEvent(ctx, meta); err != nil {
+		loggerFrom(c).Warn("failed to publish job event", "job_id", jobID, "error", err)
+	}
+	if orig.Params.GreenhouseID != meta.Params.GreenhouseID {
+		if err := releaseActiveLock(ctx, orig.Params.GreenhouseID, jobID); err != nil {
+			loggerFrom(c).Warn("failed to release greenhouse lock", "job_id", jobID, "error", err)
+		}
+	}
+
+	resp := gin.H{"job_id": jobID, "status": StatusQueued, "units": units, "params": meta.Params, "links": jobLinks(jobID)}
+	if len(warnings) > 0 {
+		resp["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// replaceQueuedJob swaps the queued payload raw (in list key) for meta's and
+// stores meta. It reports false when a worker already took the job.
+func replaceQueuedJob(ctx context.Context, raw, key string, meta JobMeta) (bool, error) {
+	metaBytes, err := json.Marshal(meta)
+	if err != nil {
+		return false, err
+	}
+	// keep the trace of the submission the worker will continue
+	var old JobPayload
+	_ = json.Unmarshal([]byte(raw), &old)
+	payload := payloadFor(meta)
+	payload.TraceContext = old.TraceContext
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	n, err := replacePayloadScript.Run(ctx, rdb,
+		[]string{key, namedQueueFor(meta.Priority, meta.Queue), jobMetaKey(meta.JobID)},
+		raw, payloadBytes, metaBytes, metaTTL(meta).Milliseconds()).Int()
+	return n == 1, err
+}
+
+// reindexPatchedJob moves the tag, reuse and expiry entries of orig over to
+// the patched meta.
+func reindexPatchedJob(ctx context.Context, orig, meta JobMeta) error {
+	// the old params hash no longer describes this job; drop it if still ours
+	if err := releaseActiveScript.Run(ctx, rdb, []string{paramsHashKey(physicsHash(orig.Params))}, meta.JobID).Err(); err != nil {
+		return err
+	}
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, tag := range orig.Tags {
+			pipe.SRem(ctx, jobsByTagKey(tag), meta.JobID)
+		}
+		for _, tag := range meta.Tags {
+			pipe.SAdd(ctx, jobsByTagKey(tag), meta.JobID)
+		}
+		pipe.Set(ctx, paramsHashKey(physicsHash(meta.Params)), meta.JobID, metaTTL(meta))
+		setExpiryMarker(ctx, pipe, meta)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func patchJob(router *gin.Engine, jobID, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PATCH", "/jobs/"+jobID, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// queuedPayloads decodes the payloads waiting in list key, head first.
+func queuedPayloads(t *testing.T, ctx context.Context, key string) []JobPayload {
+	t.Helper()
+	items, err := rdb.LRange(ctx, key, 0, -1).Result()
+	require.NoError(t, err)
+	payloads := make([]JobPayload, len(items))
+	for i, item := range items {
+		require.NoError(t, json.Unmarshal([]byte(item), &payloads[i]))
+	}
+	return payloads
+}
+
+func TestPatchQueuedJob(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	var ids []string
+	for _, body := range []string{
+		withSite(`{"setpoint": 14, "start_date": "2025-11-01", "end_date": "2025-11-03", "tags": ["draft"]}`),
+		withSite(`{"setpoint": 16}`),
+	} {
+		w := postSimulate(router, body)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		ids = append(ids, resp["job_id"].(string))
+	}
+	before, err := metaStore.GetMeta(ctx, ids[0])
+	require.NoError(t, err)
+
+	w := patchJob(router, ids[0], `{"setpoint": 18, "tags": ["final"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		JobID  string           `json:"job_id"`
+		Status string           `json:"status"`
+		Params SimulationParams `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, ids[0], resp.JobID, "the job keeps its id")
+	assert.Equal(t, StatusQueued, resp.Status)
+	assert.Equal(t, 18.0, *resp.Params.Setpoint)
+
+	meta, err := metaStore.GetMeta(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, meta.Status)
+	assert.Equal(t, 18.0, *meta.Params.Setpoint)
+	assert.Equal(t, "2025-11-01", meta.Params.StartDate, "unpatched params are kept")
+	assert.Equal(t, *before.Params.Seed, *meta.Params.Seed, "so is the seed")
+	assert.Equal(t, before.CreatedAt, meta.CreatedAt)
+	assert.True(t, meta.UpdatedAt.After(before.UpdatedAt))
+	assert.Equal(t, []string{"final"}, meta.Tags)
+	assert.Empty(t, rdb.SMembers(ctx, jobsByTagKey("draft")).Val())
+	assert.Equal(t, []string{ids[0]}, rdb.SMembers(ctx, jobsByTagKey("final")).Val())
+
+	// the payload is replaced in place: same position, new params, no duplicate
+	payloads := queuedPayloads(t, ctx, queueFor(PriorityNormal))
+	require.Len(t, payloads, 2)
+	assert.Equal(t, ids[0], payloads[0].JobID)
+	assert.Equal(t, 18.0, *payloads[0].Params.Setpoint)
+	assert.Equal(t, ids[1], payloads[1].JobID)
+
+	// a new priority moves the job to that list
+	w = patchJob(router, ids[1], `{"priority": "high"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	payloads = queuedPayloads(t, ctx, queueFor(PriorityNormal))
+	require.Len(t, payloads, 1)
+	assert.Equal(t, ids[0], payloads[0].JobID)
+	payloads = queuedPayloads(t, ctx, queueFor(PriorityHigh))
+	require.Len(t, payloads, 1)
+	assert.Equal(t, ids[1], payloads[0].JobID)
+	meta, err = metaStore.GetMeta(ctx, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, PriorityHigh, meta.Priority)
+
+	// an invalid patch changes nothing
+	w = patchJob(router, ids[0], `{"tau_glass": 3}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "tau_glass", decodeError(t, w).Fields[0].Field)
+	meta, err = metaStore.GetMeta(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, *before.Params.TauGlass, *meta.Params.TauGlass)
+}
+
+func TestPatchRunningJobConflicts(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	running := seedJobMeta(t, ctx, "patch-running", StatusRunning)
+
+	w := patchJob(router, "patch-running", `{"setpoint": 18}`)
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, CodeConflict, decodeError(t, w).Error.Code)
+	assert.Contains(t, w.Body.String(), `"status":"running"`)
+	meta, err := metaStore.GetMeta(ctx, "patch-running")
+	require.NoError(t, err)
+	assert.Equal(t, running.Params, meta.Params)
+	assert.Equal(t, StatusRunning, meta.Status)
+
+	// still queued in the meta, but a worker already popped the payload
+	seedJobMeta(t, ctx, "patch-popped", StatusQueued)
+	w = patchJob(router, "patch-popped", withSite(`{"setpoint": 18}`))
+	require.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, decodeError(t, w).Error.Message, "already picked up")
+	assert.Zero(t, rdb.LLen(ctx, queueFor(PriorityNormal)).Val())
+
+	assert.Equal(t, http.StatusNotFound, patchJob(router, "patch-unknown", `{"setpoint": 18}`).Code)
+}
@@ -0,0 +1,389 @@
+package main
+
+// backend/jobs.go
+//
+// Job management handlers (list, delete, cancel, retry) operating on the
+// job_meta / job_result keys, the jobs queue and the recent-jobs list.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// Pagination limits for GET /jobs
+const (
+	DefaultJobsPageLimit = 20
+	MaxJobsPageLimit     = 200
+)
+
+// knownStatuses is the set accepted by the ?status= filter.
+var knownStatuses = map[string]bool{
+	StatusQueued:    true,
+	StatusRunning:   true,
+	StatusDone:      true,
+	StatusError:     true,
+	StatusCancelled: true,
+}
+
+// loadMetas fetches the meta for each id with a single MGET, preserving order.
+// Ids whose meta has expired or cannot be parsed are skipped.
+func loadMetas(ctx context.Context, ids []string) ([]JobMeta, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = jobMetaKey(id)
+	}
+	vals, err := rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]JobMeta, 0, len(vals))
+	for _, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var meta JobMeta
+		if json.Unmarshal([]byte(s), &meta) != nil {
+			continue
+		}
+		metas = append(metas, meta)
+	}
+	return metas, nil
+}
+
+// parsePage reads ?limit= (default 20, capped at 200) and ?offset=. On bad
+// input it writes a 400 and returns ok=false.
+func parsePage(c *gin.Context) (limit, offset int, ok bool) {
+	limit = DefaultJobsPageLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondInvalidParam(c, "limit", "limit must be a positive integer")
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if limit > MaxJobsPageLimit {
+		limit = MaxJobsPageLimit
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondInvalidParam(c, "offset", "offset must be a non-negative integer")
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+// listJobsHandler returns recent jobs' metadata, optionally filtered by status
+// and by one or more ?tag= (jobs must carry all of them), paginated with
+// ?limit= (default 20, max 200) and ?offset=.
+func listJobsHandler(c *gin.Context) {
+	limit, offset, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	if status != "" && !knownStatuses[status] {
+		respondInvalidParam(c, "status", "unknown status: "+status)
+		return
+	}
+
+	tags := normalizeTags(c.QueryArray("tag"))
+	for _, t := range tags {
+		if !validTag(t) {
+			respondInvalidParam(c, "tag", "invalid tag: "+t)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	page, total, err := metaStore.ListMeta(ctx, MetaQuery{Status: status, Tags: tags, Limit: limit, Offset: offset})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+		"jobs":   page,
+	})
+}
+
+// getJobParamsHandler returns just the job's resolved params: what the worker
+// ran with after defaults and derived values (such as C) were filled in, so the
+// body can be resubmitted to reproduce the run.
+func getJobParamsHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	meta, err := metaStore.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, meta.Params)
+}
+
+// deleteJobHandler removes a job's meta, result, partial result, logs, note,
+// recent-list entry and tag index entries in a single MULTI/EXEC. A durable
+// metadata backend drops its copy first, so a failure there leaves the job
+// whole for a retry.
+func deleteJobHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	metaStr, err := rdb.Get(ctx, jobMetaKey(jobID)).Result()
+	if err == redis.Nil {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	var meta JobMeta
+	_ = json.Unmarshal([]byte(metaStr), &meta)
+
+	metaKey := jobMetaKey(jobID)
+	resultKey := jobResultKey(jobID)
+	artifacts, err := artifactKeys(ctx, []string{jobID})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	if mirrorsMeta() {
+		if err := metaStore.DeleteMeta(ctx, jobID); err != nil {
+			respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+			return
+		}
+	}
+	var metaDel, resultDel, recentRem *redis.IntCmd
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		metaDel = pipe.Del(ctx, metaKey)
+		resultDel = pipe.Del(ctx, resultKey)
+		pipe.Del(ctx, partialResultKey(jobID))
+		pipe.Del(ctx, jobLogsKey(jobID))
+		pipe.Del(ctx, jobProgressKey(jobID))
+		pipe.Del(ctx, jobNotesKey(jobID))
+		pipe.Del(ctx, jobExpiryKey(jobID))
+		pipe.Del(ctx, artifacts...)
+		recentRem = pipe.LRem(ctx, redisKey(RedisRecentJobsList), 0, jobID)
+		for _, tag := range meta.Tags {
+			pipe.SRem(ctx, jobsByTagKey(tag), jobID)
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to delete job: "+err.Error())
+		return
+	}
+	if err := releaseActiveLock(ctx, meta.Params.GreenhouseID, jobID); err != nil {
+		loggerFrom(c).Warn("failed to release greenhouse lock", "job_id", jobID, "error", err)
+	}
+
+	deleted := []string{}
+	if metaDel.Val() > 0 {
+		deleted = append(deleted, metaKey)
+	}
+	if resultDel.Val() > 0 {
+		deleted = append(deleted, resultKey)
+	}
+	resp := gin.H{
+		"job_id":              jobID,
+		"deleted_keys":        deleted,
+		"removed_from_recent": recentRem.Val() > 0,
+	}
+	if meta.Status == StatusRunning {
+		resp["warning"] = "job was running; the worker may still write an orphaned result"
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// scanQueue looks for the payload carrying jobID in the jobs lists of the named
+// queue, in the order workers drain them. It returns the raw list element, the
+// list holding it, its index across all lists (-1 if absent) and the combined
+// length of the lists.
+func scanQueue(ctx context.Context, queue, jobID string) (raw, key string, index, length int, err error) {
+	index = -1
+	for _, q := range jobQueues {
+		items, err := rdb.LRange(ctx, q.KeyFor(queue), 0, -1).Result()
+		if err != nil && err != redis.Nil {
+			return "", "", -1, 0, err
+		}
+		for i, item := range items {
+			if index >= 0 {
+				break
+			}
+			var payload JobPayload
+			if json.Unmarshal([]byte(item), &payload) != nil {
+				continue
+			}
+			if payload.JobID == jobID {
+				raw, key, index = item, q.KeyFor(queue), length+i
+			}
+		}
+		length += len(items)
+	}
+	return raw, key, index, length, nil
+}
+
+// findQueuedPayload returns the raw list element for jobID in the named queue
+// and the list holding it (needed for LREM). found is false once a worker has
+// already popped it.
+func findQueuedPayload(ctx context.Context, queue, jobID string) (raw, key string, found bool, err error) {
+	raw, key, index, _, err := scanQueue(ctx, queue, jobID)
+	return raw, key, index >= 0, err
+}
+
+// queueInfo is added to responses for queued jobs. Workers pop from the head of
+// the jobs lists, highest priority first, so position 1 is the next job to run.
+type queueInfo struct {
+	QueuePosition int `json:"queue_position,omitempty"`
+	QueueLength   int `json:"queue_length,omitempty"`
+}
+
+// lookupQueueInfo returns the job's place in its queue; it is empty when the
+// job is not (or no longer) waiting in the jobs list.
+func lookupQueueInfo(ctx context.Context, queue, jobID string) (queueInfo, error) {
+	_, _, index, length, err := scanQueue(ctx, queue, jobID)
+	if err != nil || index < 0 {
+		return queueInfo{}, err
+	}
+	return queueInfo{QueuePosition: index + 1, QueueLength: length}, nil
+}
+
+// jobMetaResponse is the body of GET /jobs/:job_id.
+type jobMetaResponse struct {
+	JobMeta
+	queueInfo
+	Progress *float64 `json:"progress,omitempty"` // percent done, see jobProgress
+	Note     string   `json:"note,omitempty"`     // see notes.go
+}
+
+// cancelJobHandler cancels a queued job: its payload is pulled from the jobs
+// list so no worker picks it up, and the meta status becomes cancelled.
+func cancelJobHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	metaStr, err := rdb.Get(ctx, jobMetaKey(jobID)).Result()
+	if err == redis.Nil {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	var meta JobMeta
+	if err := json.Unmarshal([]byte(metaStr), &meta); err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to parse job meta")
+		return
+	}
+	if meta.Status != StatusQueued {
+		respondError(c, http.StatusConflict, "only queued jobs can be cancelled", gin.H{"job_id": jobID, "status": meta.Status})
+		return
+	}
+
+	raw, key, found, err := findQueuedPayload(ctx, meta.Queue, jobID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	removed := int64(0)
+	if found {
+		removed, err = rdb.LRem(ctx, key, 1, raw).Result()
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "failed to dequeue job: "+err.Error())
+			return
+		}
+	}
+	if removed == 0 {
+		// a worker popped the payload between our reads; it is effectively running
+		respondError(c, http.StatusConflict, "job was already picked up by a worker", gin.H{"job_id": jobID, "status": StatusRunning})
+		return
+	}
+
+	meta.Status = StatusCancelled
+	meta.UpdatedAt = time.Now().UTC()
+	metaBytes, _ := json.Marshal(meta)
+	if err := rdb.Set(ctx, jobMetaKey(jobID), metaBytes, redis.KeepTTL).Err(); err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to update job meta: "+err.Error())
+		return
+	}
+	observeJobFinished(ctx, meta)
+	if err := publishJobEvent(ctx, meta); err != nil {
+		loggerFrom(c).Warn("failed to publish job event", "job_id", jobID, "error", err)
+	}
+	if err := releaseActiveLock(ctx, meta.Params.GreenhouseID, jobID); err != nil {
+		loggerFrom(c).Warn("failed to release greenhouse lock", "job_id", jobID, "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"job_id": jobID, "status": StatusCancelled})
+}
+
+// retryMeta is a new queued job running orig's params, linked by retry_of.
+func retryMeta(orig JobMeta, now time.Time) JobMeta {
+	meta := newJobMeta(orig.Params, now)
+	meta.RetryOf = orig.JobID
+	meta.Units = orig.Units
+	meta.Tags = orig.Tags
+	return meta
+}
+
+// retryJobHandler enqueues a new job with the original's params. Only jobs that
+// reached error or done can be retried; the new meta points back via retry_of.
+func retryJobHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	orig, err := metaStore.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	if orig.Status != StatusError && orig.Status != StatusDone {
+		respondError(c, http.StatusConflict, "only finished or failed jobs can be retried", gin.H{"job_id": jobID, "status": orig.Status})
+		return
+	}
+
+	meta := retryMeta(orig, time.Now().UTC())
+	if !claimJobQuota(c, ctx, []JobMeta{meta}) {
+		return
+	}
+	if err := enqueueJobs(ctx, []JobMeta{meta}); err != nil {
+		releaseJobQuota(c, ctx, []JobMeta{meta})
+		respondError(c, http.StatusInternalServerError, "failed to enqueue job: "+err.Error())
+		return
+	}
+
+	links := jobLinks(meta.JobID)
+	c.Header("Location", links["result"])
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":   meta.JobID,
+		"status":   StatusQueued,
+		"retry_of": jobID,
+		"links":    links,
+	})
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedOption adjusts the JobMeta seedJobMeta stores.
+type seedOption func(*JobMeta)
+
+// seededAgo backdates the job's creation and last update by age.
+func seededAgo(age time.Duration) seedOption {
+	return func(m *JobMeta) {
+		m.CreatedAt = m.CreatedAt.Add(-age)
+		m.UpdatedAt = m.CreatedAt
+	}
+}
+
+// seededTags tags the job.
+func seededTags(tags ...string) seedOption {
+	return func(m *JobMeta) { m.Tags = tags }
+}
+
+// seedJobMeta stores a JobMeta with the given status, adjusted by opts, and
+// returns it.
+func seedJobMeta(t *testing.T, ctx context.Context, jobID, status string, opts ...seedOption) JobMeta {
+	t.Helper()
+	now := time.Now().UTC()
+	meta := JobMeta{
+		JobID:     jobID,
+		Status:    status,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ResultKey: jobResultKey(jobID),
+	}
+	for _, opt := range opts {
+		opt(&meta)
+	}
+	metaBytes, err := json.Marshal(meta)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, jobMetaKey(jobID), metaBytes, DefaultResultTTL).Err())
+	return meta
+}
+
+func TestDeleteJob(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	jobID := "delete-me"
+	seedJobMeta(t, ctx, jobID, StatusDone)
+	rdb.Set(ctx, RedisResultsPrefix+jobID, `{"data": []}`, DefaultResultTTL)
+	rdb.LPush(ctx, RedisRecentJobsList, "other-job", jobID)
+
+	req, _ := http.NewRequest("DELETE", "/jobs/"+jobID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.ElementsMatch(t, []interface{}{RedisJobMetaPrefix + jobID, RedisResultsPrefix + jobID}, response["deleted_keys"])
+	assert.Equal(t, true, response["removed_from_recent"])
+	assert.NotContains(t, response, "warning")
+
+	assert.Equal(t, redis.Nil, rdb.Get(ctx, RedisJobMetaPrefix+jobID).Err())
+	assert.Equal(t, redis.Nil, rdb.Get(ctx, RedisResultsPrefix+jobID).Err())
+	ids, _ := rdb.LRange(ctx, RedisRecentJobsList, 0, -1).Result()
+	assert.Equal(t, []string{"other-job"}, ids)
+}
+
+func TestDeleteRunningJobWarns(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	jobID := "running-job"
+	seedJobMeta(t, ctx, jobID, StatusRunning)
+
+	req, _ := http.NewRequest("DELETE", "/jobs/"+jobID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response, "warning")
+	assert.Equal(t, []interface{}{RedisJobMetaPrefix + jobID}, response["deleted_keys"])
+	assert.Equal(t, redis.Nil, rdb.Get(ctx, RedisJobMetaPrefix+jobID).Err())
+}
+
+func TestDeleteJobNotFound(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("DELETE", "/jobs/nonexistent", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// seedQueuedPayload pushes a job payload onto the jobs list like submitJobHandler does.
+func seedQueuedPayload(t *testing.T, ctx context.Context, jobID string) {
+	t.Helper()
+	payloadBytes, err := json.Marshal(JobPayload{JobID: jobID, CreatedAt: time.Now().UTC()})
+	require.NoError(t, err)
+	require.NoError(t, rdb.RPush(ctx, RedisJobsList, payloadBytes).Err())
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	seedJobMeta(t, ctx, "keep-me", StatusQueued)
+	seedQueuedPayload(t, ctx, "keep-me")
+	seedJobMeta(t, ctx, "cancel-me", StatusQueued)
+	seedQueuedPayload(t, ctx, "cancel-me")
+
+	req, _ := http.NewRequest("POST", "/jobs/cancel-me/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, StatusCancelled, response["status"])
+
+	metaStr, err := rdb.Get(ctx, RedisJobMetaPrefix+"cancel-me").Result()
+	require.NoError(t, err)
+	var meta JobMeta
+	require.NoError(t, json.Unmarshal([]byte(metaStr), &meta))
+	assert.Equal(t, StatusCancelled, meta.Status)
+
+	_, _, found, err := findQueuedPayload(ctx, "", "cancel-me")
+	require.NoError(t, err)
+	assert.False(t, found)
+	_, _, found, err = findQueuedPayload(ctx, "", "keep-me")
+	require.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestCancelNonQueuedJobConflicts(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+
+	for _, status := range []string{StatusRunning, StatusDone, StatusError, StatusCancelled} {
+		t.Run(status, func(t *testing.T) {
+			rdb.FlushDB(ctx)
+			seedJobMeta(t, ctx, "job-1", status)
+
+			req, _ := http.NewRequest("POST", "/jobs/job-1/cancel", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusConflict, w.Code)
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, status, response["status"])
+		})
+	}
+}
+
+func TestCancelJobAlreadyPoppedConflicts(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	// meta still says queued but the payload is gone from the list
+	seedJobMeta(t, ctx, "popped", StatusQueued)
+
+	req, _ := http.NewRequest("POST", "/jobs/popped/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCancelJobNotFound(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("POST", "/jobs/nonexistent/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// listJobs calls GET /jobs with the given query string and decodes the page.
+func listJobs(t *testing.T, router http.Handler, query string) (int, int, []JobMeta) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/jobs"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response struct {
+		Total int       `json:"total"`
+		Jobs  []JobMeta `json:"jobs"`
+	}
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	}
+	return w.Code, response.Total, response.Jobs
+}
+
+func TestListJobsFilteringAndPagination(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	statuses := []string{StatusQueued, StatusDone, StatusDone, StatusError, StatusRunning, StatusDone}
+	for i, status := range statuses {
+		jobID := "job-" + strconv.Itoa(i)
+		seedJobMeta(t, ctx, jobID, status)
+		rdb.LPush(ctx, RedisRecentJobsList, jobID)
+	}
+	// an id whose meta has expired is skipped
+	rdb.LPush(ctx, RedisRecentJobsList, "expired-job")
+
+	code, total, jobs := listJobs(t, router, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, len(statuses), total)
+	assert.Len(t, jobs, len(statuses))
+	assert.Equal(t, "job-5", jobs[0].JobID) // most recent first
+
+	_, total, jobs = listJobs(t, router, "?status=done")
+	assert.Equal(t, 3, total)
+	for _, j := range jobs {
+		assert.Equal(t, StatusDone, j.Status)
+	}
+
+	_, total, jobs = listJobs(t, router, "?status=done&limit=2&offset=1")
+	assert.Equal(t, 3, total)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, "job-2", jobs[0].JobID)
+	assert.Equal(t, "job-1", jobs[1].JobID)
+
+	_, total, jobs = listJobs(t, router, "?offset=10")
+	assert.Equal(t, len(statuses), total)
+	assert.Empty(t, jobs)
+}
+
+func TestListJobsRejectsBadQuery(t *testing.T) {
+	router := setupRouter()
+
+	for _, query := range []string{"?limit=0", "?limit=abc", "?offset=-1", "?status=bogus"} {
+		code, _, _ := listJobs(t, router, query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+}
+
+func TestGetJobParamsEchoesResolvedDefaults(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{"thermal_mass_kg": 1000, "A_glass": 75}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var submitted map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &submitted))
+
+	req, _ = http.NewRequest("GET", "/jobs/"+submitted["job_id"].(string)+"/params", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var params SimulationParams
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &params))
+	assert.Equal(t, 75.0, *params.A_glass) // as submitted
+	assert.Equal(t, 1000.0, *params.ThermalMassKg)
+	// defaulted
+	assert.Equal(t, 4186.0, *params.CpMass)
+	assert.Equal(t, 0.85, *params.TauGlass)
+	assert.Equal(t, 0.5, *params.ACH)
+	assert.Equal(t, 100.0, *params.Volume)
+	assert.Equal(t, 12.0, *params.Setpoint)
+	// derived from thermal_mass_kg * cp_mass
+	assert.Equal(t, 1000*4186.0, *params.C)
+}
+
+func TestGetJobParamsNotFound(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("GET", "/jobs/nonexistent/params", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestQueuePosition(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	var jobIDs []string
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{}`)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		jobIDs = append(jobIDs, response["job_id"].(string))
+	}
+
+	// queueFields fetches path and returns its queue_position / queue_length (nil if absent).
+	queueFields := func(path string) (interface{}, interface{}) {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["queue_position"], response["queue_length"]
+	}
+
+	for i, jobID := range jobIDs {
+		for _, path := range []string{"/jobs/" + jobID, "/results/" + jobID} {
+			pos, length := queueFields(path)
+			assert.Equal(t, float64(i+1), pos, path)
+			assert.Equal(t, float64(3), length, path)
+		}
+	}
+
+	// a worker pops the head; its meta still says queued until it reports running
+	require.NoError(t, rdb.LPop(ctx, RedisJobsList).Err())
+	pos, length := queueFields("/jobs/" + jobIDs[0])
+	assert.Nil(t, pos)
+	assert.Nil(t, length)
+	pos, length = queueFields("/results/" + jobIDs[2])
+	assert.Equal(t, float64(2), pos)
+	assert.Equal(t, float64(2), length)
+
+	// non-queued jobs never report a position
+	seedJobMeta(t, ctx, "running-job", StatusRunning)
+	pos, _ = queueFields("/jobs/running-job")
+	assert.Nil(t, pos)
+}
+
+func TestRetryErroredJob(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	orig := seedJobMeta(t, ctx, "failed", StatusError)
+	orig.Params = SimulationParams{Setpoint: floatPtr(16), Priority: PriorityHigh}
+	orig.Error = "weather API timeout"
+	metaBytes, _ := json.Marshal(orig)
+	require.NoError(t, rdb.Set(ctx, RedisJobMetaPrefix+"failed", metaBytes, DefaultResultTTL).Err())
+
+	req, _ := http.NewRequest("POST", "/jobs/failed/retry", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	newID := response["job_id"].(string)
+	assert.NotEqual(t, "failed", newID)
+	assert.Equal(t, "failed", response["retry_of"])
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, newID)
+	require.NoError(t, err)
+	assert.Equal(t, "failed", meta.RetryOf)
+	assert.Equal(t, StatusQueued, meta.Status)
+	assert.Equal(t, 16.0, *meta.Params.Setpoint)
+	assert.Empty(t, meta.Error)
+	_, key, found, err := findQueuedPayload(ctx, "", newID)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, queueFor(PriorityHigh), key)
+
+	// the original is left untouched
+	prev, err := redisMetaStore{}.GetMeta(ctx, "failed")
+	require.NoError(t, err)
+	assert.Equal(t, StatusError, prev.Status)
+}
+
+func TestRetryJobConflictsAndNotFound(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+
+	for _, status := range []string{StatusQueued, StatusRunning, StatusCancelled} {
+		t.Run(status, func(t *testing.T) {
+			rdb.FlushDB(ctx)
+			seedJobMeta(t, ctx, "job-1", status)
+
+			req, _ := http.NewRequest("POST", "/jobs/job-1/retry", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusConflict, w.Code)
+			assert.Equal(t, int64(0), rdb.LLen(ctx, RedisJobsList).Val())
+		})
+	}
+
+	t.Run("not found", func(t *testing.T) {
+		rdb.FlushDB(ctx)
+		req, _ := http.NewRequest("POST", "/jobs/nonexistent/retry", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,67 @@
+package main
+
+// backend/keys.go
+//
+// Redis key construction. Every key and pub/sub channel the backend uses is
+// built here and carries REDIS_KEY_PREFIX (e.g. "staging:"), so several
+// environments can share one Redis database without seeing each other's jobs.
+// The worker reads the same variable. The Redis*Prefix constants next to each
+// feature name the key families; handlers call the helpers below rather than
+// concatenating them.
+
+import (
+	"os"
+	"strings"
+)
+
+// redisKeyPrefix namespaces all keys; empty keeps the historical key names.
+var redisKeyPrefix = ""
+
+func loadKeyPrefixConfig() {
+	redisKeyPrefix = os.Getenv("REDIS_KEY_PREFIX")
+}
+
+// redisKey returns name in the configured namespace.
+func redisKey(name string) string {
+	return redisKeyPrefix + name
+}
+
+func jobMetaKey(jobID string) string       { return redisKey(RedisJobMetaPrefix + jobID) }
+func jobResultKey(jobID string) string     { return redisKey(RedisResultsPrefix + jobID) }
+func partialResultKey(jobID string) string { return redisKey(RedisPartialResultsPrefix + jobID) }
+func jobProgressKey(jobID string) string   { return redisKey(RedisJobProgressPrefix + jobID) }
+func jobNotesKey(jobID string) string      { return redisKey(RedisJobNotesPrefix + jobID) }
+func jobLogsKey(jobID string) string       { return redisKey(RedisJobLogsPrefix + jobID) }
+func jobEventsChannel(jobID string) string { return redisKey(RedisJobEventsPrefix + jobID) }
+func jobsByTagKey(tag string) string       { return redisKey(RedisJobsByTagPrefix + tag) }
+func activeJobKey(greenhouseID string) string {
+	return redisKey(RedisActiveJobPrefix + greenhouseID)
+}
+func activeJobsKey(client string) string { return redisKey(RedisActiveJobsPrefix + client) }
+func idempotencyKey(client, key string) string {
+	return redisKey(RedisIdempotencyPrefix + client + ":" + key)
+}
+func rateLimitKey(client string) string    { return redisKey(RedisRateLimitPrefix + client) }
+func paramsHashKey(hash string) string     { return redisKey(RedisParamsHashPrefix + hash) }
+func scenarioKey(id string) string         { return redisKey(RedisScenarioPrefix + id) }
+func dedupKey(hash string) string          { return redisKey(RedisDedupPrefix + hash) }
+func requeueMarkKey(status string) string  { return redisKey(RedisRequeueMarkPrefix + status) }
+func artifactIndexKey(jobID string) string { return redisKey(RedisArtifactIndexPrefix + jobID) }
+func jobExpiryKey(jobID string) string     { return redisKey(RedisJobExpiryPrefix + jobID) }
+func workerHeartbeatKey(workerID string) string {
+	return redisKey(RedisWorkerHeartbeatPrefix + workerID)
+}
+func presetRunKey(preset, hash string) string {
+	return redisKey(RedisPresetRunPrefix + preset + ":" + hash)
+}
+func metricsSeenKey(transition, jobID string) string {
+	return redisKey(RedisMetricsSeenPrefix + transition + ":" + jobID)
+}
+func artifactKey(jobID, name string) string {
+	return redisKey(RedisArtifactPrefix + jobID + ":" + name)
+}
+
+// jobIDFromMetaKey is the inverse of jobMetaKey, for keys found by SCAN.
+func jobIDFromMetaKey(key string) string {
+	return strings.TrimPrefix(key, jobMetaKey(""))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withKeyPrefix(t *testing.T, prefix string) {
+	t.Helper()
+	orig := redisKeyPrefix
+	redisKeyPrefix = prefix
+	t.Cleanup(func() { redisKeyPrefix = orig })
+}
+
+func TestRedisKeysNamespaced(t *testing.T) {
+	assert.Equal(t, "job_meta:abc", jobMetaKey("abc"), "no prefix keeps the historical names")
+
+	withKeyPrefix(t, "staging:")
+	assert.Equal(t, "staging:job_meta:abc", jobMetaKey("abc"))
+	assert.Equal(t, "staging:job_result:abc", jobResultKey("abc"))
+	assert.Equal(t, "staging:job_events:abc", jobEventsChannel("abc"))
+	assert.Equal(t, "staging:simulation_jobs", queueFor(PriorityNormal))
+	assert.Equal(t, "staging:simulation_jobs_high", queueFor(PriorityHigh))
+	assert.Equal(t, "staging:recent_simulation_ids", redisKey(RedisRecentJobsList))
+	assert.Equal(t, "staging:weather:1.5:2:2025-11-01:2025-11-02", weatherQuery{1.5, 2, "2025-11-01", "2025-11-02"}.key())
+	assert.Equal(t, "abc", jobIDFromMetaKey(jobMetaKey("abc")))
+}
+
+func TestKeyPrefixesDoNotCollide(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	get := func(path string) int {
+		req, _ := http.NewRequest("GET", path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	withKeyPrefix(t, "dev:")
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{"setpoint": 12}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp struct {
+		JobID string `json:"job_id"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.Equal(t, int64(1), rdb.Exists(ctx, "dev:job_meta:"+resp.JobID).Val())
+	assert.Equal(t, int64(1), rdb.LLen(ctx, "dev:simulation_jobs").Val())
+	assert.Equal(t, int64(1), rdb.LLen(ctx, "dev:recent_simulation_ids").Val())
+	assert.Zero(t, rdb.Exists(ctx, "job_meta:"+resp.JobID, "simulation_jobs", "recent_simulation_ids").Val())
+
+	// another environment on the same database sees none of it
+	redisKeyPrefix = "staging:"
+	assert.Equal(t, http.StatusNotFound, get("/jobs/"+resp.JobID))
+	seedJobMeta(t, ctx, resp.JobID, StatusDone)
+	assert.Equal(t, http.StatusOK, get("/jobs/"+resp.JobID))
+	assert.Equal(t, int64(1), rdb.Exists(ctx, "staging:job_meta:"+resp.JobID).Val())
+
+	redisKeyPrefix = "dev:"
+	req, _ = http.NewRequest("GET", "/jobs/"+resp.JobID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"queued"`, "each prefix keeps its own meta")
+}
@@ -0,0 +1,126 @@
+package main
+
+// backend/logging.go
+//
+// Structured JSON logging via log/slog. Every request gets an X-Request-ID
+// (propagated from the client or generated) that is attached to the request's
+// logger, echoed in the response header and included in error responses.
+//
+// The access log line can be sampled: with LOG_SAMPLE_RATE=N only one in N
+// successful requests is logged, while errors (status >= 400) and requests
+// slower than LOG_SLOW_THRESHOLD_MS are always logged. Sampled lines carry
+// sample_rate so counts can be scaled back up.
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request id in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// ctxRequestID is the gin context key holding the request id.
+const ctxRequestID = "request_id"
+
+// Access log sampling defaults
+const (
+	DefaultLogSampleRate    = 1           // log every request
+	DefaultLogSlowThreshold = time.Second // requests at least this slow are always logged
+)
+
+var (
+	logSampleRate    = DefaultLogSampleRate
+	logSlowThreshold = DefaultLogSlowThreshold
+	// logSampleCount numbers the requests eligible for sampling
+	logSampleCount atomic.Uint64
+)
+
+func loadLoggingConfig() {
+	logSampleRate = envInt("LOG_SAMPLE_RATE", DefaultLogSampleRate)
+	if logSampleRate < 1 {
+		slog.Warn("ignoring invalid LOG_SAMPLE_RATE", "value", logSampleRate)
+		logSampleRate = DefaultLogSampleRate
+	}
+	logSlowThreshold = envMillis("LOG_SLOW_THRESHOLD_MS", DefaultLogSlowThreshold)
+}
+
+type loggerCtxKey struct{}
+
+// initLogger makes JSON the default slog output.
+func initLogger() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+}
+
+// loggerFromContext returns the request-scoped logger stored by requestLogger,
+// or the default logger.
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerCtxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// loggerFrom returns the request-scoped logger for c.
+func loggerFrom(c *gin.Context) *slog.Logger {
+	return loggerFromContext(c.Request.Context())
+}
+
+// accessLogRate decides whether a request that ended with status after
+// latency gets an access log line. It returns 0 to skip it, 1 when the line is
+// unsampled (errors, slow requests, sampling off) and logSampleRate when it
+// stands for that many requests.
+func accessLogRate(status int, latency time.Duration) int {
+	if logSampleRate <= 1 || status >= http.StatusBadRequest {
+		return 1
+	}
+	if logSlowThreshold > 0 && latency >= logSlowThreshold {
+		return 1
+	}
+	if logSampleCount.Add(1)%uint64(logSampleRate) != 1 {
+		return 0
+	}
+	return logSampleRate
+}
+
+// requestLogger assigns the request id, attaches a logger carrying it to the
+// request context and logs method, path, status and latency once handled,
+// subject to sampling.
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		reqID := c.GetHeader(RequestIDHeader)
+		if reqID == "" {
+			reqID = uuid.NewString()
+		}
+		c.Set(ctxRequestID, reqID)
+		c.Header(RequestIDHeader, reqID)
+
+		logger := slog.Default().With(slog.String("request_id", reqID))
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), loggerCtxKey{}, logger))
+
+		c.Next()
+
+		status, latency := c.Writer.Status(), time.Since(start)
+		rate := accessLogRate(status, latency)
+		if rate == 0 {
+			return
+		}
+		attrs := []any{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", latency),
+		}
+		if rate > 1 {
+			attrs = append(attrs, slog.Int("sample_rate", rate))
+		}
+		logger.Info("request", attrs...)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDGeneratedAndEchoed(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDPropagatedToErrorResponse(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/jobs?limit=abc", nil)
+	req.Header.Set(RequestIDHeader, "client-supplied-id")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "client-supplied-id", w.Header().Get(RequestIDHeader))
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "client-supplied-id", response["request_id"])
+}
+
+// captureAccessLog sends the default logger to a buffer for the test and
+// returns a function decoding the "request" lines written so far.
+func captureAccessLog(t *testing.T) func() []map[string]interface{} {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return func() []map[string]interface{} {
+		var lines []map[string]interface{}
+		sc := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for sc.Scan() {
+			var line map[string]interface{}
+			require.NoError(t, json.Unmarshal(sc.Bytes(), &line))
+			if line["msg"] == "request" {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	router := setupRouter()
+	logSampleRate, logSlowThreshold = 10, time.Hour
+	defer func() { logSampleRate, logSlowThreshold = DefaultLogSampleRate, DefaultLogSlowThreshold }()
+	logSampleCount.Store(0)
+	accessLog := captureAccessLog(t)
+
+	get := func(path string) {
+		req, _ := http.NewRequest("GET", path, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	for i := 0; i < 1000; i++ {
+		get("/health")
+	}
+	lines := accessLog()
+	require.Len(t, lines, 100, "one in ten successful requests")
+	for _, line := range lines {
+		assert.Equal(t, 10.0, line["sample_rate"])
+	}
+
+	// errors are never sampled out
+	for i := 0; i < 25; i++ {
+		get("/jobs?limit=abc")
+	}
+	lines = accessLog()[100:]
+	require.Len(t, lines, 25)
+	for _, line := range lines {
+		assert.Equal(t, 400.0, line["status"])
+		assert.NotContains(t, line, "sample_rate")
+	}
+}
+
+func TestAccessLogRate(t *testing.T) {
+	logSampleRate, logSlowThreshold = 4, time.Second
+	defer func() { logSampleRate, logSlowThreshold = DefaultLogSampleRate, DefaultLogSlowThreshold }()
+	logSampleCount.Store(0)
+
+	assert.Equal(t, 1, accessLogRate(http.StatusInternalServerError, time.Millisecond))
+	assert.Equal(t, 1, accessLogRate(http.StatusNotFound, time.Millisecond))
+	assert.Equal(t, 1, accessLogRate(http.StatusOK, 2*time.Second), "slow")
+	logged := 0
+	for i := 0; i < 400; i++ {
+		if accessLogRate(http.StatusOK, time.Millisecond) > 0 {
+			logged++
+		}
+	}
+	assert.Equal(t, 100, logged)
+
+	logSampleRate = 1
+	assert.Equal(t, 1, accessLogRate(http.StatusOK, time.Millisecond), "sampling off")
+}
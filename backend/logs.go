@@ -0,0 +1,54 @@
+package main
+
+// backend/logs.go
+//
+// Worker diagnostics. While it processes a job the worker appends plain-text
+// lines ("<timestamp> <LEVEL> <message>") to the list job_logs:<id>, including
+// the traceback when a job fails, and expires the list with the job.
+// GET /jobs/:job_id/logs returns them oldest first; ?tail=N returns only the
+// last N lines.
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedisJobLogsPrefix keys job_logs:<jobID> -> list of log lines, oldest first.
+const RedisJobLogsPrefix = "job_logs:"
+
+func getJobLogsHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	start := int64(0)
+	if v := c.Query("tail"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondInvalidParam(c, "tail", "tail must be a positive integer")
+			return
+		}
+		start = -int64(n)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	key := jobLogsKey(jobID)
+	pipe := rdb.TxPipeline()
+	total := pipe.LLen(ctx, key)
+	lines := pipe.LRange(ctx, key, start, -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	if total.Val() == 0 {
+		respondError(c, http.StatusNotFound, "no logs for job")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"job_id": jobID,
+		"total":  total.Val(),
+		"lines":  lines.Val(),
+	})
+}
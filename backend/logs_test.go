@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logsResponse struct {
+	JobID string   `json:"job_id"`
+	Total int      `json:"total"`
+	Lines []string `json:"lines"`
+}
+
+func getLogs(t *testing.T, router *gin.Engine, jobID, query string) (int, logsResponse) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/jobs/"+jobID+"/logs"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp logsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestJobLogs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	lines := []interface{}{
+		"2025-11-01T08:00:00+00:00 INFO Processing job log-job",
+		"2025-11-01T08:00:01+00:00 INFO Fetched 24 weather rows",
+		"2025-11-01T08:00:02+00:00 ERROR Simulation failed: division by zero",
+		"2025-11-01T08:00:02+00:00 ERROR Traceback (most recent call last): ...",
+	}
+	require.NoError(t, rdb.RPush(ctx, RedisJobLogsPrefix+"log-job", lines...).Err())
+
+	t.Run("all lines oldest first", func(t *testing.T) {
+		code, resp := getLogs(t, router, "log-job", "")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "log-job", resp.JobID)
+		assert.Equal(t, 4, resp.Total)
+		require.Len(t, resp.Lines, 4)
+		for i, l := range lines {
+			assert.Equal(t, l, resp.Lines[i])
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		code, resp := getLogs(t, router, "log-job", "?tail=2")
+		require.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 4, resp.Total)
+		assert.Equal(t, []string{lines[2].(string), lines[3].(string)}, resp.Lines)
+	})
+
+	t.Run("tail longer than the log", func(t *testing.T) {
+		code, resp := getLogs(t, router, "log-job", "?tail=100")
+		require.Equal(t, http.StatusOK, code)
+		assert.Len(t, resp.Lines, 4)
+		assert.Equal(t, lines[0], resp.Lines[0])
+	})
+
+	t.Run("invalid tail", func(t *testing.T) {
+		for _, q := range []string{"?tail=0", "?tail=-1", "?tail=ten"} {
+			code, _ := getLogs(t, router, "log-job", q)
+			assert.Equal(t, http.StatusBadRequest, code, q)
+		}
+	})
+
+	t.Run("no logs", func(t *testing.T) {
+		code, _ := getLogs(t, router, "quiet-job", "")
+		assert.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+func TestDeleteJobRemovesLogs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	seedJobMeta(t, ctx, "logged", StatusError)
+	require.NoError(t, rdb.RPush(ctx, RedisJobLogsPrefix+"logged", "line").Err())
+
+	req, _ := http.NewRequest("DELETE", "/jobs/logged", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, rdb.Exists(ctx, RedisJobLogsPrefix+"logged").Val())
+}
@@ -8,33 +8,39 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
 // JobStatus constants
 const (
-	StatusQueued  = "queued"
-	StatusRunning = "running"
-	StatusDone    = "done"
-	StatusError   = "error"
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusDone      = "done"
+	StatusError     = "error"
+	StatusCancelled = "cancelled"
 )
 
 // Redis keys / lists
 const (
-	RedisJobsList        = "simulation_jobs"        // list where full job JSON is pushed
+	RedisJobsList        = "simulation_jobs"        // list where full job JSON is pushed (normal priority)
 	RedisResultsPrefix   = "job_result:"            // job_result:<jobID> -> JSON results (string)
 	RedisJobMetaPrefix   = "job_meta:"              // job_meta:<jobID> -> JSON metadata
 	RedisRecentJobsList  = "recent_simulation_ids"  // push job ids here for quick listing
 	DefaultResultTTL     = 24 * time.Hour           // how long results persist in Redis by default
-	RecentJobsMaxRetain  = 100                      // how many recent job IDs to keep in list
+	RecentJobsMaxRetain  = 100                      // default for RECENT_JOBS_MAX_RETAIN: how many recent job IDs to keep in list
 	RedisOpTimeout       = 5 * time.Second          // Redis operation timeout
 	DefaultRedisAddr     = "redis:6379"             // default service name in docker-compose
 	DefaultRedisDB       = 0
@@ -47,45 +53,82 @@ var (
 
 type SimulationParams struct {
 	// physics params (snake_case in json expected)
-	ThermalMass      *float64 `json:"thermal_mass,omitempty"`       // J/K (optional)
+	ThermalMass      *float64 `json:"thermal_mass,omitempty"`       // J/K (optional); see resolveHeatCapacity for precedence
 	ThermalMassKg    *float64 `json:"thermal_mass_kg,omitempty"`    // kg (optional)
 	CpMass           *float64 `json:"cp_mass,omitempty"`            // J/kgK (optional, default water)
-	VentilationRate  *float64 `json:"ventilation_rate,omitempty"`   // ACH or m3/s depending on your preference (we use ACH)
+	VentilationRate  *float64 `json:"ventilation_rate,omitempty"`   // m3/s; converted to ACH using V (see applyDefaults)
 	U_day            *float64 `json:"U_day,omitempty"`
 	U_night          *float64 `json:"U_night,omitempty"`
 	A_glass          *float64 `json:"A_glass,omitempty"`
 	TauGlass         *float64 `json:"tau_glass,omitempty"`
-	ACH              *float64 `json:"ACH,omitempty"`
+	ACH              *float64 `json:"ACH,omitempty"` // air changes per hour; the only ventilation value the worker reads
 	Volume           *float64 `json:"V,omitempty"` // greenhouse volume (m3)
 	C                *float64 `json:"C,omitempty"` // alternate direct C (J/K)
 	T_init           *float64 `json:"T_init,omitempty"`
 	Setpoint         *float64 `json:"setpoint,omitempty"`
-	Lat              *float64 `json:"lat,omitempty"`
-	Lon              *float64 `json:"lon,omitempty"`
+	Lat              *float64 `json:"lat,omitempty"` // degrees; required in the weather mode, else given together or not at all
+	Lon              *float64 `json:"lon,omitempty"` // degrees; when both are omitted the worker uses its default site
 	StartDate        string   `json:"start_date,omitempty"`
 	EndDate          string   `json:"end_date,omitempty"`
+	RelativeStart    string   `json:"relative_start,omitempty"` // e.g. "-7d"; resolved to start_date on submit (see reldates.go)
+	RelativeEnd      string   `json:"relative_end,omitempty"`   // e.g. "now" or "+14d"; resolved to end_date on submit
 	HeaterMaxW       *float64 `json:"heater_max_w,omitempty"`
 	EvapRate         *float64 `json:"evap_rate,omitempty"`
 	FractionSolarAir *float64 `json:"fraction_solar_to_air,omitempty"`
+	Model            string   `json:"model,omitempty"` // thermal model: lumped (default) or multinode
+	HeaterControl    string   `json:"heater_control,omitempty"`  // onoff (default) or proportional
+	HeaterDeadband   *float64 `json:"heater_deadband,omitempty"` // K; onoff switches at setpoint ± deadband/2
+	HeaterKp         *float64 `json:"heater_kp,omitempty"`       // W/K; proportional gain, scaled to C by the worker when omitted
+	TimestepSeconds  *float64 `json:"timestep_seconds,omitempty"` // integration step; the worker defaults to DefaultTimestepSeconds
+	// outdoor temperature source: weather (default), constant or sine; see outdoortemp.go
+	OutdoorTempMode      string   `json:"outdoor_temp_mode,omitempty"`
+	OutdoorTempConst     *float64 `json:"outdoor_temp_const,omitempty"`     // °C; constant only
+	OutdoorTempMean      *float64 `json:"outdoor_temp_mean,omitempty"`      // °C; sine only
+	OutdoorTempAmplitude *float64 `json:"outdoor_temp_amplitude,omitempty"` // K above and below the mean; sine only
+	Seed             *int64   `json:"seed,omitempty"`             // RNG seed for stochastic inputs; newJobMeta picks one when omitted
+	// named starting point from GET /presets; explicit fields override it
+	Preset string `json:"preset,omitempty"`
+	// unit system of the fields above: si (default) or imperial; converted to SI on submit
+	Units string `json:"units,omitempty"`
+	// scheduling and storage options (not physics)
+	Priority         string   `json:"priority,omitempty"`           // high, normal (default) or low
+	Queue            string   `json:"queue,omitempty"`              // named worker queue from JOB_QUEUES; omitted is the default queue
+	Tags             []string `json:"tags,omitempty"`               // labels for grouping; moved to JobMeta.Tags on submit
+	ResultTTLSeconds *int64   `json:"result_ttl_seconds,omitempty"` // overrides DefaultResultTTL, clamped to maxResultTTL
+	GreenhouseID     string   `json:"greenhouse_id,omitempty"`      // with ?unique_active=true, at most one active job per id
 	// ... you can add more fields used by physics model
 }
 
 // Metadata stored in Redis for each job
 type JobMeta struct {
-	JobID     string           `json:"job_id"`
-	Status    string           `json:"status"`
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
-	Params    SimulationParams `json:"params"`
-	Error     string           `json:"error,omitempty"`
-	ResultKey string           `json:"result_key,omitempty"`
+	JobID      string           `json:"job_id"`
+	Status     string           `json:"status"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	StartedAt  *time.Time       `json:"started_at,omitempty"` // set by the worker when it marks the job running
+	Params     SimulationParams `json:"params"`
+	Error      string           `json:"error,omitempty"`
+	ResultKey  string           `json:"result_key,omitempty"`
+	BatchID    string           `json:"batch_id,omitempty"`    // set for jobs submitted via /simulate/batch, /simulate/upload, /simulate/sweep or /scenarios
+	Priority   string           `json:"priority"`              // selects the jobs list the payload was pushed to
+	Queue      string           `json:"queue,omitempty"`       // named queue (worker pool) the payload was pushed to; empty is the default
+	RetryOf    string           `json:"retry_of,omitempty"`    // original job when created via /jobs/:job_id/retry
+	ClonedFrom string           `json:"cloned_from,omitempty"` // original job when created via /jobs/:job_id/clone
+	Units      string           `json:"units,omitempty"`       // unit system the client submitted in; Params are always SI
+	Tags       []string         `json:"tags,omitempty"`        // normalized (sorted, deduped); indexed in jobs_by_tag:<tag>
+	ExpiresAt  *time.Time       `json:"expires_at,omitempty"`  // when Redis purges meta and result; renewed with the TTL
+	// how long meta and result are kept in Redis
+	ResultTTLSeconds int64 `json:"result_ttl_seconds"`
 }
 
 // job payload pushed to Redis (includes job id + params + created_at)
 type JobPayload struct {
-	JobID     string           `json:"job_id"`
-	CreatedAt time.Time        `json:"created_at"`
-	Params    SimulationParams `json:"params"`
+	JobID            string           `json:"job_id"`
+	CreatedAt        time.Time        `json:"created_at"`
+	Params           SimulationParams `json:"params"`
+	ResultTTLSeconds int64            `json:"result_ttl_seconds"` // TTL the worker uses for the result and meta
+	// W3C trace context (traceparent, tracestate) of the submitting request; see tracing.go
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
 
 func initRedis() {
@@ -93,218 +136,760 @@ func initRedis() {
 	if rdbAddr == "" {
 		rdbAddr = DefaultRedisAddr
 	}
-	rdb = redis.NewClient(&redis.Options{
-		Addr: rdbAddr,
-		DB:   DefaultRedisDB,
-	})
-	// quick ping
-	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
-	defer cancel()
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		log.Fatalf("failed to connect to redis at %s: %v", rdbAddr, err)
+	rdb = newRedisClient(rdbAddr)
+	// wait for Redis to come up (see redisconn.go)
+	ping := func(ctx context.Context) error { return rdb.Ping(ctx).Err() }
+	if err := pingWithRetry(context.Background(), ping, redisConnectAttempts, redisConnectBackoff, redisConnectMaxBackoff); err != nil {
+		slog.Error("failed to connect to redis", "addr", rdbAddr, "attempts", redisConnectAttempts, "error", err)
+		os.Exit(1)
 	}
-	log.Printf("connected to redis at %s", rdbAddr)
+	slog.Info("connected to redis", "addr", rdbAddr)
 }
 
 func main() {
 	// read configuration from environment if needed
+	initLogger()
+	loadConfig()
+	if path := os.Getenv("DEFAULTS_CONFIG"); path != "" {
+		d, err := loadParamDefaults(path)
+		if err != nil {
+			slog.Error("failed to load DEFAULTS_CONFIG", "error", err)
+			os.Exit(1)
+		}
+		paramDefaults = d
+		slog.Info("loaded parameter defaults", "path", path)
+	}
 	initRedis()
+	initMetrics()
+	shutdownTracing, err := initTracing(context.Background())
+	if err != nil {
+		slog.Error("failed to initialise tracing", "error", err)
+		os.Exit(1)
+	}
+
+	// cancelled on SIGINT/SIGTERM to trigger graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	storeCtx, storeCancel := context.WithTimeout(ctx, RedisOpTimeout)
+	err = initMetaStore(storeCtx)
+	storeCancel()
+	if err != nil {
+		slog.Error("failed to initialise metadata backend", "error", err)
+		os.Exit(1)
+	}
+	if mirrorsMeta() {
+		go runMetaSync(ctx)
+	}
+
+	go runJobEventMetrics(ctx)
+	go runMetricsTracker(ctx, MetricsScanInterval)
+	if jobTimeout > 0 {
+		go runStaleJobSweeper(ctx, staleSweepInterval)
+	}
 
-	// Gin router
-	router := gin.Default()
+	// Gin router (request logging is done by requestLogger as structured JSON)
+	router := gin.New()
+	router.Use(gin.Recovery(), traceRequests(), requestLogger())
 
-	// CORS for local frontend dev (adjust origins in production)
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://127.0.0.1:3000"},
-		AllowMethods:     []string{"GET", "POST", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	// CORS: origins from CORS_ALLOWED_ORIGINS, defaulting to the local frontend dev server
+	corsCfg, err := corsConfig(os.Getenv("CORS_ALLOWED_ORIGINS"))
+	if err != nil {
+		slog.Error("invalid CORS_ALLOWED_ORIGINS", "error", err)
+		os.Exit(1)
+	}
+	router.Use(cors.New(corsCfg))
 
 	// Health
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// Readiness (pings Redis)
+	router.GET("/ready", readyHandler)
+
+	// Worker liveness (503 when no worker heartbeat is live)
+	router.GET("/health/workers", workersHealthHandler)
+
+	// Build info (commit, build time, Go version)
+	router.GET("/version", versionHandler)
+
+	// Prometheus metrics
+	router.GET("/metrics", metricsHandler())
+
+	// OpenAPI document and Swagger UI
+	router.GET("/openapi.json", openAPIHandler)
+	router.GET("/docs", docsHandler)
+
+	// Shared result links carry their own signed token instead of an API key
+	router.GET("/shared/:token", sharedResultHandler)
+
+	// Worker status reports authenticate with the internal shared secret
+	router.POST("/internal/jobs/:job_id/status", internalAuth(), workerStatusHandler)
+
+	// Everything below requires an API key
+	api := router.Group("", apiKeyAuth())
+	// gzip results and job listings for clients sending Accept-Encoding: gzip
+	api.Use(compressResponses())
+
 	// Submit a job
-	router.POST("/simulate", submitJobHandler)
+	api.POST("/simulate", rateLimit(), limitBody(&maxBodyBytes), submitJobHandler)
+
+	// Submit a job from query parameters (bookmarkable links)
+	api.GET("/simulate", rateLimit(), submitQueryHandler)
+
+	// Validate params without submitting them (not rate limited; no Redis access)
+	api.POST("/validate", limitBody(&maxBodyBytes), validateParamsHandler)
+
+	// Estimate a job's timesteps and runtime without enqueuing it
+	api.POST("/simulate/estimate", limitBody(&maxBodyBytes), estimateHandler)
+
+	// Submit many jobs at once
+	api.POST("/simulate/batch", rateLimit(), limitBody(&maxBatchBodyBytes), submitBatchHandler)
+
+	// Submit a CSV of parameter sets (one job per row) as multipart/form-data
+	api.POST("/simulate/upload", rateLimit(), limitBody(&maxBatchBodyBytes), submitUploadHandler)
+
+	// Submit a parameter sweep (one job per grid point)
+	api.POST("/simulate/sweep", rateLimit(), limitBody(&maxBodyBytes), submitSweepHandler)
+
+	// Submit labeled parameter sets as one scenario set
+	api.POST("/scenarios", rateLimit(), limitBody(&maxBatchBodyBytes), submitScenarioHandler)
+
+	// Aggregate status and results of a scenario set
+	api.GET("/scenarios/:scenario_id", getScenarioHandler)
 
 	// Get results for a job
-	router.GET("/results/:job_id", getResultsHandler)
+	api.GET("/results/:job_id", getResultsHandler)
+
+	// Download results as CSV
+	api.GET("/results/:job_id/csv", getResultsCSVHandler)
+
+	// Download results as Parquet
+	api.GET("/results/:job_id/parquet", getResultsParquetHandler)
+
+	// Result rows computed so far, in chunks (?from= skips chunks already read)
+	api.GET("/results/:job_id/partial", getPartialResultsHandler)
+
+	// Aggregates of a job's series (temperature stats, heating energy)
+	api.GET("/results/:job_id/summary", getResultSummaryHandler)
 
 	// Get recent results (list of recent job ids)
-	router.GET("/results", getRecentJobsHandler)
+	api.GET("/results", getRecentJobsHandler)
+
+	// List job metadata (paginated, filterable by status)
+	api.GET("/jobs", listJobsHandler)
+
+	// Purge finished jobs created before a cutoff (admin keys only)
+	api.DELETE("/jobs", adminOnly(), purgeJobsHandler)
+
+	// Requeue every failed job as a retry (admin keys only)
+	api.POST("/admin/requeue", adminOnly(), requeueJobsHandler)
+
+	// Search all stored jobs by creation time and approximate lat/lon
+	api.GET("/jobs/search", searchJobsHandler)
+
+	// Statuses of many jobs at once (?ids=id1,id2,...)
+	api.GET("/jobs/status", bulkJobStatusHandler)
+
+	// List parameter presets usable via "preset" on /simulate
+	api.GET("/presets", listPresetsHandler)
 
 	// Get job metadata
-	router.GET("/jobs/:job_id", getJobMetaHandler)
+	api.GET("/jobs/:job_id", getJobMetaHandler)
+
+	// Get the job's fully resolved params (defaults and derived values filled in)
+	api.GET("/jobs/:job_id/params", getJobParamsHandler)
+
+	// Delete a job and its result
+	api.DELETE("/jobs/:job_id", deleteJobHandler)
+
+	// Fix the params of a queued job in place
+	api.PATCH("/jobs/:job_id", limitBody(&maxBodyBytes), patchJobHandler)
+
+	// Cancel a queued job
+	api.POST("/jobs/:job_id/cancel", cancelJobHandler)
+
+	// Rerun a finished or failed job with the same params under a new id
+	api.POST("/jobs/:job_id/retry", rateLimit(), retryJobHandler)
+
+	// Re-run a job with a params patch merged over its original params
+	api.POST("/jobs/:job_id/clone", rateLimit(), limitBody(&maxBodyBytes), cloneJobHandler)
+
+	// Compare a finished job with a run of a preset over the same dates
+	api.GET("/jobs/:job_id/compare-preset/:preset_name", rateLimit(), comparePresetHandler)
+
+	// Set, replace or clear (with "") a free-text note on a job
+	api.PUT("/jobs/:job_id/notes", limitBody(&maxBodyBytes), putJobNotesHandler)
+
+	// Issue a time-limited link to a job's result that needs no API key
+	api.POST("/jobs/:job_id/share", shareJobHandler)
+
+	// Stream job status transitions (Server-Sent Events)
+	api.GET("/jobs/:job_id/events", jobEventsHandler)
+
+	// Worker log lines for a job (?tail=N returns the last N)
+	api.GET("/jobs/:job_id/logs", getJobLogsHandler)
+
+	// Named result artifacts; the main result is "default"
+	api.GET("/jobs/:job_id/artifacts", listArtifactsHandler)
+	api.GET("/jobs/:job_id/artifacts/:name", getArtifactHandler)
+
+	// Params, result, summary and logs of a finished job as one zip
+	api.GET("/jobs/:job_id/bundle", getJobBundleHandler)
+
+	// Diff the results of two or more finished jobs
+	api.GET("/compare", compareJobsHandler)
+
+	// Operational summary (queue depth, jobs by status, stored results)
+	api.GET("/stats", statsHandler)
+
+	// Cached hourly weather for a location and date window
+	api.GET("/weather", weatherHandler)
 
 	// Start server
 	addr := ":8080"
 	if p := os.Getenv("PORT"); p != "" {
 		addr = ":" + p
 	}
-	log.Printf("starting backend on %s", addr)
-	if err := router.Run(addr); err != nil {
-		log.Fatalf("failed to run server: %v", err)
+	srv := &http.Server{Addr: addr, Handler: withRequestTimeout(router)}
+	slog.Info("starting backend", "addr", addr)
+	if err := runServer(ctx, srv, shutdownGrace); err != nil {
+		slog.Error("failed to run server", "error", err)
+		os.Exit(1)
+	}
+	if err := rdb.Close(); err != nil {
+		slog.Warn("failed to close redis client", "error", err)
+	}
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), shutdownGrace)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("failed to flush traces", "error", err)
 	}
+	flushCancel()
+	slog.Info("backend stopped")
 }
 
-// applyDefaults sets reasonable defaults for missing fields
-func applyDefaults(p *SimulationParams) {
-	// defaults chosen to match your worker model defaults
+// runServer serves until ctx is cancelled, then gives in-flight requests up to
+// grace to finish before closing. It returns nil after a clean shutdown.
+func runServer(ctx context.Context, srv *http.Server, grace time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		// server failed to start or stopped on its own
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "grace", grace.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// applyDefaults sets reasonable defaults for missing fields. It returns
+// warnings about inputs that were accepted but overridden.
+func applyDefaults(p *SimulationParams) (warnings []string) {
+	// values come from paramDefaults (built-in, or overridden by DEFAULTS_CONFIG)
 	if p.A_glass == nil {
-		def := 50.0
+		def := paramDefaults.A_glass
 		p.A_glass = &def
 	}
 	if p.TauGlass == nil {
-		def := 0.85
+		def := paramDefaults.TauGlass
 		p.TauGlass = &def
 	}
 	if p.U_day == nil {
-		def := 3.0
+		def := paramDefaults.U_day
 		p.U_day = &def
 	}
 	if p.U_night == nil {
-		def := 0.6
+		def := paramDefaults.U_night
 		p.U_night = &def
 	}
-	if p.ACH == nil {
-		def := 0.5
-		p.ACH = &def
-	}
 	if p.Volume == nil {
-		def := 100.0
+		def := paramDefaults.Volume
 		p.Volume = &def
 	}
-	if p.C == nil && p.ThermalMassKg == nil {
-		// default C equivalent (J/K)
-		def := 2e7
-		p.C = &def
+	if w := normalizeVentilation(p); w != "" {
+		warnings = append(warnings, w)
+	}
+	if p.ACH == nil {
+		def := paramDefaults.ACH
+		p.ACH = &def
+	}
+	if w := resolveHeatCapacity(p); w != "" {
+		warnings = append(warnings, w)
 	}
 	if p.CpMass == nil {
-		def := 4186.0
+		def := paramDefaults.CpMass
 		p.CpMass = &def
 	}
 	if p.T_init == nil {
-		def := 15.0
+		def := paramDefaults.T_init
 		p.T_init = &def
 	}
 	if p.Setpoint == nil {
-		def := 12.0
+		def := paramDefaults.Setpoint
 		p.Setpoint = &def
 	}
 	if p.HeaterMaxW == nil {
-		def := 5000.0
+		def := paramDefaults.HeaterMaxW
 		p.HeaterMaxW = &def
 	}
 	if p.FractionSolarAir == nil {
-		def := 0.5
+		def := paramDefaults.FractionSolarAir
 		p.FractionSolarAir = &def
 	}
+	if p.Model == "" {
+		p.Model = paramDefaults.Model
+	}
+	if w := resolveHeaterControl(p); w != "" {
+		warnings = append(warnings, w)
+	}
+	if w := resolveOutdoorTemp(p); w != "" {
+		warnings = append(warnings, w)
+	}
 	// lat/lon left nil if not provided
+	return warnings
+}
+
+// resolveHeaterControl defaults heater_control and, for onoff, heater_deadband.
+// The setting the other mode uses is dropped with a warning, so it neither
+// reaches the worker nor changes the params hash. Unknown modes are left for
+// validation.
+func resolveHeaterControl(p *SimulationParams) string {
+	if p.HeaterControl == "" {
+		p.HeaterControl = paramDefaults.HeaterControl
+	}
+	var ignored string
+	switch p.HeaterControl {
+	case HeaterOnOff:
+		if p.HeaterKp != nil {
+			ignored, p.HeaterKp = "heater_kp", nil
+		}
+		if p.HeaterDeadband == nil {
+			def := paramDefaults.HeaterDeadband
+			p.HeaterDeadband = &def
+		}
+	case HeaterProportional:
+		if p.HeaterDeadband != nil {
+			ignored, p.HeaterDeadband = "heater_deadband", nil
+		}
+	}
+	if ignored == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s is not used with heater_control=%s; ignoring it", ignored, p.HeaterControl)
+}
+
+// ventilationTolerance is how far (in ACH) an explicit ACH may differ from the
+// one derived from ventilation_rate before the two are reported as conflicting.
+const ventilationTolerance = 1e-6
+
+// normalizeVentilation makes ACH the single ventilation value: a ventilation_rate
+// (m3/s) takes precedence and is converted with ACH = rate * 3600 / V. V must
+// already be set. Values validation will reject are left alone. It returns a
+// warning when an explicit ACH disagrees with ventilation_rate.
+func normalizeVentilation(p *SimulationParams) string {
+	if p.VentilationRate == nil || *p.VentilationRate < 0 || p.Volume == nil || *p.Volume <= 0 {
+		return ""
+	}
+	ach := *p.VentilationRate * 3600 / *p.Volume
+	warning := ""
+	if p.ACH != nil && math.Abs(*p.ACH-ach) > ventilationTolerance {
+		warning = fmt.Sprintf("ACH %g conflicts with ventilation_rate %g m3/s (%g ACH for V=%g m3); using ventilation_rate",
+			*p.ACH, *p.VentilationRate, ach, *p.Volume)
+	}
+	p.ACH = &ach
+	return warning
+}
+
+// heatCapacitySource is one way the request specifies heat capacity.
+type heatCapacitySource struct {
+	fields string  // the inputs, as named in the request
+	c      float64 // the C (J/K) they imply
+}
+
+// resolveHeatCapacity sets the effective heat capacity C (J/K), by precedence:
+//  1. an explicitly provided C is used as-is
+//  2. otherwise thermal_mass, which is already in J/K
+//  3. otherwise thermal_mass_kg * cp_mass (cp_mass defaults to water)
+//  4. otherwise the default C
+//
+// It returns a warning naming the ignored inputs when more than one of these
+// was given. cp_mass without thermal_mass_kg does not specify C on its own.
+func resolveHeatCapacity(p *SimulationParams) string {
+	var given []heatCapacitySource
+	if p.C != nil {
+		given = append(given, heatCapacitySource{"C", *p.C})
+	}
+	if p.ThermalMass != nil {
+		given = append(given, heatCapacitySource{"thermal_mass", *p.ThermalMass})
+	}
+	if p.ThermalMassKg != nil {
+		src := heatCapacitySource{"thermal_mass_kg", *p.ThermalMassKg * paramDefaults.CpMass}
+		if p.CpMass != nil {
+			src = heatCapacitySource{"thermal_mass_kg * cp_mass", *p.ThermalMassKg * *p.CpMass}
+		}
+		given = append(given, src)
+	}
+	if len(given) == 0 {
+		def := paramDefaults.C
+		p.C = &def
+		return ""
+	}
+	c := given[0].c
+	p.C = &c
+	if len(given) == 1 {
+		return ""
+	}
+	ignored := make([]string, 0, len(given)-1)
+	for _, g := range given[1:] {
+		ignored = append(ignored, g.fields)
+	}
+	return fmt.Sprintf("heat capacity given more than once; using %s (C = %g J/K) and ignoring %s",
+		given[0].fields, c, strings.Join(ignored, ", "))
 }
 
 // Handler functions for better testability
 func submitJobHandler(c *gin.Context) {
-	var params SimulationParams
-	if err := c.BindJSON(&params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON: " + err.Error()})
+	params, ok := bindParamsBody(c)
+	if !ok {
 		return
 	}
+	submitParams(c, params)
+}
 
-	// basic validation & defaults
-	applyDefaults(&params)
-
-	// create job id and payload
-	jobID := uuid.NewString()
-	now := time.Now().UTC()
+// bindParamsBody reads a SimulationParams body (JSON or YAML), applying the
+// schema check and strict mode when enabled. It answers the request and
+// returns ok=false when the body is rejected.
+func bindParamsBody(c *gin.Context) (params SimulationParams, ok bool) {
+	if !acceptParamsBody(c, maxBodyBytes) {
+		return params, false
+	}
+	if schemaValidation && !checkBodySchema(c, maxBodyBytes) {
+		return params, false
+	}
+	if strictParams(c) && !checkUnknownFields(c, maxBodyBytes) {
+		return params, false
+	}
+	if err := c.ShouldBindJSON(&params); err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, maxBodyBytes)
+			return params, false
+		}
+		respondError(c, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return params, false
+	}
+	return params, true
+}
 
-	payload := JobPayload{
-		JobID:     jobID,
-		CreatedAt: now,
-		Params:    params,
+// resolveParams converts params to SI, merges the preset, applies defaults and
+// validates the result (preset < explicit fields < defaults). It returns the
+// unit system the client used and any warnings, or answers 400 and returns
+// ok=false.
+func resolveParams(c *gin.Context, params *SimulationParams) (units string, warnings []string, ok bool) {
+	units, err := toSI(params)
+	if err != nil {
+		respondValidationError(c, err)
+		return "", nil, false
 	}
-	payloadBytes, err := json.Marshal(payload)
+	if err := applyPreset(params); err != nil {
+		respondValidationError(c, err)
+		return "", nil, false
+	}
+	warnings = applyDefaults(params)
+	implausible, err := validateParams(params, c.Query("allow_extreme") == "true")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to marshal job payload"})
+		respondValidationError(c, err)
+		return "", nil, false
+	}
+	return units, append(warnings, implausible...), true
+}
+
+// submitParams validates params and enqueues them as one job, answering the
+// request; POST /simulate and GET /simulate share it.
+func submitParams(c *gin.Context, params SimulationParams) {
+	units, warnings, ok := resolveParams(c, &params)
+	if !ok {
+		return
+	}
+	uniqueActive := c.Query("unique_active") == "true"
+	if uniqueActive && params.GreenhouseID == "" {
+		respondValidationError(c, &ValidationError{Fields: []FieldError{{Field: "greenhouse_id", Value: nil, Allowed: "required with unique_active=true"}}})
+		return
+	}
+	waitTimeout, ok := parseWait(c)
+	if !ok {
 		return
 	}
 
-	// push payload into list (queue)
-	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
-	defer cancel()
-	if err := rdb.RPush(ctx, RedisJobsList, payloadBytes).Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to enqueue job: " + err.Error()})
+	// ?dry_run=true stops here: nothing is stored or queued
+	if c.Query("dry_run") == "true" {
+		resp := gin.H{"dry_run": true, "units": units, "params": params}
+		if len(warnings) > 0 {
+			resp["warnings"] = warnings
+		}
+		c.JSON(http.StatusOK, resp)
 		return
 	}
 
-	// create job meta and store
-	meta := JobMeta{
-		JobID:     jobID,
-		Status:    StatusQueued,
-		CreatedAt: now,
-		UpdatedAt: now,
-		Params:    params,
-		ResultKey: RedisResultsPrefix + jobID,
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	// ?reuse=true hands back a finished run with the same physics instead of a new job
+	if c.Query("reuse") == "true" {
+		prev, found, err := findReusableJob(ctx, physicsHash(params))
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+			return
+		}
+		if found {
+			links := jobLinks(prev.JobID)
+			c.Header("Location", links["result"])
+			c.JSON(http.StatusOK, gin.H{
+				"job_id": prev.JobID,
+				"status": prev.Status,
+				"links":  links,
+				"reused": true,
+			})
+			return
+		}
+	}
+
+	// create job meta (id, timestamps, resolved params)
+	meta := newJobMeta(params, time.Now().UTC())
+	meta.Units = units
+	jobID := meta.JobID
+
+	// a retried request with the same Idempotency-Key gets the original job back
+	idemKey, idemClient := c.GetHeader(IdempotencyKeyHeader), rateLimitClient(c)
+	if idemKey != "" {
+		hash := paramsHash(&params)
+		existing, claimed, err := reserveIdempotencyKey(ctx, idemClient, idemKey, idempotencyRecord{JobID: jobID, ParamsHash: hash})
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+			return
+		}
+		if !claimed {
+			respondIdempotentReplay(c, ctx, existing, hash)
+			return
+		}
+	}
+
+	// the same client sending the same params again within dedupWindow gets the first job back
+	dedup := ""
+	if dedupEnabled(c) {
+		hash := dedupHash(c, &params)
+		holder, claimed, err := claimDedup(ctx, hash, jobID)
+		if err != nil {
+			respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+			return
+		}
+		if !claimed {
+			respondDeduplicated(c, ctx, holder)
+			return
+		}
+		dedup = hash
+	}
+
+	if !claimJobQuota(c, ctx, []JobMeta{meta}) {
+		if idemKey != "" {
+			releaseIdempotencyKey(ctx, idemClient, idemKey)
+		}
+		if dedup != "" {
+			releaseDedup(ctx, dedup, jobID)
+		}
+		return
 	}
-	metaBytes, _ := json.Marshal(meta)
-	if err := rdb.Set(ctx, RedisJobMetaPrefix+jobID, metaBytes, DefaultResultTTL).Err(); err != nil {
-		// log but do not fail enqueue (best-effort)
-		log.Printf("warning: failed to set job meta: %v", err)
+
+	// ?unique_active=true: at most one queued or running job per greenhouse
+	if uniqueActive {
+		holder, locked, err := acquireActiveLock(ctx, params.GreenhouseID, jobID, metaTTL(meta))
+		if err != nil || !locked {
+			if idemKey != "" {
+				releaseIdempotencyKey(ctx, idemClient, idemKey)
+			}
+			if dedup != "" {
+				releaseDedup(ctx, dedup, jobID)
+			}
+			releaseJobQuota(c, ctx, []JobMeta{meta})
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+				return
+			}
+			respondError(c, http.StatusConflict, "greenhouse already has an active job",
+				gin.H{"greenhouse_id": params.GreenhouseID, "active_job_id": holder})
+			return
+		}
 	}
 
-	// push job id into recent list (trim)
-	if err := rdb.LPush(ctx, RedisRecentJobsList, jobID).Err(); err == nil {
-		rdb.LTrim(ctx, RedisRecentJobsList, 0, RecentJobsMaxRetain-1)
+	// store meta and push payload into list (queue)
+	if err := enqueueJobs(ctx, []JobMeta{meta}); err != nil {
+		if idemKey != "" {
+			releaseIdempotencyKey(ctx, idemClient, idemKey)
+		}
+		if uniqueActive {
+			releaseActiveLock(ctx, params.GreenhouseID, jobID)
+		}
+		if dedup != "" {
+			releaseDedup(ctx, dedup, jobID)
+		}
+		releaseJobQuota(c, ctx, []JobMeta{meta})
+		respondError(c, http.StatusInternalServerError, "failed to enqueue job: "+err.Error())
+		return
 	}
 
-	c.JSON(http.StatusAccepted, gin.H{
+	links := jobLinks(jobID)
+	c.Header("Location", links["result"])
+	resp := gin.H{
 		"job_id": jobID,
 		"status": StatusQueued,
-	})
+		"links":  links,
+		"seed":   *meta.Params.Seed, // resubmit with this seed to reproduce the run
+	}
+	// echo the canonical (validated) dates so the client can confirm them
+	if params.StartDate != "" {
+		resp["start_date"] = params.StartDate
+		resp["end_date"] = params.EndDate
+	}
+	if len(warnings) > 0 {
+		loggerFrom(c).Warn("parameters adjusted", "job_id", jobID, "warnings", warnings)
+		resp["warnings"] = warnings
+	}
+	// ?wait=true holds the request until the job finishes or the wait runs out
+	if waitTimeout > 0 {
+		respondAfterWait(c, jobID, waitTimeout, resp)
+		return
+	}
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// jobLinks returns the URLs a client can follow for a job.
+func jobLinks(jobID string) map[string]string {
+	return map[string]string{
+		"self":   apiURL("/jobs/" + jobID),
+		"result": apiURL("/results/" + jobID),
+		"meta":   apiURL("/jobs/" + jobID),
+	}
+}
+
+// respondValidationError writes a 400 listing every offending field when err is a *ValidationError.
+func respondValidationError(c *gin.Context, err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		respondAPIError(c, http.StatusBadRequest, apiError{Code: CodeInvalidParam, Message: "invalid parameters", Field: verr.Fields[0].Field},
+			gin.H{"fields": verr.Fields})
+		return
+	}
+	respondError(c, http.StatusBadRequest, err.Error())
 }
 
 func getResultsHandler(c *gin.Context) {
 	jobID := c.Param("job_id")
-	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
+	view, ok := parseResultView(c)
+	if !ok {
+		return
+	}
+	format, ok := negotiateResultFormat(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
 	defer cancel()
 
-	res, err := rdb.Get(ctx, RedisResultsPrefix+jobID).Result()
+	if format != gin.MIMEJSON {
+		// a status object is no use to a CSV or NDJSON reader: 409 until there is a result
+		res, ok := loadStoredResult(c, ctx, jobID)
+		if !ok {
+			return
+		}
+		respondWithResult(c, jobID, res, view, format)
+		return
+	}
+
+	res, err := rdb.Get(ctx, jobResultKey(jobID)).Result()
 	if err == redis.Nil {
 		// not ready
 		// return status from job_meta if exists
-		metaBytes, err2 := rdb.Get(ctx, RedisJobMetaPrefix+jobID).Result()
+		metaBytes, err2 := rdb.Get(ctx, jobMetaKey(jobID)).Result()
 		if err2 == nil {
 			var meta JobMeta
 			_ = json.Unmarshal([]byte(metaBytes), &meta)
-			c.JSON(http.StatusOK, gin.H{"job_id": jobID, "status": meta.Status})
+			resp := gin.H{"job_id": jobID, "status": meta.Status}
+			if !isTerminalStatus(meta.Status) {
+				// back off clients polling a long job instead of hammering us at a fixed rate
+				setPollRetryAfter(c, meta.CreatedAt)
+			}
+			if meta.Status == StatusQueued {
+				q, err := lookupQueueInfo(ctx, meta.Queue, jobID)
+				if err != nil {
+					respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+					return
+				}
+				if q.QueuePosition > 0 {
+					resp["queue_position"] = q.QueuePosition
+					resp["queue_length"] = q.QueueLength
+				}
+			}
+			progress, err := jobProgress(ctx, meta)
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+				return
+			}
+			if progress != nil {
+				resp["progress"] = *progress
+			}
+			respondJSONWithETag(c, resp)
 			return
 		}
-		c.JSON(http.StatusNotFound, gin.H{"error": "no result or job not found"})
+		respondJobMissing(c, ctx, jobID, "no result or job not found")
 		return
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "redis error: " + err.Error()})
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
 		return
 	}
-
-	// return JSON result as-is (assuming worker stores JSON string)
-	var parsed interface{}
-	if err := json.Unmarshal([]byte(res), &parsed); err == nil {
-		c.JSON(http.StatusOK, gin.H{"job_id": jobID, "status": StatusDone, "result": parsed})
+	if res, err = decodeResult(res); err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	respondWithResult(c, jobID, res, view, format)
+}
 
-	// fallback raw
-	c.JSON(http.StatusOK, gin.H{"job_id": jobID, "status": StatusDone, "result": res})
+// respondWithResult validates a decoded result and writes it in format.
+func respondWithResult(c *gin.Context, jobID, res string, view resultView, format string) {
+	parsed, err := parseSimulationResult(res)
+	if err != nil {
+		// the worker wrote something clients can't rely on; don't pass it through
+		respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": jobID})
+		return
+	}
+	resp := gin.H{"job_id": jobID, "status": StatusDone, "progress": 100.0, "result": parsed, "units": resultUnits}
+	// the result is purged when its key expires
+	if ttl, err := rdb.PTTL(c.Request.Context(), jobResultKey(jobID)).Result(); err == nil && ttl > 0 {
+		resp["expires_at"] = time.Now().UTC().Add(ttl).Truncate(time.Second)
+	}
+	// ?resolution=N or ?page=&page_size= trim the series
+	view.apply(resp, parsed)
+	resultSerializers[format](c, resp, parsed)
 }
 
 func getRecentJobsHandler(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
 	defer cancel()
-	ids, err := rdb.LRange(ctx, RedisRecentJobsList, 0, 49).Result()
+	ids, err := rdb.LRange(ctx, redisKey(RedisRecentJobsList), 0, 49).Result()
 	if err != nil && err != redis.Nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "redis error: " + err.Error()})
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"recent_job_ids": ids})
@@ -312,20 +897,30 @@ func getRecentJobsHandler(c *gin.Context) {
 
 func getJobMetaHandler(c *gin.Context) {
 	jobID := c.Param("job_id")
-	ctx, cancel := context.WithTimeout(context.Background(), RedisOpTimeout)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
 	defer cancel()
-	metaStr, err := rdb.Get(ctx, RedisJobMetaPrefix+jobID).Result()
-	if err == redis.Nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+	meta, err := metaStore.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondJobMissing(c, ctx, jobID, "job not found")
 		return
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "redis error: " + err.Error()})
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	resp := jobMetaResponse{JobMeta: meta}
+	if meta.Status == StatusQueued {
+		if resp.queueInfo, err = lookupQueueInfo(ctx, meta.Queue, jobID); err != nil {
+			respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+			return
+		}
+	}
+	if resp.Progress, err = jobProgress(ctx, meta); err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
 		return
 	}
-	var meta JobMeta
-	if err := json.Unmarshal([]byte(metaStr), &meta); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse job meta"})
+	if resp.Note, err = jobNote(ctx, jobID); err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, meta)
+	respondJSONWithETag(c, resp)
 }
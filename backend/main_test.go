@@ -4,21 +4,57 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
+// testRedisAddr is the Redis the tests run against: a live server on
+// localhost:6379 when one is up (as in CI), otherwise an in-process miniredis
+// started on first use, so the Redis-backed tests run rather than skip.
+var (
+	testRedisAddr = "localhost:6379"
+	testRedisOnce sync.Once
+)
+
+func resolveTestRedis() {
+	live := redis.NewClient(&redis.Options{Addr: testRedisAddr})
+	defer live.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if live.Ping(ctx).Err() == nil {
+		return
+	}
+	m := miniredis.NewMiniRedis()
+	if err := m.Start(); err != nil {
+		return // neither is available; checkRedisAvailable skips
+	}
+	testRedisAddr = m.Addr()
+	// miniredis only expires keys when its clock is moved; keep it in step with ours
+	go func() {
+		for range time.Tick(10 * time.Millisecond) {
+			m.FastForward(10 * time.Millisecond)
+		}
+	}()
+}
+
 // Test helpers
 func setupTestRedis() *redis.Client {
+	testRedisOnce.Do(resolveTestRedis)
 	client := redis.NewClient(&redis.Options{
-		Addr: "localhost:6379",
+		Addr: testRedisAddr,
 		DB:   1, // Use different DB for tests
 	})
 	// Test connection
@@ -43,8 +79,13 @@ func setupRouter() *gin.Engine {
 	// Use test Redis
 	testRdb := setupTestRedis()
 	rdb = testRdb
+	// individual auth tests switch this back on
+	authEnabled = false
+	// most tests submit the same params repeatedly; dedup tests switch this back on
+	dedupWindow = 0
 	
 	router := gin.Default()
+	router.Use(traceRequests(), requestLogger())
 	router.Use(func(c *gin.Context) {
 		// Simple CORS for tests
 		c.Header("Access-Control-Allow-Origin", "*")
@@ -55,66 +96,32 @@ func setupRouter() *gin.Engine {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
-	router.POST("/simulate", func(c *gin.Context) {
-		var params SimulationParams
-		if err := c.BindJSON(&params); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON: " + err.Error()})
-			return
-		}
-		applyDefaults(&params)
-		jobID := "test-job-id"
-		now := time.Now().UTC()
-		payload := JobPayload{
-			JobID:     jobID,
-			CreatedAt: now,
-			Params:    params,
-		}
-		payloadBytes, _ := json.Marshal(payload)
-		ctx := c.Request.Context()
-		rdb.RPush(ctx, RedisJobsList, payloadBytes)
-		meta := JobMeta{
-			JobID:     jobID,
-			Status:    StatusQueued,
-			CreatedAt: now,
-			UpdatedAt: now,
-			Params:    params,
-			ResultKey: RedisResultsPrefix + jobID,
-		}
-		metaBytes, _ := json.Marshal(meta)
-		rdb.Set(ctx, RedisJobMetaPrefix+jobID, metaBytes, DefaultResultTTL)
-		c.JSON(http.StatusAccepted, gin.H{
-			"job_id": jobID,
-			"status": StatusQueued,
-		})
-	})
-
-	router.GET("/results/:job_id", func(c *gin.Context) {
-		jobID := c.Param("job_id")
-		ctx := c.Request.Context()
-		res, err := rdb.Get(ctx, RedisResultsPrefix+jobID).Result()
-		if err == redis.Nil {
-			metaBytes, err2 := rdb.Get(ctx, RedisJobMetaPrefix+jobID).Result()
-			if err2 == nil {
-				var meta JobMeta
-				_ = json.Unmarshal([]byte(metaBytes), &meta)
-				c.JSON(http.StatusOK, gin.H{"job_id": jobID, "status": meta.Status})
-				return
-			}
-			c.JSON(http.StatusNotFound, gin.H{"error": "no result or job not found"})
-			return
-		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "redis error: " + err.Error()})
-			return
-		}
-		var parsed interface{}
-		if err := json.Unmarshal([]byte(res), &parsed); err == nil {
-			c.JSON(http.StatusOK, gin.H{"job_id": jobID, "status": StatusDone, "result": parsed})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"job_id": jobID, "status": StatusDone, "result": res})
-	})
-
-	router.GET("/results", func(c *gin.Context) {
+	router.GET("/ready", readyHandler)
+	router.GET("/health/workers", workersHealthHandler)
+	router.GET("/version", versionHandler)
+
+	initMetrics()
+	router.GET("/metrics", metricsHandler())
+	router.GET("/openapi.json", openAPIHandler)
+	router.GET("/docs", docsHandler)
+	router.GET("/shared/:token", sharedResultHandler)
+	router.POST("/internal/jobs/:job_id/status", internalAuth(), workerStatusHandler)
+
+	api := router.Group("", apiKeyAuth())
+	api.Use(compressResponses())
+	api.POST("/simulate", rateLimit(), limitBody(&maxBodyBytes), submitJobHandler)
+	api.GET("/simulate", rateLimit(), submitQueryHandler)
+	api.POST("/validate", limitBody(&maxBodyBytes), validateParamsHandler)
+	api.POST("/simulate/estimate", limitBody(&maxBodyBytes), estimateHandler)
+	api.POST("/simulate/batch", rateLimit(), limitBody(&maxBatchBodyBytes), submitBatchHandler)
+	api.POST("/simulate/upload", rateLimit(), limitBody(&maxBatchBodyBytes), submitUploadHandler)
+	api.POST("/simulate/sweep", rateLimit(), limitBody(&maxBodyBytes), submitSweepHandler)
+
+	api.POST("/scenarios", submitScenarioHandler)
+	api.GET("/scenarios/:scenario_id", getScenarioHandler)
+	api.GET("/results/:job_id", getResultsHandler)
+
+	api.GET("/results", func(c *gin.Context) {
 		ctx := c.Request.Context()
 		ids, err := rdb.LRange(ctx, RedisRecentJobsList, 0, 49).Result()
 		if err != nil && err != redis.Nil {
@@ -124,24 +131,35 @@ func setupRouter() *gin.Engine {
 		c.JSON(http.StatusOK, gin.H{"recent_job_ids": ids})
 	})
 
-	router.GET("/jobs/:job_id", func(c *gin.Context) {
-		jobID := c.Param("job_id")
-		ctx := c.Request.Context()
-		metaStr, err := rdb.Get(ctx, RedisJobMetaPrefix+jobID).Result()
-		if err == redis.Nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
-			return
-		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "redis error: " + err.Error()})
-			return
-		}
-		var meta JobMeta
-		if err := json.Unmarshal([]byte(metaStr), &meta); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse job meta"})
-			return
-		}
-		c.JSON(http.StatusOK, meta)
-	})
+	api.GET("/presets", listPresetsHandler)
+	api.GET("/jobs/:job_id", getJobMetaHandler)
+	api.GET("/jobs/:job_id/params", getJobParamsHandler)
+
+	api.GET("/results/:job_id/csv", getResultsCSVHandler)
+	api.GET("/results/:job_id/parquet", getResultsParquetHandler)
+	api.GET("/results/:job_id/partial", getPartialResultsHandler)
+	api.GET("/results/:job_id/summary", getResultSummaryHandler)
+	api.GET("/jobs", listJobsHandler)
+	api.DELETE("/jobs", adminOnly(), purgeJobsHandler)
+	api.POST("/admin/requeue", adminOnly(), requeueJobsHandler)
+	api.GET("/jobs/search", searchJobsHandler)
+	api.GET("/jobs/status", bulkJobStatusHandler)
+	api.DELETE("/jobs/:job_id", deleteJobHandler)
+	api.PATCH("/jobs/:job_id", limitBody(&maxBodyBytes), patchJobHandler)
+	api.POST("/jobs/:job_id/cancel", cancelJobHandler)
+	api.POST("/jobs/:job_id/retry", rateLimit(), retryJobHandler)
+	api.POST("/jobs/:job_id/clone", rateLimit(), cloneJobHandler)
+	api.GET("/jobs/:job_id/compare-preset/:preset_name", rateLimit(), comparePresetHandler)
+	api.PUT("/jobs/:job_id/notes", limitBody(&maxBodyBytes), putJobNotesHandler)
+	api.POST("/jobs/:job_id/share", shareJobHandler)
+	api.GET("/jobs/:job_id/events", jobEventsHandler)
+	api.GET("/jobs/:job_id/logs", getJobLogsHandler)
+	api.GET("/jobs/:job_id/artifacts", listArtifactsHandler)
+	api.GET("/jobs/:job_id/artifacts/:name", getArtifactHandler)
+	api.GET("/jobs/:job_id/bundle", getJobBundleHandler)
+	api.GET("/compare", compareJobsHandler)
+	api.GET("/stats", statsHandler)
+	api.GET("/weather", weatherHandler)
 
 	return router
 }
@@ -197,6 +215,93 @@ func TestApplyDefaultsPreservesExistingValues(t *testing.T) {
 	assert.NotNil(t, params.U_day)
 }
 
+func TestApplyDefaultsHeatCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   SimulationParams
+		wantC    float64
+		wantWarn string
+	}{
+		{"neither", SimulationParams{}, 2e7, ""},
+		{"only C", SimulationParams{C: floatPtr(5e6)}, 5e6, ""},
+		{"only thermal_mass", SimulationParams{ThermalMass: floatPtr(8e6)}, 8e6, ""},
+		{"only mass", SimulationParams{ThermalMassKg: floatPtr(1000)}, 1000 * 4186.0, ""},
+		{"mass with cp", SimulationParams{ThermalMassKg: floatPtr(1000), CpMass: floatPtr(880)}, 880000, ""},
+		{"only cp", SimulationParams{CpMass: floatPtr(880)}, 2e7, ""},
+		{"C and mass", SimulationParams{C: floatPtr(5e6), ThermalMassKg: floatPtr(1000)}, 5e6,
+			"heat capacity given more than once; using C (C = 5e+06 J/K) and ignoring thermal_mass_kg"},
+		{"C and thermal_mass", SimulationParams{C: floatPtr(5e6), ThermalMass: floatPtr(8e6)}, 5e6,
+			"heat capacity given more than once; using C (C = 5e+06 J/K) and ignoring thermal_mass"},
+		{"thermal_mass and mass with cp", SimulationParams{ThermalMass: floatPtr(8e6), ThermalMassKg: floatPtr(1000), CpMass: floatPtr(880)}, 8e6,
+			"heat capacity given more than once; using thermal_mass (C = 8e+06 J/K) and ignoring thermal_mass_kg * cp_mass"},
+		{"all three", SimulationParams{C: floatPtr(5e6), ThermalMass: floatPtr(8e6), ThermalMassKg: floatPtr(1000)}, 5e6,
+			"heat capacity given more than once; using C (C = 5e+06 J/K) and ignoring thermal_mass, thermal_mass_kg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			warnings := applyDefaults(&params)
+			require.NotNil(t, params.C)
+			assert.Equal(t, tt.wantC, *params.C)
+			if tt.wantWarn == "" {
+				assert.Empty(t, warnings)
+			} else {
+				assert.Equal(t, []string{tt.wantWarn}, warnings)
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsVentilation(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   SimulationParams
+		wantACH  float64
+		wantWarn bool
+	}{
+		{"neither", SimulationParams{}, 0.5, false},
+		{"only ACH", SimulationParams{ACH: floatPtr(2)}, 2, false},
+		{"only rate, default V", SimulationParams{VentilationRate: floatPtr(0.05)}, 1.8, false},
+		{"only rate, explicit V", SimulationParams{VentilationRate: floatPtr(0.05), Volume: floatPtr(360)}, 0.5, false},
+		{"both agreeing", SimulationParams{VentilationRate: floatPtr(0.05), ACH: floatPtr(1.8)}, 1.8, false},
+		{"both conflicting", SimulationParams{VentilationRate: floatPtr(0.05), ACH: floatPtr(3)}, 1.8, true},
+		// left for validation to reject
+		{"negative rate", SimulationParams{VentilationRate: floatPtr(-1), ACH: floatPtr(3)}, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			warnings := applyDefaults(&params)
+			require.NotNil(t, params.ACH)
+			assert.InDelta(t, tt.wantACH, *params.ACH, 1e-9)
+			if tt.wantWarn {
+				require.Len(t, warnings, 1)
+				assert.Contains(t, warnings[0], "ventilation_rate")
+			} else {
+				assert.Empty(t, warnings)
+			}
+		})
+	}
+}
+
+func TestSubmitJobReportsVentilationConflict(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{"ventilation_rate": 0.05, "ACH": 3}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response["warnings"], 1)
+}
+
 func TestSubmitJob(t *testing.T) {
 	if !checkRedisAvailable(t) {
 		return
@@ -365,3 +470,79 @@ func floatPtr(f float64) *float64 {
 	return &f
 }
 
+
+func TestSubmitJobReturnsLinks(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+	orig := apiBaseURL
+	apiBaseURL = "https://api.example.com/greensim"
+	defer func() { apiBaseURL = orig }()
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var response struct {
+		JobID string            `json:"job_id"`
+		Links map[string]string `json:"links"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	base := "https://api.example.com/greensim"
+	assert.Equal(t, base+"/results/"+response.JobID, w.Header().Get("Location"))
+	assert.Equal(t, base+"/jobs/"+response.JobID, response.Links["self"])
+	assert.Equal(t, base+"/results/"+response.JobID, response.Links["result"])
+	assert.Equal(t, base+"/jobs/"+response.JobID, response.Links["meta"])
+}
+
+func TestRunServerGracefulShutdownOnSignal(t *testing.T) {
+	// reserve a free port for the server
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	started := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	defer stop()
+
+	done := make(chan error, 1)
+	go func() { done <- runServer(ctx, srv, 2*time.Second) }()
+
+	// issue an in-flight request, then signal while it is being handled
+	respCh := make(chan int, 1)
+	go func() {
+		for i := 0; i < 50; i++ {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err == nil {
+				resp.Body.Close()
+				respCh <- resp.StatusCode
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		respCh <- 0
+	}()
+	<-started
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not shut down within the grace period")
+	}
+	assert.Equal(t, http.StatusOK, <-respCh)
+}
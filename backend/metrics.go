@@ -0,0 +1,203 @@
+package main
+
+// backend/metrics.go
+//
+// Prometheus metrics exposed at /metrics. Submissions are counted in the submit
+// handler. Queue wait and terminal statuses are observed where a job's status
+// moves: the worker status endpoint, cancel and the stale sweep, and, for the
+// worker's own writes to Redis, the job_events:* message every status change
+// publishes (runJobEventMetrics). Each transition is claimed with a
+// metrics_seen:<transition>:<job_id> marker, so a job seen both ways, by
+// several backends or in a repeated event is counted once. A background pass
+// over recent job meta calibrates the per-step cost used by
+// /simulate/estimate; it is marked per job the same way.
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+)
+
+// MetricsScanInterval is how often the calibration pass scans recent job meta.
+const MetricsScanInterval = 15 * time.Second
+
+// RedisMetricsSeenPrefix marks metrics_seen:<transition>:<job_id> once the
+// transition ("started" or "finished") has been observed.
+const RedisMetricsSeenPrefix = "metrics_seen:"
+
+// metricsSeenTTL outlasts any redelivery of a transition's event.
+const metricsSeenTTL = 24 * time.Hour
+
+// DefaultQueueWaitBuckets cover sub-minute to multi-minute waits (seconds).
+var DefaultQueueWaitBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800}
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+	metricsOnce     sync.Once
+
+	jobsSubmitted prometheus.Counter
+	jobsFinished  *prometheus.CounterVec
+	queueWait     prometheus.Histogram
+)
+
+// initMetrics creates and registers the collectors. Buckets for the queue wait
+// histogram come from METRICS_QUEUE_WAIT_BUCKETS (comma-separated seconds).
+func initMetrics() {
+	metricsOnce.Do(func() {
+		jobsSubmitted = prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "greensim_jobs_submitted_total",
+			Help: "Number of simulation jobs accepted by /simulate.",
+		})
+		jobsFinished = prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "greensim_jobs_finished_total",
+			Help: "Number of jobs the backend moved to a terminal status.",
+		}, []string{"status"})
+		queueWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "greensim_job_queue_wait_seconds",
+			Help:    "Time from job creation until a worker marked it running.",
+			Buckets: queueWaitBuckets(),
+		})
+		metricsRegistry.MustRegister(
+			jobsSubmitted,
+			jobsFinished,
+			queueWait,
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+	})
+}
+
+func queueWaitBuckets() []float64 {
+	v := os.Getenv("METRICS_QUEUE_WAIT_BUCKETS")
+	if v == "" {
+		return DefaultQueueWaitBuckets
+	}
+	var buckets []float64
+	for _, part := range strings.Split(v, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || (len(buckets) > 0 && f <= buckets[len(buckets)-1]) {
+			slog.Warn("ignoring invalid METRICS_QUEUE_WAIT_BUCKETS", "value", v)
+			return DefaultQueueWaitBuckets
+		}
+		buckets = append(buckets, f)
+	}
+	return buckets
+}
+
+func metricsHandler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+}
+
+func isTerminalStatus(status string) bool {
+	return status == StatusDone || status == StatusError || status == StatusCancelled
+}
+
+// claimTransition reports whether this call is the first to observe transition
+// for jobID. Redis errors are logged and the transition left uncounted.
+func claimTransition(ctx context.Context, transition, jobID string) bool {
+	ok, err := rdb.SetNX(ctx, metricsSeenKey(transition, jobID), "1", metricsSeenTTL).Result()
+	if err != nil {
+		slog.Warn("failed to mark job metric", "job_id", jobID, "transition", transition, "error", err)
+		return false
+	}
+	return ok
+}
+
+// observeJobStarted records the queue wait of a job that reported running,
+// once per job.
+func observeJobStarted(ctx context.Context, meta JobMeta) {
+	if queueWait == nil || meta.StartedAt == nil || !claimTransition(ctx, "started", meta.JobID) {
+		return
+	}
+	queueWait.Observe(meta.StartedAt.Sub(meta.CreatedAt).Seconds())
+}
+
+// observeJobFinished counts a job that reached a terminal status, once per
+// job, and feeds a done job's running time into the step cost.
+func observeJobFinished(ctx context.Context, meta JobMeta) {
+	if jobsFinished != nil && claimTransition(ctx, "finished", meta.JobID) {
+		jobsFinished.WithLabelValues(meta.Status).Inc()
+	}
+	if err := recordStepCost(ctx, meta); err != nil {
+		slog.Warn("failed to record step cost", "job_id", meta.JobID, "error", err)
+	}
+}
+
+// runJobEventMetrics observes the transitions published on job_events:* until
+// ctx is done. It is how jobs the worker updates directly in Redis are seen.
+func runJobEventMetrics(ctx context.Context) {
+	sub := rdb.PSubscribe(ctx, jobEventsChannel("*"))
+	defer sub.Close()
+	msgs := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			var meta JobMeta
+			if err := json.Unmarshal([]byte(msg.Payload), &meta); err != nil {
+				slog.Warn("ignoring malformed job event", "channel", msg.Channel, "error", err)
+				continue
+			}
+			obsCtx, cancel := context.WithTimeout(ctx, RedisOpTimeout)
+			switch {
+			case meta.Status == StatusRunning:
+				observeJobStarted(obsCtx, meta)
+			case isTerminalStatus(meta.Status):
+				observeJobFinished(obsCtx, meta)
+			}
+			cancel()
+		}
+	}
+}
+
+// calibrateStepCosts does one pass over the recent jobs, folding done jobs
+// whose worker wrote the status itself into the step cost.
+func calibrateStepCosts(ctx context.Context) error {
+	ids, err := rdb.LRange(ctx, redisKey(RedisRecentJobsList), 0, -1).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	metas, err := loadMetas(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, m := range metas {
+		if err := recordStepCost(ctx, m); err != nil {
+			slog.Warn("failed to record step cost", "job_id", m.JobID, "error", err)
+		}
+	}
+	return nil
+}
+
+// runMetricsTracker calls calibrateStepCosts every interval until ctx is done.
+func runMetricsTracker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scanCtx, cancel := context.WithTimeout(ctx, RedisOpTimeout)
+			if err := calibrateStepCosts(scanCtx); err != nil {
+				slog.Warn("step cost calibration failed", "error", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scrapeMetric fetches /metrics and returns the value of the named unlabelled sample.
+func scrapeMetric(t *testing.T, router *gin.Engine, name string) float64 {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == name {
+			v, err := strconv.ParseFloat(fields[1], 64)
+			require.NoError(t, err)
+			return v
+		}
+	}
+	t.Fatalf("metric %s not found", name)
+	return 0
+}
+
+func TestMetricsCountSubmissions(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	before := scrapeMetric(t, router, "greensim_jobs_submitted_total")
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+
+	after := scrapeMetric(t, router, "greensim_jobs_submitted_total")
+	assert.Equal(t, before+1, after)
+}
+
+func TestJobTransitionMetrics(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withInternalSecret(t, "s3cret")
+
+	var ids []string
+	for _, body := range []string{withSite(`{"setpoint": 14}`), withSite(`{"setpoint": 15}`)} {
+		w := postSimulate(router, body)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		ids = append(ids, resp["job_id"].(string))
+	}
+	finished := func(status string) float64 { return testutil.ToFloat64(jobsFinished.WithLabelValues(status)) }
+	waitsBefore := scrapeMetric(t, router, "greensim_job_queue_wait_seconds_count")
+	doneBefore, cancelledBefore, errorBefore := finished(StatusDone), finished(StatusCancelled), finished(StatusError)
+
+	// the first running report observes the queue wait, later ones do not
+	require.Equal(t, http.StatusOK, reportStatus(t, router, "s3cret", ids[0], gin.H{"status": StatusRunning}).Code)
+	require.Equal(t, http.StatusOK, reportStatus(t, router, "s3cret", ids[0], gin.H{"status": StatusRunning, "progress": 50}).Code)
+	assert.Equal(t, waitsBefore+1, scrapeMetric(t, router, "greensim_job_queue_wait_seconds_count"))
+
+	result := json.RawMessage(sampleResult)
+	require.Equal(t, http.StatusOK, reportStatus(t, router, "s3cret", ids[0], gin.H{"status": StatusDone, "result": result}).Code)
+	require.Equal(t, http.StatusConflict, reportStatus(t, router, "s3cret", ids[0], gin.H{"status": StatusDone, "result": result}).Code)
+	assert.Equal(t, doneBefore+1, finished(StatusDone), "a repeated report is not counted again")
+
+	req, _ := http.NewRequest("POST", "/jobs/"+ids[1]+"/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, cancelledBefore+1, finished(StatusCancelled))
+
+	seedJobMeta(t, ctx, "m-stale", StatusRunning)
+	now := time.Now().UTC()
+	ok, err := failStaleJob(ctx, "m-stale", now.Add(time.Minute), now)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, errorBefore+1, finished(StatusError))
+
+	// the background pass only calibrates; terminal jobs on the recent list
+	// (e.g. after a restart) are not counted again
+	require.NoError(t, calibrateStepCosts(ctx))
+	assert.Equal(t, doneBefore+1, finished(StatusDone))
+	assert.Equal(t, cancelledBefore+1, finished(StatusCancelled))
+	assert.Equal(t, errorBefore+1, finished(StatusError))
+}
+
+func TestJobEventMetrics(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	evCtx, stop := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		runJobEventMetrics(evCtx)
+		close(done)
+	}()
+	defer func() {
+		stop()
+		<-done
+	}()
+	require.Eventually(t, func() bool {
+		return rdb.PubSubNumPat(ctx).Val() > 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// what the worker does on every status change: write the meta, then publish it
+	workerWrite := func(meta JobMeta) {
+		b, err := json.Marshal(meta)
+		require.NoError(t, err)
+		require.NoError(t, rdb.Set(ctx, jobMetaKey(meta.JobID), b, DefaultResultTTL).Err())
+		require.NoError(t, rdb.Publish(ctx, jobEventsChannel(meta.JobID), b).Err())
+	}
+	waits := func() float64 { return scrapeMetric(t, router, "greensim_job_queue_wait_seconds_count") }
+	finished := func(status string) float64 { return testutil.ToFloat64(jobsFinished.WithLabelValues(status)) }
+	waitsBefore, doneBefore, errorBefore := waits(), finished(StatusDone), finished(StatusError)
+	sumBefore := scrapeMetric(t, router, "greensim_job_queue_wait_seconds_sum")
+
+	for _, id := range []string{"w-done", "w-error"} {
+		meta := seedJobMeta(t, ctx, id, StatusQueued, seededAgo(30*time.Second))
+		started := time.Now().UTC()
+		meta.Status, meta.StartedAt, meta.UpdatedAt = StatusRunning, &started, started
+		workerWrite(meta)
+		workerWrite(meta) // a repeated running write is not a second start
+		meta.Status, meta.UpdatedAt = StatusDone, time.Now().UTC()
+		if id == "w-error" {
+			meta.Status = StatusError
+		}
+		workerWrite(meta)
+		workerWrite(meta)
+	}
+
+	require.Eventually(t, func() bool {
+		return waits() == waitsBefore+2 && finished(StatusDone) == doneBefore+1 && finished(StatusError) == errorBefore+1
+	}, 2*time.Second, 10*time.Millisecond)
+	sum := scrapeMetric(t, router, "greensim_job_queue_wait_seconds_sum") - sumBefore
+	assert.InDelta(t, 60.0, sum, 5, "two jobs that waited about 30s each")
+
+	// duplicates arrive in order on one connection, so by now they have been ignored
+	workerWrite(JobMeta{JobID: "w-sync", Status: StatusCancelled})
+	require.Eventually(t, func() bool {
+		return rdb.Exists(ctx, metricsSeenKey("finished", "w-sync")).Val() == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, waitsBefore+2, waits())
+	assert.Equal(t, doneBefore+1, finished(StatusDone))
+	assert.Equal(t, errorBefore+1, finished(StatusError))
+}
+
+func TestQueueWaitBucketsFromEnv(t *testing.T) {
+	t.Setenv("METRICS_QUEUE_WAIT_BUCKETS", "0.5, 2, 10")
+	assert.Equal(t, []float64{0.5, 2, 10}, queueWaitBuckets())
+
+	t.Setenv("METRICS_QUEUE_WAIT_BUCKETS", "10,2")
+	assert.Equal(t, DefaultQueueWaitBuckets, queueWaitBuckets())
+}
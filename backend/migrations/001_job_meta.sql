@@ -0,0 +1,15 @@
+-- backend/migrations/001_job_meta.sql
+--
+-- Durable copy of job metadata for METADATA_BACKEND=postgres. The full JobMeta
+-- document lives in meta; the columns beside it exist for filtering and ordering.
+
+CREATE TABLE IF NOT EXISTS job_meta (
+    job_id     TEXT PRIMARY KEY,
+    status     TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    meta       JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS job_meta_created_at_idx ON job_meta (created_at DESC);
+CREATE INDEX IF NOT EXISTS job_meta_status_created_at_idx ON job_meta (status, created_at DESC);
@@ -0,0 +1,5 @@
+-- backend/migrations/002_job_meta_tags.sql
+--
+-- Index for GET /jobs?tag=..., which filters with meta->'tags' @> '["tag"]'.
+
+CREATE INDEX IF NOT EXISTS job_meta_tags_idx ON job_meta USING GIN ((meta->'tags'));
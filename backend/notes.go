@@ -0,0 +1,89 @@
+package main
+
+// backend/notes.go
+//
+// Free-text notes on jobs ("the run we presented to the board"). PUT
+// /jobs/:job_id/notes sets or replaces a job's note and an empty note clears
+// it; GET /jobs/:job_id returns it as "note". Notes live in job_notes:<id>
+// rather than in the meta so a worker rewriting the meta cannot drop them,
+// and they expire with the meta.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisJobNotesPrefix keys job_notes:<jobID> -> the note text.
+const RedisJobNotesPrefix = "job_notes:"
+
+// MaxNoteLength caps a note, in characters.
+const MaxNoteLength = 4000
+
+// notesRequest is the body of PUT /jobs/:job_id/notes.
+type notesRequest struct {
+	Note *string `json:"note"` // "" clears the note
+}
+
+// jobNote returns jobID's note, or "" when it has none.
+func jobNote(ctx context.Context, jobID string) (string, error) {
+	note, err := rdb.Get(ctx, jobNotesKey(jobID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return note, err
+}
+
+// setJobNote stores note for meta's job, or deletes it when note is empty.
+func setJobNote(ctx context.Context, meta JobMeta, note string) error {
+	key := jobNotesKey(meta.JobID)
+	if note == "" {
+		return rdb.Del(ctx, key).Err()
+	}
+	// follow the meta's remaining lifetime so the note does not outlive the job
+	ttl, err := rdb.PTTL(ctx, jobMetaKey(meta.JobID)).Result()
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = metaTTL(meta)
+	}
+	return rdb.Set(ctx, key, note, ttl).Err()
+}
+
+func putJobNotesHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	var req notesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if req.Note == nil {
+		respondInvalidParam(c, "note", `note is required (send "" to clear it)`)
+		return
+	}
+	if n := utf8.RuneCountInString(*req.Note); n > MaxNoteLength {
+		respondInvalidParam(c, "note", "note is too long", gin.H{"length": n, "max_length": MaxNoteLength})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	meta, err := metaStore.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	if err := setJobNote(ctx, meta, *req.Note); err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job_id": jobID, "note": *req.Note})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func putNote(router *gin.Engine, jobID, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PUT", "/jobs/"+jobID+"/notes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// getNote returns the "note" field of GET /jobs/:job_id, or nil if absent.
+func getNote(t *testing.T, router *gin.Engine, jobID string) interface{} {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/jobs/"+jobID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body["note"]
+}
+
+func TestJobNotes(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "noted-job", StatusDone)
+	assert.Nil(t, getNote(t, router, "noted-job"))
+
+	w := putNote(router, "noted-job", `{"note": "presented to the board"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"job_id": "noted-job", "note": "presented to the board"}`, w.Body.String())
+	assert.Equal(t, "presented to the board", getNote(t, router, "noted-job"))
+	assert.Positive(t, rdb.TTL(ctx, RedisJobNotesPrefix+"noted-job").Val(), "expires with the job")
+
+	// overwriting replaces the note
+	w = putNote(router, "noted-job", `{"note": "superseded by the March run"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "superseded by the March run", getNote(t, router, "noted-job"))
+
+	// an empty note clears it
+	w = putNote(router, "noted-job", `{"note": ""}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Nil(t, getNote(t, router, "noted-job"))
+	assert.Zero(t, rdb.Exists(ctx, RedisJobNotesPrefix+"noted-job").Val())
+}
+
+func TestJobNotesErrors(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "noted-job", StatusDone)
+
+	assert.Equal(t, http.StatusNotFound, putNote(router, "missing-job", `{"note": "hi"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, putNote(router, "noted-job", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, putNote(router, "noted-job", `{"note": 42}`).Code)
+
+	long, _ := json.Marshal(gin.H{"note": strings.Repeat("é", MaxNoteLength+1)})
+	w := putNote(router, "noted-job", string(long))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "too long")
+}
+
+func TestDeleteJobRemovesNote(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "noted-job", StatusDone)
+	require.Equal(t, http.StatusOK, putNote(router, "noted-job", `{"note": "keep?"}`).Code)
+
+	req, _ := http.NewRequest("DELETE", "/jobs/noted-job", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Zero(t, rdb.Exists(ctx, RedisJobNotesPrefix+"noted-job").Val())
+}
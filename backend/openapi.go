@@ -0,0 +1,619 @@
+package main
+
+// backend/openapi.go
+//
+// OpenAPI 3 description of the API, served at /openapi.json with a Swagger UI
+// at /docs. Component schemas are generated from the Go types by reflecting on
+// their json tags, so the spec cannot drift from what the handlers bind and
+// return; only the path table below is written by hand.
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// spec is a JSON object in the OpenAPI document.
+type spec = map[string]interface{}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaComponents are the named types exposed under components.schemas.
+var schemaComponents = map[string]reflect.Type{
+	"SimulationParams": reflect.TypeOf(SimulationParams{}),
+	"JobMeta":          reflect.TypeOf(JobMeta{}),
+	"FieldError":       reflect.TypeOf(FieldError{}),
+	"Preset":           reflect.TypeOf(Preset{}),
+	"BatchItem":        reflect.TypeOf(batchItem{}),
+	"BatchItemError":   reflect.TypeOf(batchItemError{}),
+	"SweepItem":        reflect.TypeOf(sweepItem{}),
+	"UploadItem":       reflect.TypeOf(uploadItem{}),
+	"UploadRowError":   reflect.TypeOf(uploadRowError{}),
+	"ScenarioChild":    reflect.TypeOf(scenarioChild{}),
+}
+
+// schemaRef returns a $ref for named component types and an inline schema otherwise.
+func schemaRef(t reflect.Type) spec {
+	for name, ct := range schemaComponents {
+		if ct == t {
+			return spec{"$ref": "#/components/schemas/" + name}
+		}
+	}
+	return schemaFor(t)
+}
+
+// schemaFor builds the JSON schema of t from its kind and json tags. Embedded
+// structs are flattened like encoding/json does; fields without omitempty are
+// listed as required.
+func schemaFor(t reflect.Type) spec {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return spec{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return spec{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return spec{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return spec{"type": "number"}
+	case reflect.String:
+		return spec{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 { // json.RawMessage: any JSON value
+			return spec{}
+		}
+		return spec{"type": "array", "items": schemaRef(t.Elem())}
+	case reflect.Map:
+		return spec{"type": "object", "additionalProperties": schemaRef(t.Elem())}
+	case reflect.Struct:
+		props := spec{}
+		var required []string
+		collectFields(t, props, &required)
+		s := spec{"type": "object", "properties": props}
+		if len(required) > 0 {
+			s["required"] = required
+		}
+		return s
+	}
+	return spec{}
+}
+
+func collectFields(t reflect.Type, props spec, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			collectFields(f.Type, props, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = schemaRef(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// Shared pieces of the path table
+var (
+	jobIDParam = spec{"name": "job_id", "in": "path", "required": true, "schema": spec{"type": "string"}}
+	errorBody  = spec{"description": "error", "content": jsonContent(spec{
+		"type": "object",
+		"properties": spec{
+			"error": spec{"type": "object", "required": []string{"code", "message"}, "properties": spec{
+				"code":    spec{"type": "string", "enum": errorCodes},
+				"message": spec{"type": "string"},
+				"field":   spec{"type": "string", "description": "the offending input, when there is one"},
+			}},
+			"request_id": spec{"type": "string"},
+			"fields":     spec{"type": "array", "items": spec{"$ref": "#/components/schemas/FieldError"}},
+		},
+	})}
+	linksSchema = spec{"type": "object", "additionalProperties": spec{"type": "string"}}
+)
+
+func jsonContent(schema spec) spec {
+	return spec{"application/json": spec{"schema": schema}}
+}
+
+func ref(name string) spec {
+	return spec{"$ref": "#/components/schemas/" + name}
+}
+
+func queryParam(name, description string, schema spec) spec {
+	return spec{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+func response(description string, schema spec) spec {
+	return spec{"description": description, "content": jsonContent(schema)}
+}
+
+func operation(summary string, params []spec, body spec, responses spec) spec {
+	op := spec{"summary": summary, "responses": responses}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if body != nil {
+		op["requestBody"] = spec{"required": true, "content": jsonContent(body)}
+	}
+	return op
+}
+
+// openAPIPaths describes every authenticated route registered in main().
+func openAPIPaths() spec {
+	boolean := spec{"type": "boolean"}
+	allowExtreme := queryParam("allow_extreme", "accept setpoint and T_init outside the plausible temperature band", boolean)
+	accepted := response("job queued", spec{"type": "object", "properties": spec{
+		"job_id":     spec{"type": "string"},
+		"status":     spec{"type": "string"},
+		"links":      linksSchema,
+		"start_date": spec{"type": "string"},
+		"end_date":   spec{"type": "string"},
+		"warnings":   spec{"type": "array", "items": spec{"type": "string"}},
+	}})
+
+	scenarioSchema := spec{"type": "object", "properties": spec{
+		"scenario_id": spec{"type": "string"},
+		"name":        spec{"type": "string"},
+		"status":      spec{"type": "string", "enum": []string{ScenarioPending, ScenarioDone, ScenarioError}},
+		"jobs":        spec{"type": "array", "items": ref("ScenarioChild")},
+	}}
+
+	upload := operation("Submit a CSV of parameter sets (one job per row)", []spec{allowExtreme}, nil, spec{
+		"202": response("valid rows queued", spec{"type": "object", "properties": spec{
+			"batch_id": spec{"type": "string"},
+			"jobs":     spec{"type": "array", "items": ref("UploadItem")},
+			"errors":   spec{"type": "array", "items": ref("UploadRowError")},
+		}}),
+		"400": errorBody, "413": errorBody, "429": errorBody,
+	})
+	upload["requestBody"] = spec{"required": true, "content": spec{"multipart/form-data": spec{"schema": spec{
+		"type":       "object",
+		"required":   []string{UploadFormField},
+		"properties": spec{UploadFormField: spec{"type": "string", "format": "binary", "description": "CSV with a header row of SimulationParams field names"}},
+	}}}}
+
+	shared := operation("Get a shared job's results",
+		[]spec{{"name": "token", "in": "path", "required": true, "schema": spec{"type": "string"}}},
+		nil, spec{
+			"200": response("results", spec{"type": "object"}),
+			"403": errorBody, "404": errorBody, "409": errorBody,
+		})
+	shared["security"] = []spec{} // the token is the credential
+
+	workerStatus := operation("Report a job status from a worker", []spec{jobIDParam}, spec{
+		"type":     "object",
+		"required": []string{"status"},
+		"properties": spec{
+			"status":   spec{"type": "string", "enum": []string{StatusRunning, StatusDone, StatusError}},
+			"error":    spec{"type": "string", "description": "required with status error"},
+			"result":   spec{"type": "object", "description": "the simulation result; required with status done"},
+			"progress": spec{"type": "number", "minimum": 0, "maximum": 100, "description": "percent done; only with status running"},
+		},
+	}, spec{
+		"200": response("updated job metadata", ref("JobMeta")),
+		"400": errorBody, "401": errorBody, "404": errorBody, "409": errorBody, "503": errorBody,
+	})
+	workerStatus["security"] = []spec{{"internalSecret": []string{}}}
+
+	submitQuery := []spec{
+		queryParam("dry_run", "validate and return the resolved params without enqueuing", boolean),
+		allowExtreme,
+		queryParam("reuse", "return a finished job with identical physics instead of enqueuing", boolean),
+		queryParam("dedup", "false enqueues even when the same params were just submitted (default true)", boolean),
+		queryParam("strict", "reject unknown fields with 400 instead of ignoring them (also X-Strict-Params: true)", boolean),
+		queryParam("unique_active", "refuse with 409 while another job for greenhouse_id is queued or running", boolean),
+		queryParam("wait", "hold the request until the job finishes and inline its result", boolean),
+		queryParam("timeout", "seconds to wait with wait=true before answering 202 (default 30)", spec{"type": "integer"}),
+	}
+	// GET /simulate takes every SimulationParams field as a query parameter
+	paramQuery := append([]spec{}, submitQuery...)
+	for _, name := range sortedUploadColumns() {
+		paramQuery = append(paramQuery, queryParam(name, "SimulationParams field", schemaFor(uploadColumns[name])))
+	}
+
+	submit := operation("Submit a simulation job (JSON, or YAML with Content-Type application/yaml)",
+		append(submitQuery, spec{"name": IdempotencyKeyHeader, "in": "header", "schema": spec{"type": "string"}}),
+		ref("SimulationParams"),
+		spec{
+			"202": accepted,
+			"200": response("dry run result, reused or deduplicated job, or finished job with wait=true", spec{"type": "object"}),
+			"400": errorBody, "409": errorBody, "413": errorBody, "415": errorBody, "429": errorBody, "502": errorBody,
+		})
+	submit["requestBody"].(spec)["content"].(spec)[MIMEYAML] = spec{"schema": ref("SimulationParams")}
+
+	estimate := operation("Estimate a job's timesteps and runtime without enqueuing it",
+		[]spec{allowExtreme}, ref("SimulationParams"),
+		spec{
+			"200": response("timesteps and estimated runtime", spec{"type": "object", "properties": spec{
+				"params":                    ref("SimulationParams"),
+				"model":                     spec{"type": "string"},
+				"timesteps":                 spec{"type": "integer"},
+				"seconds_per_step":          spec{"type": "number"},
+				"estimated_runtime_seconds": spec{"type": "number"},
+				"calibration_samples":       spec{"type": "integer", "description": "finished jobs the per-step cost is calibrated from; 0 for the built-in guess"},
+			}}),
+			"400": errorBody, "413": errorBody, "415": errorBody,
+		})
+	estimate["requestBody"].(spec)["content"].(spec)[MIMEYAML] = spec{"schema": ref("SimulationParams")}
+
+	return spec{
+		"/simulate": spec{"post": submit,
+			"get": operation("Submit a simulation job from query parameters (tags repeat or are separated by ;)",
+				paramQuery, nil,
+				spec{
+					"202": accepted,
+					"200": response("dry run result, reused job, or finished job with wait=true", spec{"type": "object"}),
+					"400": errorBody, "409": errorBody, "429": errorBody, "502": errorBody,
+				})},
+		"/simulate/batch": spec{"post": operation("Submit many parameter sets",
+			[]spec{
+				queryParam("atomic", "reject the whole batch if any set is invalid", boolean),
+				queryParam("dry_run", "validate and return the resolved params without enqueuing", boolean),
+				allowExtreme,
+			},
+			spec{"type": "array", "items": ref("SimulationParams")},
+			spec{
+				"202": response("valid sets queued", spec{"type": "object", "properties": spec{
+					"batch_id": spec{"type": "string"},
+					"jobs":     spec{"type": "array", "items": ref("BatchItem")},
+					"errors":   spec{"type": "array", "items": ref("BatchItemError")},
+				}}),
+				"400": errorBody, "413": errorBody, "429": errorBody,
+			})},
+		"/simulate/estimate": spec{"post": estimate},
+		"/validate": spec{"post": operation("Resolve and validate params without submitting them",
+			[]spec{allowExtreme, queryParam("strict", "reject unknown fields with 400 instead of ignoring them (also X-Strict-Params: true)", boolean)},
+			ref("SimulationParams"),
+			spec{
+				"200": response("the params are valid", spec{"type": "object", "properties": spec{
+					"valid":           spec{"type": "boolean"},
+					"units":           spec{"type": "string"},
+					"resolved_params": ref("SimulationParams"),
+					"warnings":        spec{"type": "array", "items": spec{"type": "string"}},
+				}}),
+				"400": errorBody, "413": errorBody, "415": errorBody,
+			})},
+		"/simulate/upload": spec{"post": upload},
+		"/simulate/sweep": spec{"post": operation("Submit a parameter sweep (one job per grid point)",
+			[]spec{allowExtreme},
+			schemaFor(reflect.TypeOf(sweepRequest{})),
+			spec{
+				"202": response("sweep queued", spec{"type": "object", "properties": spec{
+					"sweep_id": spec{"type": "string"},
+					"total":    spec{"type": "integer"},
+					"jobs":     spec{"type": "array", "items": ref("SweepItem")},
+				}}),
+				"400": errorBody, "413": errorBody, "429": errorBody,
+			})},
+		"/scenarios": spec{"post": operation("Submit labeled parameter sets as one scenario set",
+			[]spec{allowExtreme},
+			schemaFor(reflect.TypeOf(scenarioRequest{})),
+			spec{
+				"202": response("scenario set queued", scenarioSchema),
+				"400": errorBody, "413": errorBody, "429": errorBody,
+			})},
+		"/scenarios/{scenario_id}": spec{"get": operation("Get a scenario set's aggregate status and results",
+			[]spec{{"name": "scenario_id", "in": "path", "required": true, "schema": spec{"type": "string"}}},
+			nil, spec{
+				"200": response("pending until every job is finished, then done or error", scenarioSchema),
+				"304": spec{"description": "not modified (If-None-Match)"},
+				"404": errorBody,
+			})},
+		"/results": spec{"get": operation("List recent job ids", nil, nil, spec{
+			"200": response("recent job ids", spec{"type": "object"}),
+		})},
+		"/results/{job_id}": spec{"get": operation("Get a job's results",
+			[]spec{
+				jobIDParam,
+				queryParam("resolution", "downsample the series to at most this many points (at least 3)", spec{"type": "integer"}),
+				queryParam("page", "return one page of the series (1-based); adds pagination metadata", spec{"type": "integer"}),
+				queryParam("page_size", "points per page (default 500, max 5000)", spec{"type": "integer"}),
+			},
+			nil, spec{
+				"200": spec{"description": "results (as negotiated by Accept), or the current status while pending as JSON", "content": spec{
+					"application/json": spec{"schema": spec{"type": "object"}},
+					MIMECSV:            spec{"schema": spec{"type": "string"}},
+					MIMENDJSON:         spec{"schema": spec{"type": "string", "description": "one JSON data point per line"}},
+				}},
+				"304": spec{"description": "not modified (If-None-Match)"},
+				"400": errorBody, "404": errorBody, "406": errorBody, "409": errorBody, "410": errorBody, "502": errorBody,
+			})},
+		"/results/{job_id}/csv": spec{"get": operation("Download a job's results as CSV", []spec{jobIDParam}, nil, spec{
+			"200": spec{"description": "CSV", "content": spec{"text/csv": spec{"schema": spec{"type": "string"}}}},
+			"404": errorBody, "409": errorBody, "410": errorBody, "502": errorBody,
+		})},
+		"/results/{job_id}/parquet": spec{"get": operation("Download a job's results as Parquet", []spec{jobIDParam}, nil, spec{
+			"200": spec{"description": "Parquet file, one row group", "content": spec{MIMEParquet: spec{"schema": spec{"type": "string", "format": "binary"}}}},
+			"404": errorBody, "409": errorBody, "410": errorBody, "502": errorBody,
+		})},
+		"/results/{job_id}/summary": spec{"get": operation("Get aggregates of a job's series; partial rows while it runs",
+			[]spec{jobIDParam},
+			nil, spec{
+				"200": response("temperature, heating energy and setpoint statistics", schemaFor(reflect.TypeOf(resultStats{}))),
+				"404": errorBody, "502": errorBody,
+			})},
+		"/results/{job_id}/partial": spec{"get": operation("Get result rows computed so far",
+			[]spec{jobIDParam, queryParam("from", "first chunk index to return", spec{"type": "integer"})},
+			nil, spec{
+				"200": response("chunks of rows and whether more can follow", spec{"type": "object", "properties": spec{
+					"job_id":   spec{"type": "string"},
+					"status":   spec{"type": "string"},
+					"from":     spec{"type": "integer"},
+					"next":     spec{"type": "integer"},
+					"chunks":   spec{"type": "array", "items": spec{"type": "array", "items": spec{"type": "object"}}},
+					"complete": spec{"type": "boolean"},
+				}}),
+				"400": errorBody, "404": errorBody,
+			})},
+		"/jobs": spec{"get": operation("List job metadata",
+			[]spec{
+				queryParam("status", "filter by status", spec{"type": "string"}),
+				queryParam("limit", "page size", spec{"type": "integer"}),
+				queryParam("offset", "page offset", spec{"type": "integer"}),
+			}, nil, spec{
+				"200": response("page of jobs", spec{"type": "object", "properties": spec{
+					"total":  spec{"type": "integer"},
+					"limit":  spec{"type": "integer"},
+					"offset": spec{"type": "integer"},
+					"jobs":   spec{"type": "array", "items": ref("JobMeta")},
+				}}),
+				"400": errorBody,
+			}),
+			"delete": operation("Purge finished jobs created before a cutoff (admin API key)",
+				[]spec{{"name": "created_before", "in": "query", "required": true, "description": "RFC 3339 time or YYYY-MM-DD", "schema": spec{"type": "string"}}},
+				nil, spec{
+					"200": response("purge summary", spec{"type": "object", "properties": spec{
+						"created_before": spec{"type": "string", "format": "date-time"},
+						"purged":         spec{"type": "integer"},
+						"skipped_active": spec{"type": "integer"},
+						"scanned":        spec{"type": "integer"},
+					}}),
+					"400": errorBody, "403": errorBody,
+				}),
+		},
+		"/admin/requeue": spec{"post": operation("Requeue every job in a failed status as a retry (admin API key)",
+			[]spec{
+				queryParam("status", "status to requeue (default error)", spec{"type": "string", "enum": []string{StatusError, StatusCancelled}}),
+				queryParam("updated_after", "only jobs that reached the status at or after this RFC 3339 time or YYYY-MM-DD", spec{"type": "string"}),
+				queryParam("updated_before", "only jobs that reached the status before this time (default now)", spec{"type": "string"}),
+			},
+			nil, spec{
+				"200": response("requeue summary; jobs covered by an earlier run are skipped", schemaFor(reflect.TypeOf(requeueResult{}))),
+				"400": errorBody, "403": errorBody,
+			})},
+		"/jobs/status": spec{"get": operation("Get the status of many jobs at once",
+			[]spec{queryParam("ids", "comma-separated job ids (at most 200)", spec{"type": "string"})},
+			nil, spec{
+				"200": response("one entry per distinct id, in request order", spec{"type": "object", "properties": spec{
+					"jobs":    spec{"type": "array", "items": schemaFor(reflect.TypeOf(jobStatusEntry{}))},
+					"missing": spec{"type": "array", "items": spec{"type": "string"}},
+				}}),
+				"400": errorBody,
+			})},
+		"/jobs/search": spec{"get": operation("Search stored jobs by creation time and location",
+			[]spec{
+				queryParam("created_after", "inclusive lower bound (YYYY-MM-DD or RFC 3339)", spec{"type": "string"}),
+				queryParam("created_before", "exclusive upper bound (YYYY-MM-DD or RFC 3339)", spec{"type": "string"}),
+				queryParam("lat", "latitude to match", spec{"type": "number"}),
+				queryParam("lon", "longitude to match", spec{"type": "number"}),
+				queryParam("tolerance", "allowed lat/lon difference in degrees (default 0.1)", spec{"type": "number"}),
+				queryParam("limit", "page size", spec{"type": "integer"}),
+				queryParam("offset", "page offset", spec{"type": "integer"}),
+			}, nil, spec{
+				"200": response("page of matching jobs", spec{"type": "object", "properties": spec{
+					"total":     spec{"type": "integer"},
+					"limit":     spec{"type": "integer"},
+					"offset":    spec{"type": "integer"},
+					"truncated": spec{"type": "boolean"},
+					"jobs":      spec{"type": "array", "items": ref("JobMeta")},
+				}}),
+				"400": errorBody,
+			})},
+		"/jobs/{job_id}": spec{
+			"get": operation("Get job metadata", []spec{jobIDParam}, nil, spec{
+				"200": response("job metadata", ref("JobMeta")),
+				"304": spec{"description": "not modified (If-None-Match)"},
+				"404": errorBody,
+				"410": errorBody,
+			}),
+			"delete": operation("Delete a job and its result", []spec{jobIDParam}, nil, spec{
+				"200": response("deleted", spec{"type": "object"}),
+				"404": errorBody,
+			}),
+			"patch": operation("Merge a params patch into a queued job, keeping its id and place in the queue",
+				[]spec{jobIDParam, allowExtreme}, ref("SimulationParams"), spec{
+					"200": response("updated", spec{"type": "object", "properties": spec{
+						"job_id":   spec{"type": "string"},
+						"status":   spec{"type": "string"},
+						"units":    spec{"type": "string"},
+						"params":   ref("SimulationParams"),
+						"links":    linksSchema,
+						"warnings": spec{"type": "array", "items": spec{"type": "string"}},
+					}}),
+					"400": errorBody, "404": errorBody, "409": errorBody, "413": errorBody,
+				}),
+		},
+		"/jobs/{job_id}/params": spec{"get": operation("Get a job's resolved params", []spec{jobIDParam}, nil, spec{
+			"200": response("resolved params", ref("SimulationParams")),
+			"404": errorBody,
+		})},
+		"/jobs/{job_id}/cancel": spec{"post": operation("Cancel a queued job", []spec{jobIDParam}, nil, spec{
+			"200": response("cancelled", spec{"type": "object"}),
+			"404": errorBody, "409": errorBody,
+		})},
+		"/jobs/{job_id}/retry": spec{"post": operation("Rerun a finished or failed job", []spec{jobIDParam}, nil, spec{
+			"202": accepted,
+			"404": errorBody, "409": errorBody, "429": errorBody,
+		})},
+		"/jobs/{job_id}/clone": spec{"post": operation("Rerun a job with a params patch merged over its params",
+			[]spec{jobIDParam, allowExtreme}, ref("SimulationParams"), spec{
+				"202": accepted,
+				"400": errorBody, "404": errorBody, "413": errorBody, "429": errorBody,
+			})},
+		"/jobs/{job_id}/compare-preset/{preset_name}": spec{"get": operation("Compare a finished job with a run of a preset over the same dates and site",
+			[]spec{jobIDParam, {"name": "preset_name", "in": "path", "required": true, "schema": spec{"type": "string"}}},
+			nil, spec{
+				"200": response("per-timestep deltas of the job from the preset run", spec{"type": "object", "properties": spec{
+					"job_id":          spec{"type": "string"},
+					"preset":          spec{"type": "string"},
+					"baseline_job_id": spec{"type": "string"},
+					"timesteps":       spec{"type": "integer"},
+					"summary":         spec{"type": "object"},
+					"series":          spec{"type": "array", "items": spec{"type": "object"}},
+				}}),
+				"202": response("the preset run is queued or running; poll again", spec{"type": "object"}),
+				"404": errorBody, "409": errorBody, "429": errorBody, "502": errorBody,
+			})},
+		"/jobs/{job_id}/notes": spec{"put": operation("Set, replace or clear a job's note", []spec{jobIDParam},
+			spec{"type": "object", "required": []string{"note"}, "properties": spec{
+				"note": spec{"type": "string", "maxLength": MaxNoteLength, "description": `free text; "" clears the note`},
+			}},
+			spec{
+				"200": response("note stored", spec{"type": "object", "properties": spec{
+					"job_id": spec{"type": "string"},
+					"note":   spec{"type": "string"},
+				}}),
+				"400": errorBody, "404": errorBody, "413": errorBody,
+			})},
+		"/jobs/{job_id}/share": spec{"post": operation("Create a time-limited link to a job's results",
+			[]spec{jobIDParam, queryParam("ttl", "link lifetime in seconds (default 86400)", spec{"type": "integer"})},
+			nil, spec{
+				"201": response("share link", spec{"type": "object", "properties": spec{
+					"job_id":     spec{"type": "string"},
+					"token":      spec{"type": "string"},
+					"url":        spec{"type": "string"},
+					"expires_at": spec{"type": "string", "format": "date-time"},
+				}}),
+				"400": errorBody, "404": errorBody,
+			})},
+		"/shared/{token}":                spec{"get": shared},
+		"/internal/jobs/{job_id}/status": spec{"post": workerStatus},
+		"/jobs/{job_id}/events": spec{"get": operation("Stream job status transitions", []spec{jobIDParam}, nil, spec{
+			"200": spec{"description": "Server-Sent Events", "content": spec{"text/event-stream": spec{"schema": spec{"type": "string"}}}},
+		})},
+		"/jobs/{job_id}/logs": spec{"get": operation("Get the worker's log lines for a job",
+			[]spec{jobIDParam, queryParam("tail", "return only the last N lines", spec{"type": "integer"})},
+			nil, spec{
+				"200": response("log lines, oldest first", spec{"type": "object", "properties": spec{
+					"job_id": spec{"type": "string"},
+					"total":  spec{"type": "integer"},
+					"lines":  spec{"type": "array", "items": spec{"type": "string"}},
+				}}),
+				"400": errorBody, "404": errorBody,
+			})},
+		"/jobs/{job_id}/bundle": spec{"get": operation("Download a finished job's params, result, summary and logs as a zip", []spec{jobIDParam}, nil, spec{
+			"200": spec{"description": "zip of params.json, result.json, summary.json and logs.txt (when there are logs)", "content": spec{MIMEZip: spec{"schema": spec{"type": "string", "format": "binary"}}}},
+			"404": errorBody, "409": errorBody, "410": errorBody, "502": errorBody,
+		})},
+		"/jobs/{job_id}/artifacts": spec{"get": operation("List a job's result artifacts",
+			[]spec{jobIDParam},
+			nil, spec{
+				"200": response("artifacts by name; the main result is \"default\"", spec{"type": "object", "properties": spec{
+					"job_id":    spec{"type": "string"},
+					"artifacts": spec{"type": "array", "items": schemaFor(reflect.TypeOf(artifactInfo{}))},
+				}}),
+				"404": errorBody,
+			})},
+		"/jobs/{job_id}/artifacts/{name}": spec{"get": operation("Get one result artifact",
+			[]spec{jobIDParam, {"name": "name", "in": "path", "required": true, "schema": spec{"type": "string"}}},
+			nil, spec{
+				"200": response("the artifact document", spec{"type": "object"}),
+				"400": errorBody, "404": errorBody, "409": errorBody, "502": errorBody,
+			})},
+		"/compare": spec{"get": operation("Diff the results of finished jobs",
+			[]spec{queryParam("jobs", "comma-separated job ids; the first is the baseline", spec{"type": "string"})},
+			nil, spec{
+				"200": response("aligned differences", spec{"type": "object"}),
+				"400": errorBody, "409": errorBody, "502": errorBody,
+			})},
+		"/presets": spec{"get": operation("List parameter presets", nil, nil, spec{
+			"200": response("presets", spec{"type": "object", "properties": spec{
+				"presets": spec{"type": "array", "items": ref("Preset")},
+			}}),
+		})},
+		"/stats": spec{"get": operation("Operational summary", nil, nil, spec{
+			"200": response("queue depth and job counts", spec{"type": "object"}),
+		})},
+		"/weather": spec{"get": operation("Hourly weather for a location and date window (cached)",
+			[]spec{
+				{"name": "lat", "in": "query", "required": true, "schema": spec{"type": "number"}},
+				{"name": "lon", "in": "query", "required": true, "schema": spec{"type": "number"}},
+				{"name": "start", "in": "query", "required": true, "schema": spec{"type": "string", "format": "date"}},
+				{"name": "end", "in": "query", "required": true, "schema": spec{"type": "string", "format": "date"}},
+			},
+			nil, spec{
+				"200": response("Open-Meteo hourly response; X-Cache is HIT or MISS", spec{"type": "object"}),
+				"400": errorBody, "502": errorBody,
+			})},
+	}
+}
+
+// openAPISpec assembles the full document. It is built per request because the
+// server URL comes from API_BASE_URL, which is loaded after package init.
+func openAPISpec() spec {
+	schemas := spec{}
+	for name, t := range schemaComponents {
+		schemas[name] = schemaFor(t)
+	}
+	doc := spec{
+		"openapi": "3.0.3",
+		"info": spec{
+			"title":   "greensim API",
+			"version": "1.0.0",
+		},
+		"paths": openAPIPaths(),
+		"components": spec{
+			"schemas": schemas,
+			"securitySchemes": spec{
+				"apiKey":         spec{"type": "apiKey", "in": "header", "name": APIKeyHeader},
+				"internalSecret": spec{"type": "apiKey", "in": "header", "name": InternalSecretHeader},
+			},
+		},
+		"security": []spec{{"apiKey": []string{}}},
+	}
+	if apiBaseURL != "" {
+		doc["servers"] = []spec{{"url": apiBaseURL}}
+	}
+	return doc
+}
+
+func openAPIHandler(c *gin.Context) {
+	respondJSONWithETag(c, openAPISpec())
+}
+
+// docsPage loads Swagger UI from a CDN and points it at /openapi.json.
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>greensim API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+func docsHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(docsPage))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fetchOpenAPI(t *testing.T) map[string]interface{} {
+	t.Helper()
+	router := setupRouter()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	return doc
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	doc := fetchOpenAPI(t)
+	assert.Equal(t, "3.0.3", doc["openapi"])
+
+	paths := doc["paths"].(map[string]interface{})
+	for _, p := range []string{"/simulate", "/simulate/batch", "/results", "/results/{job_id}", "/jobs", "/jobs/{job_id}"} {
+		assert.Contains(t, paths, p)
+	}
+
+	schemas := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	params := schemas["SimulationParams"].(map[string]interface{})["properties"].(map[string]interface{})
+	for _, field := range []string{"setpoint", "V", "ACH", "lat", "lon", "start_date", "preset", "units", "priority"} {
+		assert.Contains(t, params, field)
+	}
+	assert.Equal(t, map[string]interface{}{"type": "number"}, params["setpoint"])
+
+	meta := schemas["JobMeta"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/SimulationParams"},
+		meta["properties"].(map[string]interface{})["params"])
+	assert.Contains(t, meta["required"], "job_id")
+	assert.NotContains(t, meta["required"], "error")
+}
+
+// Every authenticated route must be described, so the spec cannot silently fall behind.
+func TestOpenAPICoversAllRoutes(t *testing.T) {
+	doc := fetchOpenAPI(t)
+	paths := doc["paths"].(map[string]interface{})
+	public := map[string]bool{"/health": true, "/ready": true, "/health/workers": true, "/version": true, "/metrics": true, "/openapi.json": true, "/docs": true}
+	param := regexp.MustCompile(`:([a-z_]+)`)
+
+	for _, r := range setupRouter().Routes() {
+		if public[r.Path] {
+			continue
+		}
+		path := param.ReplaceAllString(r.Path, "{$1}")
+		ops, ok := paths[path].(map[string]interface{})
+		if assert.True(t, ok, "missing path %s", path) {
+			assert.Contains(t, ops, strings.ToLower(r.Method), "missing %s %s", r.Method, path)
+		}
+	}
+}
+
+func TestDocsPage(t *testing.T) {
+	router := setupRouter()
+	req, _ := http.NewRequest("GET", "/docs", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "openapi.json")
+}
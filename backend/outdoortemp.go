@@ -0,0 +1,93 @@
+package main
+
+// backend/outdoortemp.go
+//
+// Where the worker takes the outdoor temperature from, chosen with the
+// "outdoor_temp_mode" field: the fetched weather (default), a constant
+// outdoor_temp_const, or a daily sine around outdoor_temp_mean with
+// outdoor_temp_amplitude. The weather mode needs lat/lon for the lookup. The
+// synthetic modes replace only the temperature; solar radiation and humidity
+// still come from the weather at lat/lon, and the worker's default site applies
+// when those are omitted. When the weather fetch fails they run anyway, without
+// sun and at a default humidity.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Outdoor temperature sources, chosen with the "outdoor_temp_mode" field.
+const (
+	OutdoorTempWeather  = "weather"  // temperature_2m from the weather fetch
+	OutdoorTempConstant = "constant" // outdoor_temp_const throughout
+	OutdoorTempSine     = "sine"     // outdoor_temp_mean ± outdoor_temp_amplitude, coldest at 03:00 and warmest at 15:00
+)
+
+// knownOutdoorTempModes is the set accepted in the "outdoor_temp_mode" field.
+var knownOutdoorTempModes = map[string]bool{
+	OutdoorTempWeather:  true,
+	OutdoorTempConstant: true,
+	OutdoorTempSine:     true,
+}
+
+// usesWeather reports whether p takes its outdoor temperature from the weather
+// fetch: the weather mode, given or omitted.
+func usesWeather(p *SimulationParams) bool {
+	return p.OutdoorTempMode == "" || p.OutdoorTempMode == OutdoorTempWeather
+}
+
+// resolveOutdoorTemp drops the settings the chosen mode does not use, with a
+// warning, like resolveHeaterControl. An omitted mode means weather and stays
+// omitted, so the params hash of jobs that never set it is unchanged. Unknown
+// modes are left for validation.
+func resolveOutdoorTemp(p *SimulationParams) string {
+	mode := p.OutdoorTempMode
+	if mode == "" {
+		mode = OutdoorTempWeather
+	}
+	var ignored []string
+	drop := func(field string, v **float64) {
+		if *v != nil {
+			ignored, *v = append(ignored, field), nil
+		}
+	}
+	switch mode {
+	case OutdoorTempWeather:
+		drop("outdoor_temp_const", &p.OutdoorTempConst)
+		drop("outdoor_temp_mean", &p.OutdoorTempMean)
+		drop("outdoor_temp_amplitude", &p.OutdoorTempAmplitude)
+	case OutdoorTempConstant:
+		drop("outdoor_temp_mean", &p.OutdoorTempMean)
+		drop("outdoor_temp_amplitude", &p.OutdoorTempAmplitude)
+	case OutdoorTempSine:
+		drop("outdoor_temp_const", &p.OutdoorTempConst)
+	}
+	switch len(ignored) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("%s is not used with outdoor_temp_mode=%s; ignoring it", ignored[0], mode)
+	}
+	return fmt.Sprintf("%s are not used with outdoor_temp_mode=%s; ignoring them", strings.Join(ignored, ", "), mode)
+}
+
+// validateOutdoorTemp checks the mode and that the settings it needs are given.
+func validateOutdoorTemp(p *SimulationParams) []FieldError {
+	var errs []FieldError
+	required := func(field string, v *float64) {
+		if v == nil {
+			errs = append(errs, FieldError{Field: field, Value: nil, Allowed: "required when outdoor_temp_mode is " + p.OutdoorTempMode})
+		}
+	}
+	switch p.OutdoorTempMode {
+	case "", OutdoorTempWeather:
+	case OutdoorTempConstant:
+		required("outdoor_temp_const", p.OutdoorTempConst)
+	case OutdoorTempSine:
+		required("outdoor_temp_mean", p.OutdoorTempMean)
+		required("outdoor_temp_amplitude", p.OutdoorTempAmplitude)
+	default:
+		errs = append(errs, FieldError{Field: "outdoor_temp_mode", Value: p.OutdoorTempMode, Allowed: "one of weather, constant, sine"})
+	}
+	return errs
+}
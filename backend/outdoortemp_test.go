@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateOutdoorTempModes(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      SimulationParams
+		wantField   string
+		wantAllowed string
+	}{
+		{"omitted is weather", sitedParams(), "", ""},
+		{"weather", SimulationParams{OutdoorTempMode: OutdoorTempWeather, Lat: floatPtr(52.1), Lon: floatPtr(5.2)}, "", ""},
+		{"omitted without lat", SimulationParams{Lon: floatPtr(5.2)}, "lat", "required when outdoor_temp_mode is weather"},
+		{"weather without lon", SimulationParams{OutdoorTempMode: OutdoorTempWeather, Lat: floatPtr(52.1)}, "lon", "required when outdoor_temp_mode is weather"},
+		{"constant", SimulationParams{OutdoorTempMode: OutdoorTempConstant, OutdoorTempConst: floatPtr(-5)}, "", ""},
+		{"constant without value", SimulationParams{OutdoorTempMode: OutdoorTempConstant}, "outdoor_temp_const", "required when outdoor_temp_mode is constant"},
+		{"constant below absolute zero", SimulationParams{OutdoorTempMode: OutdoorTempConstant, OutdoorTempConst: floatPtr(-300)}, "outdoor_temp_const", "(-273.15, +inf)"},
+		{"sine", SimulationParams{OutdoorTempMode: OutdoorTempSine, OutdoorTempMean: floatPtr(8), OutdoorTempAmplitude: floatPtr(6)}, "", ""},
+		{"flat sine", SimulationParams{OutdoorTempMode: OutdoorTempSine, OutdoorTempMean: floatPtr(8), OutdoorTempAmplitude: floatPtr(0)}, "", ""},
+		{"sine without mean", SimulationParams{OutdoorTempMode: OutdoorTempSine, OutdoorTempAmplitude: floatPtr(6)}, "outdoor_temp_mean", "required when outdoor_temp_mode is sine"},
+		{"sine without amplitude", SimulationParams{OutdoorTempMode: OutdoorTempSine, OutdoorTempMean: floatPtr(8)}, "outdoor_temp_amplitude", "required when outdoor_temp_mode is sine"},
+		{"negative amplitude", SimulationParams{OutdoorTempMode: OutdoorTempSine, OutdoorTempMean: floatPtr(8), OutdoorTempAmplitude: floatPtr(-1)}, "outdoor_temp_amplitude", "[0, +inf)"},
+		{"unknown mode", SimulationParams{OutdoorTempMode: "forecast"}, "outdoor_temp_mode", "one of weather, constant, sine"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.params
+			applyDefaults(&p)
+			_, err := validateParams(&p, false)
+			if tt.wantField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			require.Len(t, verr.Fields, 1)
+			assert.Equal(t, tt.wantField, verr.Fields[0].Field)
+			assert.Equal(t, tt.wantAllowed, verr.Fields[0].Allowed)
+		})
+	}
+
+	// settings the mode does not use are dropped with a warning
+	p := SimulationParams{OutdoorTempConst: floatPtr(5)}
+	assert.Equal(t, []string{"outdoor_temp_const is not used with outdoor_temp_mode=weather; ignoring it"}, applyDefaults(&p))
+	assert.Nil(t, p.OutdoorTempConst)
+	assert.Empty(t, p.OutdoorTempMode, "an omitted mode stays omitted")
+	p = SimulationParams{OutdoorTempMode: OutdoorTempConstant, OutdoorTempConst: floatPtr(5), OutdoorTempMean: floatPtr(8), OutdoorTempAmplitude: floatPtr(6)}
+	assert.Equal(t, []string{"outdoor_temp_mean, outdoor_temp_amplitude are not used with outdoor_temp_mode=constant; ignoring them"}, applyDefaults(&p))
+	assert.Nil(t, p.OutdoorTempMean)
+	assert.Nil(t, p.OutdoorTempAmplitude)
+	assert.Equal(t, 5.0, *p.OutdoorTempConst)
+}
+
+func TestSubmitJobOutdoorTempMode(t *testing.T) {
+	router := setupRouter()
+	dryRun := func(body string) (*httptest.ResponseRecorder, SimulationParams) {
+		req, _ := http.NewRequest("POST", "/simulate?dry_run=true", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp struct {
+			Params SimulationParams `json:"params"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w, resp.Params
+	}
+
+	// synthetic modes need neither a location nor dates
+	w, p := dryRun(`{"outdoor_temp_mode": "constant", "outdoor_temp_const": 2}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, OutdoorTempConstant, p.OutdoorTempMode)
+	assert.Equal(t, 2.0, *p.OutdoorTempConst)
+	assert.Nil(t, p.Lat)
+	assert.Empty(t, p.StartDate)
+
+	w, p = dryRun(`{"units": "imperial", "outdoor_temp_mode": "sine", "outdoor_temp_mean": 50, "outdoor_temp_amplitude": 9}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.InDelta(t, 10.0, *p.OutdoorTempMean, 1e-9)
+	assert.InDelta(t, 5.0, *p.OutdoorTempAmplitude, 1e-9, "a difference: no 32° offset")
+
+	w, _ = dryRun(`{"outdoor_temp_mode": "sine", "outdoor_temp_mean": 8}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "outdoor_temp_amplitude", decodeError(t, w).Fields[0].Field)
+
+	// weather mode, given or not, needs a full coordinate for the lookup
+	for _, body := range []string{`{"lat": 52.1}`, `{"outdoor_temp_mode": "weather", "lat": 52.1}`} {
+		w, _ = dryRun(body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Equal(t, "lon", decodeError(t, w).Fields[0].Field, body)
+	}
+	w, p = dryRun(`{"outdoor_temp_mode": "weather", "lat": 52.1, "lon": 5.2}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 52.1, *p.Lat)
+}
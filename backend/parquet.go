@@ -0,0 +1,328 @@
+package main
+
+// backend/parquet.go
+//
+// Parquet export. GET /results/:job_id/parquet serves the data series as a
+// Parquet file, so it loads into pandas or pyarrow with its column types
+// intact. The columns are those of the CSV export (the keys of the first data
+// point). A column whose values are all numbers is DOUBLE, one whose values
+// are all timestamps (the datetime column) is INT64 TIMESTAMP_MILLIS, and
+// anything else is UTF8. Every column is OPTIONAL, so nulls in the series
+// stay nulls.
+//
+// The file is written here rather than with a Parquet library. A flat table
+// needs only a small part of the format: one row group, one uncompressed
+// PLAIN data page per column, and footer metadata in the Thrift compact
+// protocol, which thriftWriter encodes.
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MIMEParquet is the registered Parquet media type.
+const MIMEParquet = "application/vnd.apache.parquet"
+
+// parquetMagic starts and ends every Parquet file.
+const parquetMagic = "PAR1"
+
+// Values of the parquet.thrift enums used here.
+const (
+	parquetInt64     = 2 // Type
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0 // ConvertedType
+	parquetTimestampMillis = 9
+
+	parquetOptional     = 1 // FieldRepetitionType
+	parquetPlain        = 0 // Encoding
+	parquetRLE          = 3
+	parquetDataPage     = 0 // PageType
+	parquetUncompressed = 0 // CompressionCodec
+)
+
+// resultTimeLayouts are the datetime formats the worker writes: ISO 8601 with
+// or without an offset.
+var resultTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+
+func parseResultTime(s string) (time.Time, bool) {
+	for _, layout := range resultTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// parquetColumn is one column of the table, encoded.
+type parquetColumn struct {
+	name      string
+	typ       int32
+	converted int32 // ConvertedType, or -1 for none
+	defined   []bool
+	values    []byte // PLAIN encoding of the non-null values
+}
+
+// newParquetColumn picks the column's type from its values and encodes them.
+func newParquetColumn(name string, values []interface{}) *parquetColumn {
+	numbers, times := true, true
+	for _, v := range values {
+		switch x := v.(type) {
+		case nil:
+		case float64:
+			times = false
+		case string:
+			numbers = false
+			if _, ok := parseResultTime(x); !ok {
+				times = false
+			}
+		default:
+			numbers, times = false, false
+		}
+	}
+
+	col := &parquetColumn{name: name, typ: parquetByteArray, converted: parquetUTF8, defined: make([]bool, len(values))}
+	switch {
+	case numbers:
+		col.typ, col.converted = parquetDouble, -1 // an all-null column ends up here too
+	case times:
+		col.typ, col.converted = parquetInt64, parquetTimestampMillis
+	}
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		col.defined[i] = true
+		switch col.typ {
+		case parquetDouble:
+			col.values = binary.LittleEndian.AppendUint64(col.values, math.Float64bits(v.(float64)))
+		case parquetInt64:
+			t, _ := parseResultTime(v.(string))
+			col.values = binary.LittleEndian.AppendUint64(col.values, uint64(t.UnixMilli()))
+		default:
+			s := csvValue(v)
+			col.values = binary.LittleEndian.AppendUint32(col.values, uint32(len(s)))
+			col.values = append(col.values, s...)
+		}
+	}
+	return col
+}
+
+// page returns the column's data page body: the definition levels
+// (RLE-encoded, length-prefixed) followed by the values.
+func (col *parquetColumn) page() []byte {
+	var levels []byte
+	for i := 0; i < len(col.defined); {
+		j := i
+		for j < len(col.defined) && col.defined[j] == col.defined[i] {
+			j++
+		}
+		levels = binary.AppendUvarint(levels, uint64(j-i)<<1) // an RLE run
+		if col.defined[i] {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		i = j
+	}
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+	return append(page, col.values...)
+}
+
+// resultToParquet converts the data series of a stored result to a Parquet file.
+func resultToParquet(res string) ([]byte, error) {
+	columns, points, err := decodeResultRows(res)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]*parquetColumn, len(columns))
+	for i, name := range columns {
+		values := make([]interface{}, len(points))
+		for j, point := range points {
+			values[j] = point[name]
+		}
+		cols[i] = newParquetColumn(name, values)
+	}
+	return writeParquet(cols, len(points)), nil
+}
+
+// writeParquet lays out cols as a file with a single row group.
+func writeParquet(cols []*parquetColumn, rows int) []byte {
+	out := bytes.NewBufferString(parquetMagic)
+	offsets := make([]int64, len(cols))
+	sizes := make([]int64, len(cols))
+	var total int64
+	for i, col := range cols {
+		page := col.page()
+		var h thriftWriter
+		h.beginStruct() // PageHeader
+		h.i32(1, parquetDataPage)
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(len(page)))
+		h.field(5, thriftStruct)
+		h.beginStruct() // DataPageHeader
+		h.i32(1, int32(rows))
+		h.i32(2, parquetPlain)
+		h.i32(3, parquetRLE)
+		h.i32(4, parquetRLE)
+		h.endStruct()
+		h.endStruct()
+
+		offsets[i] = int64(out.Len())
+		sizes[i] = int64(h.buf.Len() + len(page))
+		total += sizes[i]
+		out.Write(h.buf.Bytes())
+		out.Write(page)
+	}
+
+	var m thriftWriter
+	m.beginStruct() // FileMetaData
+	m.i32(1, 1)
+	m.list(2, thriftStruct, len(cols)+1)
+	m.beginStruct() // the root SchemaElement
+	m.str(4, "schema")
+	m.i32(5, int32(len(cols)))
+	m.endStruct()
+	for _, col := range cols {
+		m.beginStruct()
+		m.i32(1, col.typ)
+		m.i32(3, parquetOptional)
+		m.str(4, col.name)
+		if col.converted >= 0 {
+			m.i32(6, col.converted)
+		}
+		m.endStruct()
+	}
+	m.i64(3, int64(rows))
+	m.list(4, thriftStruct, 1)
+	m.beginStruct() // RowGroup
+	m.list(1, thriftStruct, len(cols))
+	for i, col := range cols {
+		m.beginStruct() // ColumnChunk
+		m.i64(2, offsets[i])
+		m.field(3, thriftStruct)
+		m.beginStruct() // ColumnMetaData
+		m.i32(1, col.typ)
+		m.list(2, thriftI32, 2)
+		m.varint(parquetPlain)
+		m.varint(parquetRLE)
+		m.list(3, thriftBinary, 1)
+		m.binary(col.name)
+		m.i32(4, parquetUncompressed)
+		m.i64(5, int64(rows))
+		m.i64(6, sizes[i])
+		m.i64(7, sizes[i])
+		m.i64(9, offsets[i])
+		m.endStruct()
+		m.endStruct()
+	}
+	m.i64(2, total)
+	m.i64(3, int64(rows))
+	m.endStruct()
+	m.str(6, "greensim")
+	m.endStruct()
+
+	out.Write(m.buf.Bytes())
+	out.Write(binary.LittleEndian.AppendUint32(nil, uint32(m.buf.Len())))
+	out.WriteString(parquetMagic)
+	return out.Bytes()
+}
+
+// Thrift compact protocol type ids.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes the part of the Thrift compact protocol that Parquet
+// metadata uses: structs, i32, i64, strings and lists.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16 // id of the previous field in each open struct
+}
+
+func (w *thriftWriter) beginStruct() { w.last = append(w.last, 0) }
+
+func (w *thriftWriter) endStruct() {
+	w.buf.WriteByte(0) // STOP
+	w.last = w.last[:len(w.last)-1]
+}
+
+// field writes a field header, as a delta from the previous id when it fits.
+func (w *thriftWriter) field(id int16, typ byte) {
+	prev := &w.last[len(w.last)-1]
+	if d := id - *prev; d > 0 && d <= 15 {
+		w.buf.WriteByte(byte(d)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	*prev = id
+}
+
+// varint writes a zigzag varint, the encoding of every integer type.
+func (w *thriftWriter) varint(v int64) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(v<<1)^uint64(v>>63)))
+}
+
+func (w *thriftWriter) binary(s string) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.varint(int64(v))
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.varint(v)
+}
+
+func (w *thriftWriter) str(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.binary(s)
+}
+
+// list writes the header of a list of n elements; the caller writes them.
+func (w *thriftWriter) list(id int16, elem byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elem)
+	w.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+// getResultsParquetHandler serves a job's time series as a Parquet attachment.
+func getResultsParquetHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	res, ok := loadStoredResult(c, ctx, jobID)
+	if !ok {
+		return
+	}
+	file, err := resultToParquet(res)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "unexpected result structure: "+err.Error())
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+".parquet"))
+	c.Data(http.StatusOK, MIMEParquet, file)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftReader decodes the Thrift compact protocol generically: structs as
+// map[int16]interface{}, lists as []interface{}, integers as int64 and binary
+// as string.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) next() byte {
+	r.pos++
+	return r.b[r.pos-1]
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) zigzag() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 3:
+		return int64(r.next())
+	case 4, thriftI32, thriftI64:
+		return r.zigzag()
+	case 7:
+		r.pos += 8
+		return math.Float64frombits(binary.LittleEndian.Uint64(r.b[r.pos-8:]))
+	case thriftBinary:
+		n := int(r.uvarint())
+		r.pos += n
+		return string(r.b[r.pos-n : r.pos])
+	case thriftList:
+		h := r.next()
+		n, elem := int(h>>4), h&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i] = r.value(elem)
+		}
+		return items
+	case thriftStruct:
+		fields := map[int16]interface{}{}
+		var id int16
+		for {
+			h := r.next()
+			if h == 0 {
+				return fields
+			}
+			if d := int16(h >> 4); d != 0 {
+				id += d
+			} else {
+				id = int16(r.zigzag())
+			}
+			fields[id] = r.value(h & 0x0f)
+		}
+	}
+	panic("unsupported thrift type")
+}
+
+// parquetFile is a Parquet file with its footer decoded.
+type parquetFile struct {
+	data []byte
+	meta map[int16]interface{} // FileMetaData
+}
+
+func readParquet(t *testing.T, data []byte) parquetFile {
+	t.Helper()
+	require.Greater(t, len(data), 12)
+	require.Equal(t, parquetMagic, string(data[:4]))
+	require.Equal(t, parquetMagic, string(data[len(data)-4:]))
+	n := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	r := thriftReader{b: data[len(data)-8-n : len(data)-8]}
+	meta := r.value(thriftStruct).(map[int16]interface{})
+	require.Equal(t, n, r.pos, "the footer length matches its content")
+	return parquetFile{data: data, meta: meta}
+}
+
+// columns returns the names and types of the leaf columns.
+func (f parquetFile) columns() (names []string, types []int64) {
+	for _, el := range f.meta[2].([]interface{})[1:] {
+		el := el.(map[int16]interface{})
+		names = append(names, el[4].(string))
+		types = append(types, el[1].(int64))
+	}
+	return names, types
+}
+
+// column reads column i back: nil for nulls, float64 for DOUBLE, int64 for
+// INT64 and string for BYTE_ARRAY.
+func (f parquetFile) column(t *testing.T, i int) []interface{} {
+	t.Helper()
+	rowGroup := f.meta[4].([]interface{})[0].(map[int16]interface{})
+	chunk := rowGroup[1].([]interface{})[i].(map[int16]interface{})
+	colMeta := chunk[3].(map[int16]interface{})
+	r := thriftReader{b: f.data, pos: int(colMeta[9].(int64))}
+	header := r.value(thriftStruct).(map[int16]interface{})
+	page := f.data[r.pos : r.pos+int(header[3].(int64))]
+	rows := int(header[5].(map[int16]interface{})[1].(int64))
+
+	n := int(binary.LittleEndian.Uint32(page))
+	levels := thriftReader{b: page[4 : 4+n]}
+	var defined []bool
+	for levels.pos < len(levels.b) {
+		run := int(levels.uvarint())
+		require.Zero(t, run&1, "only RLE runs are written")
+		v := levels.next() == 1
+		for k := 0; k < run>>1; k++ {
+			defined = append(defined, v)
+		}
+	}
+	require.Len(t, defined, rows)
+
+	vals := page[4+n:]
+	out := make([]interface{}, rows)
+	for k := range out {
+		if !defined[k] {
+			continue
+		}
+		switch colMeta[1].(int64) {
+		case parquetDouble:
+			out[k] = math.Float64frombits(binary.LittleEndian.Uint64(vals))
+			vals = vals[8:]
+		case parquetInt64:
+			out[k] = int64(binary.LittleEndian.Uint64(vals))
+			vals = vals[8:]
+		default:
+			l := int(binary.LittleEndian.Uint32(vals))
+			out[k] = string(vals[4 : 4+l])
+			vals = vals[4+l:]
+		}
+	}
+	assert.Empty(t, vals, "every value is read")
+	return out
+}
+
+func TestGetResultsParquet(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, jobResultKey("pq-job"), sampleResult, DefaultResultTTL)
+
+	req, _ := http.NewRequest("GET", "/results/pq-job/parquet", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, MIMEParquet, w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="pq-job.parquet"`, w.Header().Get("Content-Disposition"))
+
+	f := readParquet(t, w.Body.Bytes())
+	assert.Equal(t, int64(3), f.meta[3], "num_rows")
+	names, types := f.columns()
+	assert.Equal(t, []string{"datetime", "Tin", "Tout", "Q_heater"}, names)
+	assert.Equal(t, []int64{parquetInt64, parquetDouble, parquetDouble, parquetDouble}, types)
+
+	first := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+	assert.Equal(t, []interface{}{first, first + 3600000, first + 7200000}, f.column(t, 0))
+	assert.Equal(t, []interface{}{12.5, 12.1, 11.9}, f.column(t, 1))
+	assert.Equal(t, []interface{}{3.0, 2.5, nil}, f.column(t, 2), "nulls stay nulls")
+	assert.Equal(t, []interface{}{0.0, 1500.25, 3000.0}, f.column(t, 3))
+}
+
+func TestGetResultsParquetErrors(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "pq-pending", StatusRunning)
+	rdb.Set(ctx, jobResultKey("pq-bad"), `{"data": [{"Tin": 1}, 2]}`, DefaultResultTTL)
+
+	get := func(jobID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/results/"+jobID+"/parquet", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	w := get("pq-pending")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), StatusRunning)
+	assert.Equal(t, http.StatusNotFound, get("pq-unknown").Code)
+	w = get("pq-bad")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "data point 1 is not an object")
+}
+
+func TestResultToParquetColumnTypes(t *testing.T) {
+	file, err := resultToParquet(`{"data": [
+		{"datetime": "2025-11-01T00:00:00+01:00", "label": "a", "flag": true, "empty": null},
+		{"datetime": "2025-11-01T01:00:00+01:00", "label": null, "flag": false, "empty": null}
+	]}`)
+	require.NoError(t, err)
+	f := readParquet(t, file)
+	names, types := f.columns()
+	assert.Equal(t, []string{"datetime", "label", "flag", "empty"}, names)
+	assert.Equal(t, []int64{parquetInt64, parquetByteArray, parquetByteArray, parquetDouble}, types)
+	assert.Equal(t, int64(parquetTimestampMillis), f.meta[2].([]interface{})[1].(map[int16]interface{})[6])
+	assert.Equal(t, int64(parquetUTF8), f.meta[2].([]interface{})[2].(map[int16]interface{})[6])
+
+	first := time.Date(2025, 10, 31, 23, 0, 0, 0, time.UTC).UnixMilli()
+	assert.Equal(t, []interface{}{first, first + 3600000}, f.column(t, 0), "offsets are folded into UTC")
+	assert.Equal(t, []interface{}{"a", nil}, f.column(t, 1))
+	assert.Equal(t, []interface{}{"true", "false"}, f.column(t, 2))
+	assert.Equal(t, []interface{}{nil, nil}, f.column(t, 3))
+
+	// an empty series is a valid file with no rows
+	file, err = resultToParquet(`{"data": []}`)
+	require.NoError(t, err)
+	f = readParquet(t, file)
+	assert.Equal(t, int64(0), f.meta[3])
+
+	_, err = resultToParquet(`{"summary": {}}`)
+	assert.ErrorContains(t, err, "no data array")
+}
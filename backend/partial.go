@@ -0,0 +1,67 @@
+package main
+
+// backend/partial.go
+//
+// Partial results for long simulations. While a job runs, the worker appends
+// chunks of result rows to the list job_result_partial:<id>; each element is a
+// JSON array of row objects shaped like the final result's data. GET
+// /results/:job_id/partial?from=N returns the chunks from index N on, so a
+// client can poll with from=next and render the series progressively.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RedisPartialResultsPrefix keys job_result_partial:<jobID> -> list of JSON row chunks.
+const RedisPartialResultsPrefix = "job_result_partial:"
+
+func getPartialResultsHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	from := 0
+	if v := c.Query("from"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			respondInvalidParam(c, "from", "from must be a non-negative integer")
+			return
+		}
+		from = n
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	// read the status first: the worker pushes its last chunk before marking the
+	// job finished, so a terminal status here means the list below is complete
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	raw, err := rdb.LRange(ctx, partialResultKey(jobID), int64(from), -1).Result()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+
+	chunks := make([]json.RawMessage, len(raw))
+	for i, s := range raw {
+		chunks[i] = json.RawMessage(s)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":   jobID,
+		"status":   meta.Status,
+		"from":     from,
+		"next":     from + len(chunks),
+		"chunks":   chunks,
+		"complete": isTerminalStatus(meta.Status),
+	})
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type partialResponse struct {
+	Status   string              `json:"status"`
+	From     int                 `json:"from"`
+	Next     int                 `json:"next"`
+	Chunks   [][]json.RawMessage `json:"chunks"`
+	Complete bool                `json:"complete"`
+}
+
+func getPartial(t *testing.T, router http.Handler, jobID, query string) (int, partialResponse) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/results/"+jobID+"/partial"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp partialResponse
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return w.Code, resp
+}
+
+func TestPartialResults(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	seedJobMeta(t, ctx, "long-run", StatusRunning)
+	key := RedisPartialResultsPrefix + "long-run"
+	rdb.RPush(ctx, key,
+		`[{"datetime":"2025-11-01T00:00:00","Tin":14.1},{"datetime":"2025-11-01T01:00:00","Tin":13.8}]`,
+		`[{"datetime":"2025-11-01T02:00:00","Tin":13.5}]`)
+
+	code, resp := getPartial(t, router, "long-run", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusRunning, resp.Status)
+	assert.Equal(t, 0, resp.From)
+	assert.Equal(t, 2, resp.Next)
+	require.Len(t, resp.Chunks, 2)
+	assert.Len(t, resp.Chunks[0], 2)
+	assert.False(t, resp.Complete)
+
+	// the worker appends another chunk and finishes
+	rdb.RPush(ctx, key, `[{"datetime":"2025-11-01T03:00:00","Tin":13.3}]`)
+	seedJobMeta(t, ctx, "long-run", StatusDone)
+
+	code, resp = getPartial(t, router, "long-run", "?from=2")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, resp.From)
+	assert.Equal(t, 3, resp.Next)
+	require.Len(t, resp.Chunks, 1)
+	assert.JSONEq(t, `{"datetime":"2025-11-01T03:00:00","Tin":13.3}`, string(resp.Chunks[0][0]))
+	assert.True(t, resp.Complete)
+
+	// polling past the end returns nothing new
+	_, resp = getPartial(t, router, "long-run", "?from=3")
+	assert.Empty(t, resp.Chunks)
+	assert.Equal(t, 3, resp.Next)
+}
+
+func TestPartialResultsQueuedAndErrors(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	seedJobMeta(t, ctx, "waiting", StatusQueued)
+	code, resp := getPartial(t, router, "waiting", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, resp.Chunks)
+	assert.False(t, resp.Complete)
+
+	code, _ = getPartial(t, router, "waiting", "?from=-1")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = getPartial(t, router, "nonexistent", "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
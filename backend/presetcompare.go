@@ -0,0 +1,173 @@
+package main
+
+// backend/presetcompare.go
+//
+// GET /jobs/:job_id/compare-preset/:preset_name: "how does my config differ
+// from the standard". The job is compared, as by /compare, against a run of
+// the named preset over the job's date range, at its site and with its outdoor
+// temperature source. Preset runs are shared: the first request for a preset
+// under those conditions enqueues one and records it in
+// preset_run:<preset>:<hash>, the physicsHash of the run; later requests for
+// any job under the same conditions reuse it. While that run is pending the
+// endpoint answers 202 with its job id; a run that failed or expired is
+// replaced on the next request.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisPresetRunPrefix keys preset_run:<preset>:<hash> -> id of the preset's
+// run with that physicsHash.
+const RedisPresetRunPrefix = "preset_run:"
+
+// presetRunParams returns the resolved params of preset under the conditions of
+// job: its dates, site and outdoor temperature source.
+func presetRunParams(preset string, job SimulationParams) (SimulationParams, error) {
+	params := SimulationParams{
+		Preset:               preset,
+		StartDate:            job.StartDate,
+		EndDate:              job.EndDate,
+		Lat:                  job.Lat,
+		Lon:                  job.Lon,
+		OutdoorTempMode:      job.OutdoorTempMode,
+		OutdoorTempConst:     job.OutdoorTempConst,
+		OutdoorTempMean:      job.OutdoorTempMean,
+		OutdoorTempAmplitude: job.OutdoorTempAmplitude,
+	}
+	if err := applyPreset(&params); err != nil {
+		return SimulationParams{}, err
+	}
+	applyDefaults(&params)
+	if _, err := validateParams(&params, true); err != nil {
+		return SimulationParams{}, err
+	}
+	return params, nil
+}
+
+// presetRun returns the cached run of preset under the conditions of job,
+// enqueuing one when there is none or the cached one failed or expired.
+func presetRun(ctx context.Context, preset string, job SimulationParams) (JobMeta, error) {
+	params, err := presetRunParams(preset, job)
+	if err != nil {
+		return JobMeta{}, err
+	}
+	key := presetRunKey(preset, physicsHash(params))
+	for attempt := 0; attempt < 2; attempt++ {
+		runID, err := rdb.Get(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			return JobMeta{}, err
+		}
+		if err == nil {
+			run, err := redisMetaStore{}.GetMeta(ctx, runID)
+			if err != nil && !errors.Is(err, ErrMetaNotFound) {
+				return JobMeta{}, err
+			}
+			if err == nil && run.Status != StatusError && run.Status != StatusCancelled {
+				return run, nil
+			}
+			// the same compare-and-delete as the greenhouse lock
+			if err := releaseActiveScript.Run(ctx, rdb, []string{key}, runID).Err(); err != nil {
+				return JobMeta{}, err
+			}
+		}
+
+		run := newJobMeta(params, time.Now().UTC())
+		ok, err := rdb.SetNX(ctx, key, run.JobID, metaTTL(run)).Result()
+		if err != nil {
+			return JobMeta{}, err
+		}
+		if !ok {
+			continue // another request enqueued it first
+		}
+		if err := enqueueJobs(ctx, []JobMeta{run}); err != nil {
+			_ = releaseActiveScript.Run(ctx, rdb, []string{key}, run.JobID).Err()
+			return JobMeta{}, err
+		}
+		return run, nil
+	}
+	return JobMeta{}, errors.New("preset run changed concurrently, retry")
+}
+
+func comparePresetHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	preset := c.Param("preset_name")
+	if _, ok := presets[preset]; !ok {
+		respondError(c, http.StatusNotFound, "preset not found", gin.H{"presets": presetNames})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	job, err := metaStore.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	if job.Status != StatusDone {
+		respondError(c, http.StatusConflict, "job is not done", gin.H{"job_id": jobID, "status": job.Status})
+		return
+	}
+
+	run, err := presetRun(ctx, preset, job.Params)
+	if err != nil {
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			respondError(c, http.StatusConflict, "preset cannot run under this job's conditions: "+verr.Error())
+			return
+		}
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	if run.Status != StatusDone {
+		setPollRetryAfter(c, run.CreatedAt)
+		c.JSON(http.StatusAccepted, gin.H{
+			"job_id":          jobID,
+			"preset":          preset,
+			"baseline_job_id": run.JobID,
+			"status":          run.Status,
+			"links":           jobLinks(run.JobID),
+		})
+		return
+	}
+
+	vals, err := rdb.MGet(ctx, jobResultKey(run.JobID), jobResultKey(jobID)).Result()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	ids := []string{run.JobID, jobID}
+	runs := make(map[string]compareRun, len(ids))
+	for i, v := range vals {
+		stored, ok := v.(string)
+		if !ok {
+			respondError(c, http.StatusConflict, "result has expired", gin.H{"job_id": ids[i]})
+			return
+		}
+		r, err := decodeCompareRun(stored)
+		if err != nil {
+			respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": ids[i]})
+			return
+		}
+		runs[ids[i]] = r
+	}
+
+	steps, summary := compareSeries(ids, runs)
+	c.JSON(http.StatusOK, gin.H{
+		"job_id":          jobID,
+		"preset":          preset,
+		"baseline_job_id": run.JobID,
+		"timesteps":       len(steps),
+		"summary":         summary[jobID],
+		"series":          steps,
+	})
+}
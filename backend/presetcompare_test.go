@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// comparePresetResponse mirrors the /jobs/:job_id/compare-preset body.
+type comparePresetResponse struct {
+	BaselineJobID string         `json:"baseline_job_id"`
+	Status        string         `json:"status"`
+	Timesteps     int            `json:"timesteps"`
+	Summary       compareSummary `json:"summary"`
+	Series        []compareStep  `json:"series"`
+}
+
+func getComparePreset(t *testing.T, router http.Handler, jobID, preset string) (int, comparePresetResponse) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/jobs/"+jobID+"/compare-preset/"+preset, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response comparePresetResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+// seedDatedJob stores a job over 2025-11-01 with the given status and result.
+func seedDatedJob(t *testing.T, ctx context.Context, jobID, status, result string) {
+	t.Helper()
+	seedJobMeta(t, ctx, jobID, status, func(m *JobMeta) {
+		m.Params = sitedParams()
+		m.Params.StartDate, m.Params.EndDate = "2025-11-01", "2025-11-01"
+	})
+	if result != "" {
+		require.NoError(t, rdb.Set(ctx, jobResultKey(jobID), result, DefaultResultTTL).Err())
+	}
+}
+
+// cachePresetRun records runID as the run of preset under the conditions of jobID.
+func cachePresetRun(t *testing.T, ctx context.Context, jobID, preset, runID string) {
+	t.Helper()
+	job, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	require.NoError(t, err)
+	params, err := presetRunParams(preset, job.Params)
+	require.NoError(t, err)
+	require.NoError(t, rdb.Set(ctx, presetRunKey(preset, physicsHash(params)), runID, DefaultResultTTL).Err())
+}
+
+func TestComparePresetCached(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedDatedJob(t, ctx, "mine", StatusDone, modifiedResult)
+	seedDatedJob(t, ctx, "preset-run", StatusDone, baselineResult)
+	cachePresetRun(t, ctx, "mine", "polytunnel", "preset-run")
+
+	code, response := getComparePreset(t, router, "mine", "polytunnel")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "preset-run", response.BaselineJobID)
+	assert.Equal(t, 2, response.Timesteps)
+	require.Len(t, response.Series, 2)
+	assert.InDelta(t, 2.5, *response.Series[0].TinDelta["mine"], 1e-9)
+	assert.InDelta(t, -500, *response.Series[0].QHeaterDelta["mine"], 1e-9)
+	assert.InDelta(t, 1.5, *response.Summary.TinDeltaMean, 1e-9)
+	assert.InDelta(t, -1500*3600, response.Summary.HeaterTotalDeltaJ, 1e-9)
+	assert.Zero(t, rdb.LLen(ctx, queueFor(PriorityNormal)).Val(), "the cached run is reused")
+}
+
+func TestComparePresetMalformedResult(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedDatedJob(t, ctx, "mine", StatusDone, "not json")
+	seedDatedJob(t, ctx, "preset-run", StatusDone, baselineResult)
+	cachePresetRun(t, ctx, "mine", "polytunnel", "preset-run")
+
+	req, _ := http.NewRequest("GET", "/jobs/mine/compare-preset/polytunnel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response["error"].(map[string]interface{})["message"], "malformed result from worker")
+	assert.Equal(t, "mine", response["job_id"])
+}
+
+func TestComparePresetEnqueuesRun(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedDatedJob(t, ctx, "mine", StatusDone, modifiedResult)
+	seedDatedJob(t, ctx, "other", StatusDone, modifiedResult)
+
+	code, first := getComparePreset(t, router, "mine", "polytunnel")
+	require.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, StatusQueued, first.Status)
+	run, err := redisMetaStore{}.GetMeta(ctx, first.BaselineJobID)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-11-01", run.Params.StartDate)
+	assert.Equal(t, "2025-11-01", run.Params.EndDate)
+	assert.Equal(t, *presets["polytunnel"].Params.A_glass, *run.Params.A_glass)
+
+	// another job over the same dates shares the run
+	code, second := getComparePreset(t, router, "other", "polytunnel")
+	require.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, first.BaselineJobID, second.BaselineJobID)
+	assert.Equal(t, int64(1), rdb.LLen(ctx, queueFor(PriorityNormal)).Val())
+
+	// a failed run is replaced
+	seedDatedJob(t, ctx, first.BaselineJobID, StatusError, "")
+	code, third := getComparePreset(t, router, "mine", "polytunnel")
+	require.Equal(t, http.StatusAccepted, code)
+	assert.NotEqual(t, first.BaselineJobID, third.BaselineJobID)
+	assert.Equal(t, int64(2), rdb.LLen(ctx, queueFor(PriorityNormal)).Val())
+}
+
+func TestComparePresetRejected(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedDatedJob(t, ctx, "running", StatusRunning, "")
+
+	code, _ := getComparePreset(t, router, "running", "no_such_preset")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = getComparePreset(t, router, "missing", "polytunnel")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = getComparePreset(t, router, "running", "polytunnel")
+	assert.Equal(t, http.StatusConflict, code)
+}
@@ -0,0 +1,105 @@
+package main
+
+// backend/presets.go
+//
+// Named starting configurations (presets.json, embedded at build time). A
+// submission may name one in "preset"; its values fill any field the client did
+// not send, and applyDefaults then fills whatever is still missing.
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed presets.json
+var presetsJSON []byte
+
+// Preset is a named, documented set of SimulationParams.
+type Preset struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Params      SimulationParams `json:"params"`
+}
+
+// presets is keyed by name; presetNames is sorted for stable listings.
+var presets, presetNames = mustLoadPresets(presetsJSON)
+
+func mustLoadPresets(raw []byte) (map[string]Preset, []string) {
+	var byName map[string]Preset
+	if err := json.Unmarshal(raw, &byName); err != nil {
+		panic("presets.json: " + err.Error())
+	}
+	names := make([]string, 0, len(byName))
+	for name, p := range byName {
+		p.Name = name
+		byName[name] = p
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return byName, names
+}
+
+// applyPreset merges the named preset under p: fields set in p win, fields only
+// set in the preset are copied. It is a no-op when p.Preset is empty.
+func applyPreset(p *SimulationParams) error {
+	if p.Preset == "" {
+		return nil
+	}
+	preset, ok := presets[p.Preset]
+	if !ok {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   "preset",
+			Value:   p.Preset,
+			Allowed: "one of " + strings.Join(presetNames, ", "),
+		}}}
+	}
+
+	merged, err := overlayParams(preset.Params, *p)
+	if err != nil {
+		return err
+	}
+	*p = merged
+	return nil
+}
+
+// overlayParams returns base with every field set in top copied over it.
+// omitempty keeps unset fields of top out of the merge.
+func overlayParams(base, top SimulationParams) (SimulationParams, error) {
+	merged := map[string]json.RawMessage{}
+	for _, src := range []SimulationParams{base, top} {
+		b, err := json.Marshal(src)
+		if err != nil {
+			return SimulationParams{}, err
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b, &fields); err != nil {
+			return SimulationParams{}, err
+		}
+		for k, v := range fields {
+			merged[k] = v
+		}
+	}
+	b, err := json.Marshal(merged)
+	if err != nil {
+		return SimulationParams{}, err
+	}
+	var out SimulationParams
+	if err := json.Unmarshal(b, &out); err != nil {
+		return SimulationParams{}, err
+	}
+	return out, nil
+}
+
+// listPresetsHandler serves GET /presets.
+func listPresetsHandler(c *gin.Context) {
+	list := make([]Preset, 0, len(presetNames))
+	for _, name := range presetNames {
+		list = append(list, presets[name])
+	}
+	c.JSON(http.StatusOK, gin.H{"presets": list})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPresetsAreValid(t *testing.T) {
+	require.NotEmpty(t, presetNames)
+	for _, name := range presetNames {
+		t.Run(name, func(t *testing.T) {
+			p := presets[name]
+			assert.Equal(t, name, p.Name)
+			assert.NotEmpty(t, p.Description)
+			params := p.Params
+			params.Lat, params.Lon = floatPtr(52.1), floatPtr(5.2)
+			applyDefaults(&params)
+			warnings, err := validateParams(&params, false)
+			assert.NoError(t, err)
+			assert.Empty(t, warnings)
+		})
+	}
+}
+
+func TestApplyPreset(t *testing.T) {
+	base := presets["polytunnel"].Params
+
+	t.Run("preset only", func(t *testing.T) {
+		params := SimulationParams{Preset: "polytunnel"}
+		require.NoError(t, applyPreset(&params))
+		assert.Equal(t, *base.A_glass, *params.A_glass)
+		assert.Equal(t, *base.U_night, *params.U_night)
+		assert.Equal(t, "polytunnel", params.Preset)
+	})
+
+	t.Run("explicit fields win", func(t *testing.T) {
+		params := SimulationParams{Preset: "polytunnel", A_glass: floatPtr(99), Setpoint: floatPtr(0), StartDate: "2025-11-01"}
+		require.NoError(t, applyPreset(&params))
+		assert.Equal(t, 99.0, *params.A_glass)
+		assert.Equal(t, 0.0, *params.Setpoint) // an explicit zero still overrides
+		assert.Equal(t, "2025-11-01", params.StartDate)
+		assert.Equal(t, *base.Volume, *params.Volume)
+	})
+
+	t.Run("defaults fill the rest", func(t *testing.T) {
+		params := SimulationParams{Preset: "polytunnel"}
+		require.NoError(t, applyPreset(&params))
+		applyDefaults(&params)
+		require.Nil(t, base.T_init)
+		assert.Equal(t, 15.0, *params.T_init)
+		// C derives from the preset's thermal mass rather than the default
+		assert.Equal(t, *base.ThermalMassKg*4186.0, *params.C)
+	})
+
+	t.Run("unknown preset", func(t *testing.T) {
+		params := SimulationParams{Preset: "igloo"}
+		err := applyPreset(&params)
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, "preset", verr.Fields[0].Field)
+	})
+}
+
+func TestListPresets(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/presets", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Presets []Preset `json:"presets"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	names := make([]string, len(response.Presets))
+	for i, p := range response.Presets {
+		names[i] = p.Name
+	}
+	assert.Equal(t, []string{"commercial_glass", "polytunnel", "small_hobby"}, names)
+}
+
+func TestSubmitWithPreset(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{"preset": "commercial_glass", "setpoint": 18}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, response["job_id"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, 18.0, *meta.Params.Setpoint)
+	assert.Equal(t, *presets["commercial_glass"].Params.A_glass, *meta.Params.A_glass)
+
+	req, _ = http.NewRequest("POST", "/simulate", bytes.NewBufferString(`{"preset": "igloo"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "preset")
+}
@@ -0,0 +1,49 @@
+package main
+
+// backend/progress.go
+//
+// Job progress for progress bars. While a job runs, the worker writes the
+// share of rows simulated so far, as a percentage (0-100), to
+// job_progress:<id> after every partial chunk. GET /jobs/:job_id and the
+// pending /results/:job_id response report it as "progress": 0 while queued,
+// the latest value while running and 100 once done. Failed and cancelled jobs
+// report how far they got, or nothing if they never started.
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisJobProgressPrefix keys job_progress:<jobID> -> percentage done, as a decimal string.
+const RedisJobProgressPrefix = "job_progress:"
+
+// jobProgress returns the progress to report for meta, or nil when there is none.
+func jobProgress(ctx context.Context, meta JobMeta) (*float64, error) {
+	var p float64
+	switch meta.Status {
+	case StatusQueued:
+		return &p, nil
+	case StatusDone:
+		p = 100
+		return &p, nil
+	}
+	raw, err := rdb.Get(ctx, jobProgressKey(meta.JobID)).Result()
+	if errors.Is(err, redis.Nil) {
+		if meta.Status == StatusRunning {
+			return &p, nil
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	p, err = strconv.ParseFloat(raw, 64)
+	if err != nil {
+		// a garbled value is no reason to fail the status request
+		return nil, nil
+	}
+	p = min(max(p, 0), 100)
+	return &p, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getProgress returns the "progress" field of GET path, or nil if absent.
+func getProgress(t *testing.T, router *gin.Engine, path string) interface{} {
+	t.Helper()
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body["progress"]
+}
+
+func TestJobProgress(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	seedJobMeta(t, ctx, "progress-job", StatusQueued)
+	assert.Equal(t, 0.0, getProgress(t, router, "/jobs/progress-job"))
+
+	seedJobMeta(t, ctx, "progress-job", StatusRunning)
+	assert.Equal(t, 0.0, getProgress(t, router, "/jobs/progress-job"), "running before the first chunk")
+
+	rdb.Set(ctx, RedisJobProgressPrefix+"progress-job", "42.5", DefaultResultTTL)
+	assert.Equal(t, 42.5, getProgress(t, router, "/jobs/progress-job"))
+	assert.Equal(t, 42.5, getProgress(t, router, "/results/progress-job"))
+
+	seedJobMeta(t, ctx, "progress-job", StatusDone)
+	rdb.Set(ctx, RedisResultsPrefix+"progress-job", sampleResult, DefaultResultTTL)
+	assert.Equal(t, 100.0, getProgress(t, router, "/jobs/progress-job"))
+	assert.Equal(t, 100.0, getProgress(t, router, "/results/progress-job"))
+
+	// failed jobs report how far they got, or nothing
+	seedJobMeta(t, ctx, "failed-job", StatusError)
+	assert.Nil(t, getProgress(t, router, "/jobs/failed-job"))
+	rdb.Set(ctx, RedisJobProgressPrefix+"failed-job", "63", DefaultResultTTL)
+	assert.Equal(t, 63.0, getProgress(t, router, "/jobs/failed-job"))
+}
+
+func TestWorkerStatusProgress(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withInternalSecret(t, "s3cret")
+	seedJobMeta(t, ctx, "reported-job", StatusRunning)
+
+	w := reportStatus(t, router, "s3cret", "reported-job", gin.H{"status": StatusRunning, "progress": 25})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 25.0, getProgress(t, router, "/jobs/reported-job"))
+
+	w = reportStatus(t, router, "s3cret", "reported-job", gin.H{"status": StatusRunning, "progress": 120})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = reportStatus(t, router, "s3cret", "reported-job", gin.H{"status": StatusError, "error": "boom", "progress": 30})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,156 @@
+package main
+
+// backend/purge.go
+//
+// DELETE /jobs?created_before=<time> (admin only) removes finished jobs older
+// than the cutoff: meta, result, partial results, logs, progress, tag index
+// entries and their place in the recent list. It walks job_meta:* with SCAN
+// and deletes each batch in one pipeline. Queued and running jobs are left alone, since
+// deleting them under a worker would only orphan its output.
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// purgeScanBatch is the SCAN COUNT hint and the number of jobs deleted per pipeline.
+const purgeScanBatch = 500
+
+// purgeTimeout bounds a whole purge; it spans many round trips, unlike RedisOpTimeout.
+var purgeTimeout = 2 * time.Minute
+
+// purgeResult summarizes one purge.
+type purgeResult struct {
+	Purged        int `json:"purged"`
+	SkippedActive int `json:"skipped_active"` // older than the cutoff but still queued or running
+	Scanned       int `json:"scanned"`
+}
+
+// scanJobMetas walks job_meta:* with SCAN, calling fn with each batch of
+// metas (up to purgeScanBatch), and returns the number of keys scanned.
+func scanJobMetas(ctx context.Context, fn func([]JobMeta) error) (int, error) {
+	scanned := 0
+	var cursor uint64
+	for {
+		keys, next, err := rdb.Scan(ctx, cursor, jobMetaKey("*"), purgeScanBatch).Result()
+		if err != nil {
+			return scanned, err
+		}
+		scanned += len(keys)
+		ids := make([]string, len(keys))
+		for i, k := range keys {
+			ids[i] = jobIDFromMetaKey(k)
+		}
+		metas, err := loadMetas(ctx, ids)
+		if err != nil {
+			return scanned, err
+		}
+		if err := fn(metas); err != nil {
+			return scanned, err
+		}
+		if next == 0 {
+			return scanned, nil
+		}
+		cursor = next
+	}
+}
+
+// purgeJobsBefore deletes every finished job created before cutoff.
+func purgeJobsBefore(ctx context.Context, cutoff time.Time) (purgeResult, error) {
+	var res purgeResult
+	scanned, err := scanJobMetas(ctx, func(metas []JobMeta) error {
+		var old []JobMeta
+		for _, m := range metas {
+			if !m.CreatedAt.Before(cutoff) {
+				continue
+			}
+			if !isTerminalStatus(m.Status) {
+				res.SkippedActive++
+				continue
+			}
+			old = append(old, m)
+		}
+		n, err := deleteJobsPipelined(ctx, old)
+		res.Purged += n
+		return err
+	})
+	res.Scanned = scanned
+	return res, err
+}
+
+// deleteJobsPipelined removes metas' jobs and everything keyed by them in one
+// pipeline (after one more to read their artifact names), returning how many
+// metas were still there to delete. A durable metadata backend drops its copies
+// first, as in deleteJobHandler.
+func deleteJobsPipelined(ctx context.Context, metas []JobMeta) (int, error) {
+	if len(metas) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, len(metas))
+	for i, m := range metas {
+		ids[i] = m.JobID
+	}
+	if mirrorsMeta() {
+		if err := metaStore.DeleteMeta(ctx, ids...); err != nil {
+			return 0, fmt.Errorf("metadata backend: %w", err)
+		}
+	}
+	artifacts, err := artifactKeys(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	dels := make([]*redis.IntCmd, len(metas))
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, artifacts...)
+		for i, m := range metas {
+			dels[i] = pipe.Del(ctx, jobMetaKey(m.JobID))
+			pipe.Del(ctx, jobResultKey(m.JobID), partialResultKey(m.JobID), jobLogsKey(m.JobID), jobProgressKey(m.JobID), jobNotesKey(m.JobID), jobExpiryKey(m.JobID))
+			pipe.LRem(ctx, redisKey(RedisRecentJobsList), 0, m.JobID)
+			for _, tag := range m.Tags {
+				pipe.SRem(ctx, jobsByTagKey(tag), m.JobID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, d := range dels {
+		n += int(d.Val())
+	}
+	return n, nil
+}
+
+func purgeJobsHandler(c *gin.Context) {
+	v := c.Query("created_before")
+	if v == "" {
+		respondInvalidParam(c, "created_before", "created_before is required")
+		return
+	}
+	cutoff, err := parseSearchTime(v)
+	if err != nil {
+		respondInvalidParam(c, "created_before", "created_before must be RFC 3339 or YYYY-MM-DD")
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), purgeTimeout)
+	defer cancel()
+
+	res, err := purgeJobsBefore(ctx, cutoff)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "purge failed: "+err.Error(), gin.H{"purged": res.Purged})
+		return
+	}
+	loggerFrom(c).Info("purged jobs", "created_before", cutoff, "purged", res.Purged, "skipped_active", res.SkippedActive)
+	c.JSON(http.StatusOK, gin.H{
+		"created_before": cutoff,
+		"purged":         res.Purged,
+		"skipped_active": res.SkippedActive,
+		"scanned":        res.Scanned,
+	})
+}
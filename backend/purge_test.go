@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedAgedJob stores a job created age ago with a result, logs, a tag and a recent-list entry.
+func seedAgedJob(t *testing.T, ctx context.Context, jobID, status string, age time.Duration) {
+	t.Helper()
+	seedJobMeta(t, ctx, jobID, status, seededAgo(age), seededTags("trial"))
+	require.NoError(t, rdb.Set(ctx, jobResultKey(jobID), sampleResult, DefaultResultTTL).Err())
+	require.NoError(t, rdb.RPush(ctx, jobLogsKey(jobID), "INFO started").Err())
+	require.NoError(t, rdb.SAdd(ctx, jobsByTagKey("trial"), jobID).Err())
+	require.NoError(t, rdb.LPush(ctx, redisKey(RedisRecentJobsList), jobID).Err())
+}
+
+func purgeRequest(router http.Handler, key, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("DELETE", "/jobs"+query, nil)
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPurgeJobs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withAuth(t, "user-key")
+	adminKeys = map[string]bool{"admin-key": true}
+	defer func() { adminKeys = map[string]bool{} }()
+
+	seedAgedJob(t, ctx, "old-done", StatusDone, 60*24*time.Hour)
+	seedAgedJob(t, ctx, "old-error", StatusError, 45*24*time.Hour)
+	seedAgedJob(t, ctx, "old-running", StatusRunning, 40*24*time.Hour)
+	seedAgedJob(t, ctx, "new-done", StatusDone, time.Hour)
+	cutoff := time.Now().UTC().Add(-30 * 24 * time.Hour).Format(time.RFC3339)
+
+	w := purgeRequest(router, "user-key", "?created_before="+cutoff)
+	assert.Equal(t, http.StatusForbidden, w.Code, "ordinary keys cannot purge")
+
+	w = purgeRequest(router, "admin-key", "?created_before="+cutoff)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response purgeResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, purgeResult{Purged: 2, SkippedActive: 1, Scanned: 4}, response)
+
+	for _, id := range []string{"old-done", "old-error"} {
+		for _, key := range []string{jobMetaKey(id), jobResultKey(id)} {
+			assert.Equal(t, redis.Nil, rdb.Get(ctx, key).Err(), key)
+		}
+		assert.Equal(t, int64(0), rdb.Exists(ctx, jobLogsKey(id)).Val())
+	}
+	assert.ElementsMatch(t, []string{"old-running", "new-done"}, rdb.LRange(ctx, redisKey(RedisRecentJobsList), 0, -1).Val())
+	assert.ElementsMatch(t, []string{"old-running", "new-done"}, rdb.SMembers(ctx, jobsByTagKey("trial")).Val())
+	for _, id := range []string{"old-running", "new-done"} {
+		assert.NoError(t, rdb.Get(ctx, jobMetaKey(id)).Err(), id)
+		assert.NoError(t, rdb.Get(ctx, jobResultKey(id)).Err(), id)
+	}
+
+	// nothing left to purge the second time
+	w = purgeRequest(router, "admin-key", "?created_before="+cutoff)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 0, response.Purged)
+}
+
+func TestPurgeJobsRequiresCutoff(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+
+	for _, query := range []string{"", "?created_before=last-week"} {
+		w := purgeRequest(router, "", query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
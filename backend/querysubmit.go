@@ -0,0 +1,76 @@
+package main
+
+// backend/querysubmit.go
+//
+// GET /simulate: submit a job from the query string, so a parameterized run
+// can be bookmarked or shared as a link (/simulate?preset=tomato&setpoint=20).
+// Parameters use the SimulationParams json names and are converted like CSV
+// upload cells: tags may repeat or be separated by ";". The submit options of
+// POST /simulate (dry_run, reuse, wait, ...) work the same way; any other
+// name is rejected so a typo does not silently fall back to a default (GET is
+// always as strict as POST with strict=true). Note
+// that every request enqueues a new job unless reuse=true or dry_run=true.
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// submitOptions are the query parameters of POST /simulate that are not params.
+var submitOptions = map[string]bool{
+	"allow_extreme": true,
+	"dry_run":       true,
+	"reuse":         true,
+	"dedup":         true,
+	"strict":        true,
+	"unique_active": true,
+	"wait":          true,
+	"timeout":       true,
+}
+
+// parseQueryParams builds a parameter set from query values.
+func parseQueryParams(query map[string][]string) (SimulationParams, error) {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names) // report problems in a stable order
+
+	var cols, record []string
+	var verr ValidationError
+	for _, name := range names {
+		values := query[name]
+		if submitOptions[name] {
+			continue
+		}
+		t, ok := uploadColumns[name]
+		if !ok {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Value: strings.Join(values, ","), Allowed: "a SimulationParams field or submit option"})
+			continue
+		}
+		if len(values) > 1 && t.Kind() != reflect.Slice {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Value: values, Allowed: "a single value"})
+			continue
+		}
+		cols = append(cols, name)
+		record = append(record, strings.Join(values, ";"))
+	}
+	if len(verr.Fields) > 0 {
+		return SimulationParams{}, &verr
+	}
+	return parseUploadRow(cols, record)
+}
+
+func submitQueryHandler(c *gin.Context) {
+	params, err := parseQueryParams(c.Request.URL.Query())
+	if err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	// a GET that enqueues must never be answered from a cache
+	c.Header("Cache-Control", "no-store")
+	submitParams(c, params)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func submitAndLoadMeta(t *testing.T, router *gin.Engine, req *http.Request) JobMeta {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	meta, err := metaStore.GetMeta(context.Background(), resp["job_id"].(string))
+	require.NoError(t, err)
+	return meta
+}
+
+func TestSubmitQueryMatchesPost(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	body := withSite(`{"A_glass": 80, "tau_glass": 0.7, "setpoint": 14, "start_date": "2025-11-01", "end_date": "2025-11-03", "tags": ["north", "trial"], "priority": "high", "seed": 7}`)
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	posted := submitAndLoadMeta(t, router, req)
+
+	req, _ = http.NewRequest("GET", "/simulate?A_glass=80&tau_glass=0.7&setpoint=14&lat=52.1&lon=5.2&start_date=2025-11-01&end_date=2025-11-03&tags=north&tags=trial&priority=high&seed=7", nil)
+	got := submitAndLoadMeta(t, router, req)
+
+	assert.NotEqual(t, posted.JobID, got.JobID)
+	assert.Equal(t, posted.Params, got.Params)
+	assert.Equal(t, posted.Tags, got.Tags)
+	assert.Equal(t, posted.Priority, got.Priority)
+	assert.Equal(t, StatusQueued, got.Status)
+	n, err := rdb.LLen(context.Background(), queueFor(PriorityHigh)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestSubmitQueryOptions(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/simulate?preset=small_hobby&setpoint=18&lat=52.1&lon=5.2&dry_run=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var resp struct {
+		DryRun bool             `json:"dry_run"`
+		Params SimulationParams `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.DryRun)
+	require.NotNil(t, resp.Params.Setpoint)
+	assert.Equal(t, 18.0, *resp.Params.Setpoint)
+}
+
+func TestSubmitQueryRejectsBadParams(t *testing.T) {
+	router := setupRouter()
+	tests := []struct {
+		name, query, field string
+	}{
+		{"unknown name", "setpiont=18", "setpiont"},
+		{"not a number", "A_glass=big", "A_glass"},
+		{"repeated scalar", "setpoint=18&setpoint=20", "setpoint"},
+		{"out of range", "tau_glass=3", "tau_glass"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/simulate?"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			env := decodeError(t, w)
+			assert.Equal(t, CodeInvalidParam, env.Error.Code)
+			assert.Equal(t, tt.field, env.Error.Field)
+		})
+	}
+}
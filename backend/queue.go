@@ -0,0 +1,238 @@
+package main
+
+// backend/queue.go
+//
+// Job creation shared by every submission path: build the meta for a set of
+// resolved params, then write meta, queue payload and recent-list entry for one
+// or many jobs in a single pipeline.
+//
+// Each priority has its own jobs list. Workers BLPOP them in jobQueues order,
+// so a high-priority job runs before anything already waiting at normal or low.
+//
+// A job may also name a queue (the "queue" field) to reach a separate worker
+// pool, e.g. one per customer tier. Its lists are the priority lists suffixed
+// with :<queue> (simulation_jobs:<queue>, simulation_jobs_high:<queue>, ...),
+// drained by workers started with WORKER_QUEUE=<queue>. Only names in
+// JOB_QUEUES (comma-separated) are accepted; without a queue a job goes to the
+// default lists, whose names are unchanged.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Job priorities
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+// knownPriorities is the set accepted in the "priority" field.
+var knownPriorities = map[string]bool{
+	PriorityHigh:   true,
+	PriorityNormal: true,
+	PriorityLow:    true,
+}
+
+// jobQueueNames is the allow-list of named queues, from JOB_QUEUES.
+var jobQueueNames = map[string]bool{}
+
+func loadQueueConfig() {
+	jobQueueNames = map[string]bool{}
+	for _, name := range strings.Split(os.Getenv("JOB_QUEUES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			jobQueueNames[name] = true
+		}
+	}
+}
+
+// sortedQueueNames lists the named queues for error messages and stats.
+func sortedQueueNames() []string {
+	names := make([]string, 0, len(jobQueueNames))
+	for name := range jobQueueNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// jobQueue is the jobs list for one priority.
+type jobQueue struct {
+	Priority string
+	List     string // list name before REDIS_KEY_PREFIX
+}
+
+// Key returns the list's Redis key.
+func (q jobQueue) Key() string {
+	return redisKey(q.List)
+}
+
+// KeyFor returns the key of the list in the named queue; "" is the default queue.
+func (q jobQueue) KeyFor(name string) string {
+	if name == "" {
+		return q.Key()
+	}
+	return redisKey(q.List + ":" + name)
+}
+
+// jobQueues lists the jobs lists in the order workers drain them. Normal keeps
+// the original list name so existing workers and tooling still see those jobs.
+var jobQueues = []jobQueue{
+	{PriorityHigh, RedisJobsList + "_high"},
+	{PriorityNormal, RedisJobsList},
+	{PriorityLow, RedisJobsList + "_low"},
+}
+
+// queueFor returns the default queue's jobs list key for priority; unknown or
+// empty means normal.
+func queueFor(priority string) string {
+	return namedQueueFor(priority, "")
+}
+
+// namedQueueFor returns the jobs list key for priority in the named queue.
+func namedQueueFor(priority, name string) string {
+	for _, q := range jobQueues {
+		if q.Priority == priority {
+			return q.KeyFor(name)
+		}
+	}
+	return jobQueues[1].KeyFor(name)
+}
+
+// newJobMeta creates queued metadata with a fresh job id for params.
+func newJobMeta(params SimulationParams, now time.Time) JobMeta {
+	jobID := uuid.NewString()
+	priority := params.Priority
+	if priority == "" {
+		priority = PriorityNormal
+	}
+	// tags describe the job, not the run; keep them on the meta only
+	tags := normalizeTags(params.Tags)
+	params.Tags = nil
+	// record a seed for every run so it can be reproduced exactly
+	if params.Seed == nil {
+		seed := int64(rand.Uint32())
+		params.Seed = &seed
+	}
+	meta := JobMeta{
+		JobID:            jobID,
+		Status:           StatusQueued,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		Params:           params,
+		ResultKey:        jobResultKey(jobID),
+		Priority:         priority,
+		Queue:            params.Queue,
+		Tags:             tags,
+		ResultTTLSeconds: int64(resultTTLFor(&params) / time.Second),
+	}
+	setExpiry(&meta, now, metaTTL(meta))
+	return meta
+}
+
+// resultTTLFor returns the requested result TTL clamped to maxResultTTL, or
+// DefaultResultTTL when none was requested.
+func resultTTLFor(p *SimulationParams) time.Duration {
+	if p.ResultTTLSeconds == nil {
+		return DefaultResultTTL
+	}
+	// clamp in seconds: a huge value would overflow the Duration
+	return time.Duration(min(*p.ResultTTLSeconds, int64(maxResultTTL/time.Second))) * time.Second
+}
+
+// metaTTL is the expiry for meta's Redis keys.
+func metaTTL(meta JobMeta) time.Duration {
+	if meta.ResultTTLSeconds <= 0 {
+		return DefaultResultTTL
+	}
+	return time.Duration(meta.ResultTTLSeconds) * time.Second
+}
+
+// payloadFor returns the queue payload the worker consumes for meta.
+func payloadFor(meta JobMeta) JobPayload {
+	return JobPayload{
+		JobID:            meta.JobID,
+		CreatedAt:        meta.CreatedAt,
+		Params:           meta.Params,
+		ResultTTLSeconds: meta.ResultTTLSeconds,
+	}
+}
+
+// recentJobsMaxRetain is how many ids the recent list keeps, from
+// RECENT_JOBS_MAX_RETAIN.
+var recentJobsMaxRetain = RecentJobsMaxRetain
+
+func loadRecentJobsConfig() {
+	recentJobsMaxRetain = max(envInt("RECENT_JOBS_MAX_RETAIN", RecentJobsMaxRetain), 1)
+}
+
+// pushRecentScript pushes ARGV[2..] onto the recent list (KEYS[1]) and trims it
+// to ARGV[1] ids in one step, so concurrent submissions never leave it longer
+// than the limit, not even between the push and the trim.
+var pushRecentScript = redis.NewScript(`
+for i = 2, #ARGV do
+  redis.call('LPUSH', KEYS[1], ARGV[i])
+end
+redis.call('LTRIM', KEYS[1], 0, tonumber(ARGV[1]) - 1)
+return redis.call('LLEN', KEYS[1])
+`)
+
+// enqueueJobs stores each job's meta, pushes its payload onto the jobs list for
+// its priority and records it in the recent list, all in one pipeline. Meta is
+// written before the payload so a worker never pops a job whose meta does not
+// exist yet.
+//
+// When a separate metadata backend is configured it records each meta first,
+// so a job is never queued without a durable record.
+func enqueueJobs(ctx context.Context, metas []JobMeta) error {
+	if mirrorsMeta() {
+		for _, meta := range metas {
+			if err := metaStore.SaveMeta(ctx, meta); err != nil {
+				return fmt.Errorf("metadata backend: %w", err)
+			}
+		}
+	}
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		recent := make([]interface{}, 0, len(metas)+1)
+		recent = append(recent, recentJobsMaxRetain)
+		traceCtx := traceContext(ctx)
+		for _, meta := range metas {
+			metaBytes, err := json.Marshal(meta)
+			if err != nil {
+				return err
+			}
+			payload := payloadFor(meta)
+			payload.TraceContext = traceCtx
+			payloadBytes, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			pipe.Set(ctx, jobMetaKey(meta.JobID), metaBytes, metaTTL(meta))
+			setExpiryMarker(ctx, pipe, meta)
+			pipe.RPush(ctx, namedQueueFor(meta.Priority, meta.Queue), payloadBytes)
+			recent = append(recent, meta.JobID)
+			pipe.Set(ctx, paramsHashKey(physicsHash(meta.Params)), meta.JobID, metaTTL(meta))
+			for _, tag := range meta.Tags {
+				pipe.SAdd(ctx, jobsByTagKey(tag), meta.JobID)
+			}
+		}
+		// Eval rather than Run: EVALSHA's NOSCRIPT fallback does not work in a pipeline
+		pushRecentScript.Eval(ctx, pipe, []string{redisKey(RedisRecentJobsList)}, recent...)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	jobsSubmitted.Add(float64(len(metas)))
+	return nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultTTLFor(t *testing.T) {
+	orig := maxResultTTL
+	maxResultTTL = 48 * time.Hour
+	defer func() { maxResultTTL = orig }()
+
+	assert.Equal(t, DefaultResultTTL, resultTTLFor(&SimulationParams{}))
+	ttl := int64(3600)
+	assert.Equal(t, time.Hour, resultTTLFor(&SimulationParams{ResultTTLSeconds: &ttl}))
+	huge := int64(365 * 24 * 3600)
+	assert.Equal(t, 48*time.Hour, resultTTLFor(&SimulationParams{ResultTTLSeconds: &huge}))
+	// beyond about 9.2e9 s the value no longer fits a Duration
+	overflow := int64(10000000000)
+	assert.Equal(t, 48*time.Hour, resultTTLFor(&SimulationParams{ResultTTLSeconds: &overflow}))
+	maxInt := int64(math.MaxInt64)
+	assert.Equal(t, 48*time.Hour, resultTTLFor(&SimulationParams{ResultTTLSeconds: &maxInt}))
+}
+
+func TestSubmitJobAppliesResultTTL(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	orig := maxResultTTL
+	maxResultTTL = 48 * time.Hour
+	defer func() { maxResultTTL = orig }()
+
+	tests := []struct {
+		name string
+		body string
+		want time.Duration
+	}{
+		{"default", withSite(`{}`), DefaultResultTTL},
+		{"override", withSite(`{"result_ttl_seconds": 600}`), 10 * time.Minute},
+		{"clamped", withSite(`{"result_ttl_seconds": 999999999}`), 48 * time.Hour},
+		{"clamped past Duration overflow", withSite(`{"result_ttl_seconds": 10000000000}`), 48 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusAccepted, w.Code)
+			var response map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			jobID := response["job_id"].(string)
+
+			ttl, err := rdb.TTL(ctx, RedisJobMetaPrefix+jobID).Result()
+			require.NoError(t, err)
+			assert.InDelta(t, tt.want.Seconds(), ttl.Seconds(), 2)
+
+			metaStr, err := rdb.Get(ctx, RedisJobMetaPrefix+jobID).Result()
+			require.NoError(t, err)
+			var meta JobMeta
+			require.NoError(t, json.Unmarshal([]byte(metaStr), &meta))
+			assert.Equal(t, int64(tt.want/time.Second), meta.ResultTTLSeconds)
+
+			raw, _, found, err := findQueuedPayload(ctx, "", jobID)
+			require.NoError(t, err)
+			require.True(t, found)
+			var payload JobPayload
+			require.NoError(t, json.Unmarshal([]byte(raw), &payload))
+			assert.Equal(t, meta.ResultTTLSeconds, payload.ResultTTLSeconds)
+		})
+	}
+}
+
+func TestSubmitJobRejectsNonPositiveResultTTL(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(`{"result_ttl_seconds": 0}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "result_ttl_seconds")
+}
+
+func TestSubmitJobPriority(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	submit := func(body string) string {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["job_id"].(string)
+	}
+	lowID := submit(withSite(`{"priority": "low"}`))
+	normalID := submit(withSite(`{}`))
+	highID := submit(withSite(`{"priority": "high"}`))
+
+	assert.Equal(t, int64(1), rdb.LLen(ctx, "simulation_jobs_high").Val())
+	assert.Equal(t, int64(1), rdb.LLen(ctx, RedisJobsList).Val())
+	assert.Equal(t, int64(1), rdb.LLen(ctx, "simulation_jobs_low").Val())
+
+	for id, want := range map[string]string{lowID: PriorityLow, normalID: PriorityNormal, highID: PriorityHigh} {
+		meta, err := redisMetaStore{}.GetMeta(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, want, meta.Priority)
+	}
+
+	// the high job is next despite being submitted last
+	info, err := lookupQueueInfo(ctx, "", highID)
+	require.NoError(t, err)
+	assert.Equal(t, queueInfo{QueuePosition: 1, QueueLength: 3}, info)
+	info, err = lookupQueueInfo(ctx, "", lowID)
+	require.NoError(t, err)
+	assert.Equal(t, queueInfo{QueuePosition: 3, QueueLength: 3}, info)
+
+	// cancelling removes the payload from whichever list holds it
+	req, _ := http.NewRequest("POST", "/jobs/"+highID+"/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(0), rdb.LLen(ctx, "simulation_jobs_high").Val())
+}
+
+func TestSubmitJobRejectsUnknownPriority(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(`{"priority": "urgent"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "priority")
+}
+
+func TestSubmitJobNamedQueue(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	jobQueueNames = map[string]bool{"gold": true, "silver": true}
+	defer func() { jobQueueNames = map[string]bool{} }()
+
+	submit := func(body string) string {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["job_id"].(string)
+	}
+	goldID := submit(withSite(`{"queue": "gold"}`))
+	goldHighID := submit(withSite(`{"queue": "gold", "priority": "high"}`))
+	defaultID := submit(withSite(`{}`))
+
+	assert.Equal(t, int64(1), rdb.LLen(ctx, "simulation_jobs:gold").Val())
+	assert.Equal(t, int64(1), rdb.LLen(ctx, "simulation_jobs_high:gold").Val())
+	assert.Equal(t, int64(1), rdb.LLen(ctx, RedisJobsList).Val(), "the default queue keeps its name")
+	assert.Zero(t, rdb.LLen(ctx, "simulation_jobs:silver").Val())
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, goldID)
+	require.NoError(t, err)
+	assert.Equal(t, "gold", meta.Queue)
+	meta, err = redisMetaStore{}.GetMeta(ctx, defaultID)
+	require.NoError(t, err)
+	assert.Empty(t, meta.Queue)
+
+	// positions are within the job's own queue
+	info, err := lookupQueueInfo(ctx, "gold", goldID)
+	require.NoError(t, err)
+	assert.Equal(t, queueInfo{QueuePosition: 2, QueueLength: 2}, info)
+	info, err = lookupQueueInfo(ctx, "", defaultID)
+	require.NoError(t, err)
+	assert.Equal(t, queueInfo{QueuePosition: 1, QueueLength: 1}, info)
+
+	req, _ := http.NewRequest("POST", "/jobs/"+goldHighID+"/cancel", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, rdb.LLen(ctx, "simulation_jobs_high:gold").Val())
+
+	// /stats counts every queue and breaks down the named ones
+	req, _ = http.NewRequest("GET", "/stats", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var stats struct {
+		QueueLength int64            `json:"queue_length"`
+		ByName      map[string]int64 `json:"queue_by_name"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.Equal(t, int64(2), stats.QueueLength)
+	assert.Equal(t, map[string]int64{"gold": 1, "silver": 0}, stats.ByName)
+}
+
+func TestSubmitJobRejectsUnknownQueue(t *testing.T) {
+	router := setupRouter()
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"queue": "gold"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "omitted (no named queues are configured)", decodeError(t, w).Fields[0].Allowed)
+
+	jobQueueNames = map[string]bool{"gold": true, "silver": true}
+	defer func() { jobQueueNames = map[string]bool{} }()
+	w = post(`{"queue": "platinum"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	env := decodeError(t, w)
+	assert.Equal(t, "queue", env.Error.Field)
+	assert.Equal(t, "one of gold, silver, or omitted for the default queue", env.Fields[0].Allowed)
+}
+
+func TestSubmitJobSeed(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	submit := func(body string) (float64, JobMeta) {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		meta, err := redisMetaStore{}.GetMeta(ctx, response["job_id"].(string))
+		require.NoError(t, err)
+		require.NotNil(t, meta.Params.Seed)
+		return response["seed"].(float64), meta
+	}
+
+	// a provided seed is kept and sent to the worker
+	seed, meta := submit(withSite(`{"seed": 42}`))
+	assert.Equal(t, 42.0, seed)
+	assert.Equal(t, int64(42), *meta.Params.Seed)
+	payload, err := rdb.LIndex(ctx, RedisJobsList, 0).Result()
+	require.NoError(t, err)
+	assert.Contains(t, payload, `"seed":42`)
+
+	// an omitted seed is chosen, recorded in meta and returned
+	seed, meta = submit(withSite(`{}`))
+	assert.Equal(t, float64(*meta.Params.Seed), seed)
+	assert.True(t, *meta.Params.Seed >= 0 && *meta.Params.Seed <= MaxSeed)
+}
+
+func TestSubmitJobRejectsBadSeed(t *testing.T) {
+	router := setupRouter()
+
+	for _, body := range []string{`{"seed": -1}`, `{"seed": 4294967296}`, `{"seed": 1.5}`} {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), "seed", body)
+	}
+}
+
+func TestConcurrentSubmitsKeepRecentListTrimmed(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	recentJobsMaxRetain = 5
+	defer func() { recentJobsMaxRetain = RecentJobsMaxRetain }()
+
+	// watch the list length while the submissions run
+	done := make(chan struct{})
+	watched := make(chan int64)
+	go func() {
+		var longest int64
+		for {
+			select {
+			case <-done:
+				watched <- longest
+				return
+			default:
+			}
+			if n := rdb.LLen(ctx, redisKey(RedisRecentJobsList)).Val(); n > longest {
+				longest = n
+			}
+		}
+	}()
+
+	const submits = 40
+	var wg sync.WaitGroup
+	codes := make([]int, submits)
+	for i := 0; i < submits; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = postSimulate(router, withSite(`{"A_glass": 80}`)).Code
+		}(i)
+	}
+	wg.Wait()
+	close(done)
+	longest := <-watched
+
+	for i, code := range codes {
+		assert.Equal(t, http.StatusAccepted, code, "submit %d", i)
+	}
+	assert.LessOrEqual(t, longest, int64(5), "the list never exceeds the retain limit")
+	assert.Equal(t, int64(5), rdb.LLen(ctx, redisKey(RedisRecentJobsList)).Val())
+	assert.Equal(t, int64(submits), rdb.LLen(ctx, queueFor(PriorityNormal)).Val(), "every job was queued")
+}
+
+func TestLoadRecentJobsConfig(t *testing.T) {
+	defer func() { recentJobsMaxRetain = RecentJobsMaxRetain }()
+	t.Setenv("RECENT_JOBS_MAX_RETAIN", "250")
+	loadRecentJobsConfig()
+	assert.Equal(t, 250, recentJobsMaxRetain)
+	t.Setenv("RECENT_JOBS_MAX_RETAIN", "0")
+	loadRecentJobsConfig()
+	assert.Equal(t, 1, recentJobsMaxRetain, "at least the newest job is kept")
+}
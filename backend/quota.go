@@ -0,0 +1,156 @@
+package main
+
+// backend/quota.go
+//
+// Cap on how many jobs one client (API key, or IP when auth is off) may have
+// queued or running at once. Each client's active jobs are tracked in the
+// sorted set active_jobs:<client>, scored by when they were reserved; its size
+// is the client's active count. Members are reconciled against job status on
+// every submission: ids whose job finished, failed or was cancelled are dropped
+// before counting, as are ids with no meta once quotaReservationGrace has
+// passed. The grace covers the window between the reservation and the meta
+// write in enqueueJobs, so a concurrent submission cannot evict a job still
+// being queued. A slot frees up however a job ends (worker, cancel, stale
+// sweeper, delete) without those paths knowing about quotas, and decrements
+// are idempotent.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisActiveJobsPrefix keys active_jobs:<client> -> sorted set of the client's
+// job ids, scored by reservation time in unix milliseconds.
+const RedisActiveJobsPrefix = "active_jobs:"
+
+// DefaultMaxActiveJobsPerKey of 0 leaves active jobs unlimited.
+const DefaultMaxActiveJobsPerKey = 0
+
+// quotaWatchRetries bounds retries when concurrent submissions by one client race.
+const quotaWatchRetries = 3
+
+// quotaReservationGrace is how long a reserved id may have no meta before it is
+// taken for a submission that failed without releasing it (or a deleted job).
+const quotaReservationGrace = time.Minute
+
+var maxActiveJobsPerKey = DefaultMaxActiveJobsPerKey
+
+func loadQuotaConfig() {
+	maxActiveJobsPerKey = envInt("MAX_ACTIVE_JOBS_PER_KEY", DefaultMaxActiveJobsPerKey)
+}
+
+// reserveActiveJobs adds jobIDs to client's active set unless that would take
+// it past quota. active is the number of the client's jobs still queued or
+// running before the reservation.
+func reserveActiveJobs(ctx context.Context, client string, jobIDs []string, quota int) (active int, ok bool, err error) {
+	key := activeJobsKey(client)
+	reserve := func(tx *redis.Tx) error {
+		now := time.Now()
+		members, err := tx.ZRangeWithScores(ctx, key, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		active = 0
+		var finished []interface{}
+		if len(members) > 0 {
+			metaKeys := make([]string, len(members))
+			for i, m := range members {
+				metaKeys[i] = jobMetaKey(m.Member.(string))
+			}
+			vals, err := tx.MGet(ctx, metaKeys...).Result()
+			if err != nil {
+				return err
+			}
+			expired := float64(now.Add(-quotaReservationGrace).UnixMilli())
+			for i, v := range vals {
+				s, _ := v.(string)
+				var meta JobMeta
+				switch {
+				case s == "" && members[i].Score >= expired:
+					// reserved, but the submission has not written the meta yet
+				case s == "" || json.Unmarshal([]byte(s), &meta) != nil || isTerminalStatus(meta.Status):
+					finished = append(finished, members[i].Member)
+					continue
+				}
+				active++
+			}
+		}
+		ok = active+len(jobIDs) <= quota
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(finished) > 0 {
+				pipe.ZRem(ctx, key, finished...)
+			}
+			if ok {
+				reserved := make([]redis.Z, len(jobIDs))
+				for i, id := range jobIDs {
+					reserved[i] = redis.Z{Score: float64(now.UnixMilli()), Member: id}
+				}
+				pipe.ZAdd(ctx, key, reserved...)
+				// no job outlives maxResultTTL, so neither should an idle client's set
+				pipe.Expire(ctx, key, maxResultTTL)
+			}
+			return nil
+		})
+		return err
+	}
+	for attempt := 0; attempt < quotaWatchRetries; attempt++ {
+		err = rdb.Watch(ctx, reserve, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return active, ok, err
+		}
+	}
+	return 0, false, err
+}
+
+// releaseActiveJobs drops jobIDs from client's active set.
+func releaseActiveJobs(ctx context.Context, client string, jobIDs []string) error {
+	ids := make([]interface{}, len(jobIDs))
+	for i, id := range jobIDs {
+		ids[i] = id
+	}
+	return rdb.ZRem(ctx, activeJobsKey(client), ids...).Err()
+}
+
+// claimJobQuota reserves quota for metas on behalf of the caller. When the
+// caller already has too many active jobs it writes 429 and returns false.
+func claimJobQuota(c *gin.Context, ctx context.Context, metas []JobMeta) bool {
+	if maxActiveJobsPerKey <= 0 {
+		return true
+	}
+	active, ok, err := reserveActiveJobs(ctx, rateLimitClient(c), jobIDsOf(metas), maxActiveJobsPerKey)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return false
+	}
+	if !ok {
+		respondError(c, http.StatusTooManyRequests, "too many active jobs for this API key, wait for some to finish",
+			gin.H{"active_jobs": active, "max_active_jobs": maxActiveJobsPerKey})
+		return false
+	}
+	return true
+}
+
+// releaseJobQuota returns the slots taken by claimJobQuota, for submissions
+// that fail after claiming.
+func releaseJobQuota(c *gin.Context, ctx context.Context, metas []JobMeta) {
+	if maxActiveJobsPerKey <= 0 {
+		return
+	}
+	if err := releaseActiveJobs(ctx, rateLimitClient(c), jobIDsOf(metas)); err != nil {
+		loggerFrom(c).Warn("failed to release job quota", "error", err)
+	}
+}
+
+func jobIDsOf(metas []JobMeta) []string {
+	ids := make([]string, len(metas))
+	for i, m := range metas {
+		ids[i] = m.JobID
+	}
+	return ids
+}
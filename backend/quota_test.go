@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// submitAs posts body to path with the given API key.
+func submitAs(t *testing.T, router http.Handler, key, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(APIKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func withJobQuota(t *testing.T, n int) {
+	t.Helper()
+	maxActiveJobsPerKey = n
+	t.Cleanup(func() { maxActiveJobsPerKey = DefaultMaxActiveJobsPerKey })
+}
+
+func TestActiveJobQuota(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withAuth(t, "key-a", "key-b")
+	withJobQuota(t, 2)
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		code, response := submitAs(t, router, "key-a", "/simulate", withSite(`{}`))
+		require.Equal(t, http.StatusAccepted, code)
+		ids = append(ids, response["job_id"].(string))
+	}
+
+	code, response := submitAs(t, router, "key-a", "/simulate", withSite(`{}`))
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.Equal(t, float64(2), response["active_jobs"])
+	assert.Equal(t, float64(2), response["max_active_jobs"])
+	assert.Equal(t, int64(2), rdb.LLen(ctx, RedisJobsList).Val(), "rejected job is not queued")
+
+	// quotas are per key
+	code, _ = submitAs(t, router, "key-b", "/simulate", withSite(`{}`))
+	assert.Equal(t, http.StatusAccepted, code)
+
+	// a job reaching a terminal state frees its slot
+	markDone(t, ctx, ids[0])
+	code, _ = submitAs(t, router, "key-a", "/simulate", withSite(`{}`))
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, redis.Nil, rdb.ZScore(ctx, activeJobsKey("key:key-a"), ids[0]).Err())
+
+	code, _ = submitAs(t, router, "key-a", "/simulate", withSite(`{}`))
+	assert.Equal(t, http.StatusTooManyRequests, code)
+}
+
+func TestActiveJobQuotaCountsBatches(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withAuth(t, "key-a")
+	withJobQuota(t, 3)
+
+	code, response := submitAs(t, router, "key-a", "/simulate", withSite(`{}`))
+	require.Equal(t, http.StatusAccepted, code)
+	firstID := response["job_id"].(string)
+
+	// the whole batch must fit; none of it is queued otherwise
+	threeJobs := "[" + withSite(`{}`) + ", " + withSite(`{}`) + ", " + withSite(`{}`) + "]"
+	code, _ = submitAs(t, router, "key-a", "/simulate/batch", threeJobs)
+	assert.Equal(t, http.StatusTooManyRequests, code)
+	assert.Equal(t, int64(1), rdb.LLen(ctx, RedisJobsList).Val())
+
+	// cancelled jobs no longer count
+	seedJobMeta(t, ctx, firstID, StatusCancelled)
+	code, _ = submitAs(t, router, "key-a", "/simulate/batch", threeJobs)
+	assert.Equal(t, http.StatusAccepted, code)
+}
+
+func TestActiveJobQuotaConcurrentSubmits(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withAuth(t, "key-a")
+	withJobQuota(t, 2)
+
+	// a reservation whose meta is not written yet still holds its slot
+	_, ok, err := reserveActiveJobs(ctx, "key:key-a", []string{"in-flight"}, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+	active, ok, err := reserveActiveJobs(ctx, "key:key-a", []string{"next"}, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, active, "the in-flight job counts")
+	_, ok, err = reserveActiveJobs(ctx, "key:key-a", []string{"third"}, 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// until the grace runs out: then it is taken for a failed submission
+	stale := float64(time.Now().Add(-2 * quotaReservationGrace).UnixMilli())
+	rdb.ZAdd(ctx, activeJobsKey("key:key-a"), redis.Z{Score: stale, Member: "in-flight"}, redis.Z{Score: stale, Member: "next"})
+	active, ok, err = reserveActiveJobs(ctx, "key:key-a", []string{"third"}, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Zero(t, active)
+	require.NoError(t, releaseActiveJobs(ctx, "key:key-a", []string{"third"}))
+
+	// concurrent submissions never take the client past its quota
+	const submits = 6
+	codes := make(chan int, submits)
+	var wg sync.WaitGroup
+	for i := 0; i < submits; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{}`)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(APIKeyHeader, "key-a")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			codes <- w.Code
+		}()
+	}
+	wg.Wait()
+	close(codes)
+	accepted := 0
+	for code := range codes {
+		if code == http.StatusAccepted {
+			accepted++
+		}
+	}
+	assert.NotZero(t, accepted)
+	assert.LessOrEqual(t, accepted, 2)
+	assert.Equal(t, int64(accepted), rdb.LLen(ctx, RedisJobsList).Val())
+	assert.LessOrEqual(t, rdb.ZCard(ctx, activeJobsKey("key:key-a")).Val(), int64(2))
+}
+
+func TestActiveJobQuotaDisabled(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	for i := 0; i < 3; i++ {
+		code, _ := submitReuse(t, router, "/simulate", withSite(`{}`))
+		require.Equal(t, http.StatusAccepted, code)
+	}
+	keys, err := rdb.Keys(ctx, RedisActiveJobsPrefix+"*").Result()
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
@@ -0,0 +1,86 @@
+package main
+
+// backend/ratelimit.go
+//
+// Per-client submission rate limiting backed by a Redis counter per window.
+// Clients are identified by API key, falling back to their IP address.
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRateLimitPrefix keys a client's counter: rate_limit:<client>
+const RedisRateLimitPrefix = "rate_limit:"
+
+// Rate limit defaults
+const (
+	DefaultRateLimitPerWindow = 60
+	DefaultRateLimitWindow    = time.Minute
+)
+
+var (
+	// rateLimitPerWindow of 0 disables limiting
+	rateLimitPerWindow = DefaultRateLimitPerWindow
+	rateLimitWindow    = DefaultRateLimitWindow
+)
+
+// rateLimitScript increments the counter, starting the window on the first hit,
+// and returns the new count plus the window's remaining milliseconds.
+var rateLimitScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {n, redis.call('PTTL', KEYS[1])}
+`)
+
+func loadRateLimitConfig() {
+	rateLimitPerWindow = envInt("RATE_LIMIT_PER_MIN", DefaultRateLimitPerWindow)
+	rateLimitWindow = envSeconds("RATE_LIMIT_WINDOW_SECONDS", DefaultRateLimitWindow)
+}
+
+// rateLimitClient identifies the caller for rate limiting purposes.
+func rateLimitClient(c *gin.Context) string {
+	if key := c.GetString(ctxAPIKey); key != "" {
+		return "key:" + key
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimit rejects requests beyond rateLimitPerWindow per client with 429 and Retry-After.
+// Redis failures let the request through rather than blocking all submissions.
+func rateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rateLimitPerWindow <= 0 || rdb == nil {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+		defer cancel()
+		key := rateLimitKey(rateLimitClient(c))
+		res, err := rateLimitScript.Run(ctx, rdb, []string{key}, rateLimitWindow.Milliseconds()).Int64Slice()
+		if err != nil || len(res) != 2 {
+			loggerFrom(c).Warn("rate limit check failed", "error", err)
+			c.Next()
+			return
+		}
+		count, ttlMs := res[0], res[1]
+		if count > int64(rateLimitPerWindow) {
+			retryAfter := int64(math.Ceil(float64(ttlMs) / 1000))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			respondError(c, http.StatusTooManyRequests, "rate limit exceeded, retry later")
+			return
+		}
+		c.Next()
+	}
+}
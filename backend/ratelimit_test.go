@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withRateLimit overrides the limit and window for one test.
+func withRateLimit(t *testing.T, limit int, window time.Duration) {
+	t.Helper()
+	origLimit, origWindow := rateLimitPerWindow, rateLimitWindow
+	rateLimitPerWindow, rateLimitWindow = limit, window
+	t.Cleanup(func() { rateLimitPerWindow, rateLimitWindow = origLimit, origWindow })
+}
+
+func submitWithKey(router *gin.Engine, key string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitRejectsOverLimit(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	withAuth(t, "key-a", "key-b")
+	withRateLimit(t, 3, time.Minute)
+	rdb.FlushDB(context.Background())
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusAccepted, submitWithKey(router, "key-a").Code)
+	}
+	w := submitWithKey(router, "key-a")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.True(t, retryAfter > 0 && retryAfter <= 60)
+
+	// other keys have their own budget
+	assert.Equal(t, http.StatusAccepted, submitWithKey(router, "key-b").Code)
+}
+
+func TestRateLimitResetsAfterWindow(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	withRateLimit(t, 1, time.Second)
+	rdb.FlushDB(context.Background())
+
+	require.Equal(t, http.StatusAccepted, submitWithKey(router, "").Code)
+	require.Equal(t, http.StatusTooManyRequests, submitWithKey(router, "").Code)
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, http.StatusAccepted, submitWithKey(router, "").Code)
+}
@@ -0,0 +1,187 @@
+package main
+
+// backend/redisconn.go
+//
+// Redis client settings and the startup connection loop. Redis may come up a
+// moment after the API container, so the first ping is retried with
+// exponential backoff instead of exiting on the first failure. Once running,
+// every command goes through redisRetryHook, which retries connection drops
+// and failover replies (MOVED, READONLY, ...) a few times so a Redis failover
+// does not reach clients as a 500.
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis connection defaults
+const (
+	DefaultRedisConnectAttempts   = 10                     // pings before startup gives up
+	DefaultRedisConnectBackoff    = 500 * time.Millisecond // wait after the first failed ping, doubled each time
+	DefaultRedisConnectMaxBackoff = 10 * time.Second       // cap on the wait between pings
+	DefaultRedisMaxRetries        = 3                      // per-command retries inside the client
+	DefaultRedisDialTimeout       = 5 * time.Second
+	DefaultRedisPoolSize          = 0 // 0 keeps go-redis' default (10 per CPU)
+	DefaultRedisMinIdleConns      = 0
+	DefaultRedisOpRetries         = 2                      // retries of a command that failed transiently
+	DefaultRedisOpRetryBackoff    = 100 * time.Millisecond // wait before the first retry, doubled each time
+)
+
+var (
+	redisConnectAttempts   = DefaultRedisConnectAttempts
+	redisConnectBackoff    = DefaultRedisConnectBackoff
+	redisConnectMaxBackoff = DefaultRedisConnectMaxBackoff
+	redisMaxRetries        = DefaultRedisMaxRetries
+	redisDialTimeout       = DefaultRedisDialTimeout
+	redisPoolSize          = DefaultRedisPoolSize
+	redisMinIdleConns      = DefaultRedisMinIdleConns
+	redisOpRetries         = DefaultRedisOpRetries
+	redisOpRetryBackoff    = DefaultRedisOpRetryBackoff
+)
+
+func loadRedisConfig() {
+	redisConnectAttempts = envInt("REDIS_CONNECT_ATTEMPTS", DefaultRedisConnectAttempts)
+	redisConnectBackoff = envMillis("REDIS_CONNECT_BACKOFF_MS", DefaultRedisConnectBackoff)
+	redisConnectMaxBackoff = envMillis("REDIS_CONNECT_MAX_BACKOFF_MS", DefaultRedisConnectMaxBackoff)
+	redisMaxRetries = envInt("REDIS_MAX_RETRIES", DefaultRedisMaxRetries)
+	redisDialTimeout = envSeconds("REDIS_DIAL_TIMEOUT_SECONDS", DefaultRedisDialTimeout)
+	redisPoolSize = envInt("REDIS_POOL_SIZE", DefaultRedisPoolSize)
+	redisMinIdleConns = envInt("REDIS_MIN_IDLE_CONNS", DefaultRedisMinIdleConns)
+	redisOpRetries = envInt("REDIS_OP_RETRIES", DefaultRedisOpRetries)
+	redisOpRetryBackoff = envMillis("REDIS_OP_RETRY_BACKOFF_MS", DefaultRedisOpRetryBackoff)
+}
+
+// redisOptions returns the client options for addr from the loaded settings.
+func redisOptions(addr string) *redis.Options {
+	return &redis.Options{
+		Addr:         addr,
+		DB:           DefaultRedisDB,
+		MaxRetries:   redisMaxRetries,
+		DialTimeout:  redisDialTimeout,
+		PoolSize:     redisPoolSize,
+		MinIdleConns: redisMinIdleConns,
+	}
+}
+
+// newRedisClient returns a client for addr whose commands are traced (see
+// redisTracingHook) and retry transient failures (see redisRetryHook).
+func newRedisClient(addr string) *redis.Client {
+	client := redis.NewClient(redisOptions(addr))
+	client.AddHook(redisTracingHook{}) // outermost: one span covers the retries
+	client.AddHook(redisRetryHook{retries: redisOpRetries, backoff: redisOpRetryBackoff})
+	return client
+}
+
+// transientReplyPrefixes start the Redis error replies sent while a cluster
+// reshards or fails over; the same command succeeds once it settles.
+var transientReplyPrefixes = []string{"MOVED ", "ASK ", "TRYAGAIN ", "CLUSTERDOWN ", "LOADING ", "READONLY ", "MASTERDOWN "}
+
+// isTransientRedisError reports whether err is a connection-level failure or
+// a failover reply worth retrying. redis.Nil, other error replies, a closed
+// client and an ended context are final.
+func isTransientRedisError(err error) bool {
+	if err == nil || errors.Is(err, redis.Nil) || errors.Is(err, redis.ErrClosed) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true // refused, reset, broken pipe, timed out
+	}
+	var reply redis.Error
+	if errors.As(err, &reply) {
+		for _, prefix := range transientReplyPrefixes {
+			if strings.HasPrefix(reply.Error(), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryRedis calls op, then again up to retries times while it fails with a
+// transient error, sleeping backoff before the first retry and doubling it
+// after each. It returns op's last error, also when ctx ends while waiting.
+func retryRedis(ctx context.Context, retries int, backoff time.Duration, op func() error) error {
+	err := op()
+	for attempt := 1; attempt <= retries && isTransientRedisError(err); attempt++ {
+		slog.Warn("redis command failed, retrying", "attempt", attempt, "of", retries, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+		err = op()
+	}
+	return err
+}
+
+// redisRetryHook runs every command and pipeline through retryRedis. These
+// retries wrap go-redis' own MaxRetries, which resend immediately and do not
+// cover redirects. Like those, a retry may repeat a write whose reply was lost.
+type redisRetryHook struct {
+	retries int
+	backoff time.Duration
+}
+
+func (h redisRetryHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h redisRetryHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		return retryRedis(ctx, h.retries, h.backoff, func() error {
+			cmd.SetErr(nil)
+			return next(ctx, cmd)
+		})
+	}
+}
+
+func (h redisRetryHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		return retryRedis(ctx, h.retries, h.backoff, func() error {
+			for _, cmd := range cmds {
+				cmd.SetErr(nil)
+			}
+			return next(ctx, cmds)
+		})
+	}
+}
+
+// pingWithRetry calls ping up to attempts times, sleeping backoff after the
+// first failure and doubling it (up to maxBackoff) after each one. It returns
+// nil on the first success and the last error once every attempt has failed.
+func pingWithRetry(ctx context.Context, ping func(context.Context) error, attempts int, backoff, maxBackoff time.Duration) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, RedisOpTimeout)
+		err = ping(pingCtx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		slog.Warn("redis ping failed, retrying", "attempt", attempt, "of", attempts, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+	return err
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyPing fails until it has been called succeedOn times (never when 0).
+func flakyPing(succeedOn int) (ping func(context.Context) error, calls *int) {
+	calls = new(int)
+	return func(context.Context) error {
+		*calls++
+		if succeedOn > 0 && *calls >= succeedOn {
+			return nil
+		}
+		return errors.New("connection refused")
+	}, calls
+}
+
+func TestPingWithRetry(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("eventually succeeds", func(t *testing.T) {
+		ping, calls := flakyPing(3)
+		require.NoError(t, pingWithRetry(ctx, ping, 5, time.Millisecond, 4*time.Millisecond))
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("gives up after N attempts", func(t *testing.T) {
+		ping, calls := flakyPing(0)
+		err := pingWithRetry(ctx, ping, 4, time.Millisecond, 2*time.Millisecond)
+		assert.EqualError(t, err, "connection refused")
+		assert.Equal(t, 4, *calls)
+	})
+
+	t.Run("stops when the context ends", func(t *testing.T) {
+		ping, calls := flakyPing(0)
+		cctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		err := pingWithRetry(cctx, ping, 100, time.Hour, time.Hour)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("real client", func(t *testing.T) {
+		if !checkRedisAvailable(t) {
+			return
+		}
+		client := redis.NewClient(redisOptions(testRedisAddr))
+		defer client.Close()
+		ping := func(ctx context.Context) error { return client.Ping(ctx).Err() }
+		assert.NoError(t, pingWithRetry(ctx, ping, 2, time.Millisecond, time.Millisecond))
+	})
+}
+
+// redisReply is an error reply from the server.
+type redisReply string
+
+func (e redisReply) Error() string { return string(e) }
+func (redisReply) RedisError()     {}
+
+func TestIsTransientRedisError(t *testing.T) {
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{redis.Nil, false},
+		{redis.ErrClosed, false},
+		{context.DeadlineExceeded, false},
+		{io.EOF, true},
+		{connReset, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, true},
+		{redisReply("MOVED 3999 10.0.0.2:6379"), true},
+		{redisReply("READONLY You can't write against a read only replica."), true},
+		{redisReply("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+		{errors.New("invalid JSON"), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, isTransientRedisError(tt.err), "%v", tt.err)
+	}
+}
+
+// flakyProcess is a mock client: it fails the first failures calls with err
+// and then answers GET with "v".
+func flakyProcess(failures int, err error) (redis.ProcessHook, *int) {
+	calls := new(int)
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		*calls++
+		if *calls <= failures {
+			cmd.SetErr(err)
+			return err
+		}
+		cmd.(*redis.StringCmd).SetVal("v")
+		return nil
+	}, calls
+}
+
+func TestRedisRetryHook(t *testing.T) {
+	ctx := context.Background()
+	hook := redisRetryHook{retries: 2, backoff: time.Millisecond}
+	connReset := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+	t.Run("transient error then success", func(t *testing.T) {
+		next, calls := flakyProcess(2, connReset)
+		cmd := redis.NewStringCmd(ctx, "get", "k")
+		require.NoError(t, hook.ProcessHook(next)(ctx, cmd))
+		assert.Equal(t, 3, *calls)
+		assert.NoError(t, cmd.Err(), "the failed attempts' error is cleared")
+		assert.Equal(t, "v", cmd.Val())
+	})
+
+	t.Run("gives up after the retries", func(t *testing.T) {
+		next, calls := flakyProcess(10, redisReply("MOVED 3999 10.0.0.2:6379"))
+		cmd := redis.NewStringCmd(ctx, "get", "k")
+		err := hook.ProcessHook(next)(ctx, cmd)
+		assert.EqualError(t, err, "MOVED 3999 10.0.0.2:6379")
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("redis.Nil is an answer", func(t *testing.T) {
+		next, calls := flakyProcess(10, redis.Nil)
+		err := hook.ProcessHook(next)(ctx, redis.NewStringCmd(ctx, "get", "k"))
+		assert.ErrorIs(t, err, redis.Nil)
+		assert.Equal(t, 1, *calls)
+	})
+}
+
+// dropFirst fails the first n commands it sees as if the connection dropped.
+type dropFirst struct{ n *int }
+
+func (h dropFirst) DialHook(next redis.DialHook) redis.DialHook { return next }
+func (h dropFirst) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if *h.n > 0 {
+			*h.n--
+			cmd.SetErr(io.EOF)
+			return io.EOF
+		}
+		return next(ctx, cmd)
+	}
+}
+func (h dropFirst) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if *h.n > 0 {
+			*h.n--
+			return io.EOF
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func TestRedisClientRetriesDroppedConnection(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	ctx := context.Background()
+	origRetries, origBackoff := redisOpRetries, redisOpRetryBackoff
+	redisOpRetries, redisOpRetryBackoff = 2, time.Millisecond
+	defer func() { redisOpRetries, redisOpRetryBackoff = origRetries, origBackoff }()
+
+	client := newRedisClient(testRedisAddr)
+	defer client.Close()
+	drops := 0
+	client.AddHook(dropFirst{&drops})
+	defer client.Del(ctx, "retry-test")
+
+	drops = 1
+	require.NoError(t, client.Set(ctx, "retry-test", "ok", time.Minute).Err())
+	drops = 2
+	assert.Equal(t, "ok", client.Get(ctx, "retry-test").Val())
+	drops = 1
+	_, err := client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "retry-test")
+		return nil
+	})
+	assert.NoError(t, err)
+	drops = 3
+	assert.ErrorIs(t, client.Get(ctx, "retry-test").Err(), io.EOF, "more drops than retries")
+}
@@ -0,0 +1,89 @@
+package main
+
+// backend/reldates.go
+//
+// Relative date ranges. Instead of start_date/end_date a job may give
+// relative_start and relative_end, each "now" (or "today") or a signed offset
+// from now in hours, days or weeks: "-7d", "+14d", "2w", "-36h". They are
+// resolved against the submission time (UTC) by validateParams, so every
+// submit path accepts them, and replaced by the concrete dates: the stored
+// meta, reuse and clones all see an ordinary start_date/end_date. An omitted
+// side means "now", so {"relative_start": "-7d"} is the last week and
+// {"relative_end": "+14d"} the next two. Mixing relative and absolute dates
+// is rejected rather than guessing which one was meant.
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// relativeDatePattern is a signed count of h(ours), d(ays) or w(eeks).
+var relativeDatePattern = regexp.MustCompile(`^([-+]?)([0-9]{1,5})([hdw])$`)
+
+var relativeDateUnits = map[string]time.Duration{
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// relativeDateAllowed describes the grammar in validation errors.
+const relativeDateAllowed = `"now", "today" or an offset such as -7d, +12h, 2w (units h, d, w)`
+
+// parseRelativeDate returns the instant expr names relative to now.
+func parseRelativeDate(expr string, now time.Time) (time.Time, bool) {
+	expr = strings.TrimSpace(expr)
+	if expr == "now" || expr == "today" {
+		return now, true
+	}
+	m := relativeDatePattern.FindStringSubmatch(expr)
+	if m == nil {
+		return time.Time{}, false
+	}
+	n, _ := strconv.Atoi(m[2])
+	offset := time.Duration(n) * relativeDateUnits[m[3]]
+	if m[1] == "-" {
+		offset = -offset
+	}
+	return now.Add(offset), true
+}
+
+// resolveRelativeDates replaces p's relative_start/relative_end with the
+// dates they name at now. p is left alone when neither is set.
+func resolveRelativeDates(p *SimulationParams, now time.Time) []FieldError {
+	if p.RelativeStart == "" && p.RelativeEnd == "" {
+		return nil
+	}
+	if p.StartDate != "" || p.EndDate != "" {
+		var errs []FieldError
+		for _, f := range []struct{ name, value string }{{"relative_start", p.RelativeStart}, {"relative_end", p.RelativeEnd}} {
+			if f.value != "" {
+				errs = append(errs, FieldError{Field: f.name, Value: f.value, Allowed: "omitted when start_date or end_date is given"})
+			}
+		}
+		return errs
+	}
+
+	now = now.UTC()
+	var errs []FieldError
+	resolve := func(field, expr string) string {
+		if expr == "" {
+			expr = "now"
+		}
+		t, ok := parseRelativeDate(expr, now)
+		if !ok {
+			errs = append(errs, FieldError{Field: field, Value: expr, Allowed: relativeDateAllowed})
+			return ""
+		}
+		return t.Format(DateLayout)
+	}
+	start := resolve("relative_start", p.RelativeStart)
+	end := resolve("relative_end", p.RelativeEnd)
+	if len(errs) > 0 {
+		return errs
+	}
+	p.StartDate, p.EndDate = start, end
+	p.RelativeStart, p.RelativeEnd = "", ""
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRelativeDates(t *testing.T) {
+	now := time.Date(2025, 11, 10, 18, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name, start, end string
+		wantStart        string
+		wantEnd          string
+	}{
+		{"last week", "-7d", "now", "2025-11-03", "2025-11-10"},
+		{"end defaults to now", "-7d", "", "2025-11-03", "2025-11-10"},
+		{"forecast", "", "+14d", "2025-11-10", "2025-11-24"},
+		{"weeks", "-2w", "today", "2025-10-27", "2025-11-10"},
+		{"hours cross midnight", "-19h", "+6h", "2025-11-09", "2025-11-11"},
+		{"unsigned is forward", "1d", "3d", "2025-11-11", "2025-11-13"},
+		{"spaces are trimmed", " -1d ", "now ", "2025-11-09", "2025-11-10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := SimulationParams{RelativeStart: tt.start, RelativeEnd: tt.end}
+			require.Empty(t, resolveRelativeDates(&p, now))
+			assert.Equal(t, tt.wantStart, p.StartDate)
+			assert.Equal(t, tt.wantEnd, p.EndDate)
+			assert.Empty(t, p.RelativeStart, "only the concrete dates are kept")
+			assert.Empty(t, p.RelativeEnd)
+		})
+	}
+
+	// resolved against UTC whatever the zone of now
+	p := SimulationParams{RelativeStart: "now"}
+	require.Empty(t, resolveRelativeDates(&p, now.In(time.FixedZone("UTC+8", 8*3600))))
+	assert.Equal(t, "2025-11-10", p.StartDate)
+
+	p = SimulationParams{StartDate: "2025-11-01", EndDate: "2025-11-03"}
+	assert.Empty(t, resolveRelativeDates(&p, now))
+	assert.Equal(t, SimulationParams{StartDate: "2025-11-01", EndDate: "2025-11-03"}, p, "absolute dates are untouched")
+}
+
+func TestResolveRelativeDatesRejected(t *testing.T) {
+	now := time.Date(2025, 11, 10, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		params SimulationParams
+		fields []string
+	}{
+		{"unknown unit", SimulationParams{RelativeStart: "-7m"}, []string{"relative_start"}},
+		{"no count", SimulationParams{RelativeStart: "d"}, []string{"relative_start"}},
+		{"two terms", SimulationParams{RelativeEnd: "now+1d"}, []string{"relative_end"}},
+		{"both bad", SimulationParams{RelativeStart: "yesterday", RelativeEnd: "1y"}, []string{"relative_start", "relative_end"}},
+		{"with start_date", SimulationParams{RelativeStart: "-7d", StartDate: "2025-11-01"}, []string{"relative_start"}},
+		{"with end_date", SimulationParams{RelativeStart: "-7d", EndDate: "2025-11-01"}, []string{"relative_start"}},
+		{"mixed sides", SimulationParams{StartDate: "2025-11-01", RelativeEnd: "now"}, []string{"relative_end"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.params
+			errs := resolveRelativeDates(&p, now)
+			var fields []string
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+			assert.Equal(t, tt.params, p, "nothing is resolved on error")
+		})
+	}
+}
+
+func TestSubmitJobRelativeDates(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{"relative_start": "-7d", "relative_end": "now"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	meta := submitAndLoadMeta(t, router, req)
+	today := time.Now().UTC()
+	assert.Equal(t, today.AddDate(0, 0, -7).Format(DateLayout), meta.Params.StartDate)
+	assert.Equal(t, today.Format(DateLayout), meta.Params.EndDate)
+	assert.Empty(t, meta.Params.RelativeStart)
+	assert.Empty(t, meta.Params.RelativeEnd)
+
+	// the query string takes them too
+	req, _ = http.NewRequest("GET", "/simulate?relative_end=%2B14d&lat=52.1&lon=5.2", nil)
+	meta = submitAndLoadMeta(t, router, req)
+	assert.Equal(t, today.Format(DateLayout), meta.Params.StartDate)
+	assert.Equal(t, today.AddDate(0, 0, 14).Format(DateLayout), meta.Params.EndDate)
+
+	w := postSimulate(router, withSite(`{"relative_start": "-7d", "start_date": "2025-11-01", "end_date": "2025-11-03"}`))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	env := decodeError(t, w)
+	require.Len(t, env.Fields, 1)
+	assert.Equal(t, "relative_start", env.Fields[0].Field)
+
+	w = postSimulate(router, withSite(`{"relative_start": "-2000d"}`))
+	require.Equal(t, http.StatusBadRequest, w.Code, "the resolved span is checked like any other")
+	assert.Equal(t, "end_date", decodeError(t, w).Fields[0].Field)
+}
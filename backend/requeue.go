@@ -0,0 +1,124 @@
+package main
+
+// backend/requeue.go
+//
+// POST /admin/requeue?status=error (admin only) retries every job in a failed
+// status after a worker fix: each match is enqueued again as a new job with
+// retry_of pointing back, exactly like POST /jobs/:job_id/retry but without
+// the per-client quota. Jobs are matched on when they reached the status
+// (updated_at), optionally narrowed with updated_after / updated_before.
+//
+// To keep a repeated call from requeuing the same failures twice, each run
+// records its updated_before in admin_requeue:<status>, and later runs only
+// consider jobs updated at or after that mark. Jobs that fail again after
+// being requeued are new failures and are picked up by the next run.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRequeueMarkPrefix keys admin_requeue:<status> -> the updated_before
+// (RFC 3339) of the last requeue of that status.
+const RedisRequeueMarkPrefix = "admin_requeue:"
+
+// requeueStatuses are the statuses POST /admin/requeue accepts.
+var requeueStatuses = map[string]bool{StatusError: true, StatusCancelled: true}
+
+// requeueResult summarizes one requeue run.
+type requeueResult struct {
+	Status         string    `json:"status"`
+	Requeued       int       `json:"requeued"`
+	AlreadyCovered int       `json:"already_covered"` // matched the status but were handled by an earlier run
+	Scanned        int       `json:"scanned"`
+	UpdatedAfter   time.Time `json:"updated_after"`
+	UpdatedBefore  time.Time `json:"updated_before"`
+}
+
+// requeueMark returns the updated_before of status's last requeue, or the zero time.
+func requeueMark(ctx context.Context, status string) (time.Time, error) {
+	v, err := rdb.Get(ctx, requeueMarkKey(status)).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// requeueJobs enqueues a retry of every job in res.Status updated within
+// [res.UpdatedAfter, res.UpdatedBefore), skipping those before mark.
+func requeueJobs(ctx context.Context, res *requeueResult, mark time.Time) error {
+	now := time.Now().UTC()
+	scanned, err := scanJobMetas(ctx, func(metas []JobMeta) error {
+		var retries []JobMeta
+		for _, m := range metas {
+			if m.Status != res.Status || m.UpdatedAt.Before(res.UpdatedAfter) || !m.UpdatedAt.Before(res.UpdatedBefore) {
+				continue
+			}
+			if m.UpdatedAt.Before(mark) {
+				res.AlreadyCovered++
+				continue
+			}
+			retries = append(retries, retryMeta(m, now))
+		}
+		if len(retries) == 0 {
+			return nil
+		}
+		if err := enqueueJobs(ctx, retries); err != nil {
+			return err
+		}
+		res.Requeued += len(retries)
+		return nil
+	})
+	res.Scanned = scanned
+	return err
+}
+
+func requeueJobsHandler(c *gin.Context) {
+	res := requeueResult{Status: c.DefaultQuery("status", StatusError), UpdatedBefore: time.Now().UTC()}
+	if !requeueStatuses[res.Status] {
+		respondInvalidParam(c, "status", "status must be error or cancelled")
+		return
+	}
+	for name, dst := range map[string]*time.Time{"updated_after": &res.UpdatedAfter, "updated_before": &res.UpdatedBefore} {
+		if v := c.Query(name); v != "" {
+			t, err := parseSearchTime(v)
+			if err != nil {
+				respondInvalidParam(c, name, name+" must be YYYY-MM-DD or an RFC 3339 timestamp")
+				return
+			}
+			*dst = t
+		}
+	}
+	// failures that have not happened yet must not be covered by the mark
+	if now := time.Now().UTC(); res.UpdatedBefore.After(now) {
+		res.UpdatedBefore = now
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), purgeTimeout)
+	defer cancel()
+
+	mark, err := requeueMark(ctx, res.Status)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	if err := requeueJobs(ctx, &res, mark); err != nil {
+		respondError(c, http.StatusInternalServerError, "requeue failed: "+err.Error(), gin.H{"requeued": res.Requeued})
+		return
+	}
+	// only move the mark forward: a run over an older window must not reopen newer ones
+	if res.UpdatedBefore.After(mark) {
+		if err := rdb.Set(ctx, requeueMarkKey(res.Status), res.UpdatedBefore.Format(time.RFC3339Nano), 0).Err(); err != nil {
+			respondError(c, http.StatusInternalServerError, "redis error: "+err.Error(), gin.H{"requeued": res.Requeued})
+			return
+		}
+	}
+	loggerFrom(c).Info("requeued jobs", "status", res.Status, "requeued", res.Requeued, "already_covered", res.AlreadyCovered)
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedFailedJob stores a tagged job that reached status age ago.
+func seedFailedJob(t *testing.T, ctx context.Context, jobID, status string, age time.Duration) {
+	t.Helper()
+	seedJobMeta(t, ctx, jobID, status, seededAgo(age), seededTags("trial"), func(m *JobMeta) {
+		m.Params, m.Priority = SimulationParams{Setpoint: floatPtr(16)}, PriorityNormal
+	})
+}
+
+func requeueRequest(router http.Handler, key, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/admin/requeue"+query, nil)
+	if key != "" {
+		req.Header.Set(APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequeueErroredJobs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withAuth(t, "user-key")
+	adminKeys = map[string]bool{"admin-key": true}
+	defer func() { adminKeys = map[string]bool{} }()
+
+	seedFailedJob(t, ctx, "failed-1", StatusError, time.Hour)
+	seedFailedJob(t, ctx, "failed-2", StatusError, 2*time.Hour)
+	seedFailedJob(t, ctx, "failed-old", StatusError, 72*time.Hour)
+	seedFailedJob(t, ctx, "finished", StatusDone, time.Hour)
+	seedFailedJob(t, ctx, "cancelled", StatusCancelled, time.Hour)
+
+	w := requeueRequest(router, "user-key", "?status=error")
+	assert.Equal(t, http.StatusForbidden, w.Code, "ordinary keys cannot requeue")
+
+	since := time.Now().UTC().Add(-24 * time.Hour).Format(time.RFC3339)
+	w = requeueRequest(router, "admin-key", "?status=error&updated_after="+since)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var res requeueResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, StatusError, res.Status)
+	assert.Equal(t, 2, res.Requeued)
+	assert.Equal(t, 5, res.Scanned)
+
+	// the new jobs are queued retries of the failed ones
+	payloads, err := rdb.LRange(ctx, queueFor(PriorityNormal), 0, -1).Result()
+	require.NoError(t, err)
+	require.Len(t, payloads, 2)
+	var retried []string
+	for _, p := range payloads {
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(p), &payload))
+		meta, err := metaStore.GetMeta(ctx, payload["job_id"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, meta.Status)
+		assert.Equal(t, []string{"trial"}, meta.Tags)
+		assert.Equal(t, 16.0, *meta.Params.Setpoint)
+		retried = append(retried, meta.RetryOf)
+	}
+	assert.ElementsMatch(t, []string{"failed-1", "failed-2"}, retried)
+
+	// a second run skips the failures the first one requeued
+	seedFailedJob(t, ctx, "failed-new", StatusError, 0)
+	w = requeueRequest(router, "admin-key", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, 1, res.Requeued, "only the job that failed since the first run")
+	assert.Equal(t, 3, res.AlreadyCovered)
+	assert.Equal(t, int64(3), rdb.LLen(ctx, queueFor(PriorityNormal)).Val())
+}
+
+func TestRequeueRejectsBadInput(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+
+	for _, query := range []string{"?status=done", "?status=running", "?updated_after=yesterday"} {
+		w := requeueRequest(router, "", query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+// backend/results.go
+//
+// Result export handlers. The worker stores results as JSON of the form
+// {"job_id", "created_at", "params", "summary", "data": [{...}, ...]} where
+// data is the simulated time series, one object per timestep. The JSON is
+// usually gzip-compressed; readers go through decodeResult, which also accepts
+// uncompressed results written before compression was introduced.
+// GET /results/:job_id serves JSON, CSV or NDJSON as negotiated from the
+// Accept header; /results/:job_id/csv remains for links that can't set headers.
+// The JSON envelope carries "units", the unit of each numeric result field.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// storedResult is the subset of the worker's result document needed for exports.
+type storedResult struct {
+	Data []json.RawMessage `json:"data"`
+}
+
+// SimulationResult is the result document the worker stores for a finished job.
+type SimulationResult struct {
+	JobID     string          `json:"job_id"`
+	CreatedAt string          `json:"created_at,omitempty"`
+	Params    json.RawMessage `json:"params,omitempty"` // SI parameters as run, echoed verbatim
+	Summary   *ResultSummary  `json:"summary,omitempty"`
+	Data      []ResultPoint   `json:"data"`
+}
+
+// ResultSummary holds the aggregates computed over the whole series. Each is
+// null when the corresponding column was missing from the simulation output.
+type ResultSummary struct {
+	TinMin               *float64 `json:"Tin_min"`
+	TinMax               *float64 `json:"Tin_max"`
+	TinMean              *float64 `json:"Tin_mean"`
+	HeaterTotalJ         *float64 `json:"Heater_total_J"`
+	HeatToThresholdMaxJ  *float64 `json:"Heat_to_threshold_max_J,omitempty"`
+	HeatToThresholdMeanJ *float64 `json:"Heat_to_threshold_mean_J,omitempty"`
+}
+
+// ResultPoint is one simulated timestep. Temperatures are in °C, heat flows in W
+// and Q_to_threshold in J; Tout is null when the weather source had a gap.
+type ResultPoint struct {
+	Datetime     string   `json:"datetime"`
+	Tout         *float64 `json:"Tout"`
+	Tin          *float64 `json:"Tin"`
+	TMass        *float64 `json:"T_mass,omitempty"`
+	TSoil        *float64 `json:"T_soil,omitempty"`
+	QHeater      *float64 `json:"Q_heater,omitempty"`
+	QLatent      *float64 `json:"Q_latent,omitempty"`
+	QToThreshold *float64 `json:"Q_to_threshold,omitempty"`
+}
+
+// resultUnits gives the unit of each numeric field of a result's data points
+// and summary; GET /results/:job_id returns it as "units" next to the result.
+var resultUnits = map[string]string{
+	"Tout":                     "°C",
+	"Tin":                      "°C",
+	"T_mass":                   "°C",
+	"T_soil":                   "°C",
+	"Q_heater":                 "W",
+	"Q_latent":                 "W",
+	"Q_to_threshold":           "J",
+	"Tin_min":                  "°C",
+	"Tin_max":                  "°C",
+	"Tin_mean":                 "°C",
+	"Heater_total_J":           "J",
+	"Heat_to_threshold_max_J":  "J",
+	"Heat_to_threshold_mean_J": "J",
+}
+
+// parseSimulationResult decodes a stored result and checks that it matches the
+// SimulationResult contract.
+func parseSimulationResult(res string) (*SimulationResult, error) {
+	var r SimulationResult
+	if err := json.Unmarshal([]byte(res), &r); err != nil {
+		return nil, fmt.Errorf("stored result does not match the result schema: %v", err)
+	}
+	if r.Data == nil {
+		return nil, fmt.Errorf("stored result has no data array")
+	}
+	for i, p := range r.Data {
+		if p.Datetime == "" {
+			return nil, fmt.Errorf("data point %d has no datetime", i)
+		}
+		if p.Tin == nil {
+			return nil, fmt.Errorf("data point %d has no Tin", i)
+		}
+	}
+	return &r, nil
+}
+
+// MinResolution is the smallest ?resolution= accepted: the first and last
+// points are always kept, leaving at least one bucket in between.
+const MinResolution = 3
+
+// parseResolution reads ?resolution= (0 when absent). It writes 400 and
+// returns ok=false when the value is malformed.
+func parseResolution(c *gin.Context) (n int, ok bool) {
+	v := c.Query("resolution")
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < MinResolution {
+		respondInvalidParam(c, "resolution", fmt.Sprintf("resolution must be an integer of at least %d", MinResolution))
+		return 0, false
+	}
+	return n, true
+}
+
+// Result pagination defaults for ?page= and ?page_size=.
+const (
+	DefaultResultPageSize = 500
+	MaxResultPageSize     = 5000
+)
+
+// resultView is the part of a result's series a request asked for: all of
+// it, a downsampled overview or one page.
+type resultView struct {
+	Resolution int // ?resolution=, 0 to keep every point
+	Page       int // ?page= (1-based), 0 when not paginating
+	PageSize   int // ?page_size=
+}
+
+// parseResultView reads ?resolution=, ?page= and ?page_size=. Giving either
+// page parameter turns pagination on. It writes 400 and returns ok=false on
+// malformed values or when resolution and pagination are combined.
+func parseResultView(c *gin.Context) (v resultView, ok bool) {
+	if v.Resolution, ok = parseResolution(c); !ok {
+		return v, false
+	}
+	page, size := c.Query("page"), c.Query("page_size")
+	if page == "" && size == "" {
+		return v, true
+	}
+	if v.Resolution > 0 {
+		respondInvalidParam(c, "resolution", "resolution cannot be combined with page or page_size")
+		return v, false
+	}
+	v.Page, v.PageSize = 1, DefaultResultPageSize
+	if page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			respondInvalidParam(c, "page", "page must be a positive integer")
+			return v, false
+		}
+		v.Page = n
+	}
+	if size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 1 {
+			respondInvalidParam(c, "page_size", "page_size must be a positive integer")
+			return v, false
+		}
+		v.PageSize = min(n, MaxResultPageSize)
+	}
+	return v, true
+}
+
+// apply trims r's series to the view, adding what was done to resp.
+func (v resultView) apply(resp gin.H, r *SimulationResult) {
+	total := len(r.Data)
+	switch {
+	case v.Resolution > 0 && total > v.Resolution:
+		// sparklines and overviews
+		resp["downsampled_from"] = total
+		r.Data = downsampleLTTB(r.Data, v.Resolution)
+	case v.Page > 0:
+		start := min((v.Page-1)*v.PageSize, total)
+		end := min(start+v.PageSize, total)
+		r.Data = r.Data[start:end]
+		resp["pagination"] = gin.H{
+			"page":         v.Page,
+			"page_size":    v.PageSize,
+			"total_points": total,
+			"total_pages":  (total + v.PageSize - 1) / v.PageSize,
+			"has_more":     end < total,
+		}
+	}
+}
+
+// downsampleLTTB reduces points to n using Largest-Triangle-Three-Buckets on
+// Tin: the first and last points are kept and from each of the n-2 buckets
+// in between the point forming the largest triangle with its neighbours'
+// picks. Unlike averaging this keeps real timesteps and their peaks, so the
+// min/max of the series survive. points is returned as is when n >= len(points).
+func downsampleLTTB(points []ResultPoint, n int) []ResultPoint {
+	if n >= len(points) || n < MinResolution {
+		return points
+	}
+	out := make([]ResultPoint, 0, n)
+	out = append(out, points[0])
+	size := float64(len(points)-2) / float64(n-2)
+	a := 0 // index of the previously selected point
+	for i := 0; i < n-2; i++ {
+		start := int(float64(i)*size) + 1
+		end := int(float64(i+1)*size) + 1
+
+		// average of the next bucket (or the last point) is the third vertex
+		nextEnd := min(int(float64(i+2)*size)+1, len(points))
+		var avgX, avgY float64
+		for j := end; j < nextEnd; j++ {
+			avgX += float64(j)
+			avgY += *points[j].Tin
+		}
+		if cnt := float64(nextEnd - end); cnt > 0 {
+			avgX /= cnt
+			avgY /= cnt
+		} else {
+			avgX, avgY = float64(len(points)-1), *points[len(points)-1].Tin
+		}
+
+		best, bestArea := start, -1.0
+		ax, ay := float64(a), *points[a].Tin
+		for j := start; j < end; j++ {
+			area := math.Abs((ax-avgX)*(*points[j].Tin-ay) - (ax-float64(j))*(avgY-ay))
+			if area > bestArea {
+				best, bestArea = j, area
+			}
+		}
+		out = append(out, points[best])
+		a = best
+	}
+	return append(out, points[len(points)-1])
+}
+
+// Media types GET /results/:job_id can answer with, chosen from the Accept header.
+const (
+	MIMECSV    = "text/csv"
+	MIMENDJSON = "application/x-ndjson"
+)
+
+// resultFormats are offered in this order, so JSON wins for */* or no Accept.
+var resultFormats = []string{gin.MIMEJSON, MIMECSV, MIMENDJSON}
+
+// resultSerializer writes a finished job's result; resp is the JSON envelope
+// (job_id, status, result and any extras) and r the result inside it.
+type resultSerializer func(c *gin.Context, resp gin.H, r *SimulationResult)
+
+// resultSerializers maps each of resultFormats to its writer.
+var resultSerializers = map[string]resultSerializer{
+	gin.MIMEJSON: func(c *gin.Context, resp gin.H, _ *SimulationResult) { respondJSONWithETag(c, resp) },
+	MIMECSV:      writeResultCSV,
+	MIMENDJSON:   writeResultNDJSON,
+}
+
+// negotiateResultFormat picks the first of resultFormats the client accepts,
+// writing 406 and returning ok=false when it accepts none of them.
+func negotiateResultFormat(c *gin.Context) (format string, ok bool) {
+	c.Writer.Header().Add("Vary", "Accept")
+	if format = c.NegotiateFormat(resultFormats...); format == "" {
+		respondError(c, http.StatusNotAcceptable, "unsupported Accept header", gin.H{"supported": resultFormats})
+		return "", false
+	}
+	return format, true
+}
+
+// writeResultCSV writes the series as CSV, one row per point.
+func writeResultCSV(c *gin.Context, resp gin.H, r *SimulationResult) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to encode result: "+err.Error())
+		return
+	}
+	rows, err := resultToCSV(string(b))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "unexpected result structure: "+err.Error())
+		return
+	}
+	jobID, _ := resp["job_id"].(string)
+	respondCSV(c, jobID, rows)
+}
+
+// writeResultNDJSON streams the series as newline-delimited JSON, one point per line.
+func writeResultNDJSON(c *gin.Context, _ gin.H, r *SimulationResult) {
+	c.Header("Content-Type", MIMENDJSON)
+	c.Status(http.StatusOK)
+	enc := json.NewEncoder(c.Writer)
+	for _, p := range r.Data {
+		if err := enc.Encode(p); err != nil {
+			return // client went away
+		}
+	}
+}
+
+// gzipMagic starts every gzip stream; JSON results never begin with these bytes.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decodeResult returns the JSON text of a stored result, decompressing it when
+// it is gzipped. Anything else is assumed to be a legacy plain JSON result.
+func decodeResult(stored string) (string, error) {
+	if !bytes.HasPrefix([]byte(stored), gzipMagic) {
+		return stored, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader([]byte(stored)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress result: %w", err)
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress result: %w", err)
+	}
+	return string(b), nil
+}
+
+// pollRetryAfter is how long a client polling a job of the given age should
+// wait before asking again: 1s for a fresh job, doubling each time the age
+// doubles past 8s (so roughly age/8), capped at maxPollInterval.
+func pollRetryAfter(age time.Duration) time.Duration {
+	d := time.Second
+	for d < maxPollInterval && age >= 8*d {
+		d *= 2
+	}
+	return min(d, maxPollInterval)
+}
+
+// setPollRetryAfter sets Retry-After for a pending job created at createdAt.
+func setPollRetryAfter(c *gin.Context, createdAt time.Time) {
+	d := pollRetryAfter(time.Since(createdAt))
+	c.Header("Retry-After", strconv.Itoa(int(d/time.Second)))
+}
+
+// encodeResult gzips a result for storage, as the worker does by default.
+func encodeResult(result []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(result); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// loadStoredResult returns the decoded result for jobID. When there is no result it
+// writes 404 (unknown job) or 409 with the current status and returns ok=false;
+// a result that does not decompress is the worker's fault and gets 502.
+func loadStoredResult(c *gin.Context, ctx context.Context, jobID string) (string, bool) {
+	res, err := rdb.Get(ctx, jobResultKey(jobID)).Result()
+	if err == nil {
+		if res, err = decodeResult(res); err != nil {
+			respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": jobID})
+			return "", false
+		}
+		return res, true
+	}
+	if err != redis.Nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return "", false
+	}
+	metaStr, err := rdb.Get(ctx, jobMetaKey(jobID)).Result()
+	if err == redis.Nil {
+		respondJobMissing(c, ctx, jobID, "no result or job not found")
+		return "", false
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return "", false
+	}
+	var meta JobMeta
+	_ = json.Unmarshal([]byte(metaStr), &meta)
+	respondError(c, http.StatusConflict, "result not ready", gin.H{"job_id": jobID, "status": meta.Status})
+	return "", false
+}
+
+// objectKeys returns the keys of a JSON object in document order.
+func objectKeys(raw json.RawMessage) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, tok.(string))
+		// skip the value
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// csvValue renders a decoded JSON scalar for a CSV cell.
+func csvValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+}
+
+// decodeResultRows returns the data points of a stored result and its
+// columns, the keys of the first data point in order. An empty series has no
+// columns.
+func decodeResultRows(res string) ([]string, []map[string]interface{}, error) {
+	var parsed storedResult
+	if err := json.Unmarshal([]byte(res), &parsed); err != nil {
+		return nil, nil, fmt.Errorf("stored result is not a JSON object: %v", err)
+	}
+	if parsed.Data == nil {
+		return nil, nil, fmt.Errorf("stored result has no data array")
+	}
+	if len(parsed.Data) == 0 {
+		return nil, nil, nil
+	}
+	columns, err := objectKeys(parsed.Data[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("data point 0 is not an object: %v", err)
+	}
+
+	points := make([]map[string]interface{}, len(parsed.Data))
+	for i, raw := range parsed.Data {
+		if err := json.Unmarshal(raw, &points[i]); err != nil || points[i] == nil {
+			return nil, nil, fmt.Errorf("data point %d is not an object", i)
+		}
+	}
+	return columns, points, nil
+}
+
+// resultToCSV converts the data series to CSV rows, deriving the columns from
+// the keys of the first data point.
+func resultToCSV(res string) ([][]string, error) {
+	columns, points, err := decodeResultRows(res)
+	if err != nil || len(points) == 0 {
+		return nil, err
+	}
+	rows := make([][]string, 0, len(points)+1)
+	rows = append(rows, columns)
+	for _, point := range points {
+		row := make([]string, len(columns))
+		for j, col := range columns {
+			row[j] = csvValue(point[col])
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// getResultsCSVHandler serves a job's time series as a CSV attachment.
+func getResultsCSVHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	res, ok := loadStoredResult(c, ctx, jobID)
+	if !ok {
+		return
+	}
+	rows, err := resultToCSV(res)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "unexpected result structure: "+err.Error())
+		return
+	}
+	respondCSV(c, jobID, rows)
+}
+
+// respondCSV writes rows as a CSV attachment named after the job.
+func respondCSV(c *gin.Context, jobID string, rows [][]string) {
+	c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+".csv"))
+	c.Status(http.StatusOK)
+	w := csv.NewWriter(c.Writer)
+	_ = w.WriteAll(rows)
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleResult = `{
+	"job_id": "csv-job",
+	"summary": {"Tin_min": 10.5},
+	"data": [
+		{"datetime": "2025-11-01T00:00:00", "Tin": 12.5, "Tout": 3, "Q_heater": 0},
+		{"datetime": "2025-11-01T01:00:00", "Tin": 12.1, "Tout": 2.5, "Q_heater": 1500.25},
+		{"datetime": "2025-11-01T02:00:00", "Tin": 11.9, "Tout": null, "Q_heater": 3000}
+	]
+}`
+
+func TestGetResultsCSV(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"csv-job", sampleResult, DefaultResultTTL)
+
+	req, _ := http.NewRequest("GET", "/results/csv-job/csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4) // header + 3 points
+	assert.Equal(t, []string{"datetime", "Tin", "Tout", "Q_heater"}, records[0])
+	assert.Equal(t, []string{"2025-11-01T01:00:00", "12.1", "2.5", "1500.25"}, records[2])
+	assert.Equal(t, "", records[3][2])
+}
+
+func TestGetResultsCSVNotReady(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "pending-job", StatusRunning)
+
+	req, _ := http.NewRequest("GET", "/results/pending-job/csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), StatusRunning)
+
+	req, _ = http.NewRequest("GET", "/results/unknown-job/csv", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetResultsCSVMalformed(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"bad-job", `{"summary": {}}`, DefaultResultTTL)
+
+	req, _ := http.NewRequest("GET", "/results/bad-job/csv", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "no data array")
+}
+
+func TestResultToCSVRejectsNonObjectPoints(t *testing.T) {
+	_, err := resultToCSV(`{"data": [1, 2]}`)
+	assert.Error(t, err)
+
+	rows, err := resultToCSV(`{"data": []}`)
+	assert.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+// gzipString compresses s the way the worker stores results.
+func gzipString(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.String()
+}
+
+func TestDecodeResult(t *testing.T) {
+	compressed := gzipString(t, sampleResult)
+	assert.NotEqual(t, sampleResult, compressed)
+
+	got, err := decodeResult(compressed)
+	require.NoError(t, err)
+	assert.Equal(t, sampleResult, got)
+
+	// legacy results were stored as plain JSON
+	got, err = decodeResult(sampleResult)
+	require.NoError(t, err)
+	assert.Equal(t, sampleResult, got)
+
+	_, err = decodeResult(compressed[:12])
+	assert.Error(t, err)
+}
+
+func TestGetResultsDecompresses(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"gz-job", gzipString(t, sampleResult), DefaultResultTTL)
+	rdb.Set(ctx, RedisResultsPrefix+"legacy-job", sampleResult, DefaultResultTTL)
+
+	for _, jobID := range []string{"gz-job", "legacy-job"} {
+		t.Run(jobID, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/results/"+jobID, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var response struct {
+				Status string `json:"status"`
+				Result struct {
+					JobID string            `json:"job_id"`
+					Data  []json.RawMessage `json:"data"`
+				} `json:"result"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, StatusDone, response.Status)
+			assert.Equal(t, "csv-job", response.Result.JobID)
+			assert.Len(t, response.Result.Data, 3)
+
+			req, _ = http.NewRequest("GET", "/results/"+jobID+"/csv", nil)
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+			records, err := csv.NewReader(w.Body).ReadAll()
+			require.NoError(t, err)
+			assert.Len(t, records, 4)
+		})
+	}
+}
+
+// numericJSONFields returns the JSON names of t's float fields.
+func numericJSONFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type != reflect.TypeOf(0.0) && f.Type != reflect.TypeOf((*float64)(nil)) {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestResultUnitsCoverResultFields(t *testing.T) {
+	fields := append(numericJSONFields(reflect.TypeOf(ResultPoint{})), numericJSONFields(reflect.TypeOf(ResultSummary{}))...)
+	for _, name := range fields {
+		assert.Contains(t, resultUnits, name)
+	}
+	assert.Len(t, resultUnits, len(fields), "no units for fields that do not exist")
+}
+
+func TestGetResultsUnits(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"units-job", sampleResult, DefaultResultTTL)
+
+	req, _ := http.NewRequest("GET", "/results/units-job", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Units map[string]string `json:"units"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, map[string]string{
+		"Tout":                     "°C",
+		"Tin":                      "°C",
+		"T_mass":                   "°C",
+		"T_soil":                   "°C",
+		"Q_heater":                 "W",
+		"Q_latent":                 "W",
+		"Q_to_threshold":           "J",
+		"Tin_min":                  "°C",
+		"Tin_max":                  "°C",
+		"Tin_mean":                 "°C",
+		"Heater_total_J":           "J",
+		"Heat_to_threshold_max_J":  "J",
+		"Heat_to_threshold_mean_J": "J",
+	}, response.Units)
+}
+
+func TestParseSimulationResult(t *testing.T) {
+	r, err := parseSimulationResult(sampleResult)
+	require.NoError(t, err)
+	assert.Equal(t, "csv-job", r.JobID)
+	require.Len(t, r.Data, 3)
+	assert.Equal(t, "2025-11-01T01:00:00", r.Data[1].Datetime)
+	assert.Equal(t, 1500.25, *r.Data[1].QHeater)
+	assert.Nil(t, r.Data[2].Tout)
+	assert.Equal(t, 10.5, *r.Summary.TinMin)
+
+	for name, bad := range map[string]string{
+		"not json":         `not json`,
+		"no data":          `{"summary": {}}`,
+		"data not array":   `{"data": {"Tin": 1}}`,
+		"string Tin":       `{"data": [{"datetime": "2025-11-01T00:00:00", "Tin": "warm"}]}`,
+		"missing datetime": `{"data": [{"Tin": 12.5}]}`,
+		"missing Tin":      `{"data": [{"datetime": "2025-11-01T00:00:00", "Tout": 3}]}`,
+	} {
+		_, err := parseSimulationResult(bad)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestGetResultsMalformed(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	rdb.Set(ctx, RedisResultsPrefix+"bad-job", `{"job_id": "bad-job", "data": [{"datetime": "2025-11-01T00:00:00", "Tin": "warm"}]}`, DefaultResultTTL)
+
+	req, _ := http.NewRequest("GET", "/results/bad-job", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Contains(t, response["error"].(map[string]interface{})["message"], "malformed result")
+	assert.Equal(t, "bad-job", response["job_id"])
+	assert.NotContains(t, response, "result")
+}
+
+// syntheticSeries is a daily temperature cycle with one sharp cold snap, the
+// kind of extreme a sparkline must not smooth away.
+func syntheticSeries(n int) []ResultPoint {
+	start := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	points := make([]ResultPoint, n)
+	for i := range points {
+		tin := 15 + 5*math.Sin(2*math.Pi*float64(i)/24)
+		if i == n/3 {
+			tin = -8
+		}
+		points[i] = ResultPoint{Datetime: start.Add(time.Duration(i) * time.Hour).Format("2006-01-02T15:04:05"), Tin: floatPtr(tin)}
+	}
+	return points
+}
+
+func tinExtents(points []ResultPoint) (lo, hi float64) {
+	lo, hi = math.Inf(1), math.Inf(-1)
+	for _, p := range points {
+		lo, hi = math.Min(lo, *p.Tin), math.Max(hi, *p.Tin)
+	}
+	return lo, hi
+}
+
+func TestDownsampleLTTB(t *testing.T) {
+	points := syntheticSeries(2000)
+	lo, hi := tinExtents(points)
+
+	for _, n := range []int{3, 50, 200, 1999} {
+		got := downsampleLTTB(points, n)
+		require.Len(t, got, n)
+		assert.Equal(t, points[0], got[0])
+		assert.Equal(t, points[len(points)-1], got[n-1])
+		for i := 1; i < n; i++ {
+			assert.Less(t, got[i-1].Datetime, got[i].Datetime, "points stay in time order")
+		}
+		if n >= 50 {
+			gotLo, gotHi := tinExtents(got)
+			assert.Equal(t, lo, gotLo, "cold snap kept at n=%d", n)
+			assert.InDelta(t, hi, gotHi, 0.1, "daily peak kept at n=%d", n)
+		}
+	}
+
+	assert.Len(t, downsampleLTTB(points, 2000), 2000)
+	assert.Len(t, downsampleLTTB(points, 5000), 2000)
+}
+
+func TestGetResultsResolution(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	stored, err := json.Marshal(SimulationResult{JobID: "long-job", Data: syntheticSeries(1000)})
+	require.NoError(t, err)
+	rdb.Set(ctx, RedisResultsPrefix+"long-job", stored, DefaultResultTTL)
+
+	get := func(query string) (int, map[string]interface{}, []interface{}) {
+		req, _ := http.NewRequest("GET", "/results/long-job"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		result, _ := response["result"].(map[string]interface{})
+		data, _ := result["data"].([]interface{})
+		return w.Code, response, data
+	}
+
+	code, response, data := get("?resolution=200")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, data, 200)
+	assert.Equal(t, float64(1000), response["downsampled_from"])
+
+	code, response, data = get("?resolution=1000")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, data, 1000)
+	assert.NotContains(t, response, "downsampled_from")
+
+	code, _, data = get("")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, data, 1000)
+
+	for _, bad := range []string{"2", "0", "many"} {
+		code, _, _ = get("?resolution=" + bad)
+		assert.Equal(t, http.StatusBadRequest, code, bad)
+	}
+}
+
+func TestGetResultsContentNegotiation(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "neg-job", StatusDone)
+	rdb.Set(ctx, RedisResultsPrefix+"neg-job", gzipString(t, sampleResult), DefaultResultTTL)
+	seedJobMeta(t, ctx, "neg-pending", StatusRunning)
+
+	get := func(jobID, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/results/"+jobID, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, accept := range []string{"", "application/json", "*/*", "text/html, application/*;q=0.8"} {
+		t.Run("json "+accept, func(t *testing.T) {
+			w := get("neg-job", accept)
+			require.Equal(t, http.StatusOK, w.Code)
+			assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+			assert.Contains(t, w.Header().Values("Vary"), "Accept")
+			var response struct {
+				Result SimulationResult `json:"result"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Len(t, response.Result.Data, 3)
+		})
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		w := get("neg-job", "text/csv")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/csv")
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, []string{"datetime", "Tout", "Tin", "Q_heater"}, records[0])
+		assert.Equal(t, []string{"2025-11-01T01:00:00", "2.5", "12.1", "1500.25"}, records[2])
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		w := get("neg-job", "application/x-ndjson")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+		require.Len(t, lines, 3)
+		var p ResultPoint
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &p))
+		assert.Equal(t, "2025-11-01T00:00:00", p.Datetime)
+		assert.Equal(t, 12.5, *p.Tin)
+	})
+
+	t.Run("ndjson downsampled", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/results/neg-job?resolution=3", nil)
+		req.Header.Set("Accept", "application/x-ndjson")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 3, strings.Count(w.Body.String(), "\n"))
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		w := get("neg-job", "application/xml")
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.ElementsMatch(t, []interface{}{"application/json", "text/csv", "application/x-ndjson"}, response["supported"])
+	})
+
+	t.Run("csv while pending", func(t *testing.T) {
+		w := get("neg-pending", "text/csv")
+		assert.Equal(t, http.StatusConflict, w.Code)
+		w = get("neg-pending", "application/json")
+		assert.Equal(t, http.StatusOK, w.Code, "JSON clients still get the status")
+	})
+}
+
+func TestPollRetryAfter(t *testing.T) {
+	for _, tt := range []struct {
+		age  time.Duration
+		want time.Duration
+	}{
+		{0, time.Second},
+		{7 * time.Second, time.Second},
+		{8 * time.Second, 2 * time.Second},
+		{40 * time.Second, 8 * time.Second},
+		{2 * time.Minute, 16 * time.Second},
+		{time.Hour, DefaultMaxPollInterval},
+	} {
+		assert.Equal(t, tt.want, pollRetryAfter(tt.age), tt.age.String())
+	}
+}
+
+func TestGetResultsPendingRetryAfter(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	retryAfter := func(jobID string, age time.Duration) int {
+		meta := seedJobMeta(t, ctx, jobID, StatusRunning)
+		meta.CreatedAt = meta.CreatedAt.Add(-age)
+		b, _ := json.Marshal(meta)
+		rdb.Set(ctx, RedisJobMetaPrefix+jobID, b, DefaultResultTTL)
+
+		req, _ := http.NewRequest("GET", "/results/"+jobID, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		n, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		return n
+	}
+	fresh := retryAfter("fresh-job", 0)
+	old := retryAfter("old-job", 5*time.Minute)
+	assert.Equal(t, 1, fresh)
+	assert.Greater(t, old, fresh)
+	assert.LessOrEqual(t, old, int(DefaultMaxPollInterval/time.Second))
+
+	// finished jobs have nothing to poll for
+	seedJobMeta(t, ctx, "failed-job", StatusError)
+	req, _ := http.NewRequest("GET", "/results/failed-job", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Retry-After"))
+}
+
+func TestGetResultsPagination(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	series := syntheticSeries(2160) // 90 days hourly
+	stored, err := json.Marshal(SimulationResult{JobID: "paged-job", Data: series})
+	require.NoError(t, err)
+	rdb.Set(ctx, RedisResultsPrefix+"paged-job", stored, DefaultResultTTL)
+
+	get := func(query string) (int, map[string]interface{}, []interface{}) {
+		req, _ := http.NewRequest("GET", "/results/paged-job"+query, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		result, _ := response["result"].(map[string]interface{})
+		data, _ := result["data"].([]interface{})
+		return w.Code, response, data
+	}
+
+	code, response, data := get("?page=2&page_size=1000")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, data, 1000)
+	assert.Equal(t, series[1000].Datetime, data[0].(map[string]interface{})["datetime"])
+	assert.Equal(t, map[string]interface{}{
+		"page": float64(2), "page_size": float64(1000), "total_points": float64(2160), "total_pages": float64(3), "has_more": true,
+	}, response["pagination"])
+
+	code, response, data = get("?page=3&page_size=1000")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, data, 160)
+	assert.Equal(t, series[2159].Datetime, data[159].(map[string]interface{})["datetime"])
+	assert.Equal(t, false, response["pagination"].(map[string]interface{})["has_more"])
+
+	// page alone uses the default page size
+	_, response, data = get("?page=1")
+	assert.Len(t, data, DefaultResultPageSize)
+	assert.Equal(t, true, response["pagination"].(map[string]interface{})["has_more"])
+
+	_, _, data = get("?page=9&page_size=1000")
+	assert.Empty(t, data, "past the end")
+
+	// without pagination parameters the whole series comes back
+	_, response, data = get("")
+	assert.Len(t, data, 2160)
+	assert.NotContains(t, response, "pagination")
+
+	for _, bad := range []string{"?page=0", "?page=x", "?page_size=-1", "?page=1&resolution=100"} {
+		code, _, _ = get(bad)
+		assert.Equal(t, http.StatusBadRequest, code, bad)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+// backend/reuse.go
+//
+// Dedupe of identical runs. Every enqueued job records
+// params_hash:<hash> -> job_id, where the hash covers only the fields that
+// change the simulation's output. /simulate?reuse=true returns a finished job
+// with the same hash instead of running it again.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisParamsHashPrefix keys params_hash:<hash> -> most recent job id with those physics.
+const RedisParamsHashPrefix = "params_hash:"
+
+// physicsHash hashes the resolved params with the non-physics fields cleared.
+// json.Marshal emits struct fields in declaration order, so the encoding is
+// canonical for a given set of values.
+func physicsHash(p SimulationParams) string {
+	p.Preset = ""            // its values are already merged in
+	p.Units = ""             // params are converted to SI before hashing
+	p.Priority = ""          // scheduling only
+	p.Queue = ""             // scheduling only
+	p.Tags = nil             // labels only
+	p.GreenhouseID = ""      // scheduling only
+	p.ResultTTLSeconds = nil // storage only
+	p.VentilationRate = nil  // folded into ACH by applyDefaults; the worker ignores it
+	p.Seed = nil             // picked at random when omitted, so it would never match
+	b, _ := json.Marshal(p)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// findReusableJob returns the job recorded for hash if it finished successfully
+// and its result is still stored.
+func findReusableJob(ctx context.Context, hash string) (JobMeta, bool, error) {
+	jobID, err := rdb.Get(ctx, paramsHashKey(hash)).Result()
+	if err == redis.Nil {
+		return JobMeta{}, false, nil
+	} else if err != nil {
+		return JobMeta{}, false, err
+	}
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		return JobMeta{}, false, nil
+	} else if err != nil {
+		return JobMeta{}, false, err
+	}
+	if meta.Status != StatusDone {
+		return JobMeta{}, false, nil
+	}
+	n, err := rdb.Exists(ctx, jobResultKey(jobID)).Result()
+	if err != nil {
+		return JobMeta{}, false, err
+	}
+	return meta, n > 0, nil
+}
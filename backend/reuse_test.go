@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// submitReuse posts body to path and decodes the response.
+func submitReuse(t *testing.T, router http.Handler, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+// markDone finishes a submitted job the way the worker would.
+func markDone(t *testing.T, ctx context.Context, jobID string) {
+	t.Helper()
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	require.NoError(t, err)
+	meta.Status = StatusDone
+	b, _ := json.Marshal(meta)
+	require.NoError(t, rdb.Set(ctx, RedisJobMetaPrefix+jobID, b, 0).Err())
+	require.NoError(t, rdb.Set(ctx, RedisResultsPrefix+jobID, sampleResult, 0).Err())
+}
+
+func TestPhysicsHash(t *testing.T) {
+	base := SimulationParams{A_glass: floatPtr(80)}
+	applyDefaults(&base)
+
+	same := base
+	same.Preset = "small_hobby"
+	ttl := int64(3600)
+	same.ResultTTLSeconds = &ttl
+	assert.Equal(t, physicsHash(base), physicsHash(same), "non-physics fields are ignored")
+
+	other := base
+	other.Setpoint = floatPtr(*base.Setpoint + 1)
+	assert.NotEqual(t, physicsHash(base), physicsHash(other))
+}
+
+func TestSubmitReuse(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	body := withSite(`{"A_glass": 80, "setpoint": 14}`)
+	code, first := submitReuse(t, router, "/simulate", body)
+	require.Equal(t, http.StatusAccepted, code)
+	firstID := first["job_id"].(string)
+
+	t.Run("miss while still queued", func(t *testing.T) {
+		code, response := submitReuse(t, router, "/simulate?reuse=true", body)
+		assert.Equal(t, http.StatusAccepted, code)
+		assert.NotEqual(t, firstID, response["job_id"])
+		assert.Nil(t, response["reused"])
+	})
+
+	// the second submission now owns the hash; finish it
+	jobID, err := rdb.Get(ctx, RedisParamsHashPrefix+physicsHash(mustResolve(t, body))).Result()
+	require.NoError(t, err)
+	markDone(t, ctx, jobID)
+
+	t.Run("hit", func(t *testing.T) {
+		queued := rdb.LLen(ctx, RedisJobsList).Val()
+		code, response := submitReuse(t, router, "/simulate?reuse=true", withSite(`{"setpoint": 14, "A_glass": 80, "result_ttl_seconds": 60}`))
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, jobID, response["job_id"])
+		assert.Equal(t, StatusDone, response["status"])
+		assert.Equal(t, true, response["reused"])
+		assert.Contains(t, response["links"], "result")
+		assert.Equal(t, queued, rdb.LLen(ctx, RedisJobsList).Val(), "nothing enqueued")
+	})
+
+	t.Run("miss on different physics", func(t *testing.T) {
+		code, response := submitReuse(t, router, "/simulate?reuse=true", withSite(`{"A_glass": 80, "setpoint": 15}`))
+		assert.Equal(t, http.StatusAccepted, code)
+		assert.NotEqual(t, jobID, response["job_id"])
+	})
+
+	t.Run("reuse disabled", func(t *testing.T) {
+		code, response := submitReuse(t, router, "/simulate", body)
+		assert.Equal(t, http.StatusAccepted, code)
+		assert.NotEqual(t, jobID, response["job_id"])
+	})
+}
+
+// mustResolve applies presets and defaults to a JSON parameter set.
+func mustResolve(t *testing.T, body string) SimulationParams {
+	t.Helper()
+	var p SimulationParams
+	require.NoError(t, json.Unmarshal([]byte(body), &p))
+	require.NoError(t, applyPreset(&p))
+	applyDefaults(&p)
+	return p
+}
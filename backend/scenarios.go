@@ -0,0 +1,262 @@
+package main
+
+// backend/scenarios.go
+//
+// Scenario sets: several labeled parameter sets (e.g. "current climate" vs
+// "+2C") submitted as one unit. POST /scenarios enqueues one job per scenario,
+// all-or-nothing like a sweep, and stores scenario:<id> linking each label to
+// its job. GET /scenarios/:scenario_id aggregates the children: the set is
+// pending until every child is terminal, then done if all of them succeeded
+// and error otherwise.
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisScenarioPrefix is the key prefix for scenario sets: scenario:<id> -> ScenarioSet JSON.
+const RedisScenarioPrefix = "scenario:"
+
+// MaxScenariosPerSet caps the number of jobs one scenario set may create.
+const MaxScenariosPerSet = 20
+
+// Aggregate scenario set statuses
+const (
+	ScenarioPending = "pending"
+	ScenarioDone    = "done"
+	ScenarioError   = "error"
+)
+
+// scenarioInput is one labeled parameter set in POST /scenarios.
+type scenarioInput struct {
+	Label  string           `json:"label"`
+	Params SimulationParams `json:"params"`
+}
+
+// scenarioRequest is the body of POST /scenarios.
+type scenarioRequest struct {
+	Name      string          `json:"name"`
+	Scenarios []scenarioInput `json:"scenarios"`
+}
+
+// scenarioMember links a label to the job enqueued for it.
+type scenarioMember struct {
+	Label string `json:"label"`
+	JobID string `json:"job_id"`
+}
+
+// ScenarioSet is stored at scenario:<id>; members keep the submitted order.
+type ScenarioSet struct {
+	ScenarioID string           `json:"scenario_id"`
+	Name       string           `json:"name"`
+	CreatedAt  time.Time        `json:"created_at"`
+	Members    []scenarioMember `json:"members"`
+}
+
+// scenarioChild is one member's current state in GET /scenarios/:scenario_id.
+type scenarioChild struct {
+	Label  string          `json:"label"`
+	JobID  string          `json:"job_id"`
+	Status string          `json:"status"`
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"` // set once the job is done
+}
+
+// validateScenarioRequest checks the name and labels; params are validated per job.
+func validateScenarioRequest(req scenarioRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(req.Scenarios) == 0 {
+		return fmt.Errorf("scenarios must list at least one labeled parameter set")
+	}
+	if len(req.Scenarios) > MaxScenariosPerSet {
+		return fmt.Errorf("too many scenarios (max %d)", MaxScenariosPerSet)
+	}
+	seen := make(map[string]bool, len(req.Scenarios))
+	for i, s := range req.Scenarios {
+		if s.Label == "" {
+			return fmt.Errorf("scenario %d has no label", i)
+		}
+		if seen[s.Label] {
+			return fmt.Errorf("duplicate scenario label %q", s.Label)
+		}
+		seen[s.Label] = true
+	}
+	return nil
+}
+
+// scenarioStatus aggregates child statuses.
+func scenarioStatus(children []scenarioChild) string {
+	status := ScenarioDone
+	for _, ch := range children {
+		switch {
+		case ch.Status == StatusDone:
+		case isTerminalStatus(ch.Status) || ch.Status == "not_found":
+			status = ScenarioError
+		default:
+			return ScenarioPending
+		}
+	}
+	return status
+}
+
+func submitScenarioHandler(c *gin.Context) {
+	var req scenarioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, maxBatchBodyBytes)
+			return
+		}
+		respondError(c, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if err := validateScenarioRequest(req); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	set := ScenarioSet{ScenarioID: uuid.NewString(), Name: req.Name, CreatedAt: time.Now().UTC()}
+	metas := make([]JobMeta, 0, len(req.Scenarios))
+	itemErrors := []batchItemError{}
+	ttl := time.Duration(0)
+	allowExtreme := c.Query("allow_extreme") == "true"
+	for i, s := range req.Scenarios {
+		params := s.Params
+		units, err := toSI(&params)
+		if err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		if err := applyPreset(&params); err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		applyDefaults(&params)
+		if _, err := validateParams(&params, allowExtreme); err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		meta := newJobMeta(params, set.CreatedAt)
+		meta.BatchID = set.ScenarioID
+		meta.Units = units
+		metas = append(metas, meta)
+		set.Members = append(set.Members, scenarioMember{Label: s.Label, JobID: meta.JobID})
+		ttl = max(ttl, metaTTL(meta))
+	}
+	if len(itemErrors) > 0 {
+		respondAPIError(c, http.StatusBadRequest, apiError{Code: CodeInvalidParam, Message: "scenario set rejected: invalid parameter sets"}, gin.H{"errors": itemErrors})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	if !claimJobQuota(c, ctx, metas) {
+		return
+	}
+	// the set expires together with its longest-lived child
+	setBytes, err := json.Marshal(set)
+	if err != nil {
+		releaseJobQuota(c, ctx, metas)
+		respondError(c, http.StatusInternalServerError, "failed to encode scenario set")
+		return
+	}
+	if err := rdb.Set(ctx, scenarioKey(set.ScenarioID), setBytes, ttl).Err(); err != nil {
+		releaseJobQuota(c, ctx, metas)
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	if err := enqueueJobs(ctx, metas); err != nil {
+		rdb.Del(ctx, scenarioKey(set.ScenarioID))
+		releaseJobQuota(c, ctx, metas)
+		respondError(c, http.StatusInternalServerError, "failed to enqueue scenario set: "+err.Error())
+		return
+	}
+
+	children := make([]scenarioChild, len(set.Members))
+	for i, m := range set.Members {
+		children[i] = scenarioChild{Label: m.Label, JobID: m.JobID, Status: StatusQueued}
+	}
+	c.Header("Location", apiURL("/scenarios/"+set.ScenarioID))
+	c.JSON(http.StatusAccepted, gin.H{
+		"scenario_id": set.ScenarioID,
+		"name":        set.Name,
+		"status":      ScenarioPending,
+		"jobs":        children,
+	})
+}
+
+// getScenarioHandler returns the set's aggregate status and every child's
+// status, with the result of each finished child inlined.
+func getScenarioHandler(c *gin.Context) {
+	scenarioID := c.Param("scenario_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	raw, err := rdb.Get(ctx, scenarioKey(scenarioID)).Result()
+	if err == redis.Nil {
+		respondError(c, http.StatusNotFound, "scenario set not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	var set ScenarioSet
+	if err := json.Unmarshal([]byte(raw), &set); err != nil {
+		respondError(c, http.StatusInternalServerError, "failed to parse scenario set")
+		return
+	}
+
+	ids := make([]string, len(set.Members))
+	for i, m := range set.Members {
+		ids[i] = m.JobID
+	}
+	metas, err := loadMetas(ctx, ids)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	byID := make(map[string]JobMeta, len(metas))
+	for _, m := range metas {
+		byID[m.JobID] = m
+	}
+
+	children := make([]scenarioChild, len(set.Members))
+	for i, m := range set.Members {
+		child := scenarioChild{Label: m.Label, JobID: m.JobID, Status: "not_found"}
+		if meta, ok := byID[m.JobID]; ok {
+			child.Status, child.Error = meta.Status, meta.Error
+		}
+		if child.Status == StatusDone {
+			res, err := rdb.Get(ctx, jobResultKey(m.JobID)).Result()
+			if err != nil && err != redis.Nil {
+				respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+				return
+			}
+			if err == nil {
+				doc, err := decodeResult(res)
+				if err != nil || !json.Valid([]byte(doc)) {
+					respondError(c, http.StatusInternalServerError, "failed to parse result", gin.H{"job_id": m.JobID})
+					return
+				}
+				child.Result = json.RawMessage(doc)
+			}
+		}
+		children[i] = child
+	}
+
+	respondJSONWithETag(c, gin.H{
+		"scenario_id": set.ScenarioID,
+		"name":        set.Name,
+		"created_at":  set.CreatedAt,
+		"status":      scenarioStatus(children),
+		"jobs":        children,
+	})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scenarioResponse struct {
+	ScenarioID string          `json:"scenario_id"`
+	Name       string          `json:"name"`
+	Status     string          `json:"status"`
+	Jobs       []scenarioChild `json:"jobs"`
+	Error      apiError        `json:"error"`
+}
+
+func postScenarios(t *testing.T, router *gin.Engine, body string) (int, scenarioResponse) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/scenarios", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp scenarioResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func getScenario(t *testing.T, router *gin.Engine, id string) (int, scenarioResponse) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/scenarios/"+id, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp scenarioResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+const climateScenarios = `{
+	"name": "climate",
+	"scenarios": [
+		{"label": "current", "params": {"T_init": 12, "lat": 52.1, "lon": 5.2}},
+		{"label": "+2C", "params": {"T_init": 14, "lat": 52.1, "lon": 5.2}}
+	]
+}`
+
+func TestSubmitScenarios(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, resp := postScenarios(t, router, climateScenarios)
+	require.Equal(t, http.StatusAccepted, code)
+	assert.NotEmpty(t, resp.ScenarioID)
+	assert.Equal(t, "climate", resp.Name)
+	assert.Equal(t, ScenarioPending, resp.Status)
+	require.Len(t, resp.Jobs, 2)
+	assert.Equal(t, "current", resp.Jobs[0].Label)
+	assert.Equal(t, "+2C", resp.Jobs[1].Label)
+
+	for i, want := range []float64{12, 14} {
+		meta, err := redisMetaStore{}.GetMeta(ctx, resp.Jobs[i].JobID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, meta.Status)
+		assert.Equal(t, resp.ScenarioID, meta.BatchID)
+		assert.Equal(t, want, *meta.Params.T_init)
+	}
+	n, err := rdb.LLen(ctx, RedisJobsList).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestSubmitScenariosRejectsInvalid(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing name", `{"scenarios": [{"label": "a", "params": {}}]}`},
+		{"no scenarios", `{"name": "empty", "scenarios": []}`},
+		{"missing label", `{"name": "x", "scenarios": [{"params": {}}]}`},
+		{"duplicate label", `{"name": "x", "scenarios": [{"label": "a", "params": {}}, {"label": "a", "params": {}}]}`},
+		{"invalid params", `{"name": "x", "scenarios": [{"label": "a", "params": {}}, {"label": "b", "params": {"ACH": -1}}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rdb.FlushDB(ctx)
+			code, resp := postScenarios(t, router, tt.body)
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.NotEmpty(t, resp.Error.Message)
+			n, err := rdb.LLen(ctx, RedisJobsList).Result()
+			require.NoError(t, err)
+			assert.Zero(t, n, "nothing may be enqueued")
+		})
+	}
+}
+
+func TestGetScenarioAggregation(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, submitted := postScenarios(t, router, climateScenarios)
+	require.Equal(t, http.StatusAccepted, code)
+	current, warmer := submitted.Jobs[0].JobID, submitted.Jobs[1].JobID
+
+	// partial completion: one child done, the other still queued
+	markDone(t, ctx, current)
+	code, resp := getScenario(t, router, submitted.ScenarioID)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ScenarioPending, resp.Status)
+	require.Len(t, resp.Jobs, 2)
+	assert.Equal(t, StatusDone, resp.Jobs[0].Status)
+	assert.JSONEq(t, sampleResult, string(resp.Jobs[0].Result))
+	assert.Equal(t, StatusQueued, resp.Jobs[1].Status)
+	assert.Nil(t, resp.Jobs[1].Result)
+
+	// full completion
+	markDone(t, ctx, warmer)
+	code, resp = getScenario(t, router, submitted.ScenarioID)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ScenarioDone, resp.Status)
+	for _, child := range resp.Jobs {
+		assert.Equal(t, StatusDone, child.Status)
+		assert.JSONEq(t, sampleResult, string(child.Result))
+	}
+}
+
+func TestGetScenarioFailedChild(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, submitted := postScenarios(t, router, climateScenarios)
+	require.Equal(t, http.StatusAccepted, code)
+	markDone(t, ctx, submitted.Jobs[0].JobID)
+	require.NoError(t, rdb.Set(ctx, RedisJobMetaPrefix+submitted.Jobs[1].JobID,
+		mustMarshalMeta(t, ctx, submitted.Jobs[1].JobID, StatusError), 0).Err())
+
+	code, resp := getScenario(t, router, submitted.ScenarioID)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, ScenarioError, resp.Status)
+	assert.Equal(t, StatusError, resp.Jobs[1].Status)
+
+	code, _ = getScenario(t, router, "no-such-scenario")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestScenarioStatus(t *testing.T) {
+	child := func(status string) scenarioChild { return scenarioChild{Status: status} }
+	assert.Equal(t, ScenarioDone, scenarioStatus([]scenarioChild{child(StatusDone), child(StatusDone)}))
+	assert.Equal(t, ScenarioPending, scenarioStatus([]scenarioChild{child(StatusDone), child(StatusRunning)}))
+	assert.Equal(t, ScenarioPending, scenarioStatus([]scenarioChild{child(StatusError), child(StatusQueued)}))
+	assert.Equal(t, ScenarioError, scenarioStatus([]scenarioChild{child(StatusDone), child(StatusCancelled)}))
+	assert.Equal(t, ScenarioError, scenarioStatus([]scenarioChild{child("not_found"), child(StatusDone)}))
+}
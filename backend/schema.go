@@ -0,0 +1,312 @@
+package main
+
+// backend/schema.go
+//
+// Optional JSON Schema check of the POST /simulate body. With
+// SCHEMA_VALIDATION=true the raw body is checked against params.schema.json
+// (embedded at build time) before it is bound, and every violation is reported
+// in one 400 response; the usual Go validation still runs afterwards. The
+// schema is maintained by hand next to SimulationParams and is stricter in one
+// way: unknown properties are rejected instead of ignored.
+//
+// Only the subset of JSON Schema the file uses is implemented: type, enum,
+// properties, additionalProperties (boolean), required, items, maxItems,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, minLength, maxLength,
+// pattern, and $ref to "#/$defs/<name>". Loading a schema with any other
+// keyword panics, so the file cannot silently outgrow the validator.
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed params.schema.json
+var paramsSchemaJSON []byte
+
+// paramsSchema is the parsed params.schema.json.
+var paramsSchema = mustLoadSchema(paramsSchemaJSON)
+
+// schemaValidation enables the schema check on POST /simulate.
+var schemaValidation = false
+
+func loadSchemaConfig() {
+	schemaValidation = os.Getenv("SCHEMA_VALIDATION") == "true"
+}
+
+// jsonSchema is one (sub)schema. Annotations are decoded only so that unknown
+// keywords can be told apart from them.
+type jsonSchema struct {
+	Schema      string                 `json:"$schema"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Defs        map[string]*jsonSchema `json:"$defs"`
+	Ref         string                 `json:"$ref"`
+
+	Type                 schemaTypes            `json:"type"`
+	Enum                 []interface{}          `json:"enum"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Required             []string               `json:"required"`
+	Items                *jsonSchema            `json:"items"`
+	MaxItems             *int                   `json:"maxItems"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+
+	ref     *jsonSchema // resolved Ref
+	pattern *regexp.Regexp
+}
+
+// schemaTypes is "type", given as one name or a list of names.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+func mustLoadSchema(raw []byte) *jsonSchema {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var root jsonSchema
+	if err := dec.Decode(&root); err != nil {
+		panic("params.schema.json: " + err.Error())
+	}
+	if err := root.compile(&root); err != nil {
+		panic("params.schema.json: " + err.Error())
+	}
+	return &root
+}
+
+// compile resolves $refs against root's $defs and compiles patterns, in s and
+// every subschema.
+func (s *jsonSchema) compile(root *jsonSchema) error {
+	if s.Ref != "" {
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if s.ref = root.Defs[name]; !ok || s.ref == nil {
+			return fmt.Errorf("unresolvable $ref %q", s.Ref)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	subs := []*jsonSchema{s.Items}
+	for _, m := range []map[string]*jsonSchema{s.Defs, s.Properties} {
+		for _, sub := range m {
+			subs = append(subs, sub)
+		}
+	}
+	for _, sub := range subs {
+		if sub == nil {
+			continue
+		}
+		if err := sub.compile(root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateJSON checks doc against s and returns every violation.
+func (s *jsonSchema) validateJSON(doc []byte) ([]FieldError, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var errs []FieldError
+	s.validate("", v, &errs)
+	return errs, nil
+}
+
+// validate appends the violations of v, found at path, to errs. A value of
+// the wrong type is reported once, without the keywords that assume the type.
+func (s *jsonSchema) validate(path string, v interface{}, errs *[]FieldError) {
+	if s.ref != nil {
+		s.ref.validate(path, v, errs)
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, FieldError{Field: path, Value: v, Allowed: fmt.Sprintf(format, args...)})
+	}
+	if len(s.Type) > 0 && !s.Type.match(v) {
+		fail("a value of type %s", strings.Join(s.Type, " or "))
+		return
+	}
+	if len(s.Enum) > 0 && !s.enumContains(v) {
+		names := make([]string, len(s.Enum))
+		for i, e := range s.Enum {
+			names[i] = fmt.Sprint(e)
+		}
+		fail("one of %s", strings.Join(names, ", "))
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			fail("a finite number")
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("at least %g", *s.Minimum)
+		}
+		if s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum {
+			fail("greater than %g", *s.ExclusiveMinimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("at most %g", *s.Maximum)
+		}
+		if s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum {
+			fail("less than %g", *s.ExclusiveMaximum)
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("a string matching %s", s.Pattern)
+		}
+	case []interface{}:
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := name
+			if path != "" {
+				child = path + "." + name
+			}
+			if sub, ok := s.Properties[name]; ok {
+				sub.validate(child, v[name], errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, FieldError{Field: child, Value: v[name], Allowed: "no such property"})
+			}
+		}
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				child := name
+				if path != "" {
+					child = path + "." + name
+				}
+				*errs = append(*errs, FieldError{Field: child, Value: nil, Allowed: "required"})
+			}
+		}
+	}
+}
+
+// match reports whether v, as decoded with UseNumber, has one of the types.
+func (t schemaTypes) match(v interface{}) bool {
+	for _, name := range t {
+		switch v := v.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case json.Number:
+			if name == "number" {
+				return true
+			}
+			if _, err := v.Int64(); name == "integer" && err == nil {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *jsonSchema) enumContains(v interface{}) bool {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false
+		}
+		v = f
+	}
+	for _, e := range s.Enum {
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBodySchema validates the request body against paramsSchema and puts it
+// back for binding. It answers the request and returns false when the body
+// breaks the schema or is too large; a body that is not JSON at all is left
+// for binding to report.
+func checkBodySchema(c *gin.Context, limit int64) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, limit)
+			return false
+		}
+		respondError(c, http.StatusBadRequest, "reading body: "+err.Error())
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	violations, err := paramsSchema.validateJSON(body)
+	if err != nil {
+		return true
+	}
+	if len(violations) > 0 {
+		respondValidationError(c, &ValidationError{Fields: violations})
+		return false
+	}
+	return true
+}
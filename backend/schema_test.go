@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postSimulate(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSchemaReportsEveryViolation(t *testing.T) {
+	router := setupRouter()
+	schemaValidation = true
+	defer func() { schemaValidation = false }()
+
+	body := `{
+		"A_glass": -5,
+		"tau_glass": "high",
+		"lat": 95,
+		"model": "three-node",
+		"seed": 1.5,
+		"tags": ["ok", "not ok"],
+		"start_date": "01/11/2025",
+		"setpiont": 18
+	}`
+	w := postSimulate(router, body)
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	env := decodeError(t, w)
+	assert.Equal(t, CodeInvalidParam, env.Error.Code)
+
+	allowed := map[string]string{}
+	for _, f := range env.Fields {
+		allowed[f.Field] = f.Allowed
+	}
+	assert.Equal(t, map[string]string{
+		"A_glass":    "greater than 0",
+		"lat":        "at most 90",
+		"model":      "one of lumped, multinode",
+		"seed":       "a value of type integer or null",
+		"setpiont":   "no such property",
+		"start_date": "a string matching ^[0-9]{4}-[0-9]{2}-[0-9]{2}$",
+		"tags[1]":    "a string matching ^[A-Za-z0-9][A-Za-z0-9._:/-]*$",
+		"tau_glass":  `a string matching ^\s*([-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?)?\s*$`,
+	}, allowed)
+	assert.Equal(t, "A_glass", env.Error.Field, "the first violation, in name order")
+}
+
+func TestSchemaKeepsGoValidation(t *testing.T) {
+	router := setupRouter()
+	schemaValidation = true
+	defer func() { schemaValidation = false }()
+
+	// numeric strings and nulls are accepted, as by binding
+	errs, err := paramsSchema.validateJSON([]byte(`{"ACH": "0.5", "V": "", "T_init": null, "seed": 7, "tags": null}`))
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	// passes the schema; the Go layer still rejects the reversed dates
+	w := postSimulate(router, withSite(`{"start_date": "2025-11-03", "end_date": "2025-11-01"}`))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "end_date", decodeError(t, w).Error.Field)
+
+	// malformed JSON is still reported by binding
+	w = postSimulate(router, `{"A_glass": `)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, CodeInvalidRequest, decodeError(t, w).Error.Code)
+}
+
+func TestSchemaOffByDefault(t *testing.T) {
+	router := setupRouter()
+	// an unknown property is ignored by binding; only the schema rejects it
+	w := postSimulate(router, withSite(`{"setpiont": 18, "start_date": "2025-11-03", "end_date": "2025-11-01"}`))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "end_date", decodeError(t, w).Error.Field)
+}
+
+// The schema is maintained by hand; keep its bounds and enums in step with
+// the Go validation.
+func TestSchemaMatchesParamRanges(t *testing.T) {
+	for _, r := range paramRanges {
+		prop, ok := paramsSchema.Properties[r.field]
+		if !assert.True(t, ok, r.field) || r.field == "T_init" || r.field == "setpoint" ||
+			r.field == "outdoor_temp_const" || r.field == "outdoor_temp_mean" {
+			continue // temperature bounds depend on units
+		}
+		lower, upper := prop.Minimum, prop.Maximum
+		if r.minOpen {
+			lower = prop.ExclusiveMinimum
+		}
+		if r.maxOpen {
+			upper = prop.ExclusiveMaximum
+		}
+		if assert.NotNil(t, lower, r.field) {
+			assert.Equal(t, r.min, *lower, r.field)
+		}
+		if r.max == inf {
+			assert.Nil(t, upper, r.field)
+		} else if assert.NotNil(t, upper, r.field) {
+			assert.Equal(t, r.max, *upper, r.field)
+		}
+	}
+	for field, known := range map[string]map[string]bool{"model": knownModels, "priority": knownPriorities, "heater_control": knownHeaterControls, "outdoor_temp_mode": knownOutdoorTempModes} {
+		var enum []string
+		for _, e := range paramsSchema.Properties[field].Enum {
+			enum = append(enum, e.(string))
+		}
+		assert.Len(t, enum, len(known), field)
+		for _, e := range enum {
+			assert.True(t, known[e], "%s %s", field, e)
+		}
+	}
+}
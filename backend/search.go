@@ -0,0 +1,179 @@
+package main
+
+// backend/search.go
+//
+// GET /jobs/search: find past runs by submission time and approximate
+// location. Unlike GET /jobs it is not limited to the recent list.
+//
+// Redis has no secondary indexes, so the Redis store answers a search by
+// SCANning job_meta:* and filtering in the API process: cost grows with the
+// number of stored jobs, not with the number of matches. The scan stops after
+// maxSearchScan keys and the response then reports truncated=true; set
+// SEARCH_MAX_SCAN to trade completeness for latency. The Postgres store runs
+// the same filters as a query and is never truncated.
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search defaults
+const (
+	DefaultMaxSearchScan   = 10000 // job_meta keys examined per search on Redis
+	DefaultSearchTolerance = 0.1   // degrees, when lat/lon are given without tolerance
+	searchScanBatch        = 500   // SCAN COUNT hint and MGET batch size
+)
+
+var maxSearchScan = DefaultMaxSearchScan
+
+// MetaSearch filters jobs by creation time (CreatedAfter inclusive,
+// CreatedBefore exclusive; zero means unbounded) and by params.lat/params.lon
+// within Tolerance degrees. A nil Lat or Lon does not filter.
+type MetaSearch struct {
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Lat, Lon      *float64
+	Tolerance     float64
+	Limit         int
+	Offset        int
+}
+
+// MetaSearchPage is one page of search matches, most recent first.
+type MetaSearchPage struct {
+	Jobs  []JobMeta `json:"jobs"`
+	Total int       `json:"total"`
+	// Truncated is set when the scan cap was hit, so older matches may be missing.
+	Truncated bool `json:"truncated"`
+}
+
+// matches reports whether meta passes every filter in q.
+func (q MetaSearch) matches(meta JobMeta) bool {
+	if !q.CreatedAfter.IsZero() && meta.CreatedAt.Before(q.CreatedAfter) {
+		return false
+	}
+	if !q.CreatedBefore.IsZero() && !meta.CreatedAt.Before(q.CreatedBefore) {
+		return false
+	}
+	near := func(want, got *float64) bool {
+		return want == nil || (got != nil && math.Abs(*got-*want) <= q.Tolerance)
+	}
+	return near(q.Lat, meta.Params.Lat) && near(q.Lon, meta.Params.Lon)
+}
+
+// SearchMeta scans job_meta:* (see the file comment for the cost) and pages
+// through the matches sorted newest first.
+func (redisMetaStore) SearchMeta(ctx context.Context, q MetaSearch) (MetaSearchPage, error) {
+	var matches []JobMeta
+	scanned, truncated := 0, false
+	var cursor uint64
+	for {
+		keys, next, err := rdb.Scan(ctx, cursor, jobMetaKey("*"), searchScanBatch).Result()
+		if err != nil {
+			return MetaSearchPage{}, err
+		}
+		if room := maxSearchScan - scanned; len(keys) > room {
+			keys, truncated = keys[:room], true
+		}
+		scanned += len(keys)
+		ids := make([]string, len(keys))
+		for i, k := range keys {
+			ids[i] = jobIDFromMetaKey(k)
+		}
+		metas, err := loadMetas(ctx, ids)
+		if err != nil {
+			return MetaSearchPage{}, err
+		}
+		for _, m := range metas {
+			if q.matches(m) {
+				matches = append(matches, m)
+			}
+		}
+		if next == 0 {
+			break
+		}
+		if scanned >= maxSearchScan {
+			truncated = true
+			break
+		}
+		cursor = next
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.After(matches[j].CreatedAt)
+		}
+		return matches[i].JobID < matches[j].JobID
+	})
+	page := MetaSearchPage{Jobs: []JobMeta{}, Total: len(matches), Truncated: truncated}
+	if q.Offset < len(matches) {
+		page.Jobs = matches[q.Offset:min(q.Offset+q.Limit, len(matches))]
+	}
+	return page, nil
+}
+
+// parseSearchTime accepts RFC 3339 or a plain YYYY-MM-DD date (midnight UTC).
+func parseSearchTime(v string) (time.Time, error) {
+	if t, err := time.Parse(DateLayout, v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// searchJobsHandler serves GET /jobs/search?created_after=&created_before=&lat=&lon=&tolerance=.
+func searchJobsHandler(c *gin.Context) {
+	limit, offset, ok := parsePage(c)
+	if !ok {
+		return
+	}
+	q := MetaSearch{Tolerance: DefaultSearchTolerance, Limit: limit, Offset: offset}
+
+	for name, dst := range map[string]*time.Time{"created_after": &q.CreatedAfter, "created_before": &q.CreatedBefore} {
+		if v := c.Query(name); v != "" {
+			t, err := parseSearchTime(v)
+			if err != nil {
+				respondInvalidParam(c, name, name+" must be YYYY-MM-DD or an RFC 3339 timestamp")
+				return
+			}
+			*dst = t
+		}
+	}
+	for name, dst := range map[string]**float64{"lat": &q.Lat, "lon": &q.Lon} {
+		if v := c.Query(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(f) {
+				respondInvalidParam(c, name, name+" must be a number")
+				return
+			}
+			*dst = &f
+		}
+	}
+	if v := c.Query("tolerance"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(f >= 0) {
+			respondInvalidParam(c, "tolerance", "tolerance must be a non-negative number of degrees")
+			return
+		}
+		q.Tolerance = f
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	page, err := metaStore.SearchMeta(ctx, q)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"total":     page.Total,
+		"limit":     limit,
+		"offset":    offset,
+		"truncated": page.Truncated,
+		"jobs":      page.Jobs,
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seedSearchMetas stores four jobs spread over three days and two sites.
+func seedSearchMetas(t *testing.T, ctx context.Context) {
+	t.Helper()
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 12, 0, 0, 0, time.UTC) }
+	for _, m := range []struct {
+		id       string
+		created  time.Time
+		lat, lon float64
+	}{
+		{"beijing-1", day(1), 39.9, 116.4},
+		{"beijing-2", day(2), 39.95, 116.35},
+		{"amsterdam-2", day(2).Add(time.Hour), 52.37, 4.9},
+		{"beijing-3", day(3), 39.9, 116.4},
+	} {
+		meta := JobMeta{
+			JobID:     m.id,
+			Status:    StatusDone,
+			CreatedAt: m.created,
+			UpdatedAt: m.created,
+			Params:    SimulationParams{Lat: floatPtr(m.lat), Lon: floatPtr(m.lon)},
+		}
+		require.NoError(t, redisMetaStore{}.SaveMeta(ctx, meta))
+	}
+	// A job without a location only matches searches that do not filter on it.
+	require.NoError(t, redisMetaStore{}.SaveMeta(ctx, JobMeta{JobID: "nowhere", Status: StatusQueued, CreatedAt: day(2), UpdatedAt: day(2)}))
+}
+
+func searchJobs(t *testing.T, query string) (int, map[string]interface{}, []string) {
+	t.Helper()
+	router := setupRouter()
+	req, _ := http.NewRequest("GET", "/jobs/search?"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var body struct {
+		Total     int       `json:"total"`
+		Truncated bool      `json:"truncated"`
+		Jobs      []JobMeta `json:"jobs"`
+	}
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+	if w.Code != http.StatusOK {
+		return w.Code, raw, nil
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	ids := make([]string, len(body.Jobs))
+	for i, j := range body.Jobs {
+		ids[i] = j.JobID
+	}
+	return w.Code, raw, ids
+}
+
+func TestSearchJobs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedSearchMetas(t, ctx)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"no filters, newest first", "", []string{"beijing-3", "amsterdam-2", "beijing-2", "nowhere", "beijing-1"}},
+		{"date range", "created_after=2025-06-02&created_before=2025-06-03", []string{"amsterdam-2", "beijing-2", "nowhere"}},
+		{"rfc3339 bound", "created_after=2025-06-02T12:30:00Z", []string{"beijing-3", "amsterdam-2"}},
+		{"location default tolerance", "lat=39.9&lon=116.4", []string{"beijing-3", "beijing-2", "beijing-1"}},
+		{"location tight tolerance", "lat=39.9&lon=116.4&tolerance=0.01", []string{"beijing-3", "beijing-1"}},
+		{"lat only", "lat=52.4", []string{"amsterdam-2"}},
+		{"date and location", "created_before=2025-06-03&lat=39.9&lon=116.4", []string{"beijing-2", "beijing-1"}},
+		{"paginated", "limit=2&offset=1", []string{"amsterdam-2", "beijing-2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, raw, ids := searchJobs(t, tt.query)
+			require.Equal(t, http.StatusOK, code, raw)
+			assert.Equal(t, tt.want, ids)
+			assert.Equal(t, false, raw["truncated"])
+		})
+	}
+
+	code, raw, _ := searchJobs(t, "limit=2&offset=1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(5), raw["total"])
+}
+
+func TestSearchJobsScanCap(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedSearchMetas(t, ctx)
+
+	defer func(prev int) { maxSearchScan = prev }(maxSearchScan)
+	maxSearchScan = 2
+
+	code, raw, ids := searchJobs(t, "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Len(t, ids, 2)
+	assert.Equal(t, true, raw["truncated"])
+}
+
+func TestSearchJobsRejectsBadQuery(t *testing.T) {
+	for _, q := range []string{
+		"created_after=yesterday",
+		"created_before=2025-13-01",
+		"lat=north",
+		"lon=NaN",
+		"lat=1&tolerance=-1",
+		"limit=0",
+	} {
+		code, raw, _ := searchJobs(t, q)
+		assert.Equal(t, http.StatusBadRequest, code, q)
+		assert.Contains(t, raw, "error", q)
+	}
+}
+
+func TestPostgresMetaStoreSearch(t *testing.T) {
+	store, mock := newMockPostgresStore(t)
+	meta := sampleMeta()
+	b, err := json.Marshal(meta)
+	require.NoError(t, err)
+	after := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM job_meta")).
+		WithArgs(after, nil, 39.9, nil, 0.5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT meta FROM job_meta")).
+		WithArgs(after, nil, 39.9, nil, 0.5, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"meta"}).AddRow(b))
+
+	page, err := store.SearchMeta(context.Background(), MetaSearch{CreatedAfter: after, Lat: floatPtr(39.9), Tolerance: 0.5, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, page.Total)
+	assert.False(t, page.Truncated)
+	assert.Equal(t, []JobMeta{meta}, page.Jobs)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
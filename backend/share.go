@@ -0,0 +1,158 @@
+package main
+
+// backend/share.go
+//
+// Shareable result links. POST /jobs/:job_id/share returns a token that lets
+// anyone fetch the job's result from GET /shared/:token without an API key
+// until it expires. Tokens are stateless: base64url(job_id "|" expiry unix)
+// "." base64url(HMAC-SHA256 of that payload), keyed by SHARE_SECRET. Without
+// SHARE_SECRET a random key is generated at startup, so links stop working on
+// restart and are not accepted by other replicas.
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Share link defaults
+const (
+	DefaultShareTTL    = 24 * time.Hour
+	DefaultMaxShareTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// shareSecret keys token signatures; random unless SHARE_SECRET is set
+	shareSecret = randomShareSecret()
+	maxShareTTL = DefaultMaxShareTTL
+)
+
+// errInvalidShareToken covers malformed, tampered and expired tokens alike, so
+// a caller learns nothing about which check failed.
+var errInvalidShareToken = errors.New("invalid or expired share link")
+
+func loadShareConfig() {
+	maxShareTTL = envSeconds("SHARE_MAX_TTL_SECONDS", DefaultMaxShareTTL)
+	if s := os.Getenv("SHARE_SECRET"); s != "" {
+		shareSecret = []byte(s)
+		return
+	}
+	slog.Warn("SHARE_SECRET not set; share links will not survive a restart")
+}
+
+func randomShareSecret() []byte {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("share: cannot read random secret: " + err.Error())
+	}
+	return b
+}
+
+func shareSignature(payload string) []byte {
+	mac := hmac.New(sha256.New, shareSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// signShareToken returns a token granting read access to jobID's result until expires.
+func signShareToken(jobID string, expires time.Time) string {
+	payload := jobID + "|" + strconv.FormatInt(expires.Unix(), 10)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(payload)) + "." + enc.EncodeToString(shareSignature(payload))
+}
+
+// verifyShareToken returns the job id a token grants access to, or
+// errInvalidShareToken if it is malformed, was not signed by us or expired before now.
+func verifyShareToken(token string, now time.Time) (string, error) {
+	enc := base64.RawURLEncoding
+	p, s, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", errInvalidShareToken
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return "", errInvalidShareToken
+	}
+	sig, err := enc.DecodeString(s)
+	if err != nil || !hmac.Equal(sig, shareSignature(string(payload))) {
+		return "", errInvalidShareToken
+	}
+	jobID, exp, ok := strings.Cut(string(payload), "|")
+	if !ok {
+		return "", errInvalidShareToken
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || !now.Before(time.Unix(expires, 0)) {
+		return "", errInvalidShareToken
+	}
+	return jobID, nil
+}
+
+// shareJobHandler issues a share link for an existing job. ?ttl= sets its
+// lifetime in seconds (default 24h, capped at SHARE_MAX_TTL_SECONDS).
+func shareJobHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ttl := DefaultShareTTL
+	if v := c.Query("ttl"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondInvalidParam(c, "ttl", "ttl must be a positive number of seconds")
+			return
+		}
+		ttl = time.Duration(n) * time.Second
+	}
+	ttl = min(ttl, maxShareTTL)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	if _, err := metaStore.GetMeta(ctx, jobID); errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "metadata error: "+err.Error())
+		return
+	}
+
+	expires := time.Now().UTC().Add(ttl).Truncate(time.Second)
+	token := signShareToken(jobID, expires)
+	c.JSON(http.StatusCreated, gin.H{
+		"job_id":     jobID,
+		"token":      token,
+		"url":        apiURL("/shared/" + token),
+		"expires_at": expires,
+	})
+}
+
+// sharedResultHandler serves the result a share token grants access to. It
+// is registered outside the API key group; the token is the credential.
+func sharedResultHandler(c *gin.Context) {
+	jobID, err := verifyShareToken(c.Param("token"), time.Now())
+	if err != nil {
+		respondError(c, http.StatusForbidden, err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	res, ok := loadStoredResult(c, ctx, jobID)
+	if !ok {
+		return
+	}
+	parsed, err := parseSimulationResult(res)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": jobID})
+		return
+	}
+	respondJSONWithETag(c, gin.H{"job_id": jobID, "status": StatusDone, "result": parsed})
+}
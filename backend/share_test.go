@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareTokenRoundTrip(t *testing.T) {
+	now := time.Now()
+	token := signShareToken("job-1", now.Add(time.Hour))
+
+	jobID, err := verifyShareToken(token, now)
+	require.NoError(t, err)
+	assert.Equal(t, "job-1", jobID)
+
+	_, err = verifyShareToken(token, now.Add(2*time.Hour))
+	assert.ErrorIs(t, err, errInvalidShareToken, "expired")
+
+	// swapping in another job id invalidates the signature
+	_, sig, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte("job-2|"+"9999999999")) + "." + sig
+	_, err = verifyShareToken(forged, now)
+	assert.ErrorIs(t, err, errInvalidShareToken, "tampered")
+
+	for _, bad := range []string{"", "nodot", "!!!.!!!", token + "x"} {
+		_, err = verifyShareToken(bad, now)
+		assert.ErrorIs(t, err, errInvalidShareToken, bad)
+	}
+}
+
+func TestShareJobResult(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	withAuth(t, "owner-key")
+	seedJobMeta(t, ctx, "shared-job", StatusDone)
+	rdb.Set(ctx, RedisResultsPrefix+"shared-job", sampleResult, DefaultResultTTL)
+
+	req, _ := http.NewRequest("POST", "/jobs/shared-job/share?ttl=600", nil)
+	req.Header.Set(APIKeyHeader, "owner-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var share struct {
+		Token     string    `json:"token"`
+		URL       string    `json:"url"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &share))
+	assert.Equal(t, "/shared/"+share.Token, share.URL)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), share.ExpiresAt, 2*time.Second)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil) // deliberately no API key
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("valid token", func(t *testing.T) {
+		w := get(share.URL)
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			JobID  string           `json:"job_id"`
+			Result SimulationResult `json:"result"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "shared-job", response.JobID)
+		assert.Len(t, response.Result.Data, 3)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		w := get("/shared/" + signShareToken("shared-job", time.Now().Add(-time.Second)))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("tampered token", func(t *testing.T) {
+		payload, sig, _ := strings.Cut(share.Token, ".")
+		flipped := "A"
+		if sig[0] == 'A' {
+			flipped = "B"
+		}
+		sig = flipped + sig[1:]
+		w := get("/shared/" + payload + "." + sig)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("token is the only way in", func(t *testing.T) {
+		w := get("/results/shared-job")
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestShareJobNotFound(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	req, _ := http.NewRequest("POST", "/jobs/missing/share", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req, _ = http.NewRequest("POST", "/jobs/missing/share?ttl=0", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
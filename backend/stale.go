@@ -0,0 +1,148 @@
+package main
+
+// backend/stale.go
+//
+// Stale-running detection. A worker that dies mid-job leaves the meta at
+// "running" forever, so clients would poll indefinitely. The worker refreshes
+// updated_at as a heartbeat while it simulates (WORKER_HEARTBEAT_SECONDS); a
+// background sweeper fails any running job whose heartbeat is older than
+// jobTimeout.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Stale job defaults
+const (
+	DefaultJobTimeout         = 10 * time.Minute // running jobs silent this long are failed; 0 disables the sweeper
+	DefaultStaleSweepInterval = 30 * time.Second
+)
+
+var (
+	jobTimeout         = DefaultJobTimeout
+	staleSweepInterval = DefaultStaleSweepInterval
+)
+
+func loadStaleJobConfig() {
+	jobTimeout = envSeconds("JOB_TIMEOUT_SECONDS", DefaultJobTimeout)
+	staleSweepInterval = envSeconds("STALE_SWEEP_INTERVAL_SECONDS", DefaultStaleSweepInterval)
+}
+
+// workerTimeoutError is stored as the meta's error when the sweeper fails a job.
+func workerTimeoutError(timeout time.Duration) string {
+	return fmt.Sprintf("worker timeout: no heartbeat for %s", timeout)
+}
+
+// sweepStaleJobs does one pass over job_meta:*, failing running jobs last
+// updated before now-jobTimeout. It returns how many jobs were failed.
+func sweepStaleJobs(ctx context.Context, now time.Time) (int, error) {
+	cutoff := now.Add(-jobTimeout)
+	failed := 0
+	var cursor uint64
+	for {
+		keys, next, err := rdb.Scan(ctx, cursor, jobMetaKey("*"), searchScanBatch).Result()
+		if err != nil {
+			return failed, err
+		}
+		ids := make([]string, len(keys))
+		for i, k := range keys {
+			ids[i] = jobIDFromMetaKey(k)
+		}
+		metas, err := loadMetas(ctx, ids)
+		if err != nil {
+			return failed, err
+		}
+		for _, m := range metas {
+			if m.Status != StatusRunning || !m.UpdatedAt.Before(cutoff) {
+				continue
+			}
+			ok, err := failStaleJob(ctx, m.JobID, cutoff, now)
+			if err != nil {
+				return failed, err
+			}
+			if ok {
+				failed++
+			}
+		}
+		if next == 0 {
+			return failed, nil
+		}
+		cursor = next
+	}
+}
+
+// failStaleJob marks jobID as errored if it is still running and stale. The
+// meta is watched so a heartbeat or final status written by the worker in the
+// meantime wins; ok is false when the job no longer qualifies.
+func failStaleJob(ctx context.Context, jobID string, cutoff, now time.Time) (ok bool, err error) {
+	key := jobMetaKey(jobID)
+	var meta JobMeta
+	err = rdb.Watch(ctx, func(tx *redis.Tx) error {
+		raw, err := tx.Get(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(raw), &meta); err != nil {
+			return err
+		}
+		if meta.Status != StatusRunning || !meta.UpdatedAt.Before(cutoff) {
+			return nil
+		}
+		meta.Status = StatusError
+		meta.Error = workerTimeoutError(jobTimeout)
+		meta.UpdatedAt = now
+		b, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, b, redis.KeepTTL)
+			return nil
+		})
+		ok = err == nil
+		return err
+	}, key)
+	switch {
+	case errors.Is(err, redis.Nil), errors.Is(err, redis.TxFailedErr):
+		// deleted or updated by someone else; the next pass looks again
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	if ok {
+		slog.Warn("failed stale running job", "job_id", jobID, "timeout", jobTimeout.String())
+		observeJobFinished(ctx, meta)
+		if err := publishJobEvent(ctx, meta); err != nil {
+			slog.Warn("failed to publish job event", "job_id", jobID, "error", err)
+		}
+		if err := releaseActiveLock(ctx, meta.Params.GreenhouseID, jobID); err != nil {
+			slog.Warn("failed to release greenhouse lock", "job_id", jobID, "error", err)
+		}
+	}
+	return ok, nil
+}
+
+// runStaleJobSweeper calls sweepStaleJobs every interval until ctx is done.
+func runStaleJobSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sweepCtx, cancel := context.WithTimeout(ctx, RedisOpTimeout)
+			if _, err := sweepStaleJobs(sweepCtx, time.Now().UTC()); err != nil {
+				slog.Warn("stale job sweep failed", "error", err)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweepStaleJobs(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	defer func(prev time.Duration) { jobTimeout = prev }(jobTimeout)
+	jobTimeout = time.Minute
+
+	seedJobMeta(t, ctx, "stale-running", StatusRunning, seededAgo(time.Hour))
+	seedJobMeta(t, ctx, "fresh-running", StatusRunning, seededAgo(10*time.Second))
+	seedJobMeta(t, ctx, "old-queued", StatusQueued, seededAgo(time.Hour))
+	seedJobMeta(t, ctx, "old-done", StatusDone, seededAgo(time.Hour))
+
+	sub := rdb.Subscribe(ctx, RedisJobEventsPrefix+"stale-running")
+	defer sub.Close()
+	_, err := sub.Receive(ctx)
+	require.NoError(t, err)
+
+	failed, err := sweepStaleJobs(ctx, time.Now().UTC())
+	require.NoError(t, err)
+	assert.Equal(t, 1, failed)
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, "stale-running")
+	require.NoError(t, err)
+	assert.Equal(t, StatusError, meta.Status)
+	assert.Equal(t, "worker timeout: no heartbeat for 1m0s", meta.Error)
+	assert.WithinDuration(t, time.Now(), meta.UpdatedAt, 5*time.Second)
+	ttl, err := rdb.TTL(ctx, jobMetaKey("stale-running")).Result()
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Duration(0), "sweeper must keep the meta TTL")
+
+	for id, status := range map[string]string{"fresh-running": StatusRunning, "old-queued": StatusQueued, "old-done": StatusDone} {
+		m, err := redisMetaStore{}.GetMeta(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, status, m.Status, id)
+	}
+
+	msg, err := sub.ReceiveMessage(ctx)
+	require.NoError(t, err)
+	var event JobMeta
+	require.NoError(t, json.Unmarshal([]byte(msg.Payload), &event))
+	assert.Equal(t, StatusError, event.Status)
+
+	// a second pass finds nothing left to fail
+	failed, err = sweepStaleJobs(ctx, time.Now().UTC())
+	require.NoError(t, err)
+	assert.Equal(t, 0, failed)
+}
+
+func TestRunStaleJobSweeper(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	defer func(prev time.Duration) { jobTimeout = prev }(jobTimeout)
+	jobTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "abandoned", StatusRunning, seededAgo(2*time.Minute))
+
+	stopped := make(chan struct{})
+	go func() {
+		runStaleJobSweeper(ctx, 10*time.Millisecond)
+		close(stopped)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	assert.Eventually(t, func() bool {
+		meta, err := redisMetaStore{}.GetMeta(ctx, "abandoned")
+		return err == nil && meta.Status == StatusError
+	}, 2*time.Second, 10*time.Millisecond)
+}
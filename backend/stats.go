@@ -0,0 +1,110 @@
+package main
+
+// backend/stats.go
+//
+// GET /stats: a one-shot operational summary for deciding when to scale
+// workers — queue depth (total and per priority), recent jobs by status, stored
+// result count and, when the server reports it, Redis memory usage.
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statsScanCount is the COUNT hint used when scanning result keys.
+const statsScanCount = 500
+
+// countKeys counts keys matching pattern with SCAN, so a large keyspace does
+// not block Redis the way KEYS would.
+func countKeys(ctx context.Context, pattern string) (int, error) {
+	n := 0
+	iter := rdb.Scan(ctx, 0, pattern, statsScanCount).Iterator()
+	for iter.Next(ctx) {
+		n++
+	}
+	return n, iter.Err()
+}
+
+// redisUsedMemory returns used_memory from INFO memory. ok is false when the
+// server does not report it.
+func redisUsedMemory(ctx context.Context) (bytes int64, ok bool) {
+	info, err := rdb.Info(ctx, "memory").Result()
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if v, found := strings.CutPrefix(strings.TrimSpace(line), "used_memory:"); found {
+			n, err := strconv.ParseInt(v, 10, 64)
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+func statsHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	// lengths cover the default queue and every named one
+	queueLength := int64(0)
+	byPriority := make(map[string]int64, len(jobQueues))
+	byName := make(map[string]int64, len(jobQueueNames))
+	for _, name := range append([]string{""}, sortedQueueNames()...) {
+		for _, q := range jobQueues {
+			n, err := rdb.LLen(ctx, q.KeyFor(name)).Result()
+			if err != nil {
+				respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+				return
+			}
+			byPriority[q.Priority] += n
+			queueLength += n
+			if name != "" {
+				byName[name] += n
+			}
+		}
+	}
+
+	// status counts cover the recent-jobs list, the same window GET /jobs lists
+	ids, err := rdb.LRange(ctx, redisKey(RedisRecentJobsList), 0, -1).Result()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	metas, err := loadMetas(ctx, ids)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	byStatus := make(map[string]int, len(knownStatuses))
+	for status := range knownStatuses {
+		byStatus[status] = 0
+	}
+	for _, meta := range metas {
+		byStatus[meta.Status]++
+	}
+
+	results, err := countKeys(ctx, jobResultKey("*"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+
+	body := gin.H{
+		"queue_length":      queueLength,
+		"queue_by_priority": byPriority,
+		"jobs_by_status":    byStatus,
+		"recent_jobs":       len(metas),
+		"results_stored":    results,
+	}
+	if len(byName) > 0 {
+		body["queue_by_name"] = byName
+	}
+	if used, ok := redisUsedMemory(ctx); ok {
+		body["redis_used_memory_bytes"] = used
+	}
+	c.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	for id, status := range map[string]string{
+		"q1": StatusQueued, "q2": StatusQueued, "r1": StatusRunning,
+		"d1": StatusDone, "d2": StatusDone, "d3": StatusDone, "e1": StatusError,
+	} {
+		seedJobMeta(t, ctx, id, status)
+		rdb.LPush(ctx, RedisRecentJobsList, id)
+	}
+	rdb.LPush(ctx, RedisRecentJobsList, "expired") // meta gone, not counted
+	seedQueuedPayload(t, ctx, "q1")
+	seedQueuedPayload(t, ctx, "q2")
+	for _, id := range []string{"d1", "d2", "d3"} {
+		rdb.Set(ctx, RedisResultsPrefix+id, sampleResult, DefaultResultTTL)
+	}
+
+	req, _ := http.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		QueueLength   int64          `json:"queue_length"`
+		JobsByStatus  map[string]int `json:"jobs_by_status"`
+		RecentJobs    int            `json:"recent_jobs"`
+		ResultsStored int            `json:"results_stored"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(2), response.QueueLength)
+	assert.Equal(t, 7, response.RecentJobs)
+	assert.Equal(t, 3, response.ResultsStored)
+	assert.Equal(t, map[string]int{
+		StatusQueued: 2, StatusRunning: 1, StatusDone: 3, StatusError: 1, StatusCancelled: 0,
+	}, response.JobsByStatus)
+}
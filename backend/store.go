@@ -0,0 +1,184 @@
+package main
+
+// backend/store.go
+//
+// MetaStore abstracts where job metadata is kept for the API's reads. Redis is
+// the default. The worker always reads and updates job_meta:<id> in Redis, so
+// enqueueJobs writes meta there regardless of the backend. A durable backend
+// (METADATA_BACKEND=postgres) gets its own copy and is kept current from the
+// job_events:* channel.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMetaNotFound is returned by MetaStore.GetMeta for an unknown job.
+var ErrMetaNotFound = errors.New("job meta not found")
+
+// MetaQuery selects a page of jobs, most recent first. An empty Status matches
+// all; a job must carry every one of Tags.
+type MetaQuery struct {
+	Status string
+	Tags   []string
+	Limit  int
+	Offset int
+}
+
+// MetaStore persists and looks up job metadata.
+type MetaStore interface {
+	// SaveMeta creates or replaces the meta for meta.JobID.
+	SaveMeta(ctx context.Context, meta JobMeta) error
+	// GetMeta returns ErrMetaNotFound when the job is unknown.
+	GetMeta(ctx context.Context, jobID string) (JobMeta, error)
+	// DeleteMeta removes the meta of jobIDs; unknown ids are ignored.
+	DeleteMeta(ctx context.Context, jobIDs ...string) error
+	// ListMeta returns the requested page and the total number of matching jobs.
+	ListMeta(ctx context.Context, q MetaQuery) ([]JobMeta, int, error)
+	// SearchMeta filters all stored jobs by time and location (see search.go).
+	SearchMeta(ctx context.Context, q MetaSearch) (MetaSearchPage, error)
+}
+
+// metaStore is the backend used by the handlers; see initMetaStore.
+var metaStore MetaStore = redisMetaStore{}
+
+// initMetaStore selects the backend from METADATA_BACKEND ("redis" or "postgres").
+// The Postgres connection string comes from DATABASE_URL.
+func initMetaStore(ctx context.Context) error {
+	switch backend := os.Getenv("METADATA_BACKEND"); backend {
+	case "", "redis":
+		metaStore = redisMetaStore{}
+	case "postgres":
+		store, err := openPostgresMetaStore(ctx, os.Getenv("DATABASE_URL"))
+		if err != nil {
+			return err
+		}
+		metaStore = store
+	default:
+		return fmt.Errorf("unknown METADATA_BACKEND %q", backend)
+	}
+	return nil
+}
+
+// mirrorsMeta reports whether metaStore keeps its own copy of the meta that
+// enqueueJobs writes to Redis.
+func mirrorsMeta() bool {
+	_, ok := metaStore.(redisMetaStore)
+	return !ok
+}
+
+// runMetaSync copies every meta published on job_events:* into metaStore until
+// ctx is done, so a durable backend follows the worker's status updates.
+func runMetaSync(ctx context.Context) {
+	sub := rdb.PSubscribe(ctx, jobEventsChannel("*"))
+	defer sub.Close()
+	msgs := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+			var meta JobMeta
+			if err := json.Unmarshal([]byte(msg.Payload), &meta); err != nil {
+				slog.Warn("ignoring malformed job event", "channel", msg.Channel, "error", err)
+				continue
+			}
+			saveCtx, cancel := context.WithTimeout(ctx, RedisOpTimeout)
+			if err := metaStore.SaveMeta(saveCtx, meta); err != nil {
+				slog.Warn("failed to sync job meta", "job_id", meta.JobID, "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// redisMetaStore reads job_meta:<id> keys and the recent-jobs list.
+type redisMetaStore struct{}
+
+func (redisMetaStore) SaveMeta(ctx context.Context, meta JobMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return rdb.Set(ctx, jobMetaKey(meta.JobID), b, metaTTL(meta)).Err()
+}
+
+func (redisMetaStore) GetMeta(ctx context.Context, jobID string) (JobMeta, error) {
+	var meta JobMeta
+	metaStr, err := rdb.Get(ctx, jobMetaKey(jobID)).Result()
+	if err == redis.Nil {
+		return meta, ErrMetaNotFound
+	} else if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal([]byte(metaStr), &meta); err != nil {
+		return meta, fmt.Errorf("failed to parse job meta: %w", err)
+	}
+	return meta, nil
+}
+
+func (redisMetaStore) DeleteMeta(ctx context.Context, jobIDs ...string) error {
+	if len(jobIDs) == 0 {
+		return nil
+	}
+	keys := make([]string, len(jobIDs))
+	for i, id := range jobIDs {
+		keys[i] = jobMetaKey(id)
+	}
+	return rdb.Del(ctx, keys...).Err()
+}
+
+// ListMeta only sees jobs whose meta has not expired: without tags, those still
+// on the recent-jobs list; with tags, every job in the intersection of the tag
+// sets.
+func (redisMetaStore) ListMeta(ctx context.Context, q MetaQuery) ([]JobMeta, int, error) {
+	var ids []string
+	var err error
+	if len(q.Tags) > 0 {
+		ids, err = taggedJobIDs(ctx, q.Tags)
+	} else {
+		ids, err = rdb.LRange(ctx, redisKey(RedisRecentJobsList), 0, -1).Result()
+	}
+	if err != nil && err != redis.Nil {
+		return nil, 0, err
+	}
+	metas, err := loadMetas(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(q.Tags) > 0 {
+		// set members have no order; match the recent list's newest-first
+		sort.Slice(metas, func(i, j int) bool {
+			if !metas[i].CreatedAt.Equal(metas[j].CreatedAt) {
+				return metas[i].CreatedAt.After(metas[j].CreatedAt)
+			}
+			return metas[i].JobID < metas[j].JobID
+		})
+	}
+
+	filtered := make([]JobMeta, 0, len(metas))
+	for _, m := range metas {
+		if q.Status == "" || m.Status == q.Status {
+			filtered = append(filtered, m)
+		}
+	}
+	page := []JobMeta{}
+	if q.Offset < len(filtered) {
+		end := q.Offset + q.Limit
+		if end > len(filtered) {
+			end = len(filtered)
+		}
+		page = filtered[q.Offset:end]
+	}
+	return page, len(filtered), nil
+}
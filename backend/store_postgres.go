@@ -0,0 +1,205 @@
+package main
+
+// backend/store_postgres.go
+//
+// Postgres MetaStore (METADATA_BACKEND=postgres). Each job is one row in
+// job_meta holding the whole JobMeta as JSONB, so new meta fields need no
+// schema change. Migrations in migrations/ are applied at startup.
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // registers the "pgx" database/sql driver
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+type postgresMetaStore struct {
+	db *sql.DB
+}
+
+// openPostgresMetaStore connects to dsn and applies the migrations.
+func openPostgresMetaStore(ctx context.Context, dsn string) (*postgresMetaStore, error) {
+	if dsn == "" {
+		return nil, errors.New("DATABASE_URL is required when METADATA_BACKEND=postgres")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("postgres: %w", err)
+	}
+	if err := migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("postgres migrations: %w", err)
+	}
+	return &postgresMetaStore{db: db}, nil
+}
+
+// migrate runs every embedded migration in file name order. Migrations are
+// written to be idempotent (IF NOT EXISTS), so re-running them is safe.
+func migrate(ctx context.Context, db *sql.DB) error {
+	names, err := fs.Glob(migrationsFS, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		stmt, err := migrationsFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, string(stmt)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// SaveMeta upserts the row. An update older than the stored one is ignored so a
+// late event cannot roll a job's status back.
+func (s *postgresMetaStore) SaveMeta(ctx context.Context, meta JobMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO job_meta (job_id, status, created_at, updated_at, meta)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (job_id) DO UPDATE
+		SET status = EXCLUDED.status, updated_at = EXCLUDED.updated_at, meta = EXCLUDED.meta
+		WHERE job_meta.updated_at <= EXCLUDED.updated_at`,
+		meta.JobID, meta.Status, meta.CreatedAt, meta.UpdatedAt, b)
+	return err
+}
+
+func (s *postgresMetaStore) GetMeta(ctx context.Context, jobID string) (JobMeta, error) {
+	var meta JobMeta
+	var b []byte
+	err := s.db.QueryRowContext(ctx, `SELECT meta FROM job_meta WHERE job_id = $1`, jobID).Scan(&b)
+	if errors.Is(err, sql.ErrNoRows) {
+		return meta, ErrMetaNotFound
+	} else if err != nil {
+		return meta, err
+	}
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return meta, fmt.Errorf("failed to parse job meta: %w", err)
+	}
+	return meta, nil
+}
+
+func (s *postgresMetaStore) DeleteMeta(ctx context.Context, jobIDs ...string) error {
+	if len(jobIDs) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(jobIDs))
+	args := make([]interface{}, len(jobIDs))
+	for i, id := range jobIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	_, err := s.db.ExecContext(ctx, `DELETE FROM job_meta WHERE job_id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	return err
+}
+
+func (s *postgresMetaStore) ListMeta(ctx context.Context, q MetaQuery) ([]JobMeta, int, error) {
+	// tags match with JSONB containment; "[]" means no tag filter
+	tags, err := json.Marshal(append([]string{}, q.Tags...))
+	if err != nil {
+		return nil, 0, err
+	}
+	var total int
+	err = s.db.QueryRowContext(ctx, `
+		SELECT count(*) FROM job_meta
+		WHERE ($1 = '' OR status = $1) AND ($2 = '[]' OR meta->'tags' @> $2::jsonb)`,
+		q.Status, string(tags)).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT meta FROM job_meta
+		WHERE ($1 = '' OR status = $1) AND ($2 = '[]' OR meta->'tags' @> $2::jsonb)
+		ORDER BY created_at DESC, job_id
+		LIMIT $3 OFFSET $4`,
+		q.Status, string(tags), q.Limit, q.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	metas := []JobMeta{}
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, 0, err
+		}
+		var meta JobMeta
+		if err := json.Unmarshal(b, &meta); err != nil {
+			return nil, 0, fmt.Errorf("failed to parse job meta: %w", err)
+		}
+		metas = append(metas, meta)
+	}
+	return metas, total, rows.Err()
+}
+
+// searchWhere is the MetaSearch filter; unset bounds are passed as NULL.
+const searchWhere = `
+	WHERE ($1::timestamptz IS NULL OR created_at >= $1)
+	  AND ($2::timestamptz IS NULL OR created_at < $2)
+	  AND ($3::float8 IS NULL OR abs((meta->'params'->>'lat')::float8 - $3) <= $5)
+	  AND ($4::float8 IS NULL OR abs((meta->'params'->>'lon')::float8 - $4) <= $5)`
+
+func (s *postgresMetaStore) SearchMeta(ctx context.Context, q MetaSearch) (MetaSearchPage, error) {
+	timeArg := func(t time.Time) interface{} {
+		if t.IsZero() {
+			return nil
+		}
+		return t
+	}
+	floatArg := func(f *float64) interface{} {
+		if f == nil {
+			return nil
+		}
+		return *f
+	}
+	args := []interface{}{timeArg(q.CreatedAfter), timeArg(q.CreatedBefore), floatArg(q.Lat), floatArg(q.Lon), q.Tolerance}
+
+	page := MetaSearchPage{Jobs: []JobMeta{}}
+	if err := s.db.QueryRowContext(ctx, `SELECT count(*) FROM job_meta`+searchWhere, args...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT meta FROM job_meta`+searchWhere+`
+		ORDER BY created_at DESC, job_id
+		LIMIT $6 OFFSET $7`,
+		append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return page, err
+		}
+		var meta JobMeta
+		if err := json.Unmarshal(b, &meta); err != nil {
+			return page, fmt.Errorf("failed to parse job meta: %w", err)
+		}
+		page.Jobs = append(page.Jobs, meta)
+	}
+	return page, rows.Err()
+}
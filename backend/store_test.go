@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureArg is a sqlmock argument matcher that accepts anything and keeps it.
+type captureArg struct {
+	value driver.Value
+}
+
+func (a *captureArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func sampleMeta() JobMeta {
+	created := time.Date(2025, 11, 1, 8, 0, 0, 0, time.UTC)
+	started := created.Add(30 * time.Second)
+	return JobMeta{
+		JobID:            "pg-job",
+		Status:           StatusRunning,
+		CreatedAt:        created,
+		UpdatedAt:        started,
+		StartedAt:        &started,
+		Params:           SimulationParams{A_glass: floatPtr(75), StartDate: "2025-11-01", EndDate: "2025-11-02"},
+		ResultKey:        RedisResultsPrefix + "pg-job",
+		BatchID:          "batch-1",
+		ResultTTLSeconds: 3600,
+	}
+}
+
+func newMockPostgresStore(t *testing.T) (*postgresMetaStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return &postgresMetaStore{db: db}, mock
+}
+
+func TestPostgresMetaStoreRoundTrip(t *testing.T) {
+	store, mock := newMockPostgresStore(t)
+	ctx := context.Background()
+	meta := sampleMeta()
+
+	doc := &captureArg{}
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO job_meta")).
+		WithArgs(meta.JobID, meta.Status, meta.CreatedAt, meta.UpdatedAt, doc).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, store.SaveMeta(ctx, meta))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT meta FROM job_meta WHERE job_id = $1")).
+		WithArgs(meta.JobID).
+		WillReturnRows(sqlmock.NewRows([]string{"meta"}).AddRow(doc.value))
+	got, err := store.GetMeta(ctx, meta.JobID)
+	require.NoError(t, err)
+	assert.Equal(t, meta, got)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresMetaStoreGetMissing(t *testing.T) {
+	store, mock := newMockPostgresStore(t)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT meta FROM job_meta")).
+		WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows([]string{"meta"}))
+	_, err := store.GetMeta(context.Background(), "nope")
+	assert.ErrorIs(t, err, ErrMetaNotFound)
+}
+
+func TestPostgresMetaStoreDelete(t *testing.T) {
+	store, mock := newMockPostgresStore(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM job_meta WHERE job_id IN ($1, $2)")).
+		WithArgs("a", "b").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	require.NoError(t, store.DeleteMeta(context.Background(), "a", "b"))
+	require.NoError(t, store.DeleteMeta(context.Background()), "no ids, no query")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPostgresMetaStoreList(t *testing.T) {
+	store, mock := newMockPostgresStore(t)
+	meta := sampleMeta()
+	b, err := json.Marshal(meta)
+	require.NoError(t, err)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM job_meta")).
+		WithArgs(StatusRunning, `["greenhouse-42"]`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT meta FROM job_meta")).
+		WithArgs(StatusRunning, `["greenhouse-42"]`, 1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"meta"}).AddRow(b))
+
+	metas, total, err := store.ListMeta(context.Background(), MetaQuery{Status: StatusRunning, Tags: []string{"greenhouse-42"}, Limit: 1, Offset: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, []JobMeta{meta}, metas)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationsApply(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS job_meta")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX IF NOT EXISTS job_meta_tags_idx")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, migrate(context.Background(), db))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisMetaStoreRoundTrip(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	store := redisMetaStore{}
+	meta := sampleMeta()
+
+	require.NoError(t, store.SaveMeta(ctx, meta))
+	got, err := store.GetMeta(ctx, meta.JobID)
+	require.NoError(t, err)
+	assert.True(t, meta.CreatedAt.Equal(got.CreatedAt))
+	got.CreatedAt, got.UpdatedAt, got.StartedAt = meta.CreatedAt, meta.UpdatedAt, meta.StartedAt
+	assert.Equal(t, meta, got)
+
+	_, err = store.GetMeta(ctx, "nope")
+	assert.ErrorIs(t, err, ErrMetaNotFound)
+
+	require.NoError(t, store.DeleteMeta(ctx, meta.JobID, "nope"))
+	_, err = store.GetMeta(ctx, meta.JobID)
+	assert.ErrorIs(t, err, ErrMetaNotFound)
+}
+
+func TestMirroredMetaStore(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	store, mock := newMockPostgresStore(t)
+	metaStore = store
+	defer func() { metaStore = redisMetaStore{} }()
+
+	// submission records the meta in Postgres before queueing
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO job_meta")).
+		WithArgs(sqlmock.AnyArg(), StatusQueued, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// /jobs/:job_id reads through the store, not Redis
+	meta := sampleMeta()
+	b, _ := json.Marshal(meta)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT meta FROM job_meta WHERE job_id = $1")).
+		WithArgs(meta.JobID).
+		WillReturnRows(sqlmock.NewRows([]string{"meta"}).AddRow(b))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/jobs/"+meta.JobID, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// worker transitions published on job_events:* are synced into the store
+	syncCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	done := make(chan struct{})
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO job_meta")).
+		WithArgs(meta.JobID, StatusDone, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	go func() {
+		runMetaSync(syncCtx)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		return rdb.PubSubNumPat(ctx).Val() > 0
+	}, 2*time.Second, 10*time.Millisecond)
+	meta.Status = StatusDone
+	require.NoError(t, publishJobEvent(ctx, meta))
+	require.Eventually(t, func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	stop()
+	<-done
+
+	// deleting a job drops the store's copy too, and a failure there leaves Redis alone
+	seedJobMeta(t, ctx, "pg-delete", StatusDone)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM job_meta")).
+		WithArgs("pg-delete").
+		WillReturnError(errors.New("connection reset"))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/jobs/pg-delete", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, int64(1), rdb.Exists(ctx, jobMetaKey("pg-delete")).Val())
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM job_meta")).
+		WithArgs("pg-delete").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/jobs/pg-delete", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Zero(t, rdb.Exists(ctx, jobMetaKey("pg-delete")).Val())
+
+	// and so does purging it
+	old := seedJobMeta(t, ctx, "pg-old", StatusDone)
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM job_meta")).
+		WithArgs("pg-old").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	res, err := purgeJobsBefore(ctx, old.CreatedAt.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Purged)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
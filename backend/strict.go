@@ -0,0 +1,84 @@
+package main
+
+// backend/strict.go
+//
+// Strict decoding of POST /simulate. Binding ignores fields SimulationParams
+// does not have, so a typo ("setpont") quietly leaves setpoint at its default.
+// With ?strict=true or the X-Strict-Params: true header, a body with unknown
+// top-level fields is rejected with 400 listing every one of them. The fields
+// are found by comparing the body's keys with the SimulationParams json names
+// rather than with json.Decoder.DisallowUnknownFields: that stops at the first
+// unknown field and does not reach through SimulationParams.UnmarshalJSON.
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StrictParamsHeader turns on strict decoding like ?strict=true.
+const StrictParamsHeader = "X-Strict-Params"
+
+// knownParamFields holds the lowercased JSON names of SimulationParams' fields
+// (encoding/json matches keys case-insensitively, so we do too).
+var knownParamFields = func() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(SimulationParams{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[strings.ToLower(name)] = true
+		}
+	}
+	return fields
+}()
+
+// strictParams reports whether the request asked for strict decoding.
+func strictParams(c *gin.Context) bool {
+	return c.Query("strict") == "true" || c.GetHeader(StrictParamsHeader) == "true"
+}
+
+// unknownParamFields reports the top-level keys of body that are not
+// SimulationParams fields, sorted by name. A body that is not a JSON object
+// has none; binding reports it.
+func unknownParamFields(body []byte) []FieldError {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+	var unknown []FieldError
+	for name, value := range fields {
+		if !knownParamFields[strings.ToLower(name)] {
+			unknown = append(unknown, FieldError{Field: name, Value: value, Allowed: "no such field (strict mode)"})
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Field < unknown[j].Field })
+	return unknown
+}
+
+// checkUnknownFields rejects a body with unknown fields, answering the
+// request and returning false. The body is left readable for binding.
+func checkUnknownFields(c *gin.Context, limit int64) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, limit)
+			return false
+		}
+		respondError(c, http.StatusBadRequest, "reading body: "+err.Error())
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	unknown := unknownParamFields(body)
+	if len(unknown) == 0 {
+		return true
+	}
+	respondValidationError(c, &ValidationError{Fields: unknown})
+	return false
+}
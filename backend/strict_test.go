@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const typoBody = `{"A_glass": 80, "lat": 52.1, "lon": 5.2, "setpont": 18, "Tau_Glass": 0.7, "heatr_max_w": 5000}`
+
+func TestStrictRejectsUnknownFields(t *testing.T) {
+	router := setupRouter()
+	for name, prepare := range map[string]func(*http.Request){
+		"query":  func(r *http.Request) { r.URL.RawQuery = "strict=true&dry_run=true" },
+		"header": func(r *http.Request) { r.URL.RawQuery = "dry_run=true"; r.Header.Set(StrictParamsHeader, "true") },
+	} {
+		t.Run(name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(typoBody))
+			req.Header.Set("Content-Type", "application/json")
+			prepare(req)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			env := decodeError(t, w)
+			assert.Equal(t, CodeInvalidParam, env.Error.Code)
+			require.Len(t, env.Fields, 2, "every unknown field; keys match case-insensitively")
+			assert.Equal(t, "heatr_max_w", env.Fields[0].Field)
+			assert.Equal(t, "setpont", env.Fields[1].Field)
+			assert.Equal(t, 18.0, env.Fields[1].Value)
+		})
+	}
+}
+
+func TestLenientIgnoresUnknownFields(t *testing.T) {
+	router := setupRouter()
+	req, _ := http.NewRequest("POST", "/simulate?dry_run=true", bytes.NewBufferString(typoBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// strict mode accepts a body without typos
+	req, _ = http.NewRequest("POST", "/simulate?dry_run=true&strict=true", bytes.NewBufferString(withSite(`{"A_glass": 80, "setpoint": 18}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
@@ -0,0 +1,192 @@
+package main
+
+// backend/summary.go
+//
+// GET /results/:job_id/summary: report-ready aggregates of a job's series
+// instead of the raw rows. Temperatures are Tin in °C; heating energy
+// integrates Q_heater (W) over the job's timestep; hours below setpoint counts
+// the timesteps whose Tin is under the job's setpoint. While a job has no
+// final result yet, the aggregates cover the partial rows published so far
+// and "partial" is true. Aggregates over no data are null. "units" names the
+// unit of each aggregate.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+// resultStats is the body of GET /results/:job_id/summary.
+type resultStats struct {
+	JobID              string   `json:"job_id"`
+	Status             string   `json:"status,omitempty"` // the job's status, when summarizing partial rows
+	Partial            bool     `json:"partial"`
+	Points             int      `json:"points"`
+	TimestepSeconds    float64  `json:"timestep_seconds"`
+	Setpoint           *float64 `json:"setpoint"`
+	TinMin             *float64 `json:"Tin_min"`
+	TinMax             *float64 `json:"Tin_max"`
+	TinMean            *float64 `json:"Tin_mean"`
+	TinMedian          *float64 `json:"Tin_median"`
+	HeatingEnergyJ     *float64 `json:"heating_energy_J"`
+	HeatingEnergyKWh   *float64 `json:"heating_energy_kWh"`
+	HoursBelowSetpoint *float64 `json:"hours_below_setpoint"`
+	PeakHeaterW        *float64 `json:"peak_heater_W"`
+	// Units gives the unit of each numeric aggregate
+	Units map[string]string `json:"units"`
+}
+
+// summaryUnits is resultStats.Units.
+var summaryUnits = map[string]string{
+	"timestep_seconds":     "s",
+	"setpoint":             "°C",
+	"Tin_min":              "°C",
+	"Tin_max":              "°C",
+	"Tin_mean":             "°C",
+	"Tin_median":           "°C",
+	"heating_energy_J":     "J",
+	"heating_energy_kWh":   "kWh",
+	"hours_below_setpoint": "h",
+	"peak_heater_W":        "W",
+}
+
+// summarizeSeries aggregates points taken every dt seconds. Points without
+// Tin or Q_heater are left out of the aggregates that need them.
+func summarizeSeries(points []ResultPoint, dt float64, setpoint *float64) resultStats {
+	s := resultStats{Points: len(points), TimestepSeconds: dt, Setpoint: setpoint, Units: summaryUnits}
+	var temps []float64
+	var energy, peak float64
+	heaterPoints, below := 0, 0
+	for _, p := range points {
+		if p.Tin != nil {
+			temps = append(temps, *p.Tin)
+			if setpoint != nil && *p.Tin < *setpoint {
+				below++
+			}
+		}
+		if p.QHeater != nil {
+			energy += *p.QHeater * dt
+			if heaterPoints == 0 || *p.QHeater > peak {
+				peak = *p.QHeater
+			}
+			heaterPoints++
+		}
+	}
+	if len(temps) > 0 {
+		sort.Float64s(temps)
+		sum := 0.0
+		for _, t := range temps {
+			sum += t
+		}
+		s.TinMin = floatRef(temps[0])
+		s.TinMax = floatRef(temps[len(temps)-1])
+		s.TinMean = floatRef(sum / float64(len(temps)))
+		s.TinMedian = floatRef(median(temps))
+		if setpoint != nil {
+			s.HoursBelowSetpoint = floatRef(float64(below) * dt / 3600)
+		}
+	}
+	if heaterPoints > 0 {
+		s.HeatingEnergyJ = floatRef(energy)
+		s.HeatingEnergyKWh = floatRef(energy / 3.6e6)
+		s.PeakHeaterW = floatRef(peak)
+	}
+	return s
+}
+
+// median of sorted, which must not be empty.
+func median(sorted []float64) float64 {
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+// floatRef returns a pointer to v, or nil when v is not finite (JSON has no NaN).
+func floatRef(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// partialPoints decodes jobID's published partial chunks into one series.
+func partialPoints(ctx context.Context, jobID string) ([]ResultPoint, error) {
+	chunks, err := rdb.LRange(ctx, partialResultKey(jobID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	var points []ResultPoint
+	for _, chunk := range chunks {
+		var rows []ResultPoint
+		if err := json.Unmarshal([]byte(chunk), &rows); err != nil {
+			continue // a malformed chunk should not hide the rest
+		}
+		points = append(points, rows...)
+	}
+	return points, nil
+}
+
+// summarizeResult summarizes a final result with the timestep and setpoint of
+// the params the worker ran with, as echoed in the result.
+func summarizeResult(r *SimulationResult) resultStats {
+	var params SimulationParams
+	if len(r.Params) > 0 {
+		_ = json.Unmarshal(r.Params, &params)
+	}
+	return summarizeSeries(r.Data, timestepSeconds(params.TimestepSeconds), params.Setpoint)
+}
+
+func getResultSummaryHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+
+	stored, err := rdb.Get(ctx, jobResultKey(jobID)).Result()
+	if err == nil {
+		res, err := decodeResult(stored)
+		if err != nil {
+			respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": jobID})
+			return
+		}
+		r, err := parseSimulationResult(res)
+		if err != nil {
+			respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": jobID})
+			return
+		}
+		stats := summarizeResult(r)
+		stats.JobID = jobID
+		c.JSON(http.StatusOK, stats)
+		return
+	} else if !errors.Is(err, redis.Nil) {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+
+	// no final result yet: summarize what the worker has published so far
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "no result or job not found")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	points, err := partialPoints(ctx, jobID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	stats := summarizeSeries(points, timestepSeconds(meta.Params.TimestepSeconds), meta.Params.Setpoint)
+	stats.JobID = jobID
+	stats.Status = meta.Status
+	stats.Partial = true
+	c.JSON(http.StatusOK, stats)
+}
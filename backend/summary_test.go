@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// knownSeries is four hourly points with setpoint 12: two are below it.
+const knownSeries = `{
+	"job_id": "summary-job",
+	"params": {"setpoint": 12},
+	"data": [
+		{"datetime": "2025-11-01T00:00:00", "Tin": 11, "Q_heater": 2000},
+		{"datetime": "2025-11-01T01:00:00", "Tin": 13, "Q_heater": 0},
+		{"datetime": "2025-11-01T02:00:00", "Tin": 10, "Q_heater": 3000},
+		{"datetime": "2025-11-01T03:00:00", "Tin": 16, "Q_heater": 1000}
+	]
+}`
+
+func getSummary(t *testing.T, router http.Handler, jobID string) (int, resultStats) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", "/results/"+jobID+"/summary", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var stats resultStats
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+	}
+	return w.Code, stats
+}
+
+func TestSummarizeSeries(t *testing.T) {
+	var r SimulationResult
+	require.NoError(t, json.Unmarshal([]byte(knownSeries), &r))
+
+	s := summarizeSeries(r.Data, 3600, floatPtr(12))
+	assert.Equal(t, 4, s.Points)
+	assert.Equal(t, 10.0, *s.TinMin)
+	assert.Equal(t, 16.0, *s.TinMax)
+	assert.Equal(t, 12.5, *s.TinMean)
+	assert.Equal(t, 12.0, *s.TinMedian)
+	assert.Equal(t, 6000.0*3600, *s.HeatingEnergyJ)
+	assert.InDelta(t, 6.0, *s.HeatingEnergyKWh, 1e-9)
+	assert.Equal(t, 2.0, *s.HoursBelowSetpoint)
+	assert.Equal(t, 3000.0, *s.PeakHeaterW)
+
+	// 15-minute steps: a quarter of the energy and hours
+	s = summarizeSeries(r.Data, 900, floatPtr(12))
+	assert.InDelta(t, 1.5, *s.HeatingEnergyKWh, 1e-9)
+	assert.Equal(t, 0.5, *s.HoursBelowSetpoint)
+	assert.Equal(t, 11.0, *summarizeSeries(r.Data[:3], 3600, nil).TinMedian)
+}
+
+func TestSummarizeSeriesEmpty(t *testing.T) {
+	s := summarizeSeries(nil, 3600, floatPtr(12))
+	assert.Equal(t, 0, s.Points)
+	assert.Nil(t, s.TinMin)
+	assert.Nil(t, s.TinMedian)
+	assert.Nil(t, s.HeatingEnergyJ)
+	assert.Nil(t, s.HoursBelowSetpoint)
+	assert.Nil(t, s.PeakHeaterW)
+
+	// no Q_heater column: temperature stats only
+	s = summarizeSeries([]ResultPoint{{Datetime: "2025-11-01T00:00:00", Tin: floatPtr(14)}}, 3600, nil)
+	assert.Equal(t, 14.0, *s.TinMean)
+	assert.Nil(t, s.HeatingEnergyJ)
+	assert.Nil(t, s.HoursBelowSetpoint, "unknown without a setpoint")
+}
+
+func TestResultSummaryHandler(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	require.NoError(t, rdb.Set(ctx, RedisResultsPrefix+"summary-job", knownSeries, 0).Err())
+
+	code, stats := getSummary(t, router, "summary-job")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "summary-job", stats.JobID)
+	assert.False(t, stats.Partial)
+	assert.Equal(t, 12.0, *stats.Setpoint)
+	assert.Equal(t, 2.0, *stats.HoursBelowSetpoint)
+	assert.Equal(t, 3000.0, *stats.PeakHeaterW)
+	assert.Equal(t, map[string]string{
+		"timestep_seconds":     "s",
+		"setpoint":             "°C",
+		"Tin_min":              "°C",
+		"Tin_max":              "°C",
+		"Tin_mean":             "°C",
+		"Tin_median":           "°C",
+		"heating_energy_J":     "J",
+		"heating_energy_kWh":   "kWh",
+		"hours_below_setpoint": "h",
+		"peak_heater_W":        "W",
+	}, stats.Units)
+	for _, name := range numericJSONFields(reflect.TypeOf(resultStats{})) {
+		assert.Contains(t, stats.Units, name)
+	}
+
+	code, _ = getSummary(t, router, "no-such-job")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestResultSummaryPartial(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	seedJobMeta(t, ctx, "running-job", StatusRunning)
+
+	// nothing published yet
+	code, stats := getSummary(t, router, "running-job")
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, stats.Partial)
+	assert.Equal(t, StatusRunning, stats.Status)
+	assert.Equal(t, 0, stats.Points)
+	assert.Nil(t, stats.TinMean)
+
+	require.NoError(t, rdb.RPush(ctx, RedisPartialResultsPrefix+"running-job",
+		`[{"datetime": "2025-11-01T00:00:00", "Tin": 11, "Q_heater": 2000}]`,
+		`[{"datetime": "2025-11-01T01:00:00", "Tin": 13, "Q_heater": 0}]`).Err())
+	code, stats = getSummary(t, router, "running-job")
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, stats.Partial)
+	assert.Equal(t, 2, stats.Points)
+	assert.Equal(t, 12.0, *stats.TinMean)
+	assert.Equal(t, 2000.0*3600, *stats.HeatingEnergyJ)
+}
+
+func TestResultSummaryMalformed(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	for name, stored := range map[string]string{
+		"schema":   `{"data": [{"datetime": "2025-11-01T00:00:00", "Tin": "warm"}]}`,
+		"no data":  `{"summary": {}}`,
+		"bad gzip": string(gzipMagic) + "not gzip",
+		"not json": `<html>`,
+	} {
+		require.NoError(t, rdb.Set(ctx, jobResultKey("bad-job"), stored, DefaultResultTTL).Err())
+		req, _ := http.NewRequest("GET", "/results/bad-job/summary", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadGateway, w.Code, name)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), name)
+		assert.Contains(t, response["error"].(map[string]interface{})["message"], "malformed result from worker", name)
+		assert.Equal(t, "bad-job", response["job_id"], name)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+// backend/sweep.go
+//
+// POST /simulate/sweep: one request that expands into a grid of jobs. The body
+// is a normal parameter set plus "sweep", mapping field names to lists of
+// values; one job is enqueued per point of their Cartesian product. The sweep
+// is all-or-nothing: if any point fails validation nothing is enqueued.
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DefaultMaxSweepCombinations caps the number of jobs a single sweep may create.
+const DefaultMaxSweepCombinations = 100
+
+var maxSweepCombinations = DefaultMaxSweepCombinations
+
+// sweepRequest is the body of POST /simulate/sweep.
+type sweepRequest struct {
+	SimulationParams
+	Sweep map[string][]json.RawMessage `json:"sweep"`
+}
+
+// UnmarshalJSON decodes the params with their own decoder (see coerce.go) and
+// then the sweep; the promoted SimulationParams method would otherwise skip it.
+func (r *sweepRequest) UnmarshalJSON(data []byte) error {
+	if err := r.SimulationParams.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var s struct {
+		Sweep map[string][]json.RawMessage `json:"sweep"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	r.Sweep = s.Sweep
+	return nil
+}
+
+// sweepPoint maps each swept field to the value used for one job.
+type sweepPoint map[string]json.RawMessage
+
+// sweepItem is a job created for one sweep point.
+type sweepItem struct {
+	Index      int        `json:"index"`
+	JobID      string     `json:"job_id"`
+	Status     string     `json:"status"`
+	SweepPoint sweepPoint `json:"sweep_point"`
+}
+
+// sweepableFields are the JSON names of SimulationParams, the only keys a sweep may vary.
+var sweepableFields = func() map[string]bool {
+	fields := map[string]bool{}
+	t := reflect.TypeOf(SimulationParams{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// sweepPoints expands sweep into its Cartesian product in a stable order:
+// fields sorted by name, the last field varying fastest. It reports ok=false
+// without expanding when the product would exceed max.
+func sweepPoints(sweep map[string][]json.RawMessage, max int) (points []sweepPoint, total int, ok bool) {
+	fields := make([]string, 0, len(sweep))
+	total = 1
+	for field, values := range sweep {
+		fields = append(fields, field)
+		total *= len(values)
+		if total > max {
+			return nil, total, false
+		}
+	}
+	sort.Strings(fields)
+
+	points = []sweepPoint{{}}
+	for _, field := range fields {
+		next := make([]sweepPoint, 0, len(points)*len(sweep[field]))
+		for _, p := range points {
+			for _, v := range sweep[field] {
+				q := make(sweepPoint, len(p)+1)
+				for k, pv := range p {
+					q[k] = pv
+				}
+				q[field] = v
+				next = append(next, q)
+			}
+		}
+		points = next
+	}
+	return points, total, true
+}
+
+// paramsAt returns base with the point's values substituted.
+func paramsAt(base SimulationParams, point sweepPoint) (SimulationParams, error) {
+	b, err := json.Marshal(base)
+	if err != nil {
+		return SimulationParams{}, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return SimulationParams{}, err
+	}
+	for k, v := range point {
+		fields[k] = v
+	}
+	if b, err = json.Marshal(fields); err != nil {
+		return SimulationParams{}, err
+	}
+	var out SimulationParams
+	err = json.Unmarshal(b, &out)
+	return out, err
+}
+
+func submitSweepHandler(c *gin.Context) {
+	var req sweepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, maxBodyBytes)
+			return
+		}
+		respondError(c, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if len(req.Sweep) == 0 {
+		respondInvalidParam(c, "sweep", "sweep must map at least one field to a list of values")
+		return
+	}
+	for field, values := range req.Sweep {
+		if !sweepableFields[field] {
+			respondInvalidParam(c, "sweep", "unknown sweep field: "+field)
+			return
+		}
+		if len(values) == 0 {
+			respondInvalidParam(c, "sweep", "sweep field has no values: "+field)
+			return
+		}
+	}
+	points, total, ok := sweepPoints(req.Sweep, maxSweepCombinations)
+	if !ok {
+		respondInvalidParam(c, "sweep", "sweep has too many combinations",
+			gin.H{"combinations": total, "max_combinations": maxSweepCombinations})
+		return
+	}
+
+	sweepID := uuid.NewString()
+	now := time.Now().UTC()
+	metas := make([]JobMeta, 0, len(points))
+	itemErrors := []batchItemError{}
+	allowExtreme := c.Query("allow_extreme") == "true"
+	for i, point := range points {
+		params, err := paramsAt(req.SimulationParams, point)
+		if err != nil {
+			itemErrors = append(itemErrors, batchItemError{Index: i, Error: "invalid sweep value: " + err.Error()})
+			continue
+		}
+		units, err := toSI(&params)
+		if err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		if err := applyPreset(&params); err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		applyDefaults(&params)
+		if _, err := validateParams(&params, allowExtreme); err != nil {
+			itemErrors = append(itemErrors, newBatchItemError(i, err))
+			continue
+		}
+		meta := newJobMeta(params, now)
+		meta.BatchID = sweepID
+		meta.Units = units
+		metas = append(metas, meta)
+	}
+	if len(itemErrors) > 0 {
+		respondAPIError(c, http.StatusBadRequest, apiError{Code: CodeInvalidParam, Message: "sweep rejected: invalid parameter sets"}, gin.H{"errors": itemErrors})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	if !claimJobQuota(c, ctx, metas) {
+		return
+	}
+	if err := enqueueJobs(ctx, metas); err != nil {
+		releaseJobQuota(c, ctx, metas)
+		respondError(c, http.StatusInternalServerError, "failed to enqueue sweep: "+err.Error())
+		return
+	}
+
+	items := make([]sweepItem, len(metas))
+	for i, meta := range metas {
+		items[i] = sweepItem{Index: i, JobID: meta.JobID, Status: meta.Status, SweepPoint: points[i]}
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"sweep_id": sweepID,
+		"total":    len(items),
+		"jobs":     items,
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postSweep submits body to /simulate/sweep and decodes the response.
+func postSweep(t *testing.T, router http.Handler, body string) (int, map[string]interface{}) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", "/simulate/sweep", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestSweepPoints(t *testing.T) {
+	sweep := map[string][]json.RawMessage{
+		"setpoint": {json.RawMessage(`10`), json.RawMessage(`12`), json.RawMessage(`14`)},
+		"ACH":      {json.RawMessage(`0.3`), json.RawMessage(`0.5`)},
+	}
+	points, total, ok := sweepPoints(sweep, 100)
+	require.True(t, ok)
+	assert.Equal(t, 6, total)
+	require.Len(t, points, 6)
+	// fields sorted by name ("ACH" < "setpoint"), last one varies fastest
+	assert.Equal(t, sweepPoint{"ACH": json.RawMessage(`0.3`), "setpoint": json.RawMessage(`10`)}, points[0])
+	assert.Equal(t, sweepPoint{"ACH": json.RawMessage(`0.3`), "setpoint": json.RawMessage(`12`)}, points[1])
+	assert.Equal(t, sweepPoint{"ACH": json.RawMessage(`0.5`), "setpoint": json.RawMessage(`14`)}, points[5])
+
+	_, total, ok = sweepPoints(sweep, 5)
+	assert.False(t, ok)
+	assert.Greater(t, total, 5)
+}
+
+func TestSubmitSweep(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	code, response := postSweep(t, router, withSite(`{"A_glass": 80, "sweep": {"setpoint": [10, 12, 14], "ACH": [0.3, 0.5]}}`))
+	require.Equal(t, http.StatusAccepted, code)
+	assert.Equal(t, float64(6), response["total"])
+	jobs := response["jobs"].([]interface{})
+	require.Len(t, jobs, 6)
+
+	seen := map[[2]float64]bool{}
+	for _, j := range jobs {
+		item := j.(map[string]interface{})
+		point := item["sweep_point"].(map[string]interface{})
+		meta, err := redisMetaStore{}.GetMeta(ctx, item["job_id"].(string))
+		require.NoError(t, err)
+
+		// each job runs with its own point on top of the shared base
+		assert.Equal(t, point["setpoint"], *meta.Params.Setpoint)
+		assert.Equal(t, point["ACH"], *meta.Params.ACH)
+		assert.Equal(t, 80.0, *meta.Params.A_glass)
+		assert.Equal(t, response["sweep_id"], meta.BatchID)
+		seen[[2]float64{*meta.Params.Setpoint, *meta.Params.ACH}] = true
+	}
+	assert.Len(t, seen, 6)
+	assert.Equal(t, int64(6), rdb.LLen(ctx, RedisJobsList).Val())
+}
+
+func TestSubmitSweepRejects(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	orig := maxSweepCombinations
+	maxSweepCombinations = 4
+	defer func() { maxSweepCombinations = orig }()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no sweep", `{"A_glass": 80}`},
+		{"empty values", `{"sweep": {"setpoint": []}}`},
+		{"unknown field", `{"sweep": {"bogus": [1, 2]}}`},
+		{"too many combinations", `{"sweep": {"setpoint": [10, 12, 14], "ACH": [0.3, 0.5]}}`},
+		{"invalid point", `{"sweep": {"tau_glass": [0.5, 1.5]}}`},
+		{"wrong value type", `{"sweep": {"setpoint": ["warm"]}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := postSweep(t, router, tt.body)
+			assert.Equal(t, http.StatusBadRequest, code)
+		})
+	}
+	// an invalid point rejects the whole sweep
+	assert.Equal(t, int64(0), rdb.LLen(ctx, RedisJobsList).Val())
+}
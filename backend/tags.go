@@ -0,0 +1,73 @@
+package main
+
+// backend/tags.go
+//
+// Job tags. A submission may carry "tags" (e.g. a customer or greenhouse id);
+// they are stored on the meta and each job id is added to the Redis set
+// jobs_by_tag:<tag>, so GET /jobs?tag=a&tag=b can intersect the sets. Set
+// members are not expired with the job; ids whose meta is gone are skipped
+// when listing.
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedisJobsByTagPrefix keys jobs_by_tag:<tag> -> set of job ids.
+const RedisJobsByTagPrefix = "jobs_by_tag:"
+
+// Tag limits
+const (
+	MaxTagsPerJob = 20
+	MaxTagLength  = 64
+)
+
+// tagPattern keeps tags safe to use in Redis keys and query strings.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]*$`)
+
+// normalizeTags trims, dedupes and sorts tags. It returns nil for no tags.
+func normalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// validTag reports whether tag may be stored and queried.
+func validTag(tag string) bool {
+	return len(tag) <= MaxTagLength && tagPattern.MatchString(tag)
+}
+
+// validateTags returns a FieldError for the first bad tag or for too many tags.
+func validateTags(tags []string) []FieldError {
+	tags = normalizeTags(tags)
+	if len(tags) > MaxTagsPerJob {
+		return []FieldError{{Field: "tags", Value: len(tags), Allowed: fmt.Sprintf("at most %d tags", MaxTagsPerJob)}}
+	}
+	for _, t := range tags {
+		if !validTag(t) {
+			return []FieldError{{Field: "tags", Value: t, Allowed: fmt.Sprintf("up to %d letters, digits or . _ : / - (starting with a letter or digit)", MaxTagLength)}}
+		}
+	}
+	return nil
+}
+
+// taggedJobIDs returns the ids of jobs carrying every one of tags.
+func taggedJobIDs(ctx context.Context, tags []string) ([]string, error) {
+	keys := make([]string, len(tags))
+	for i, t := range tags {
+		keys[i] = jobsByTagKey(t)
+	}
+	return rdb.SInter(ctx, keys...).Result()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAndValidateTags(t *testing.T) {
+	assert.Equal(t, []string{"a", "customer:acme", "greenhouse-42"},
+		normalizeTags([]string{" greenhouse-42", "a", "customer:acme", "a", ""}))
+	assert.Nil(t, normalizeTags(nil))
+
+	assert.Empty(t, validateTags([]string{"greenhouse-42", "customer:acme", "site/north_1"}))
+	for _, bad := range []string{"has space", "-leading", "comma,separated", strings.Repeat("a", MaxTagLength+1)} {
+		assert.NotEmpty(t, validateTags([]string{bad}), bad)
+	}
+	many := make([]string, MaxTagsPerJob+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("tag-%d", i)
+	}
+	assert.NotEmpty(t, validateTags(many))
+}
+
+func TestListJobsByTag(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	submit := func(body string) string {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response["job_id"].(string)
+	}
+	a := submit(withSite(`{"tags": ["greenhouse-42", "customer:acme"]}`))
+	b := submit(withSite(`{"tags": ["greenhouse-42"]}`))
+	c := submit(withSite(`{"tags": ["customer:acme", "greenhouse-7"]}`))
+	submit(withSite(`{}`))
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, a)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"customer:acme", "greenhouse-42"}, meta.Tags)
+	assert.Nil(t, meta.Params.Tags)
+
+	ids := func(jobs []JobMeta) []string {
+		out := make([]string, len(jobs))
+		for i, j := range jobs {
+			out[i] = j.JobID
+		}
+		return out
+	}
+
+	code, total, jobs := listJobs(t, router, "?tag=greenhouse-42")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, total)
+	assert.ElementsMatch(t, []string{a, b}, ids(jobs))
+
+	code, total, jobs = listJobs(t, router, "?tag=customer:acme")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 2, total)
+	assert.ElementsMatch(t, []string{a, c}, ids(jobs))
+
+	// several tags are ANDed
+	_, total, jobs = listJobs(t, router, "?tag=greenhouse-42&tag=customer:acme")
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{a}, ids(jobs))
+
+	_, total, _ = listJobs(t, router, "?tag=greenhouse-42&tag=greenhouse-7")
+	assert.Equal(t, 0, total)
+
+	// combines with the status filter
+	rdb.Set(ctx, RedisJobMetaPrefix+b, mustMarshalMeta(t, ctx, b, StatusDone), 0)
+	_, total, jobs = listJobs(t, router, "?tag=greenhouse-42&status=done")
+	assert.Equal(t, 1, total)
+	assert.Equal(t, []string{b}, ids(jobs))
+
+	code, _, _ = listJobs(t, router, "?tag=not%20valid")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// deleting a job drops it from its tag sets
+	req, _ := http.NewRequest("DELETE", "/jobs/"+a, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, rdb.SIsMember(ctx, RedisJobsByTagPrefix+"customer:acme", a).Val())
+}
+
+// mustMarshalMeta returns jobID's stored meta with its status replaced.
+func mustMarshalMeta(t *testing.T, ctx context.Context, jobID, status string) []byte {
+	t.Helper()
+	meta, err := redisMetaStore{}.GetMeta(ctx, jobID)
+	require.NoError(t, err)
+	meta.Status = status
+	b, err := json.Marshal(meta)
+	require.NoError(t, err)
+	return b
+}
+
+func TestSubmitRejectsInvalidTags(t *testing.T) {
+	router := setupRouter()
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(`{"tags": ["ok", "not ok"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "tags")
+}
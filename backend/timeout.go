@@ -0,0 +1,75 @@
+package main
+
+// backend/timeout.go
+//
+// Per-request deadline. Every request except the SSE streams gets REQUEST_TIMEOUT
+// (default 60s) to answer; past that the client receives 503 and the request
+// context is cancelled, which aborts the handler's Redis calls since they run on
+// c.Request.Context(). Synchronous waits (?wait=true) are capped below the
+// deadline so they can still fall back to 202.
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultRequestTimeout bounds a request when REQUEST_TIMEOUT is unset; 0 disables the limit.
+const DefaultRequestTimeout = 60 * time.Second
+
+var requestTimeout = DefaultRequestTimeout
+
+// loadRequestTimeoutConfig reads REQUEST_TIMEOUT as a Go duration ("90s") or
+// a whole number of seconds.
+func loadRequestTimeoutConfig() {
+	v := os.Getenv("REQUEST_TIMEOUT")
+	if v == "" {
+		return
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		requestTimeout = d
+	} else if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		requestTimeout = time.Duration(n) * time.Second
+	} else {
+		slog.Warn("ignoring malformed REQUEST_TIMEOUT", "value", v)
+	}
+}
+
+// requestTimeoutBody is the 503 body, in the envelope respondError writes.
+var requestTimeoutBody = func() string {
+	b, _ := json.Marshal(gin.H{"error": apiError{Code: CodeTimeout, Message: "request timed out"}})
+	return string(b)
+}()
+
+// isEventStream reports whether r is for an SSE endpoint, which stays open
+// for as long as the client listens.
+func isEventStream(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, "/events")
+}
+
+// withRequestTimeout wraps the router so each request runs under
+// requestTimeout. It sits outside gin rather than being a gin middleware so
+// that a handler overrunning the deadline keeps its gin.Context to itself
+// until it returns, while the client already has its 503.
+func withRequestTimeout(h http.Handler) http.Handler {
+	if requestTimeout <= 0 {
+		return h
+	}
+	timed := http.TimeoutHandler(h, requestTimeout, requestTimeoutBody)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isEventStream(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		// TimeoutHandler writes its body without headers; a handler's own
+		// Content-Type replaces this one when it answers in time
+		w.Header().Set("Content-Type", gin.MIMEJSON+"; charset=utf-8")
+		timed.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withRequestTimeoutValue(t *testing.T, d time.Duration) {
+	t.Helper()
+	orig := requestTimeout
+	requestTimeout = d
+	t.Cleanup(func() { requestTimeout = orig })
+}
+
+func TestRequestTimeout(t *testing.T) {
+	withRequestTimeoutValue(t, 50*time.Millisecond)
+	router := gin.New()
+	cancelled := make(chan bool, 1)
+	router.GET("/slow", func(c *gin.Context) {
+		select {
+		case <-time.After(time.Second):
+			c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		case <-c.Request.Context().Done():
+			cancelled <- true
+		}
+	})
+	router.GET("/fast", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"status": "ok"})
+	})
+	handler := withRequestTimeout(router)
+
+	start := time.Now()
+	req, _ := http.NewRequest("GET", "/slow", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.JSONEq(t, `{"error": {"code": "TIMEOUT", "message": "request timed out"}}`, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("handler context was not cancelled")
+	}
+
+	req, _ = http.NewRequest("GET", "/fast", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, w.Body.String())
+}
+
+func TestRequestTimeoutSkipsEventStreams(t *testing.T) {
+	withRequestTimeoutValue(t, 20*time.Millisecond)
+	router := gin.New()
+	router.GET("/jobs/:job_id/events", func(c *gin.Context) {
+		_, hasDeadline := c.Request.Context().Deadline()
+		require.False(t, hasDeadline)
+		time.Sleep(50 * time.Millisecond)
+		c.String(http.StatusOK, "event: done\n\n")
+	})
+
+	req, _ := http.NewRequest("GET", "/jobs/abc/events", nil)
+	w := httptest.NewRecorder()
+	withRequestTimeout(router).ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRequestTimeoutDisabled(t *testing.T) {
+	withRequestTimeoutValue(t, 0)
+	router := gin.New()
+	assert.Same(t, router, withRequestTimeout(router))
+}
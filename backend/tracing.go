@@ -0,0 +1,168 @@
+package main
+
+// backend/tracing.go
+//
+// Distributed tracing with OpenTelemetry. Every request runs in a server span
+// continued from its W3C traceparent/tracestate headers (traceRequests), and
+// every Redis command or pipeline issued on its behalf gets a child span
+// (redisTracingHook). The trace context of the submitting request is copied
+// into each job payload as trace_context, so the worker continues the trace.
+//
+// Spans are exported over OTLP/HTTP, configured by the standard
+// OTEL_EXPORTER_OTLP_* variables; OTEL_SERVICE_NAME and
+// OTEL_RESOURCE_ATTRIBUTES describe the service. Without an OTLP endpoint,
+// or with OTEL_SDK_DISABLED=true, nothing is exported, but trace context is
+// still propagated from requests into payloads.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the backend's instrumentation.
+const tracerName = "github.com/cc0ffee/greensim-backend"
+
+// DefaultServiceName is reported unless OTEL_SERVICE_NAME says otherwise.
+const DefaultServiceName = "greensim-backend"
+
+func init() {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
+// tracer is looked up on each use so a provider installed later (by
+// initTracing or a test) takes effect.
+func tracer() trace.Tracer { return otel.Tracer(tracerName) }
+
+// otlpConfigured reports whether an OTLP endpoint is set for traces.
+func otlpConfigured() bool {
+	if os.Getenv("OTEL_SDK_DISABLED") == "true" {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// initTracing installs a tracer provider exporting over OTLP when one is
+// configured. The returned function flushes and stops it.
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	if !otlpConfigured() {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("otlp exporter: %w", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", DefaultServiceName)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME, OTEL_RESOURCE_ATTRIBUTES
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// traceRequests runs each request in a server span, continuing the caller's
+// trace when the request carries one.
+func traceRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		name := c.Request.Method
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+		}
+		ctx, span := tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Request.Method),
+				attribute.String("http.route", c.FullPath()),
+				attribute.String("url.path", c.Request.URL.Path),
+			))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+		}
+	}
+}
+
+// traceContext returns the trace context of ctx in its propagated form, for
+// job payloads; nil when ctx is not part of a trace.
+func traceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// redisTracingHook records a client span for each command and pipeline run
+// within a trace. Commands outside one (the background trackers) are not
+// traced, so they do not start a trace of their own every few seconds.
+type redisTracingHook struct{}
+
+func (redisTracingHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (redisTracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return next(ctx, cmd)
+		}
+		ctx, span := startRedisSpan(ctx, cmd.Name())
+		defer span.End()
+		err := next(ctx, cmd)
+		recordRedisError(span, err)
+		return err
+	}
+}
+
+func (redisTracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			return next(ctx, cmds)
+		}
+		ctx, span := startRedisSpan(ctx, "pipeline")
+		span.SetAttributes(attribute.Int("db.operation.batch.size", len(cmds)))
+		defer span.End()
+		err := next(ctx, cmds)
+		recordRedisError(span, err)
+		return err
+	}
+}
+
+func startRedisSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "redis "+operation, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation.name", operation),
+		))
+}
+
+// recordRedisError marks span failed; redis.Nil is a normal "no such key".
+func recordRedisError(span trace.Span, err error) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
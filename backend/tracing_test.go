@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// withSpanRecorder installs a tracer provider that keeps finished spans in
+// memory for the duration of the test.
+func withSpanRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	orig := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(orig)
+	})
+	return exporter
+}
+
+func spanAttr(span tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestSimulateIsTraced(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.AddHook(redisTracingHook{})
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	exporter := withSpanRecorder(t)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest("POST", "/simulate", strings.NewReader(withSite(`{"A_glass": 80}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+
+	spans := exporter.GetSpans()
+	var server *tracetest.SpanStub
+	redisSpans := 0
+	for i, span := range spans {
+		assert.Equal(t, traceID, span.SpanContext.TraceID().String(), "every span continues the caller's trace")
+		switch {
+		case span.Name == "POST /simulate":
+			server = &spans[i]
+		case strings.HasPrefix(span.Name, "redis "):
+			redisSpans++
+			assert.Equal(t, "redis", spanAttr(span, "db.system").AsString())
+		}
+	}
+	require.NotNil(t, server, "a span is recorded for /simulate")
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, int64(http.StatusAccepted), spanAttr(*server, "http.response.status_code").AsInt64())
+	assert.Equal(t, "/simulate", spanAttr(*server, "http.route").AsString())
+	assert.Positive(t, redisSpans, "redis operations get spans of their own")
+
+	// the payload carries the trace on to the worker
+	payload, err := rdb.LIndex(ctx, queueFor(PriorityNormal), 0).Result()
+	require.NoError(t, err)
+	var p JobPayload
+	require.NoError(t, json.Unmarshal([]byte(payload), &p))
+	require.Contains(t, p.TraceContext, "traceparent")
+	assert.Contains(t, p.TraceContext["traceparent"], traceID)
+	assert.NotContains(t, p.TraceContext["traceparent"], "00f067aa0ba902b7", "the worker's parent is the server span")
+}
+
+func TestRedisOutsideTraceIsNotTraced(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	rdb.AddHook(redisTracingHook{})
+	exporter := withSpanRecorder(t)
+
+	require.NoError(t, rdb.Ping(context.Background()).Err())
+	assert.Empty(t, exporter.GetSpans())
+	assert.Nil(t, traceContext(context.Background()))
+}
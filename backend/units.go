@@ -0,0 +1,64 @@
+package main
+
+// backend/units.go
+//
+// Input unit systems. The worker only understands SI, so a submission in
+// imperial units is converted once, before presets and defaults are applied
+// (both are SI), and the job keeps the original system in meta.Units so
+// results can be presented back in it.
+
+// Unit systems accepted in the "units" field
+const (
+	UnitsSI       = "si"
+	UnitsImperial = "imperial"
+)
+
+// cubicMetersPerCubicFoot is the exact ft3 -> m3 factor (0.3048^3).
+const cubicMetersPerCubicFoot = 0.028316846592
+
+func fahrenheitToCelsius(f float64) float64    { return (f - 32) * 5 / 9 }
+func celsiusToFahrenheit(c float64) float64    { return c*9/5 + 32 }
+func cubicFeetToCubicMeters(v float64) float64 { return v * cubicMetersPerCubicFoot }
+func cubicMetersToCubicFeet(v float64) float64 { return v / cubicMetersPerCubicFoot }
+
+// temperature differences and per-degree rates scale without the 32° offset
+func fahrenheitDeltaToKelvin(d float64) float64  { return d * 5 / 9 }
+func perFahrenheitToPerKelvin(r float64) float64 { return r * 9 / 5 }
+
+// convertInPlace replaces *v with conv(*v) when v is set.
+func convertInPlace(v *float64, conv func(float64) float64) {
+	if v != nil {
+		*v = conv(*v)
+	}
+}
+
+// toSI converts the fields given in p.Units to SI and clears p.Units, so the
+// stored params are always canonical. It returns the unit system the client
+// used. Under imperial, T_init, setpoint, outdoor_temp_const and
+// outdoor_temp_mean are read as °F, heater_deadband and outdoor_temp_amplitude
+// as °F differences, heater_kp as W/°F, V as ft3 and ventilation_rate as
+// ft3/s; every other field is unit-free or already SI.
+func toSI(p *SimulationParams) (string, error) {
+	units := p.Units
+	p.Units = ""
+	switch units {
+	case "", UnitsSI:
+		return UnitsSI, nil
+	case UnitsImperial:
+		convertInPlace(p.T_init, fahrenheitToCelsius)
+		convertInPlace(p.Setpoint, fahrenheitToCelsius)
+		convertInPlace(p.HeaterDeadband, fahrenheitDeltaToKelvin)
+		convertInPlace(p.OutdoorTempConst, fahrenheitToCelsius)
+		convertInPlace(p.OutdoorTempMean, fahrenheitToCelsius)
+		convertInPlace(p.OutdoorTempAmplitude, fahrenheitDeltaToKelvin)
+		convertInPlace(p.HeaterKp, perFahrenheitToPerKelvin)
+		convertInPlace(p.Volume, cubicFeetToCubicMeters)
+		convertInPlace(p.VentilationRate, cubicFeetToCubicMeters)
+		return UnitsImperial, nil
+	}
+	return "", &ValidationError{Fields: []FieldError{{
+		Field:   "units",
+		Value:   units,
+		Allowed: "one of si, imperial",
+	}}}
+}
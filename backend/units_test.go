@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnitConversionRoundTrip(t *testing.T) {
+	assert.InDelta(t, 0.0, fahrenheitToCelsius(32), 1e-12)
+	assert.InDelta(t, 100.0, fahrenheitToCelsius(212), 1e-12)
+	assert.InDelta(t, -40.0, fahrenheitToCelsius(-40), 1e-12)
+	assert.InDelta(t, 1.0, cubicFeetToCubicMeters(35.31466672148859), 1e-12)
+
+	for _, v := range []float64{-40, 0, 12.5, 55, 98.6, 3531.4667} {
+		assert.InDelta(t, v, celsiusToFahrenheit(fahrenheitToCelsius(v)), 1e-9)
+		assert.InDelta(t, v, cubicMetersToCubicFeet(cubicFeetToCubicMeters(v)), 1e-9)
+	}
+}
+
+func TestToSI(t *testing.T) {
+	t.Run("si passthrough", func(t *testing.T) {
+		for _, units := range []string{"", UnitsSI} {
+			p := SimulationParams{Units: units, Setpoint: floatPtr(12), Volume: floatPtr(100)}
+			got, err := toSI(&p)
+			require.NoError(t, err)
+			assert.Equal(t, UnitsSI, got)
+			assert.Equal(t, 12.0, *p.Setpoint)
+			assert.Equal(t, 100.0, *p.Volume)
+			assert.Empty(t, p.Units)
+		}
+	})
+
+	t.Run("imperial", func(t *testing.T) {
+		p := SimulationParams{
+			Units:           UnitsImperial,
+			Setpoint:        floatPtr(50),
+			T_init:          floatPtr(59),
+			Volume:          floatPtr(3531.4666721488586),
+			VentilationRate: floatPtr(35.31466672148859),
+			HeaterDeadband:  floatPtr(1.8),
+			HeaterKp:        floatPtr(500),
+			A_glass:         floatPtr(80),
+		}
+		got, err := toSI(&p)
+		require.NoError(t, err)
+		assert.Equal(t, UnitsImperial, got)
+		assert.InDelta(t, 10.0, *p.Setpoint, 1e-9)
+		assert.InDelta(t, 15.0, *p.T_init, 1e-9)
+		assert.InDelta(t, 100.0, *p.Volume, 1e-9)
+		assert.InDelta(t, 1.0, *p.VentilationRate, 1e-9)
+		assert.InDelta(t, 1.0, *p.HeaterDeadband, 1e-9) // a difference: no 32° offset
+		assert.InDelta(t, 900.0, *p.HeaterKp, 1e-9)     // W/°F to W/K
+		assert.Equal(t, 80.0, *p.A_glass)               // not a converted field
+		assert.Empty(t, p.Units)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		p := SimulationParams{Units: "furlongs"}
+		_, err := toSI(&p)
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr)
+		assert.Equal(t, "units", verr.Fields[0].Field)
+	})
+}
+
+func TestSubmitImperialStoresSI(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(withSite(`{"units": "imperial", "setpoint": 50, "V": 3531.4666721488586}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, response["job_id"].(string))
+	require.NoError(t, err)
+	assert.Equal(t, UnitsImperial, meta.Units)
+	assert.Empty(t, meta.Params.Units)
+	assert.InDelta(t, 10.0, *meta.Params.Setpoint, 1e-9)
+	assert.InDelta(t, 100.0, *meta.Params.Volume, 1e-9)
+	assert.Equal(t, 15.0, *meta.Params.T_init) // defaults are SI and not converted
+
+	req, _ = http.NewRequest("POST", "/simulate", bytes.NewBufferString(`{"units": "furlongs"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "units")
+}
@@ -0,0 +1,290 @@
+package main
+
+// backend/upload.go
+//
+// POST /simulate/upload: submit a spreadsheet of parameter sets as a batch.
+// The request is multipart/form-data with the CSV in the "file" field. The
+// header row names SimulationParams fields by their json names (e.g. A_glass,
+// tau_glass, setpoint, tags); every other row is one parameter set. Empty
+// cells leave the field unset and tags are separated by ";". Rows are
+// validated like /simulate/batch and the valid ones are enqueued together;
+// jobs and errors are reported by spreadsheet row number (the header is row 1).
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DefaultMaxUploadRows caps the data rows accepted by one upload.
+const DefaultMaxUploadRows = 500
+
+// UploadFormField is the multipart field holding the CSV file.
+const UploadFormField = "file"
+
+var maxUploadRows = DefaultMaxUploadRows
+
+func loadUploadConfig() {
+	maxUploadRows = envInt("UPLOAD_MAX_ROWS", DefaultMaxUploadRows)
+}
+
+// uploadItem is an accepted row and the job created for it.
+type uploadItem struct {
+	Row      int      `json:"row"`
+	JobID    string   `json:"job_id"`
+	Status   string   `json:"status"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// uploadRowError reports why the row was not enqueued.
+type uploadRowError struct {
+	Row    int          `json:"row"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+func newUploadRowError(row int, err error) uploadRowError {
+	item := uploadRowError{Row: row, Error: err.Error()}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		item.Error = "invalid parameters"
+		item.Fields = verr.Fields
+	}
+	return item
+}
+
+// uploadColumns maps each SimulationParams json name to its field type, so a
+// cell can be converted to the JSON value the field expects.
+var uploadColumns = func() map[string]reflect.Type {
+	cols := map[string]reflect.Type{}
+	t := reflect.TypeOf(SimulationParams{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			cols[name] = t.Field(i).Type
+		}
+	}
+	return cols
+}()
+
+// parseUploadHeader returns the column names, rejecting any that is not a
+// SimulationParams field or appears twice.
+func parseUploadHeader(header []string) ([]string, error) {
+	cols := make([]string, len(header))
+	seen := map[string]bool{}
+	var unknown []string
+	for i, h := range header {
+		h = strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")) // spreadsheets often add a BOM
+		if _, ok := uploadColumns[h]; !ok {
+			unknown = append(unknown, h)
+		} else if seen[h] {
+			return nil, fmt.Errorf("column %q appears more than once", h)
+		}
+		seen[h] = true
+		cols[i] = h
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown columns: %s", strings.Join(unknown, ", "))
+	}
+	return cols, nil
+}
+
+// uploadCellValue converts a non-empty cell to the JSON value for a field of type t.
+func uploadCellValue(t reflect.Type, cell string) (interface{}, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Float64:
+		return strconv.ParseFloat(cell, 64)
+	case reflect.Int64:
+		return strconv.ParseInt(cell, 10, 64)
+	case reflect.Slice:
+		var items []string
+		for _, s := range strings.Split(cell, ";") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+		return items, nil
+	default:
+		return cell, nil
+	}
+}
+
+// parseUploadRow builds the parameter set for one CSV record. Cells that do
+// not convert are reported together as a *ValidationError.
+func parseUploadRow(cols, record []string) (SimulationParams, error) {
+	var params SimulationParams
+	obj := map[string]interface{}{}
+	var verr ValidationError
+	for i, col := range cols {
+		cell := strings.TrimSpace(record[i])
+		if cell == "" {
+			continue
+		}
+		t := uploadColumns[col]
+		v, err := uploadCellValue(t, cell)
+		if err != nil {
+			allowed := "a number"
+			if t.Elem().Kind() == reflect.Int64 {
+				allowed = "a whole number"
+			}
+			verr.Fields = append(verr.Fields, FieldError{Field: col, Value: cell, Allowed: allowed})
+			continue
+		}
+		obj[col] = v
+	}
+	if len(verr.Fields) > 0 {
+		return params, &verr
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return params, err
+	}
+	err = json.Unmarshal(b, &params)
+	return params, err
+}
+
+func submitUploadHandler(c *gin.Context) {
+	fh, err := c.FormFile(UploadFormField)
+	if err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, maxBatchBodyBytes)
+			return
+		}
+		respondError(c, http.StatusBadRequest, "expected multipart/form-data with a CSV file in the \""+UploadFormField+"\" field")
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "failed to read upload: "+err.Error())
+		return
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		respondError(c, http.StatusBadRequest, "upload is empty")
+		return
+	} else if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid CSV: "+err.Error())
+		return
+	}
+	cols, err := parseUploadHeader(header)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid CSV header: "+err.Error(),
+			gin.H{"allowed_columns": sortedUploadColumns()})
+		return
+	}
+
+	batchID := uuid.NewString()
+	now := time.Now().UTC()
+	var metas []JobMeta
+	var rows []int
+	var warnings [][]string
+	rowErrors := []uploadRowError{}
+	total := 0
+	allowExtreme := c.Query("allow_extreme") == "true"
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if total++; total > maxUploadRows {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("upload has more than %d rows", maxUploadRows),
+				gin.H{"max_rows": maxUploadRows})
+			return
+		}
+		if err != nil {
+			var perr *csv.ParseError
+			if errors.As(err, &perr) && errors.Is(perr.Err, csv.ErrFieldCount) {
+				rowErrors = append(rowErrors, uploadRowError{Row: perr.StartLine,
+					Error: fmt.Sprintf("row has %d cells, the header has %d", len(record), len(cols))})
+				continue
+			}
+			respondError(c, http.StatusBadRequest, "invalid CSV: "+err.Error())
+			return
+		}
+		row, _ := r.FieldPos(0)
+
+		params, err := parseUploadRow(cols, record)
+		if err != nil {
+			rowErrors = append(rowErrors, newUploadRowError(row, err))
+			continue
+		}
+		units, err := toSI(&params)
+		if err != nil {
+			rowErrors = append(rowErrors, newUploadRowError(row, err))
+			continue
+		}
+		if err := applyPreset(&params); err != nil {
+			rowErrors = append(rowErrors, newUploadRowError(row, err))
+			continue
+		}
+		w := applyDefaults(&params)
+		implausible, err := validateParams(&params, allowExtreme)
+		if err != nil {
+			rowErrors = append(rowErrors, newUploadRowError(row, err))
+			continue
+		}
+		meta := newJobMeta(params, now)
+		meta.BatchID = batchID
+		meta.Units = units
+		metas = append(metas, meta)
+		rows = append(rows, row)
+		warnings = append(warnings, append(w, implausible...))
+	}
+	if total == 0 {
+		respondError(c, http.StatusBadRequest, "upload has no data rows")
+		return
+	}
+	if len(metas) == 0 {
+		respondAPIError(c, http.StatusBadRequest, apiError{Code: CodeInvalidParam, Message: "upload rejected: no valid rows"}, gin.H{"errors": rowErrors})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), RedisOpTimeout)
+	defer cancel()
+	if !claimJobQuota(c, ctx, metas) {
+		return
+	}
+	if err := enqueueJobs(ctx, metas); err != nil {
+		releaseJobQuota(c, ctx, metas)
+		respondError(c, http.StatusInternalServerError, "failed to enqueue upload: "+err.Error())
+		return
+	}
+
+	items := make([]uploadItem, len(metas))
+	for i, meta := range metas {
+		items[i] = uploadItem{Row: rows[i], JobID: meta.JobID, Status: meta.Status, Warnings: warnings[i]}
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"batch_id": batchID,
+		"jobs":     items,
+		"errors":   rowErrors,
+	})
+}
+
+func sortedUploadColumns() []string {
+	cols := make([]string, 0, len(uploadColumns))
+	for name := range uploadColumns {
+		cols = append(cols, name)
+	}
+	sort.Strings(cols)
+	return cols
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadResponse struct {
+	BatchID string           `json:"batch_id"`
+	Jobs    []uploadItem     `json:"jobs"`
+	Errors  []uploadRowError `json:"errors"`
+	Error   apiError         `json:"error"`
+}
+
+func postUpload(t *testing.T, router *gin.Engine, field, content string) (int, uploadResponse) {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile(field, "greenhouses.csv")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req, _ := http.NewRequest("POST", "/simulate/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp uploadResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestUploadEnqueuesValidRows(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	csv := "\ufeffA_glass,tau_glass,setpoint,preset,tags,lat,lon\n" +
+		"80,0.7,14,,north;trial,52.1,5.2\n" + // row 2
+		"120,3,16,,,52.1,5.2\n" + // row 3: tau_glass out of range
+		"big,0.7,14,,,52.1,5.2\n" + // row 4: not a number
+		"60,0.8\n" + // row 5: too few cells
+		",,18,small_hobby,,52.1,5.2\n" // row 6: preset with an override
+	code, resp := postUpload(t, router, UploadFormField, csv)
+
+	require.Equal(t, http.StatusAccepted, code, resp.Error.Message)
+	assert.NotEmpty(t, resp.BatchID)
+	require.Len(t, resp.Jobs, 2)
+	assert.Equal(t, 2, resp.Jobs[0].Row)
+	assert.Equal(t, 6, resp.Jobs[1].Row)
+	assert.Equal(t, int64(2), rdb.LLen(ctx, RedisJobsList).Val())
+
+	meta, err := redisMetaStore{}.GetMeta(ctx, resp.Jobs[0].JobID)
+	require.NoError(t, err)
+	assert.Equal(t, resp.BatchID, meta.BatchID)
+	assert.Equal(t, 80.0, *meta.Params.A_glass)
+	assert.Equal(t, []string{"north", "trial"}, meta.Tags)
+
+	meta, err = redisMetaStore{}.GetMeta(ctx, resp.Jobs[1].JobID)
+	require.NoError(t, err)
+	assert.Equal(t, 18.0, *meta.Params.Setpoint)
+	assert.Equal(t, 15.0, *meta.Params.A_glass, "from the small_hobby preset")
+
+	require.Len(t, resp.Errors, 3)
+	assert.Equal(t, 3, resp.Errors[0].Row)
+	require.Len(t, resp.Errors[0].Fields, 1)
+	assert.Equal(t, "tau_glass", resp.Errors[0].Fields[0].Field)
+	assert.Equal(t, 4, resp.Errors[1].Row)
+	require.Len(t, resp.Errors[1].Fields, 1)
+	assert.Equal(t, "A_glass", resp.Errors[1].Fields[0].Field)
+	assert.Equal(t, "a number", resp.Errors[1].Fields[0].Allowed)
+	assert.Equal(t, 5, resp.Errors[2].Row)
+	assert.Contains(t, resp.Errors[2].Error, "2 cells")
+}
+
+func TestUploadRejects(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	orig := maxUploadRows
+	maxUploadRows = 2
+	defer func() { maxUploadRows = orig }()
+
+	tests := []struct {
+		name, field, csv, want string
+	}{
+		{"wrong form field", "data", "A_glass\n80\n", "multipart/form-data"},
+		{"empty file", UploadFormField, "", "upload is empty"},
+		{"header only", UploadFormField, "A_glass,setpoint\n", "no data rows"},
+		{"unknown column", UploadFormField, "A_glass,glass_area\n80,80\n", "unknown columns: glass_area"},
+		{"duplicate column", UploadFormField, "A_glass,A_glass\n80,80\n", "more than once"},
+		{"too many rows", UploadFormField, "A_glass\n80\n90\n100\n", "more than 2 rows"},
+		{"no valid rows", UploadFormField, "tau_glass\n3\n", "no valid rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := postUpload(t, router, tt.field, tt.csv)
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Contains(t, resp.Error.Message, tt.want)
+		})
+	}
+	assert.Equal(t, int64(0), rdb.LLen(ctx, RedisJobsList).Val())
+}
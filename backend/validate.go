@@ -0,0 +1,361 @@
+package main
+
+// backend/validate.go
+//
+// Parameter validation run after applyDefaults so that obviously broken
+// physics inputs and date ranges are rejected at submit time instead of
+// crashing the worker. Values that are legal but physically implausible are
+// accepted with a warning instead, except setpoint and T_init outside the
+// plausible temperature band, which need ?allow_extreme=true.
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// DateLayout is the only accepted format for start_date / end_date.
+const DateLayout = "2006-01-02"
+
+// FieldError describes a single parameter that failed validation.
+type FieldError struct {
+	Field   string      `json:"field"`
+	Value   interface{} `json:"value"`
+	Allowed string      `json:"allowed"`
+}
+
+// ValidationError collects every offending field so clients can fix them all at once.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, fmt.Sprintf("%s=%v (allowed %s)", f.Field, f.Value, f.Allowed))
+	}
+	return "invalid parameters: " + strings.Join(parts, ", ")
+}
+
+// paramRange is the allowed interval for one numeric field.
+// minOpen/maxOpen make the corresponding bound exclusive.
+type paramRange struct {
+	field   string
+	get     func(p *SimulationParams) *float64
+	min     float64
+	max     float64
+	minOpen bool
+	maxOpen bool
+}
+
+func (r paramRange) contains(v float64) bool {
+	if math.IsNaN(v) {
+		return false
+	}
+	if v < r.min || (r.minOpen && v == r.min) {
+		return false
+	}
+	if v > r.max || (r.maxOpen && v == r.max) {
+		return false
+	}
+	return true
+}
+
+func (r paramRange) String() string {
+	lo, hi := "[", "]"
+	if r.minOpen {
+		lo = "("
+	}
+	if r.maxOpen {
+		hi = ")"
+	}
+	return fmt.Sprintf("%s%s, %s%s", lo, formatBound(r.min), formatBound(r.max), hi)
+}
+
+func formatBound(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+inf"
+	case math.IsInf(v, -1):
+		return "-inf"
+	}
+	return fmt.Sprintf("%g", v)
+}
+
+var inf = math.Inf(1)
+
+// paramRanges lists the sane physical range for each field (json names are used in errors).
+var paramRanges = []paramRange{
+	{field: "thermal_mass", get: func(p *SimulationParams) *float64 { return p.ThermalMass }, min: 0, max: inf, minOpen: true, maxOpen: true},
+	{field: "thermal_mass_kg", get: func(p *SimulationParams) *float64 { return p.ThermalMassKg }, min: 0, max: inf, minOpen: true, maxOpen: true},
+	{field: "cp_mass", get: func(p *SimulationParams) *float64 { return p.CpMass }, min: 0, max: inf, minOpen: true, maxOpen: true},
+	{field: "ventilation_rate", get: func(p *SimulationParams) *float64 { return p.VentilationRate }, min: 0, max: inf, maxOpen: true},
+	{field: "U_day", get: func(p *SimulationParams) *float64 { return p.U_day }, min: 0, max: inf, maxOpen: true},
+	{field: "U_night", get: func(p *SimulationParams) *float64 { return p.U_night }, min: 0, max: inf, maxOpen: true},
+	{field: "A_glass", get: func(p *SimulationParams) *float64 { return p.A_glass }, min: 0, max: inf, minOpen: true, maxOpen: true},
+	{field: "tau_glass", get: func(p *SimulationParams) *float64 { return p.TauGlass }, min: 0, max: 1},
+	{field: "ACH", get: func(p *SimulationParams) *float64 { return p.ACH }, min: 0, max: inf, maxOpen: true},
+	{field: "V", get: func(p *SimulationParams) *float64 { return p.Volume }, min: 0, max: inf, minOpen: true, maxOpen: true},
+	{field: "C", get: func(p *SimulationParams) *float64 { return p.C }, min: 0, max: inf, minOpen: true, maxOpen: true},
+	{field: "heater_max_w", get: func(p *SimulationParams) *float64 { return p.HeaterMaxW }, min: 0, max: inf, maxOpen: true},
+	{field: "evap_rate", get: func(p *SimulationParams) *float64 { return p.EvapRate }, min: 0, max: inf, maxOpen: true},
+	{field: "fraction_solar_to_air", get: func(p *SimulationParams) *float64 { return p.FractionSolarAir }, min: 0, max: 1},
+	{field: "lat", get: func(p *SimulationParams) *float64 { return p.Lat }, min: -90, max: 90},
+	{field: "lon", get: func(p *SimulationParams) *float64 { return p.Lon }, min: -180, max: 180},
+	{field: "T_init", get: func(p *SimulationParams) *float64 { return p.T_init }, min: AbsoluteZeroC, max: inf, minOpen: true, maxOpen: true},
+	{field: "setpoint", get: func(p *SimulationParams) *float64 { return p.Setpoint }, min: AbsoluteZeroC, max: inf, minOpen: true, maxOpen: true},
+	{field: "timestep_seconds", get: func(p *SimulationParams) *float64 { return p.TimestepSeconds }, min: MinTimestepSeconds, max: MaxTimestepSeconds},
+	{field: "heater_deadband", get: func(p *SimulationParams) *float64 { return p.HeaterDeadband }, min: 0, max: MaxHeaterDeadband},
+	{field: "heater_kp", get: func(p *SimulationParams) *float64 { return p.HeaterKp }, min: 0, max: inf, minOpen: true, maxOpen: true},
+	{field: "outdoor_temp_const", get: func(p *SimulationParams) *float64 { return p.OutdoorTempConst }, min: AbsoluteZeroC, max: inf, minOpen: true, maxOpen: true},
+	{field: "outdoor_temp_mean", get: func(p *SimulationParams) *float64 { return p.OutdoorTempMean }, min: AbsoluteZeroC, max: inf, minOpen: true, maxOpen: true},
+	{field: "outdoor_temp_amplitude", get: func(p *SimulationParams) *float64 { return p.OutdoorTempAmplitude }, min: 0, max: inf, maxOpen: true},
+}
+
+// MaxSeed is the largest seed accepted; the worker's RNG takes 32-bit seeds.
+const MaxSeed = math.MaxUint32
+
+// AbsoluteZeroC bounds temperatures even with allow_extreme.
+const AbsoluteZeroC = -273.15
+
+// Default plausible band (°C) for setpoint and T_init. Values outside it are
+// almost always typos (setpoint 120 for 12) and are rejected unless the
+// request passes ?allow_extreme=true.
+const (
+	DefaultMinPlausibleTemp = -40.0
+	DefaultMaxPlausibleTemp = 60.0
+)
+
+var (
+	minPlausibleTemp = DefaultMinPlausibleTemp
+	maxPlausibleTemp = DefaultMaxPlausibleTemp
+)
+
+func loadTemperatureBandConfig() {
+	minPlausibleTemp = envFloat("PLAUSIBLE_TEMP_MIN_C", DefaultMinPlausibleTemp)
+	maxPlausibleTemp = envFloat("PLAUSIBLE_TEMP_MAX_C", DefaultMaxPlausibleTemp)
+}
+
+// temperatureBand checks setpoint and T_init against the plausible band.
+func temperatureBand(p *SimulationParams) []FieldError {
+	band := paramRange{min: minPlausibleTemp, max: maxPlausibleTemp}
+	var fields []FieldError
+	for _, f := range []struct {
+		field string
+		v     *float64
+	}{{"T_init", p.T_init}, {"setpoint", p.Setpoint}} {
+		if f.v != nil && !band.contains(*f.v) {
+			fields = append(fields, FieldError{Field: f.field, Value: *f.v,
+				Allowed: band.String() + " °C; pass ?allow_extreme=true if this is deliberate"})
+		}
+	}
+	return fields
+}
+
+// plausibleRanges are typical greenhouse values. A value outside one passes
+// validation (it is still within paramRanges) but earns a warning.
+var plausibleRanges = []paramRange{
+	{field: "tau_glass", get: func(p *SimulationParams) *float64 { return p.TauGlass }, min: 0.1, max: 1},
+	{field: "U_day", get: func(p *SimulationParams) *float64 { return p.U_day }, min: 0.1, max: 10},
+	{field: "U_night", get: func(p *SimulationParams) *float64 { return p.U_night }, min: 0.1, max: 10},
+	{field: "ACH", get: func(p *SimulationParams) *float64 { return p.ACH }, min: 0, max: 60},
+	{field: "T_init", get: func(p *SimulationParams) *float64 { return p.T_init }, min: -40, max: 50},
+	{field: "setpoint", get: func(p *SimulationParams) *float64 { return p.Setpoint }, min: -10, max: 40},
+}
+
+// MaxGlassAreaPerVolume bounds A_glass/V (m2 of glazing per m3 enclosed). A
+// fully glazed enclosure has a ratio of roughly 1/height plus its walls, so
+// beyond 3 it would be only about 40 cm tall; real greenhouses sit well
+// below 1 (the presets range from 0.25 to 0.6).
+const MaxGlassAreaPerVolume = 3.0
+
+// Thermal models the worker implements, chosen with the "model" field.
+const (
+	ModelLumped    = "lumped"    // one node: air and structure share the heat capacity C
+	ModelMultinode = "multinode" // separate air, thermal mass and soil nodes
+)
+
+// knownModels is the set accepted in the "model" field.
+var knownModels = map[string]bool{
+	ModelLumped:    true,
+	ModelMultinode: true,
+}
+
+// Heater control modes, chosen with the "heater_control" field.
+const (
+	HeaterOnOff        = "onoff"        // full power below setpoint - deadband/2, off above setpoint + deadband/2
+	HeaterProportional = "proportional" // heater_kp * (setpoint - T), capped at heater_max_w
+)
+
+// knownHeaterControls is the set accepted in the "heater_control" field.
+var knownHeaterControls = map[string]bool{
+	HeaterOnOff:        true,
+	HeaterProportional: true,
+}
+
+// MaxHeaterDeadband (K) bounds heater_deadband; wider bands no longer track the setpoint.
+const MaxHeaterDeadband = 10.0
+
+// validateParams checks every set field against its allowed range, and
+// setpoint and T_init against the plausible temperature band unless
+// allowExtreme is set. It returns a *ValidationError listing all offending
+// fields, or nil, plus warnings about accepted values that are unlikely to be
+// intended.
+func validateParams(p *SimulationParams, allowExtreme bool) (warnings []string, err error) {
+	var verr ValidationError
+	for _, r := range paramRanges {
+		v := r.get(p)
+		if v == nil {
+			continue
+		}
+		if !r.contains(*v) {
+			verr.Fields = append(verr.Fields, FieldError{Field: r.field, Value: *v, Allowed: r.String()})
+		}
+	}
+	if p.ResultTTLSeconds != nil && *p.ResultTTLSeconds < 1 {
+		verr.Fields = append(verr.Fields, FieldError{Field: "result_ttl_seconds", Value: *p.ResultTTLSeconds, Allowed: "at least 1 (larger values are clamped)"})
+	}
+	if p.Priority != "" && !knownPriorities[p.Priority] {
+		verr.Fields = append(verr.Fields, FieldError{Field: "priority", Value: p.Priority, Allowed: "one of high, normal, low"})
+	}
+	if p.Queue != "" && !jobQueueNames[p.Queue] {
+		allowed := "omitted (no named queues are configured)"
+		if len(jobQueueNames) > 0 {
+			allowed = "one of " + strings.Join(sortedQueueNames(), ", ") + ", or omitted for the default queue"
+		}
+		verr.Fields = append(verr.Fields, FieldError{Field: "queue", Value: p.Queue, Allowed: allowed})
+	}
+	if p.Model != "" && !knownModels[p.Model] {
+		verr.Fields = append(verr.Fields, FieldError{Field: "model", Value: p.Model, Allowed: "one of lumped, multinode"})
+	}
+	if p.HeaterControl != "" && !knownHeaterControls[p.HeaterControl] {
+		verr.Fields = append(verr.Fields, FieldError{Field: "heater_control", Value: p.HeaterControl, Allowed: "one of onoff, proportional"})
+	}
+	if p.Seed != nil && (*p.Seed < 0 || *p.Seed > MaxSeed) {
+		verr.Fields = append(verr.Fields, FieldError{Field: "seed", Value: *p.Seed, Allowed: fmt.Sprintf("an integer from 0 to %d", int64(MaxSeed))})
+	}
+	verr.Fields = append(verr.Fields, validateOutdoorTemp(p)...)
+	verr.Fields = append(verr.Fields, validateLocation(p)...)
+	verr.Fields = append(verr.Fields, validateTags(p.Tags)...)
+	if p.GreenhouseID != "" && !validTag(p.GreenhouseID) {
+		verr.Fields = append(verr.Fields, FieldError{Field: "greenhouse_id", Value: p.GreenhouseID, Allowed: fmt.Sprintf("up to %d letters, digits or . _ : / - (starting with a letter or digit)", MaxTagLength)})
+	}
+	if !allowExtreme {
+		verr.Fields = append(verr.Fields, temperatureBand(p)...)
+	}
+	if errs := resolveRelativeDates(p, time.Now()); len(errs) > 0 {
+		verr.Fields = append(verr.Fields, errs...)
+	} else {
+		verr.Fields = append(verr.Fields, validateDates(p)...)
+	}
+	if len(verr.Fields) > 0 {
+		return nil, &verr
+	}
+	return plausibilityWarnings(p), nil
+}
+
+// plausibilityWarnings describes legal inputs that probably do not model a
+// working greenhouse, e.g. glazing that blocks nearly all sunlight.
+func plausibilityWarnings(p *SimulationParams) []string {
+	var warnings []string
+	for _, r := range plausibleRanges {
+		if v := r.get(p); v != nil && !r.contains(*v) {
+			warnings = append(warnings, fmt.Sprintf("%s %g is outside the typical range %s", r.field, *v, r))
+		}
+	}
+	if p.HeaterMaxW != nil && *p.HeaterMaxW == 0 && p.Setpoint != nil && p.T_init != nil && *p.Setpoint > *p.T_init {
+		warnings = append(warnings, fmt.Sprintf("heater_max_w is 0: the heater is off, so setpoint %g above T_init %g can only be reached by solar gain", *p.Setpoint, *p.T_init))
+	}
+	// solar gain and envelope loss both scale with A_glass, but heat capacity with V
+	if p.A_glass != nil && p.Volume != nil && *p.A_glass/(*p.Volume) > MaxGlassAreaPerVolume {
+		warnings = append(warnings, fmt.Sprintf("A_glass %g is implausibly large for V %g: %.3g m2 of glazing per m3 exceeds %g", *p.A_glass, *p.Volume, *p.A_glass/(*p.Volume), MaxGlassAreaPerVolume))
+	}
+	return warnings
+}
+
+// validateLocation requires lat and lon for the weather mode, which looks the
+// outdoor temperature up there. The synthetic modes (see outdoortemp.go) may
+// omit both and the worker takes solar radiation and humidity from its default
+// site (39.9, 116.4); a coordinate given with them must still be complete.
+// Dates are independent of this, since the worker has a default window.
+func validateLocation(p *SimulationParams) []FieldError {
+	if usesWeather(p) {
+		var errs []FieldError
+		if p.Lat == nil {
+			errs = append(errs, FieldError{Field: "lat", Value: nil, Allowed: "required when outdoor_temp_mode is weather"})
+		}
+		if p.Lon == nil {
+			errs = append(errs, FieldError{Field: "lon", Value: nil, Allowed: "required when outdoor_temp_mode is weather"})
+		}
+		return errs
+	}
+	switch {
+	case p.Lat != nil && p.Lon == nil:
+		return []FieldError{{Field: "lon", Value: nil, Allowed: "required when lat is set"}}
+	case p.Lon != nil && p.Lat == nil:
+		return []FieldError{{Field: "lat", Value: nil, Allowed: "required when lon is set"}}
+	}
+	return nil
+}
+
+// validateDates checks that start_date/end_date parse as YYYY-MM-DD, are given
+// together, are ordered, and span at most maxSimulationDays and maxTimesteps
+// steps. Both may be omitted, in which case the worker falls back to its own
+// default window.
+func validateDates(p *SimulationParams) []FieldError {
+	if p.StartDate == "" && p.EndDate == "" {
+		return nil
+	}
+	if p.StartDate == "" {
+		return []FieldError{{Field: "start_date", Value: p.StartDate, Allowed: "required when end_date is set"}}
+	}
+	if p.EndDate == "" {
+		return []FieldError{{Field: "end_date", Value: p.EndDate, Allowed: "required when start_date is set"}}
+	}
+
+	var errs []FieldError
+	start, err := time.Parse(DateLayout, p.StartDate)
+	if err != nil {
+		errs = append(errs, FieldError{Field: "start_date", Value: p.StartDate, Allowed: "date formatted as YYYY-MM-DD"})
+	}
+	end, err := time.Parse(DateLayout, p.EndDate)
+	if err != nil {
+		errs = append(errs, FieldError{Field: "end_date", Value: p.EndDate, Allowed: "date formatted as YYYY-MM-DD"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if end.Before(start) {
+		return []FieldError{{Field: "end_date", Value: p.EndDate, Allowed: "on or after start_date " + p.StartDate}}
+	}
+	if days := int(end.Sub(start).Hours() / 24); days > maxSimulationDays {
+		return []FieldError{{Field: "end_date", Value: p.EndDate, Allowed: fmt.Sprintf("at most %d days after start_date (got %d)", maxSimulationDays, days)}}
+	}
+	if steps := simulationSteps(start, end, p.TimestepSeconds); steps > maxTimesteps {
+		return []FieldError{{Field: "end_date", Value: p.EndDate, Allowed: fmt.Sprintf("a span of at most %d timesteps (got %d at %g s per step)", maxTimesteps, steps, timestepSeconds(p.TimestepSeconds))}}
+	}
+	return nil
+}
+
+// Bounds on timestep_seconds: a minute to a day.
+const (
+	MinTimestepSeconds = 60.0
+	MaxTimestepSeconds = 86400.0
+)
+
+// timestepSeconds is the step the worker will integrate with.
+func timestepSeconds(ts *float64) float64 {
+	if ts == nil || *ts <= 0 {
+		return DefaultTimestepSeconds
+	}
+	return *ts
+}
+
+// simulationSteps is the number of timesteps the worker runs for the dates,
+// which are inclusive: end_date is simulated through its last hour.
+func simulationSteps(start, end time.Time, ts *float64) int {
+	span := end.Sub(start) + 24*time.Hour
+	return int(math.Ceil(span.Seconds() / timestepSeconds(ts)))
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSite is the location tests give weather-mode jobs when they do not care
+// where the job runs.
+const testSite = `"lat": 52.1, "lon": 5.2`
+
+// withSite adds testSite to the JSON object body. It goes first, so any of its
+// fields body sets itself win.
+func withSite(body string) string {
+	body = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(body), "{"))
+	if body == "}" {
+		return "{" + testSite + "}"
+	}
+	return "{" + testSite + ", " + body
+}
+
+// sitedParams returns params carrying testSite, for tests that build
+// SimulationParams directly.
+func sitedParams() SimulationParams {
+	return SimulationParams{Lat: floatPtr(52.1), Lon: floatPtr(5.2)}
+}
+
+func TestValidateParamsBoundaries(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   func(p *SimulationParams)
+		field string // empty when the value should be accepted
+	}{
+		{"tau_glass lower bound", func(p *SimulationParams) { p.TauGlass = floatPtr(0) }, ""},
+		{"tau_glass upper bound", func(p *SimulationParams) { p.TauGlass = floatPtr(1) }, ""},
+		{"tau_glass below range", func(p *SimulationParams) { p.TauGlass = floatPtr(-0.01) }, "tau_glass"},
+		{"tau_glass above range", func(p *SimulationParams) { p.TauGlass = floatPtr(2.0) }, "tau_glass"},
+		{"ACH zero", func(p *SimulationParams) { p.ACH = floatPtr(0) }, ""},
+		{"ACH negative", func(p *SimulationParams) { p.ACH = floatPtr(-5) }, "ACH"},
+		{"A_glass positive", func(p *SimulationParams) { p.A_glass = floatPtr(0.1) }, ""},
+		{"A_glass zero", func(p *SimulationParams) { p.A_glass = floatPtr(0) }, "A_glass"},
+		{"heater_max_w zero", func(p *SimulationParams) { p.HeaterMaxW = floatPtr(0) }, ""},
+		{"heater_max_w negative", func(p *SimulationParams) { p.HeaterMaxW = floatPtr(-1) }, "heater_max_w"},
+		{"V positive", func(p *SimulationParams) { p.Volume = floatPtr(1) }, ""},
+		{"V zero", func(p *SimulationParams) { p.Volume = floatPtr(0) }, "V"},
+		{"U_day negative", func(p *SimulationParams) { p.U_day = floatPtr(-0.1) }, "U_day"},
+		{"U_night negative", func(p *SimulationParams) { p.U_night = floatPtr(-0.1) }, "U_night"},
+		{"C zero", func(p *SimulationParams) { p.C = floatPtr(0) }, "C"},
+		{"cp_mass zero", func(p *SimulationParams) { p.CpMass = floatPtr(0) }, "cp_mass"},
+		{"thermal_mass_kg zero", func(p *SimulationParams) { p.ThermalMassKg = floatPtr(0) }, "thermal_mass_kg"},
+		{"thermal_mass zero", func(p *SimulationParams) { p.ThermalMass = floatPtr(0) }, "thermal_mass"},
+		{"ventilation_rate negative", func(p *SimulationParams) { p.VentilationRate = floatPtr(-1) }, "ventilation_rate"},
+		{"evap_rate negative", func(p *SimulationParams) { p.EvapRate = floatPtr(-1) }, "evap_rate"},
+		{"fraction_solar_to_air lower bound", func(p *SimulationParams) { p.FractionSolarAir = floatPtr(0) }, ""},
+		{"fraction_solar_to_air negative", func(p *SimulationParams) { p.FractionSolarAir = floatPtr(-0.1) }, "fraction_solar_to_air"},
+		{"fraction_solar_to_air upper bound", func(p *SimulationParams) { p.FractionSolarAir = floatPtr(1) }, ""},
+		{"fraction_solar_to_air above range", func(p *SimulationParams) { p.FractionSolarAir = floatPtr(1.1) }, "fraction_solar_to_air"},
+		{"setpoint normal", func(p *SimulationParams) { p.Setpoint = floatPtr(12) }, ""},
+		{"setpoint band upper bound", func(p *SimulationParams) { p.Setpoint = floatPtr(60) }, ""},
+		{"setpoint typo", func(p *SimulationParams) { p.Setpoint = floatPtr(120) }, "setpoint"},
+		{"T_init band lower bound", func(p *SimulationParams) { p.T_init = floatPtr(-40) }, ""},
+		{"T_init below band", func(p *SimulationParams) { p.T_init = floatPtr(-41) }, "T_init"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := sitedParams()
+			applyDefaults(&params)
+			tt.set(&params)
+
+			_, err := validateParams(&params, false)
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			require.Len(t, verr.Fields, 1)
+			assert.Equal(t, tt.field, verr.Fields[0].Field)
+			assert.NotEmpty(t, verr.Fields[0].Allowed)
+		})
+	}
+}
+
+func TestValidateParamsDefaultsAreValid(t *testing.T) {
+	params := sitedParams()
+	applyDefaults(&params)
+	warnings, err := validateParams(&params, false)
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestValidateParamsAllowExtreme(t *testing.T) {
+	params := sitedParams()
+	applyDefaults(&params)
+	params.Setpoint = floatPtr(120)
+
+	warnings, err := validateParams(&params, true)
+	require.NoError(t, err)
+	assert.Contains(t, warnings, "setpoint 120 is outside the typical range [-10, 40]")
+
+	// the band is configurable
+	orig := maxPlausibleTemp
+	maxPlausibleTemp = 150
+	t.Cleanup(func() { maxPlausibleTemp = orig })
+	_, err = validateParams(&params, false)
+	assert.NoError(t, err)
+
+	// allow_extreme does not lift the physical limit
+	params.T_init = floatPtr(-300)
+	_, err = validateParams(&params, true)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "T_init", verr.Fields[0].Field)
+}
+
+func TestSubmitJobTemperatureBand(t *testing.T) {
+	router := setupRouter()
+	post := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/simulate?dry_run=true"+query, bytes.NewBufferString(withSite(`{"setpoint": 120}`)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response struct {
+		Fields []FieldError `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Fields, 1)
+	assert.Equal(t, "setpoint", response.Fields[0].Field)
+	assert.Equal(t, "[-40, 60] °C; pass ?allow_extreme=true if this is deliberate", response.Fields[0].Allowed)
+
+	w = post("&allow_extreme=true")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "setpoint 120 is outside the typical range")
+}
+
+func TestValidateParamsModel(t *testing.T) {
+	params := sitedParams()
+	applyDefaults(&params)
+	assert.Equal(t, ModelLumped, params.Model)
+
+	params.Model = ModelMultinode
+	_, err := validateParams(&params, false)
+	assert.NoError(t, err)
+
+	params.Model = "cfd"
+	_, err = validateParams(&params, false)
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Len(t, verr.Fields, 1)
+	assert.Equal(t, "model", verr.Fields[0].Field)
+	assert.Equal(t, "one of lumped, multinode", verr.Fields[0].Allowed)
+
+	router := setupRouter()
+	req, _ := http.NewRequest("POST", "/simulate?dry_run=true", bytes.NewBufferString(withSite(`{"model": "cfd"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"field":"model"`)
+}
+
+func TestValidateParamsHeaterControl(t *testing.T) {
+	params := sitedParams()
+	applyDefaults(&params)
+	assert.Equal(t, HeaterOnOff, params.HeaterControl)
+	assert.Equal(t, 1.0, *params.HeaterDeadband)
+	assert.Nil(t, params.HeaterKp)
+
+	tests := []struct {
+		name        string
+		params      SimulationParams
+		wantField   string
+		wantAllowed string
+	}{
+		{"onoff with deadband", SimulationParams{HeaterControl: HeaterOnOff, HeaterDeadband: floatPtr(0)}, "", ""},
+		{"proportional with kp", SimulationParams{HeaterControl: HeaterProportional, HeaterKp: floatPtr(800)}, "", ""},
+		{"proportional without kp", SimulationParams{HeaterControl: HeaterProportional}, "", ""},
+		{"unknown control", SimulationParams{HeaterControl: "pid"}, "heater_control", "one of onoff, proportional"},
+		{"negative deadband", SimulationParams{HeaterDeadband: floatPtr(-1)}, "heater_deadband", "[0, 10]"},
+		{"deadband too wide", SimulationParams{HeaterDeadband: floatPtr(12)}, "heater_deadband", "[0, 10]"},
+		{"zero kp", SimulationParams{HeaterControl: HeaterProportional, HeaterKp: floatPtr(0)}, "heater_kp", "(0, +inf)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.params
+			p.Lat, p.Lon = floatPtr(52.1), floatPtr(5.2)
+			applyDefaults(&p)
+			_, err := validateParams(&p, false)
+			if tt.wantField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			require.Len(t, verr.Fields, 1)
+			assert.Equal(t, tt.wantField, verr.Fields[0].Field)
+			assert.Equal(t, tt.wantAllowed, verr.Fields[0].Allowed)
+		})
+	}
+
+	// the other mode's setting is dropped with a warning
+	p := SimulationParams{HeaterControl: HeaterOnOff, HeaterKp: floatPtr(800)}
+	assert.Equal(t, []string{"heater_kp is not used with heater_control=onoff; ignoring it"}, applyDefaults(&p))
+	assert.Nil(t, p.HeaterKp)
+	p = SimulationParams{HeaterControl: HeaterProportional, HeaterDeadband: floatPtr(2)}
+	assert.Equal(t, []string{"heater_deadband is not used with heater_control=proportional; ignoring it"}, applyDefaults(&p))
+	assert.Nil(t, p.HeaterDeadband)
+}
+
+func TestSubmitJobHeaterControl(t *testing.T) {
+	router := setupRouter()
+	dryRun := func(body string) (int, SimulationParams) {
+		req, _ := http.NewRequest("POST", "/simulate?dry_run=true", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp struct {
+			Params SimulationParams `json:"params"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp.Params
+	}
+
+	code, p := dryRun(withSite(`{"heater_deadband": 0.5}`))
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HeaterOnOff, p.HeaterControl)
+	assert.Equal(t, 0.5, *p.HeaterDeadband)
+
+	code, p = dryRun(withSite(`{"heater_control": "proportional", "heater_kp": 1200}`))
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, HeaterProportional, p.HeaterControl)
+	assert.Equal(t, 1200.0, *p.HeaterKp)
+	assert.Nil(t, p.HeaterDeadband)
+
+	code, _ = dryRun(withSite(`{"heater_control": "bang-bang"}`))
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSubmitJobRejectsInvalidParams(t *testing.T) {
+	router := setupRouter()
+
+	body := []byte(withSite(`{"ACH": -5, "tau_glass": 2.0, "V": 0}`))
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response struct {
+		Error  apiError     `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	fields := map[string]string{}
+	for _, f := range response.Fields {
+		fields[f.Field] = f.Allowed
+	}
+	assert.Len(t, fields, 3)
+	assert.Equal(t, "[0, +inf)", fields["ACH"])
+	assert.Equal(t, "[0, 1]", fields["tau_glass"])
+	assert.Equal(t, "(0, +inf)", fields["V"])
+}
+
+func TestValidateParamsWarnings(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(p *SimulationParams)
+		want []string // nil when no warning should fire
+	}{
+		{"typical values", func(p *SimulationParams) {}, nil},
+		{"opaque glazing", func(p *SimulationParams) { p.TauGlass = floatPtr(0.02) },
+			[]string{"tau_glass 0.02 is outside the typical range [0.1, 1]"}},
+		{"heater off above T_init", func(p *SimulationParams) { p.HeaterMaxW = floatPtr(0); p.Setpoint = floatPtr(20) },
+			[]string{"heater_max_w is 0: the heater is off, so setpoint 20 above T_init 15 can only be reached by solar gain"}},
+		{"heater off below T_init", func(p *SimulationParams) { p.HeaterMaxW = floatPtr(0); p.Setpoint = floatPtr(10) }, nil},
+		{"extreme ventilation", func(p *SimulationParams) { p.ACH = floatPtr(120) },
+			[]string{"ACH 120 is outside the typical range [0, 60]"}},
+		{"perfect insulation", func(p *SimulationParams) { p.U_night = floatPtr(0) },
+			[]string{"U_night 0 is outside the typical range [0.1, 10]"}},
+		{"tropical setpoint", func(p *SimulationParams) { p.Setpoint = floatPtr(55) },
+			[]string{"setpoint 55 is outside the typical range [-10, 40]"}},
+		{"glass area at the volume limit", func(p *SimulationParams) { p.A_glass = floatPtr(300); p.Volume = floatPtr(100) }, nil},
+		{"glass area too large for volume", func(p *SimulationParams) { p.A_glass = floatPtr(500); p.Volume = floatPtr(100) },
+			[]string{"A_glass 500 is implausibly large for V 100: 5 m2 of glazing per m3 exceeds 3"}},
+		{"several at once", func(p *SimulationParams) { p.TauGlass = floatPtr(0); p.U_day = floatPtr(25) },
+			[]string{"tau_glass 0 is outside the typical range [0.1, 1]", "U_day 25 is outside the typical range [0.1, 10]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := sitedParams()
+			applyDefaults(&params)
+			tt.set(&params)
+
+			warnings, err := validateParams(&params, false)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, warnings)
+		})
+	}
+}
+
+func TestSubmitJobReturnsWarnings(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+
+	body := []byte(withSite(`{"tau_glass": 0.01, "heater_max_w": 0, "setpoint": 25}`))
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var response struct {
+		JobID    string   `json:"job_id"`
+		Warnings []string `json:"warnings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.JobID)
+	assert.Equal(t, []string{
+		"tau_glass 0.01 is outside the typical range [0.1, 1]",
+		"heater_max_w is 0: the heater is off, so setpoint 25 above T_init 15 can only be reached by solar gain",
+	}, response.Warnings)
+}
+
+func TestValidateDates(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		field string // empty when the range should be accepted
+	}{
+		{"both omitted", "", "", ""},
+		{"single day", "2025-11-01", "2025-11-01", ""},
+		{"ordered range", "2025-11-01", "2025-11-02", ""},
+		{"max span", "2025-01-01", "2026-01-02", ""},
+		{"malformed start", "next tuesday", "2025-11-02", "start_date"},
+		{"malformed end", "2025-11-01", "2025/11/02", "end_date"},
+		{"missing end", "2025-11-01", "", "end_date"},
+		{"missing start", "", "2025-11-02", "start_date"},
+		{"reversed range", "2025-11-02", "2025-11-01", "end_date"},
+		{"over-long span", "2025-01-01", "2026-01-03", "end_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateDates(&SimulationParams{StartDate: tt.start, EndDate: tt.end})
+			if tt.field == "" {
+				assert.Empty(t, errs)
+				return
+			}
+			require.Len(t, errs, 1)
+			assert.Equal(t, tt.field, errs[0].Field)
+		})
+	}
+}
+
+func TestValidateDatesRespectsConfiguredMax(t *testing.T) {
+	orig := maxSimulationDays
+	maxSimulationDays = 7
+	defer func() { maxSimulationDays = orig }()
+
+	assert.Empty(t, validateDates(&SimulationParams{StartDate: "2025-11-01", EndDate: "2025-11-08"}))
+	assert.Len(t, validateDates(&SimulationParams{StartDate: "2025-11-01", EndDate: "2025-11-09"}), 1)
+}
+
+func TestValidateDatesCapsTimesteps(t *testing.T) {
+	orig := maxTimesteps
+	maxTimesteps = 1000
+	defer func() { maxTimesteps = orig }()
+
+	// 30 days hourly is 720 steps
+	assert.Empty(t, validateDates(&SimulationParams{StartDate: "2025-11-01", EndDate: "2025-11-30"}))
+
+	// the same span at 15-minute steps is 2880
+	errs := validateDates(&SimulationParams{StartDate: "2025-11-01", EndDate: "2025-11-30", TimestepSeconds: floatPtr(900)})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "end_date", errs[0].Field)
+	assert.Contains(t, errs[0].Allowed, "at most 1000 timesteps")
+	assert.Contains(t, errs[0].Allowed, "got 2880")
+}
+
+func TestValidateParamsTimestepRange(t *testing.T) {
+	for _, ts := range []float64{0, 30, 86401} {
+		params := sitedParams()
+		params.TimestepSeconds = floatPtr(ts)
+		_, err := validateParams(&params, false)
+		var verr *ValidationError
+		require.ErrorAs(t, err, &verr, ts)
+		assert.Equal(t, "timestep_seconds", verr.Fields[0].Field)
+	}
+	params := sitedParams()
+	params.TimestepSeconds = floatPtr(600)
+	_, err := validateParams(&params, false)
+	assert.NoError(t, err)
+}
+
+func TestSubmitJobRejectsTooManyTimesteps(t *testing.T) {
+	router := setupRouter()
+
+	body := []byte(withSite(`{"start_date": "2025-01-01", "end_date": "2025-01-31", "timestep_seconds": "60"}`))
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "end_date", decodeError(t, w).Error.Field)
+	assert.Contains(t, w.Body.String(), "got 44640")
+}
+
+func TestSubmitJobEchoesCanonicalDates(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+
+	body := []byte(withSite(`{"start_date": "2025-11-01", "end_date": "2025-11-03"}`))
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "2025-11-01", response["start_date"])
+	assert.Equal(t, "2025-11-03", response["end_date"])
+}
+
+func TestSubmitJobRejectsMalformedDates(t *testing.T) {
+	router := setupRouter()
+
+	body := []byte(withSite(`{"start_date": "next tuesday", "end_date": "2025-11-02"}`))
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "start_date")
+}
+
+func TestValidateLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		lat, lon *float64
+		fields   []string // empty when the combination should be accepted
+	}{
+		{"weather, both omitted", "", nil, nil, []string{"lat", "lon"}},
+		{"explicit weather, both omitted", OutdoorTempWeather, nil, nil, []string{"lat", "lon"}},
+		{"constant, both omitted", OutdoorTempConstant, nil, nil, nil},
+		{"sine, both omitted", OutdoorTempSine, nil, nil, nil},
+		{"both set", "", floatPtr(51.5), floatPtr(-0.12), nil},
+		{"bounds", "", floatPtr(-90), floatPtr(180), nil},
+		{"lat without lon", "", floatPtr(51.5), nil, []string{"lon"}},
+		{"lon without lat", "", nil, floatPtr(-0.12), []string{"lat"}},
+		{"constant, lat without lon", OutdoorTempConstant, floatPtr(51.5), nil, []string{"lon"}},
+		{"lat above range", "", floatPtr(90.5), floatPtr(0), []string{"lat"}},
+		{"lat below range", "", floatPtr(-91), floatPtr(0), []string{"lat"}},
+		{"lon above range", "", floatPtr(0), floatPtr(180.1), []string{"lon"}},
+		{"lon below range", "", floatPtr(0), floatPtr(-200), []string{"lon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := SimulationParams{Lat: tt.lat, Lon: tt.lon, OutdoorTempMode: tt.mode}
+			switch tt.mode {
+			case OutdoorTempConstant:
+				params.OutdoorTempConst = floatPtr(5)
+			case OutdoorTempSine:
+				params.OutdoorTempMean, params.OutdoorTempAmplitude = floatPtr(8), floatPtr(6)
+			}
+			applyDefaults(&params)
+			_, err := validateParams(&params, false)
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			var got []string
+			for _, f := range verr.Fields {
+				got = append(got, f.Field)
+			}
+			assert.Equal(t, tt.fields, got)
+		})
+	}
+}
+
+func TestSubmitJobRejectsPartialLocation(t *testing.T) {
+	router := setupRouter()
+
+	body := []byte(`{"outdoor_temp_mode": "constant", "outdoor_temp_const": 5, "lat": 51.5}`)
+	req, _ := http.NewRequest("POST", "/simulate", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "required when lat is set")
+}
+
+func TestSubmitJobLocationFollowsOutdoorTempMode(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	// the weather lookup has nowhere to look
+	for _, body := range []string{`{"setpoint": 14}`, `{"outdoor_temp_mode": "weather", "setpoint": 14}`} {
+		w := postSimulate(router, body)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+		env := decodeError(t, w)
+		require.Len(t, env.Fields, 2, body)
+		assert.Equal(t, "lat", env.Fields[0].Field)
+		assert.Equal(t, "required when outdoor_temp_mode is weather", env.Fields[0].Allowed)
+		assert.Equal(t, "lon", env.Fields[1].Field)
+	}
+	assert.Zero(t, rdb.LLen(context.Background(), RedisJobsList).Val())
+
+	// a synthetic temperature needs neither
+	w := postSimulate(router, `{"outdoor_temp_mode": "constant", "outdoor_temp_const": 2, "setpoint": 14}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Equal(t, int64(1), rdb.LLen(context.Background(), RedisJobsList).Val())
+}
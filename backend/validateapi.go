@@ -0,0 +1,36 @@
+package main
+
+// backend/validateapi.go
+//
+// POST /validate: check a params body without submitting it, for forms that
+// validate on every change. The body is read and resolved exactly as by POST
+// /simulate (units, preset, defaults, validation, strict mode and the schema
+// check when enabled) and the answer is 200 with the resolved params or the
+// usual 400. Unlike /simulate?dry_run=true it is not rate limited and never
+// touches Redis.
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func validateParamsHandler(c *gin.Context) {
+	params, ok := bindParamsBody(c)
+	if !ok {
+		return
+	}
+	units, warnings, ok := resolveParams(c, &params)
+	if !ok {
+		return
+	}
+	if warnings == nil {
+		warnings = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"valid":           true,
+		"units":           units,
+		"resolved_params": params,
+		"warnings":        warnings,
+	})
+}
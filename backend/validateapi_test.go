@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postValidate(router http.Handler, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/validate", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	w := postValidate(router, withSite(`{"preset": "polytunnel", "setpoint": 16, "ACH": 2, "ventilation_rate": 0.1}`))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Valid    bool             `json:"valid"`
+		Params   SimulationParams `json:"resolved_params"`
+		Warnings []string         `json:"warnings"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Valid)
+	assert.Equal(t, 16.0, *resp.Params.Setpoint)
+	assert.Equal(t, *presets["polytunnel"].Params.A_glass, *resp.Params.A_glass, "the preset is merged")
+	require.NotNil(t, resp.Params.U_day, "defaults are applied")
+	assert.NotEmpty(t, resp.Warnings, "ventilation_rate overrides ACH")
+
+	w = postValidate(router, withSite(`{"tau_glass": 3, "start_date": "2025-11-03", "end_date": "2025-11-01"}`))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	env := decodeError(t, w)
+	assert.Equal(t, CodeInvalidParam, env.Error.Code)
+	assert.Len(t, env.Fields, 2)
+
+	assert.Zero(t, rdb.DBSize(ctx).Val(), "nothing was written to Redis")
+}
+
+func TestValidateEndpointWithoutRedis(t *testing.T) {
+	router := setupRouter()
+	orig := rdb
+	rdb = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer func() { rdb.Close(); rdb = orig }()
+
+	w := postValidate(router, withSite(`{"A_glass": 80}`))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"warnings":[]`)
+}
@@ -0,0 +1,35 @@
+package main
+
+// backend/version.go
+//
+// Build info. Commit and BuildTime are set at link time, e.g.
+//
+//	go build -ldflags "-X main.Commit=$(git rev-parse HEAD) -X main.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// and GET /version reports them with the Go version so a rollout can be
+// confirmed. Local builds without the flags report "dev" and "unknown".
+
+import (
+	"net/http"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Set via -ldflags -X; empty in dev builds.
+var Commit, BuildTime string
+
+func versionHandler(c *gin.Context) {
+	commit, built := Commit, BuildTime
+	if commit == "" {
+		commit = "dev"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"commit":     commit,
+		"build_time": built,
+		"go_version": runtime.Version(),
+	})
+}
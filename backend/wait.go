@@ -0,0 +1,165 @@
+package main
+
+// backend/wait.go
+//
+// Synchronous submission: POST /simulate?wait=true enqueues as usual, then
+// holds the request open until the job reaches a terminal state and answers
+// 200 with the result inlined. If ?timeout= seconds (default 30, capped at
+// WAIT_MAX_SECONDS and below REQUEST_TIMEOUT) pass first it answers the usual 202 so the client can
+// fall back to polling. Waiting follows job_events:<id> like the SSE stream,
+// polling the meta key if no event arrives. Idempotent replays and reused
+// jobs answer immediately as without wait.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Wait defaults
+const (
+	DefaultWaitTimeout    = 30 * time.Second
+	DefaultMaxWaitTimeout = 2 * time.Minute
+)
+
+var maxWaitTimeout = DefaultMaxWaitTimeout
+
+func loadWaitConfig() {
+	maxWaitTimeout = envSeconds("WAIT_MAX_SECONDS", DefaultMaxWaitTimeout)
+}
+
+// parseWait returns how long the caller asked to wait, or 0 without
+// ?wait=true. It writes 400 and returns ok=false for a malformed ?timeout=.
+func parseWait(c *gin.Context) (timeout time.Duration, ok bool) {
+	if c.Query("wait") != "true" {
+		return 0, true
+	}
+	timeout = DefaultWaitTimeout
+	if v := c.Query("timeout"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			respondInvalidParam(c, "timeout", "timeout must be a positive number of seconds")
+			return 0, false
+		}
+		timeout = time.Duration(n) * time.Second
+	}
+	return min(timeout, waitLimit()), true
+}
+
+// waitLimit is the longest wait granted: WAIT_MAX_SECONDS, but no more than
+// nine tenths of the request timeout so the 202 fallback goes out before it.
+func waitLimit() time.Duration {
+	if requestTimeout > 0 {
+		return min(maxWaitTimeout, requestTimeout*9/10)
+	}
+	return maxWaitTimeout
+}
+
+// waitForJob blocks until jobID reaches a terminal state and returns its meta.
+// It returns ctx's error when ctx ends first.
+func waitForJob(ctx context.Context, jobID string) (JobMeta, error) {
+	// subscribe before reading the meta so a transition in between is not missed
+	sub := rdb.Subscribe(ctx, jobEventsChannel(jobID))
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return JobMeta{}, err
+	}
+	check := func() (JobMeta, bool, error) {
+		opCtx, cancel := context.WithTimeout(ctx, RedisOpTimeout)
+		defer cancel()
+		meta, err := redisMetaStore{}.GetMeta(opCtx, jobID)
+		if err != nil {
+			return JobMeta{}, false, err
+		}
+		return meta, isTerminalStatus(meta.Status), nil
+	}
+	if meta, done, err := check(); err != nil || done {
+		return meta, err
+	}
+
+	msgs := sub.Channel()
+	poll := time.NewTimer(eventsPollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return JobMeta{}, ctx.Err()
+		case msg, ok := <-msgs:
+			if !ok {
+				return JobMeta{}, errors.New("event subscription closed")
+			}
+			var m JobMeta
+			if json.Unmarshal([]byte(msg.Payload), &m) == nil && isTerminalStatus(m.Status) {
+				return m, nil
+			}
+			poll.Reset(eventsPollInterval)
+		case <-poll.C:
+			meta, done, err := check()
+			if errors.Is(err, ErrMetaNotFound) {
+				// deleted or expired while we were waiting
+				return JobMeta{}, err
+			}
+			if err == nil && done {
+				return meta, nil
+			}
+			poll.Reset(eventsPollInterval)
+		}
+	}
+}
+
+// respondAfterWait waits up to timeout for jobID and then answers: 200 with
+// the result (or the error) inlined once the job is terminal, or 202 with
+// accepted, the usual submission response, if it is still queued or running.
+// Nothing is written if the client disconnects while waiting.
+func respondAfterWait(c *gin.Context, jobID string, timeout time.Duration, accepted gin.H) {
+	reqCtx := c.Request.Context()
+	ctx, cancel := context.WithTimeout(reqCtx, timeout)
+	defer cancel()
+
+	meta, err := waitForJob(ctx, jobID)
+	if reqCtx.Err() != nil {
+		return
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	} else if errors.Is(err, ErrMetaNotFound) {
+		respondError(c, http.StatusNotFound, "job not found", gin.H{"job_id": jobID})
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error(), gin.H{"job_id": jobID})
+		return
+	}
+
+	resp := gin.H{}
+	for k, v := range accepted {
+		resp[k] = v
+	}
+	resp["status"] = meta.Status
+	if meta.Status != StatusDone {
+		if meta.Error != "" {
+			resp["error"] = meta.Error
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	opCtx, opCancel := context.WithTimeout(reqCtx, RedisOpTimeout)
+	defer opCancel()
+	res, ok := loadStoredResult(c, opCtx, jobID)
+	if !ok {
+		return
+	}
+	parsed, err := parseSimulationResult(res)
+	if err != nil {
+		respondError(c, http.StatusBadGateway, "malformed result from worker: "+err.Error(), gin.H{"job_id": jobID})
+		return
+	}
+	resp["result"] = parsed
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWorker pops the next queued job and finishes it with status, the way the
+// worker would: the result (for done) and meta first, then the event.
+func fakeWorker(ctx context.Context, status string, publish bool) <-chan error {
+	errc := make(chan error, 1)
+	go func() {
+		vals, err := rdb.BLPop(ctx, 5*time.Second, RedisJobsList).Result()
+		if err != nil {
+			errc <- err
+			return
+		}
+		var payload JobPayload
+		if err := json.Unmarshal([]byte(vals[1]), &payload); err != nil {
+			errc <- err
+			return
+		}
+		meta, err := redisMetaStore{}.GetMeta(ctx, payload.JobID)
+		if err != nil {
+			errc <- err
+			return
+		}
+		meta.Status = status
+		if status == StatusDone {
+			rdb.Set(ctx, RedisResultsPrefix+meta.JobID, sampleResult, 0)
+		} else {
+			meta.Error = "simulation diverged"
+		}
+		b, _ := json.Marshal(meta)
+		rdb.Set(ctx, RedisJobMetaPrefix+meta.JobID, b, 0)
+		if publish {
+			errc <- publishJobEvent(ctx, meta)
+			return
+		}
+		errc <- nil
+	}()
+	return errc
+}
+
+func submitAndWait(t *testing.T, ctx context.Context, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := setupRouter()
+	req, _ := http.NewRequestWithContext(ctx, "POST", "/simulate"+query, bytes.NewBufferString(withSite(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSubmitWaitReturnsResult(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	worker := fakeWorker(ctx, StatusDone, true)
+
+	w := submitAndWait(t, ctx, "?wait=true&timeout=5")
+	require.NoError(t, <-worker)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		JobID  string            `json:"job_id"`
+		Status string            `json:"status"`
+		Links  map[string]string `json:"links"`
+		Result SimulationResult  `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.NotEmpty(t, response.JobID)
+	assert.Equal(t, StatusDone, response.Status)
+	assert.NotEmpty(t, response.Links["result"])
+	assert.Len(t, response.Result.Data, 3)
+}
+
+func TestSubmitWaitReportsFailureFromPolling(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+	orig := eventsPollInterval
+	eventsPollInterval = 50 * time.Millisecond
+	defer func() { eventsPollInterval = orig }()
+	// no event is published, so only polling the meta sees the failure
+	worker := fakeWorker(ctx, StatusError, false)
+
+	w := submitAndWait(t, ctx, "?wait=true&timeout=5")
+	require.NoError(t, <-worker)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, StatusError, response["status"])
+	assert.Equal(t, "simulation diverged", response["error"])
+	assert.NotContains(t, response, "result")
+}
+
+func TestSubmitWaitTimesOut(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	start := time.Now()
+	w := submitAndWait(t, ctx, "?wait=true&timeout=1")
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, StatusQueued, response["status"])
+	assert.NotEmpty(t, response["job_id"])
+	assert.Equal(t, int64(1), rdb.LLen(ctx, RedisJobsList).Val(), "the job stays queued")
+}
+
+func TestSubmitWaitClientDisconnect(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	rdb.FlushDB(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	w := submitAndWait(t, ctx, "?wait=true&timeout=10")
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestSubmitWaitRejectsBadTimeout(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	for _, v := range []string{"0", "-3", "soon"} {
+		w := submitAndWait(t, ctx, "?wait=true&timeout="+v)
+		assert.Equal(t, http.StatusBadRequest, w.Code, v)
+	}
+	assert.Equal(t, int64(0), rdb.LLen(ctx, RedisJobsList).Val())
+}
+
+func TestParseWaitCapsTimeout(t *testing.T) {
+	router := setupRouter()
+	var got time.Duration
+	router.GET("/wait-probe", func(c *gin.Context) {
+		got, _ = parseWait(c)
+	})
+	for query, want := range map[string]time.Duration{
+		"":                        0,
+		"?timeout=5":              0,
+		"?wait=true":              DefaultWaitTimeout,
+		"?wait=true&timeout=5":    5 * time.Second,
+		"?wait=true&timeout=9999": waitLimit(),
+	} {
+		req, _ := http.NewRequest("GET", "/wait-probe"+query, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		assert.Equal(t, want, got, query)
+	}
+
+	// the wait ends before the request timeout would cut it off
+	orig := requestTimeout
+	t.Cleanup(func() { requestTimeout = orig })
+	requestTimeout = 10 * time.Second
+	assert.Equal(t, 9*time.Second, waitLimit())
+	requestTimeout = 0
+	assert.Equal(t, maxWaitTimeout, waitLimit())
+}
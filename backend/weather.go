@@ -0,0 +1,210 @@
+package main
+
+// backend/weather.go
+//
+// Weather cache. GET /weather?lat=&lon=&start=&end= returns the hourly series
+// the worker simulates against (temperature_2m, shortwave_radiation and
+// relativehumidity_2m from Open-Meteo), cached in Redis under
+// weather:<lat>:<lon>:<start>:<end> for WEATHER_CACHE_TTL_SECONDS. On a miss
+// the upstream is fetched once, however many requests for the same key arrive
+// meanwhile. The body is the upstream JSON unchanged, so callers parse it like
+// a direct Open-Meteo response; X-Cache says whether it was a HIT or a MISS.
+// Coordinates are rounded to 4 decimals (about 11 m) so that 39.9 and 39.90
+// share an entry.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+// RedisWeatherPrefix keys weather:<lat>:<lon>:<start>:<end> -> upstream JSON (see weatherQuery.key).
+const RedisWeatherPrefix = "weather:"
+
+// Weather cache defaults
+const (
+	DefaultWeatherAPIURL   = "https://api.open-meteo.com/v1/forecast"
+	DefaultWeatherCacheTTL = 6 * time.Hour
+)
+
+// weatherHourly are the hourly variables the worker's model reads.
+const weatherHourly = "temperature_2m,shortwave_radiation,relativehumidity_2m"
+
+// maxWeatherBodyBytes bounds an upstream response; a year of hourly data is well under 1 MiB.
+const maxWeatherBodyBytes = 8 << 20
+
+var (
+	weatherAPIURL   = DefaultWeatherAPIURL
+	weatherCacheTTL = DefaultWeatherCacheTTL
+	// weatherFetchTimeout bounds a request that misses the cache, upstream call included
+	weatherFetchTimeout = 15 * time.Second
+	weatherClient       = &http.Client{Timeout: 10 * time.Second}
+	// weatherFetches collapses concurrent misses for one key into a single upstream call
+	weatherFetches singleflight.Group
+)
+
+func loadWeatherConfig() {
+	if v := os.Getenv("WEATHER_API_URL"); v != "" {
+		weatherAPIURL = v
+	}
+	weatherCacheTTL = envSeconds("WEATHER_CACHE_TTL_SECONDS", DefaultWeatherCacheTTL)
+}
+
+// weatherQuery identifies one cached series.
+type weatherQuery struct {
+	Lat, Lon   float64
+	Start, End string
+}
+
+func formatCoord(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+}
+
+func (q weatherQuery) key() string {
+	return redisKey(RedisWeatherPrefix) + formatCoord(q.Lat) + ":" + formatCoord(q.Lon) + ":" + q.Start + ":" + q.End
+}
+
+// parseWeatherQuery reads and validates the query string. It writes 400 and
+// returns ok=false when a parameter is missing or invalid.
+func parseWeatherQuery(c *gin.Context) (q weatherQuery, ok bool) {
+	var missing []string
+	for _, name := range []string{"lat", "lon", "start", "end"} {
+		if c.Query(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		respondInvalidParam(c, missing[0], "missing query parameters: "+strings.Join(missing, ", "))
+		return q, false
+	}
+	q.Start, q.End = c.Query("start"), c.Query("end")
+
+	var verr ValidationError
+	coord := func(name string, lo, hi float64) float64 {
+		r := paramRange{min: lo, max: hi}
+		v, err := strconv.ParseFloat(c.Query(name), 64)
+		if err != nil || !r.contains(v) {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Value: c.Query(name), Allowed: r.String()})
+		}
+		return v
+	}
+	q.Lat = coord("lat", -90, 90)
+	q.Lon = coord("lon", -180, 180)
+	for _, f := range validateDates(&SimulationParams{StartDate: q.Start, EndDate: q.End}) {
+		f.Field = strings.TrimSuffix(f.Field, "_date")
+		verr.Fields = append(verr.Fields, f)
+	}
+	if len(verr.Fields) > 0 {
+		respondValidationError(c, &verr)
+		return q, false
+	}
+	return q, true
+}
+
+// fetchWeather requests q's series from the upstream weather API.
+func fetchWeather(ctx context.Context, q weatherQuery) ([]byte, error) {
+	params := url.Values{
+		"latitude":   {formatCoord(q.Lat)},
+		"longitude":  {formatCoord(q.Lon)},
+		"hourly":     {weatherHourly},
+		"start_date": {q.Start},
+		"end_date":   {q.End},
+		"timezone":   {"auto"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, weatherAPIURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := weatherClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWeatherBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	var series struct {
+		Hourly struct {
+			Time []string `json:"time"`
+		} `json:"hourly"`
+	}
+	if err := json.Unmarshal(body, &series); err != nil {
+		return nil, fmt.Errorf("upstream response is not JSON: %w", err)
+	}
+	if series.Hourly.Time == nil {
+		return nil, errors.New("upstream response has no hourly series")
+	}
+	return body, nil
+}
+
+// errWeatherUpstream wraps failures of the upstream API, as opposed to Redis.
+var errWeatherUpstream = errors.New("weather upstream error")
+
+// cachedWeather returns q's series from the cache, fetching and storing it on
+// a miss. hit reports whether the cache answered.
+func cachedWeather(ctx context.Context, q weatherQuery) (body []byte, hit bool, err error) {
+	key := q.key()
+	body, err = rdb.Get(ctx, key).Bytes()
+	if err == nil {
+		return body, true, nil
+	} else if !errors.Is(err, redis.Nil) {
+		return nil, false, err
+	}
+	v, err, _ := weatherFetches.Do(key, func() (interface{}, error) {
+		body, err := fetchWeather(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errWeatherUpstream, err)
+		}
+		if err := rdb.Set(ctx, key, body, weatherCacheTTL).Err(); err != nil {
+			// still worth answering; the next request fetches again
+			slog.Warn("failed to cache weather", "key", key, "error", err)
+		}
+		return body, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return v.([]byte), false, nil
+}
+
+func weatherHandler(c *gin.Context) {
+	q, ok := parseWeatherQuery(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), weatherFetchTimeout)
+	defer cancel()
+
+	body, hit, err := cachedWeather(ctx, q)
+	if errors.Is(err, errWeatherUpstream) {
+		respondError(c, http.StatusBadGateway, err.Error())
+		return
+	} else if err != nil {
+		respondError(c, http.StatusInternalServerError, "redis error: "+err.Error())
+		return
+	}
+	cache := "MISS"
+	if hit {
+		cache = "HIT"
+	}
+	c.Header("X-Cache", cache)
+	c.Data(http.StatusOK, gin.MIMEJSON, body)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleWeather = `{"hourly": {"time": ["2025-11-01T00:00", "2025-11-01T01:00"], "temperature_2m": [10.0, 11.0], "shortwave_radiation": [0, 100], "relativehumidity_2m": [50, 55]}}`
+
+// withWeatherUpstream points the weather cache at handler for the duration of
+// the test and returns a counter of the requests it received.
+func withWeatherUpstream(t *testing.T, handler http.HandlerFunc) *atomic.Int32 {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+	orig := weatherAPIURL
+	weatherAPIURL = srv.URL
+	t.Cleanup(func() {
+		weatherAPIURL = orig
+		srv.Close()
+	})
+	return &calls
+}
+
+func getWeather(router http.Handler, query string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/weather"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestWeatherCacheMissThenHit(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	var upstreamQuery map[string][]string
+	calls := withWeatherUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		upstreamQuery = r.URL.Query()
+		w.Write([]byte(sampleWeather))
+	})
+
+	w := getWeather(router, "?lat=39.9&lon=116.4&start=2025-11-01&end=2025-11-01")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "MISS", w.Header().Get("X-Cache"))
+	assert.JSONEq(t, sampleWeather, w.Body.String())
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, []string{"39.9"}, upstreamQuery["latitude"])
+	assert.Equal(t, []string{"2025-11-01"}, upstreamQuery["start_date"])
+	assert.Equal(t, []string{weatherHourly}, upstreamQuery["hourly"])
+
+	key := RedisWeatherPrefix + "39.9:116.4:2025-11-01:2025-11-01"
+	assert.Positive(t, rdb.TTL(ctx, key).Val())
+
+	// the same location written differently shares the entry
+	w = getWeather(router, "?lat=39.90&lon=116.40000&start=2025-11-01&end=2025-11-01")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Cache"))
+	assert.JSONEq(t, sampleWeather, w.Body.String())
+	assert.Equal(t, int32(1), calls.Load(), "served from the cache")
+}
+
+func TestWeatherUpstreamFailure(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	ctx := context.Background()
+	rdb.FlushDB(ctx)
+
+	calls := withWeatherUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	})
+	query := "?lat=52.1&lon=5.2&start=2025-11-01&end=2025-11-02"
+	w := getWeather(router, query)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "429")
+
+	// failures are not cached
+	getWeather(router, query)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Zero(t, rdb.Exists(ctx, RedisWeatherPrefix+"52.1:5.2:2025-11-01:2025-11-02").Val())
+
+	withWeatherUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error": true}`))
+	})
+	w = getWeather(router, query)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "no hourly series")
+}
+
+func TestWeatherValidation(t *testing.T) {
+	router := setupRouter()
+	calls := withWeatherUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(sampleWeather))
+	})
+
+	w := getWeather(router, "?lat=39.9")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "lon, start, end")
+
+	w = getWeather(router, "?lat=91&lon=abc&start=2025-11-02&end=2025-11-01")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var resp struct {
+		Fields []FieldError `json:"fields"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var fields []string
+	for _, f := range resp.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.Equal(t, []string{"lat", "lon", "end"}, fields)
+	assert.Zero(t, calls.Load())
+}
@@ -0,0 +1,134 @@
+package main
+
+// backend/yamlbody.go
+//
+// YAML submissions. POST /simulate accepts its body as YAML when sent with
+// Content-Type application/yaml (or text/yaml, application/x-yaml). The
+// document is converted to the equivalent JSON before anything reads it, so
+// YAML bodies take exactly the JSON path: schema check, binding (with its
+// numeric string coercion), defaults, validation and enqueue. Other content
+// types get 415; a request without one is read as JSON, as it always was.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// MIMEYAML is the registered YAML media type.
+const MIMEYAML = "application/yaml"
+
+// yamlMediaTypes are read as YAML; the others are in common use.
+var yamlMediaTypes = map[string]bool{MIMEYAML: true, "text/yaml": true, "application/x-yaml": true}
+
+// paramsMediaTypes lists what POST /simulate accepts, for the 415 response.
+var paramsMediaTypes = []string{gin.MIMEJSON, MIMEYAML, "text/yaml", "application/x-yaml"}
+
+// acceptParamsBody checks the request's Content-Type and, for YAML, replaces
+// the body with its JSON form. It answers the request and returns false when
+// the type is unsupported or the YAML is invalid.
+func acceptParamsBody(c *gin.Context, limit int64) bool {
+	contentType := c.ContentType()
+	switch {
+	case contentType == "" || contentType == gin.MIMEJSON:
+		return true
+	case !yamlMediaTypes[contentType]:
+		respondError(c, http.StatusUnsupportedMediaType, "unsupported Content-Type "+contentType,
+			gin.H{"supported": paramsMediaTypes})
+		return false
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if isBodyTooLarge(err) {
+			respondBodyTooLarge(c, limit)
+			return false
+		}
+		respondError(c, http.StatusBadRequest, "reading body: "+err.Error())
+		return false
+	}
+	doc, err := yamlToJSON(body)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid YAML: "+err.Error())
+		return false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(doc))
+	return true
+}
+
+// yamlToJSON converts one YAML document to JSON. Scalars keep their YAML
+// types, except timestamps, which stay as written: start_date: 2025-11-01 is
+// the string "2025-11-01", not a time.
+func yamlToJSON(doc []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+	if root.Kind == 0 {
+		return []byte("null"), nil // empty input
+	}
+	v, err := yamlValue(&root)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// yamlValue returns n as a value encoding/json can marshal.
+func yamlValue(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(n.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(n.Alias)
+	case yaml.SequenceNode:
+		items := make([]interface{}, len(n.Content))
+		for i, item := range n.Content {
+			v, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, nil
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: mapping keys must be scalars", key.Line)
+			}
+			v, err := yamlValue(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = v
+		}
+		return m, nil
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!str", "!!timestamp":
+			return n.Value, nil
+		case "!!null":
+			return nil, nil
+		}
+		var v interface{}
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+		if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			return nil, fmt.Errorf("line %d: %s is not a finite number", n.Line, n.Value)
+		}
+		return v, nil
+	}
+	return nil, errors.New("unsupported YAML node")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitYAML(t *testing.T) {
+	if !checkRedisAvailable(t) {
+		return
+	}
+	router := setupRouter()
+	rdb.FlushDB(context.Background())
+
+	body := `# north house, winter trial
+A_glass: 80
+tau_glass: "0.7"   # numeric strings are coerced as in JSON
+setpoint: 14
+lat: 52.1
+lon: 5.2
+start_date: 2025-11-01
+end_date: 2025-11-03
+model: multinode
+tags:
+  - north
+  - trial
+priority: high
+seed: 7
+`
+	for _, contentType := range []string{"application/yaml", "text/yaml; charset=utf-8"} {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		meta := submitAndLoadMeta(t, router, req)
+
+		assert.Equal(t, StatusQueued, meta.Status, contentType)
+		assert.Equal(t, 80.0, *meta.Params.A_glass)
+		assert.Equal(t, 0.7, *meta.Params.TauGlass)
+		assert.Equal(t, 14.0, *meta.Params.Setpoint)
+		assert.Equal(t, "2025-11-01", meta.Params.StartDate)
+		assert.Equal(t, "2025-11-03", meta.Params.EndDate)
+		assert.Equal(t, ModelMultinode, meta.Params.Model)
+		assert.Equal(t, int64(7), *meta.Params.Seed)
+		assert.Equal(t, []string{"north", "trial"}, meta.Tags)
+		assert.Equal(t, PriorityHigh, meta.Priority)
+		require.NotNil(t, meta.Params.U_day, "defaults are applied as for JSON")
+	}
+}
+
+func TestSubmitYAMLRejected(t *testing.T) {
+	router := setupRouter()
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/simulate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("text/plain", "A_glass: 80")
+	require.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, CodeUnsupportedType, decodeError(t, w).Error.Code)
+	assert.Contains(t, w.Body.String(), `"supported":["application/json","application/yaml"`)
+
+	w = post("application/yaml", "A_glass: [80")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, decodeError(t, w).Error.Message, "invalid YAML")
+
+	// valid YAML, invalid params: the usual validation answers
+	w = post("application/yaml", "tau_glass: 3\nlat: 95\nlon: 0\n")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	env := decodeError(t, w)
+	assert.Equal(t, CodeInvalidParam, env.Error.Code)
+	assert.Len(t, env.Fields, 2)
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		name, yaml, json string
+	}{
+		{"dates stay strings", "start_date: 2025-11-01", `{"start_date": "2025-11-01"}`},
+		{"scalars keep their types", "a: 1\nb: 1.5\nc: true\nd: ~\ne: '2'", `{"a": 1, "b": 1.5, "c": true, "d": null, "e": "2"}`},
+		{"anchors", "base: &b {U_day: 4}\nsame: *b", `{"base": {"U_day": 4}, "same": {"U_day": 4}}`},
+		{"empty document", "", `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yamlToJSON([]byte(tt.yaml))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.json, string(got))
+		})
+	}
+
+	_, err := yamlToJSON([]byte("A_glass: .inf"))
+	assert.ErrorContains(t, err, "not a finite number")
+}
@@ -2,13 +2,18 @@
 // In Next.js, process.env is available on both client and server
 // @ts-ignore - process.env is available in Next.js
 export const API_BASE_URL = (process.env.NEXT_PUBLIC_API_URL || "http://localhost:8080");
+// @ts-ignore - process.env is available in Next.js
+const API_KEY: string | undefined = process.env.NEXT_PUBLIC_API_KEY;
 
 export async function fetchAPI<T>(endpoint: string, options: RequestInit = {}): Promise<T> {
     const url = `${API_BASE_URL}${endpoint}`;
 
-    const defaultHeaders = {
+    const defaultHeaders: Record<string, string> = {
         "Content-Type": "application/json"
     }
+    if (API_KEY) {
+        defaultHeaders["X-API-Key"] = API_KEY;
+    }
 
     const response = await fetch(url, {
         ...options,
@@ -20,7 +25,9 @@ export async function fetchAPI<T>(endpoint: string, options: RequestInit = {}):
 
     if (!response.ok) {
         const errorData = await response.json().catch(() => ({}));
-        throw new Error(`API Error (${response.status}): ${errorData.error || errorData.detail || response.statusText}`)
+        // the backend answers {"error": {"code", "message", "field"}}
+        const message = errorData.error?.message || errorData.error || errorData.detail || response.statusText
+        throw new Error(`API Error (${response.status}): ${message}`)
     }
     return await response.json()
 }
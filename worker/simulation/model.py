@@ -90,15 +90,95 @@ def calculate_heat_to_threshold(T_air: float, T_mass: float, T_soil: float,
     
     return max(0.0, total_heat)
 
-def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, substeps=60, T_bounds=(0, 50)):
+def _envelope_u(G: float, U_day: float, U_night: float) -> float:
+    """Envelope U-value for solar radiation G.
+
+    More realistic: U-value depends on solar radiation, not just time.
+    High solar = daytime behavior (ventilation open, more heat loss);
+    low solar = nighttime behavior (sealed, less heat loss).
+    """
+    if G > 100:  # Strong solar radiation (daytime)
+        return U_day
+    if G < 10:  # Very low solar (nighttime)
+        return U_night
+    # Gradual transition zone (dawn/dusk)
+    # Linear interpolation between U_night and U_day
+    solar_factor = min(1.0, max(0.0, (G - 10) / 90))
+    return U_night + (U_day - U_night) * solar_factor
+
+def _longwave_loss(T_air: float, Tout: float, A_glass: float, cloud_factor: float, params: dict) -> float:
+    """Net longwave radiation (W) from the greenhouse air to the sky."""
+    T_air_K = np.clip(T_air + 273.15, 0, 1000)
+    T_sky_K = np.clip(_sky_temperature_kelvin(Tout, cloud_factor), 0, 1000)
+    emissivity = params.get("emissivity", 0.9)
+    # Scale down longwave radiation slightly to prevent it from dominating
+    # Real greenhouses have some reflection and the effective area is less
+    lw_scale = params.get("lw_radiation_scale", 0.7)  # Scale factor for realistic magnitude
+    return lw_scale * emissivity * SIGMA * A_glass * (T_air_K**4 - T_sky_K**4)
+
+# --- Heater control modes (params["heater_control"]) ---
+HEATER_ONOFF = "onoff"                 # full power below the deadband, off above it
+HEATER_PROPORTIONAL = "proportional"   # power proportional to the gap below setpoint
+DEFAULT_HEATER_DEADBAND = 1.0          # K, width of the on/off hysteresis band
+
+class HeaterController:
+    """Heater power (W) for each substep.
+
+    onoff switches on at full heater_max_w once the air drops below
+    setpoint - heater_deadband/2 and off once it rises above
+    setpoint + heater_deadband/2, holding its state in between.
+    proportional applies heater_kp * (setpoint - T), clipped to
+    [0, heater_max_w]; without heater_kp the gain scales with the heat
+    capacity C so the gap closes gradually (heating_rate_factor per substep).
     """
-    Stable greenhouse lumped simulation with smoother dynamics.
+
+    def __init__(self, params: dict, C: float, setpoint, heater_max_w: float):
+        self.mode = params.get("heater_control") or HEATER_ONOFF
+        if self.mode not in (HEATER_ONOFF, HEATER_PROPORTIONAL):
+            raise ValueError(f"unknown heater_control {self.mode!r}, expected {HEATER_ONOFF} or {HEATER_PROPORTIONAL}")
+        self.setpoint = setpoint
+        self.heater_max_w = heater_max_w
+        self.C = C
+        self.half_band = float(params.get("heater_deadband") or DEFAULT_HEATER_DEADBAND) / 2
+        self.kp = params.get("heater_kp")
+        self.heating_rate_factor = params.get("heating_rate_factor", 0.4)
+        self.on = False
+
+    def power(self, T: float, dt_step: float) -> float:
+        if self.setpoint is None:
+            return 0.0
+        if self.mode == HEATER_ONOFF:
+            if T < self.setpoint - self.half_band:
+                self.on = True
+            elif T > self.setpoint + self.half_band:
+                self.on = False
+            return float(self.heater_max_w) if self.on else 0.0
+        if T >= self.setpoint:
+            return 0.0
+        kp = self.kp if self.kp is not None else self.C * self.heating_rate_factor / dt_step
+        return float(np.clip(kp * (self.setpoint - T), 0, self.heater_max_w))
+
+def _latent_loss(T_air: float, RH: float, evap_coeff: float, A_floor: float) -> float:
+    """Latent heat (W) carried off by evaporation from the floor."""
+    T_air_safe = np.clip(T_air, -50, 50)
+    es = 0.6108 * np.exp(17.27 * T_air_safe / (T_air_safe + 237.3))
+    ea = RH * es
+    VPD = max(es - ea, 0.0)
+    evap_kg_m2_s = evap_coeff * VPD
+    return evap_kg_m2_s * LV * A_floor
+
+def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, substeps=60, T_bounds=(0, 50),
+                        on_chunk=None, chunk_rows=24):
+    """
+    Multi-node greenhouse simulation (air, thermal mass and soil) with smoother dynamics.
 
     weather_df: must contain columns 'datetime', 'Tout', 'G', optional 'RH'
     params: dict of greenhouse parameters
     dt: timestep in seconds
     substeps: smaller internal steps for numerical stability
     T_bounds: min and max allowed temperatures for air/mass/soil
+    on_chunk: optional callback given each run of chunk_rows output rows (and the
+              remainder at the end) as they are computed
     """
 
     # --- Parameters & defaults ---
@@ -136,6 +216,8 @@ def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, subst
     m_air = rho_air * V
     C_air = m_air * cp_air
 
+    heater = HeaterController(params, C_air + C_mass, setpoint, heater_max_w)
+
     out_rows = []
 
     for _, row in weather_df.iterrows():
@@ -146,20 +228,10 @@ def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, subst
         hour = int(row["datetime"].hour) if "datetime" in row else 12
 
         # --- Determine insulation (gradual transition based on solar radiation) ---
-        # More realistic: U-value depends on solar radiation, not just time
-        # High solar = daytime behavior (ventilation open, more heat loss)
-        # Low solar = nighttime behavior (sealed, less heat loss)
-        if G > 100:  # Strong solar radiation (daytime)
-            U_env = U_day
-        elif G < 10:  # Very low solar (nighttime)
-            U_env = U_night
-        else:
-            # Gradual transition zone (dawn/dusk)
-            # Linear interpolation between U_night and U_day
-            solar_factor = min(1.0, max(0.0, (G - 10) / 90))
-            U_env = U_night + (U_day - U_night) * solar_factor
-        
+        U_env = _envelope_u(G, U_day, U_night)
+
         dt_step = float(dt) / max(1, int(substeps))
+        heater_energy = 0.0
 
         # --- Substeps for numerical stability ---
         for _s in range(max(1, int(substeps))):
@@ -175,13 +247,7 @@ def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, subst
             Q_vent = m_dot * cp_air * (T_air - Tout)
 
             # --- Longwave radiation ---
-            T_air_K = np.clip(T_air + 273.15, 0, 1000)
-            T_sky_K = np.clip(_sky_temperature_kelvin(Tout, cloud_factor), 0, 1000)
-            emissivity = params.get("emissivity", 0.9)
-            # Scale down longwave radiation slightly to prevent it from dominating
-            # Real greenhouses have some reflection and the effective area is less
-            lw_scale = params.get("lw_radiation_scale", 0.7)  # Scale factor for realistic magnitude
-            Q_lw = lw_scale * emissivity * SIGMA * A_glass * (T_air_K**4 - T_sky_K**4)
+            Q_lw = _longwave_loss(T_air, Tout, A_glass, cloud_factor, params)
 
             # --- Heat exchange with mass and soil ---
             h_am = params.get("h_am", 3.0)
@@ -191,12 +257,7 @@ def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, subst
             Q_as = h_as * A_floor * (T_soil - T_air)
 
             # --- Latent heat (evaporation) ---
-            T_air_safe = np.clip(T_air, -50, 50)
-            es = 0.6108 * np.exp(17.27 * T_air_safe / (T_air_safe + 237.3))
-            ea = RH * es
-            VPD = max(es - ea, 0.0)
-            evap_kg_m2_s = evap_coeff * VPD
-            Q_lat = evap_kg_m2_s * LV * A_floor
+            Q_lat = _latent_loss(T_air, RH, evap_coeff, A_floor)
 
             # --- Net heat flows ---
             Q_air_in = Q_air_sw + Q_am + Q_as - Q_loss_env - Q_vent - Q_lw - Q_lat
@@ -212,15 +273,10 @@ def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, subst
             T_mass += dT_mass
             T_soil += dT_soil
 
-            # --- Heater control (gradual) ---
-            Q_heater = 0.0
-            if setpoint is not None and T_air < setpoint:
-                # Gradual heating: aim to reach setpoint over ~2-3 timesteps (not instant)
-                # This prevents aggressive oscillations and makes behavior more realistic
-                heating_rate_factor = params.get("heating_rate_factor", 0.4)  # 0.4 = heat over ~2.5 hours
-                power_needed = (setpoint - T_air) * (C_air + C_mass) * heating_rate_factor / dt_step
-                Q_heater = np.clip(power_needed, 0, heater_max_w)
-                T_air += (Q_heater * dt_step) / (C_air + C_mass)
+            # --- Heater control (see HeaterController) ---
+            Q_heater = heater.power(T_air, dt_step)
+            T_air += (Q_heater * dt_step) / (C_air + C_mass)
+            heater_energy += Q_heater * dt_step
 
             # --- Clamp temperatures ---
             T_air = np.clip(T_air, *T_bounds)
@@ -245,9 +301,113 @@ def simulate_greenhouse(weather_df: pd.DataFrame, params: dict, dt=3600.0, subst
             "Tin": T_air,
             "T_mass": T_mass,
             "T_soil": T_soil,
-            "Q_heater": Q_heater,
+            "Q_heater": heater_energy / float(dt),  # mean power over the timestep
             "Q_latent": Q_lat,
             "Q_to_threshold": Q_to_threshold,  # Heat needed to reach threshold (J)
         })
+        if on_chunk is not None and chunk_rows > 0 and len(out_rows) % chunk_rows == 0:
+            on_chunk(out_rows[-chunk_rows:])
+
+    if on_chunk is not None and chunk_rows > 0 and len(out_rows) % chunk_rows:
+        on_chunk(out_rows[-(len(out_rows) % chunk_rows):])
+
+    return pd.DataFrame(out_rows)
+
+def simulate_lumped(weather_df: pd.DataFrame, params: dict, dt=3600.0, substeps=60, T_bounds=(0, 50),
+                    on_chunk=None, chunk_rows=24):
+    """
+    Lumped-capacitance greenhouse simulation: air, structure and contents share
+    one temperature and one heat capacity C (J/K), so solar gain warms them
+    together and there are no separate thermal mass or soil nodes.
+
+    Takes the same arguments as simulate_greenhouse and returns the same
+    columns except T_mass and T_soil.
+    """
+
+    # --- Parameters & defaults (as in simulate_greenhouse) ---
+    A_glass = params.get("A_glass", 50.0)
+    tau_glass = params.get("tau_glass", 0.85)
+    U_day = params.get("U_day", 2.0)
+    U_night = params.get("U_night", 0.25)
+    ACH = params.get("ACH", 0.5)
+    V = params.get("V", 100.0)
+    A_floor = params.get("A_floor", 50.0)
+    cloud_factor = params.get("cloud_factor", 0.5)
+    soil_U = params.get("soil_U", 0.5)                     # ground loss to outside, W/m2K
+    heater_max_w = params.get("heater_max_w", 5000.0)
+    evap_coeff = params.get("evap_coeff", 1e-8)
+
+    # --- Heat capacity: the backend always sends C; otherwise air plus thermal mass ---
+    C_air = RHO_AIR * V * CP_AIR
+    C = params.get("C") or C_air + params.get("thermal_mass_kg", 20000.0) * params.get("cp_mass", 4186.0)
+    C = float(C)
+
+    T = float(params.get("T_init", 15.0))
+    setpoint = params.get("setpoint", None)
+    heater = HeaterController(params, C, setpoint, heater_max_w)
+
+    out_rows = []
+
+    for _, row in weather_df.iterrows():
+        Tout = float(row.get("Tout", row.get("T_out", 0.0)))
+        G = float(row.get("G", row.get("I", 0.0)))
+        RH = float(row.get("RH", 0.5) or 0.5)
+        hour = int(row["datetime"].hour) if "datetime" in row else 12
+
+        U_env = _envelope_u(G, U_day, U_night)
+        dt_step = float(dt) / max(1, int(substeps))
+        heater_energy = 0.0
+
+        for _s in range(max(1, int(substeps))):
+            Q_sw = G * A_glass * tau_glass
+            Q_loss_env = U_env * A_glass * (T - Tout)
+            Q_vent = RHO_AIR * V * (ACH / 3600.0) * CP_AIR * (T - Tout)
+            Q_ground = soil_U * A_floor * (T - Tout)
+            Q_lw = _longwave_loss(T, Tout, A_glass, cloud_factor, params)
+            Q_lat = _latent_loss(T, RH, evap_coeff, A_floor)
+
+            T += (Q_sw - Q_loss_env - Q_vent - Q_ground - Q_lw - Q_lat) * dt_step / C
+
+            # --- Heater control (as in simulate_greenhouse) ---
+            Q_heater = heater.power(T, dt_step)
+            T += (Q_heater * dt_step) / C
+            heater_energy += Q_heater * dt_step
+
+            T = np.clip(T, *T_bounds)
+
+        Q_to_threshold = 0.0
+        if setpoint is not None and T < setpoint:
+            calc_params = params.copy()
+            calc_params["current_hour"] = hour
+            # the single node carries all of the capacity
+            Q_to_threshold = calculate_heat_to_threshold(T, T, T, setpoint, C, 0.0, 0.0, Tout, calc_params)
+
+        out_rows.append({
+            "datetime": row["datetime"],
+            "Tout": Tout,
+            "Tin": T,
+            "Q_heater": heater_energy / float(dt),
+            "Q_latent": Q_lat,
+            "Q_to_threshold": Q_to_threshold,
+        })
+        if on_chunk is not None and chunk_rows > 0 and len(out_rows) % chunk_rows == 0:
+            on_chunk(out_rows[-chunk_rows:])
+
+    if on_chunk is not None and chunk_rows > 0 and len(out_rows) % chunk_rows:
+        on_chunk(out_rows[-(len(out_rows) % chunk_rows):])
 
     return pd.DataFrame(out_rows)
+
+# Models selectable with the job's "model" param; the backend validates the name.
+MODELS = {
+    "lumped": simulate_lumped,
+    "multinode": simulate_greenhouse,
+}
+DEFAULT_MODEL = "lumped"
+
+def get_model(name: Optional[str]):
+    """Return the simulate function for a model name (DEFAULT_MODEL when empty)."""
+    name = name or DEFAULT_MODEL
+    if name not in MODELS:
+        raise ValueError(f"unknown model {name!r}, expected one of {', '.join(MODELS)}")
+    return MODELS[name]
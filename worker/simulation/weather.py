@@ -1,3 +1,4 @@
+import os
 import requests
 import pandas as pd
 import numpy as np
@@ -6,8 +7,25 @@ import logging
 
 logging.basicConfig(level=logging.INFO, format="[%(asctime)s] %(message)s")
 
-def get_weather(location: dict, start_date: str, end_date: str, timezone: str = "auto") -> pd.DataFrame:
-    lat, lon = location["lat"], location["lon"]
+# Optional backend weather cache (GET /weather), e.g. http://backend:8080/weather.
+# Jobs for the same site and window then share one upstream fetch; if the cache
+# is unreachable the worker falls back to Open-Meteo directly.
+WEATHER_CACHE_URL = os.getenv("WEATHER_CACHE_URL", "")
+WEATHER_CACHE_API_KEY = os.getenv("WEATHER_CACHE_API_KEY", "")
+
+def fetch_hourly(lat, lon, start_date: str, end_date: str, timezone: str = "auto") -> dict:
+    """Return the Open-Meteo hourly response, via the backend cache when configured."""
+    # the cache always asks Open-Meteo for timezone=auto
+    if WEATHER_CACHE_URL and timezone == "auto":
+        try:
+            headers = {"X-API-Key": WEATHER_CACHE_API_KEY} if WEATHER_CACHE_API_KEY else {}
+            r = requests.get(WEATHER_CACHE_URL, params={"lat": lat, "lon": lon, "start": start_date, "end": end_date},
+                             headers=headers, timeout=20)
+            r.raise_for_status()
+            logging.info(f"Weather cache {r.headers.get('X-Cache', '?')} for ({lat}, {lon}) {start_date}..{end_date}")
+            return r.json()
+        except Exception as e:
+            logging.warning(f"Weather cache unavailable, fetching from Open-Meteo: {e}")
 
     url = (
         f"https://api.open-meteo.com/v1/forecast?"
@@ -16,13 +34,16 @@ def get_weather(location: dict, start_date: str, end_date: str, timezone: str =
         f"&start_date={start_date}&end_date={end_date}"
         f"&timezone={timezone}"
     )
-
     logging.info(f"Fetching weather data: {url}")
+    r = requests.get(url, timeout=10)
+    r.raise_for_status()
+    return r.json()
+
+def get_weather(location: dict, start_date: str, end_date: str, timezone: str = "auto") -> pd.DataFrame:
+    lat, lon = location["lat"], location["lon"]
 
     try:
-        r = requests.get(url, timeout=10)
-        r.raise_for_status()
-        data = r.json()
+        data = fetch_hourly(lat, lon, start_date, end_date, timezone)
 
         if "hourly" not in data or "time" not in data["hourly"]:
             raise ValueError("Invalid data format from API")
@@ -40,3 +61,57 @@ def get_weather(location: dict, start_date: str, end_date: str, timezone: str =
     except Exception as e:
         logging.error(f"Failed to fetch weather data: {e}")
         return pd.DataFrame(columns=["datetime", "Tout", "G", "RH"])
+
+# Relative humidity assumed when the fetch has none (also get_weather's default).
+DEFAULT_RH = 0.5
+
+def synthetic_weather(start_date: str, end_date: str) -> pd.DataFrame:
+    """Hourly frame over start_date..end_date (inclusive) for when the fetch fails.
+
+    Only the synthetic outdoor_temp modes can run on it: Tout is left empty
+    for apply_outdoor_temp to fill, there is no sun (G=0) and RH is DEFAULT_RH.
+    """
+    index = pd.date_range(pd.Timestamp(start_date), pd.Timestamp(end_date) + pd.Timedelta(days=1),
+                          freq="h", inclusive="left")
+    return pd.DataFrame({
+        "datetime": index,
+        "Tout": np.nan,
+        "G": 0.0,
+        "RH": DEFAULT_RH,
+    })
+
+def apply_outdoor_temp(df: pd.DataFrame, params: dict) -> pd.DataFrame:
+    """Replace Tout with the synthetic source chosen by outdoor_temp_mode.
+
+    constant holds outdoor_temp_const; sine swings outdoor_temp_amplitude
+    around outdoor_temp_mean once a day, coldest at 03:00 and warmest at 15:00.
+    weather (or no mode) keeps the fetched temperature. Solar radiation and
+    humidity are the fetched ones, or synthetic_weather's when the fetch failed.
+    """
+    mode = params.get("outdoor_temp_mode") or "weather"
+    if mode == "weather" or df.empty:
+        return df
+    df = df.copy()
+    if mode == "constant":
+        df["Tout"] = float(params["outdoor_temp_const"])
+    elif mode == "sine":
+        hours = df["datetime"].dt.hour + df["datetime"].dt.minute / 60.0
+        df["Tout"] = float(params["outdoor_temp_mean"]) + float(params["outdoor_temp_amplitude"]) * np.sin(2 * np.pi * (hours - 9) / 24)
+    else:
+        raise ValueError(f"unknown outdoor_temp_mode: {mode}")
+    return df
+
+# Open-Meteo's resolution; resample_weather leaves hourly input untouched at this step.
+HOURLY_SECONDS = 3600.0
+
+def resample_weather(df: pd.DataFrame, step_seconds: float) -> pd.DataFrame:
+    """Resample hourly weather to one row per step_seconds.
+
+    Finer steps interpolate linearly between hours; coarser steps average them.
+    """
+    if df.empty or float(step_seconds) == HOURLY_SECONDS:
+        return df
+    out = (df.set_index("datetime")[["Tout", "G", "RH"]]
+             .resample(pd.Timedelta(seconds=float(step_seconds))).mean()
+             .interpolate())
+    return out.reset_index()
@@ -8,7 +8,7 @@ import os
 worker_dir = os.path.abspath(os.path.join(os.path.dirname(__file__), '..'))
 if worker_dir not in sys.path:
     sys.path.insert(0, worker_dir)
-from simulation.model import simulate_greenhouse, calculate_heat_to_threshold
+from simulation.model import simulate_greenhouse, simulate_lumped, get_model, calculate_heat_to_threshold, HeaterController
 
 @pytest.fixture
 def dummy_weather():
@@ -298,3 +298,67 @@ def test_boundary_conditions(dummy_weather):
     
     result_hot = simulate_greenhouse(hot_weather, params)
     assert result_hot["Tin"].max() < 70, "Should handle hot weather reasonably"
+
+def test_simulation_streams_chunks(dummy_weather):
+    """on_chunk receives every output row, in order, in runs of chunk_rows."""
+    chunks = []
+    result = simulate_greenhouse(dummy_weather, {"setpoint": 12.0}, on_chunk=chunks.append, chunk_rows=10)
+    assert [len(c) for c in chunks] == [10, 10, 4]
+    streamed = [row["Tin"] for chunk in chunks for row in chunk]
+    assert streamed == result["Tin"].tolist()
+
+def test_lumped_model(dummy_weather):
+    """The lumped model heats a single node and reports no mass or soil temperatures."""
+    params = {"T_init": 15.0, "setpoint": 12.0, "C": 8.4e7}
+    result = simulate_lumped(dummy_weather, params)
+    assert len(result) == len(dummy_weather)
+    assert "T_mass" not in result.columns
+    assert result["Tin"].between(0, 50).all()
+    assert (result["Q_to_threshold"] >= 0).all()
+
+    # a smaller capacity swings further over the same day
+    light = simulate_lumped(dummy_weather, {**params, "C": 1e6})
+    assert np.ptp(light["Tin"]) > np.ptp(result["Tin"])
+
+def test_get_model():
+    assert get_model(None) is simulate_lumped
+    assert get_model("lumped") is simulate_lumped
+    assert get_model("multinode") is simulate_greenhouse
+    with pytest.raises(ValueError, match="unknown model"):
+        get_model("cfd")
+
+def test_heater_onoff_hysteresis():
+    """onoff runs at full power until the air clears the deadband, then stays off until it drops below it."""
+    heater = HeaterController({"heater_control": "onoff", "heater_deadband": 2.0}, C=1e7, setpoint=15.0, heater_max_w=4000.0)
+    assert heater.power(13.9, 60) == 4000.0   # below 14: on
+    assert heater.power(15.5, 60) == 4000.0   # inside the band: still on
+    assert heater.power(16.1, 60) == 0.0      # above 16: off
+    assert heater.power(14.5, 60) == 0.0      # inside the band: still off
+    assert HeaterController({}, 1e7, None, 4000.0).power(0.0, 60) == 0.0
+
+def test_heater_proportional():
+    """proportional applies heater_kp to the gap, clipped to heater_max_w; without it the gain scales with C."""
+    heater = HeaterController({"heater_control": "proportional", "heater_kp": 500.0}, C=1e7, setpoint=15.0, heater_max_w=4000.0)
+    assert heater.power(13.0, 60) == 1000.0
+    assert heater.power(0.0, 60) == 4000.0
+    assert heater.power(15.0, 60) == 0.0
+
+    legacy = HeaterController({"heater_control": "proportional"}, C=1e7, setpoint=15.0, heater_max_w=1e9)
+    assert legacy.power(14.0, 60) == pytest.approx(1e7 * 0.4 / 60)
+
+    with pytest.raises(ValueError, match="unknown heater_control"):
+        HeaterController({"heater_control": "pid"}, 1e7, 15.0, 4000.0)
+
+def test_heater_modes_in_simulation(dummy_weather):
+    """Both modes hold a cold night near setpoint; onoff cycles through its deadband at full power."""
+    cold = dummy_weather.assign(Tout=0.0, G=0.0)
+    base = {"T_init": 15.0, "setpoint": 15.0, "heater_max_w": 20000.0, "C": 2e7}
+    onoff = simulate_lumped(cold, {**base, "heater_control": "onoff", "heater_deadband": 1.0})
+    proportional = simulate_lumped(cold, {**base, "heater_control": "proportional", "heater_kp": 5000.0})
+    for result in (onoff, proportional):
+        assert result["Tin"].iloc[6:].between(13.0, 16.0).all()
+        assert result["Q_heater"].between(0, 20000.0).all()
+        assert result["Q_heater"].sum() > 0
+    # proportional settles where kp * gap balances the loss, below setpoint
+    assert proportional["Tin"].iloc[-1] < 15.0
+    assert onoff["Tin"].iloc[6:].max() > proportional["Tin"].iloc[6:].max()
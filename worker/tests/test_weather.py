@@ -9,7 +9,7 @@ from unittest.mock import patch, Mock
 worker_dir = os.path.abspath(os.path.join(os.path.dirname(__file__), '..'))
 if worker_dir not in sys.path:
     sys.path.insert(0, worker_dir)
-from simulation.weather import get_weather
+from simulation.weather import get_weather, synthetic_weather, resample_weather, apply_outdoor_temp, DEFAULT_RH
 
 @pytest.mark.unit
 def test_get_weather_success():
@@ -105,3 +105,105 @@ def test_get_weather_different_timezone():
         # Check that timezone parameter was included in URL
         assert "timezone=America/Chicago" in mock_get.call_args[0][0]
 
+
+@pytest.mark.unit
+def test_get_weather_uses_backend_cache():
+    """With WEATHER_CACHE_URL set the series comes from the backend cache."""
+    mock_response = Mock()
+    mock_response.json.return_value = {
+        "hourly": {
+            "time": ["2025-11-01T00:00"],
+            "temperature_2m": [10.0],
+            "shortwave_radiation": [0.0],
+            "relativehumidity_2m": [50.0]
+        }
+    }
+    mock_response.raise_for_status = Mock()
+
+    with patch('simulation.weather.WEATHER_CACHE_URL', "http://backend:8080/weather"), \
+         patch('simulation.weather.WEATHER_CACHE_API_KEY', "worker-key"), \
+         patch('simulation.weather.requests.get', return_value=mock_response) as mock_get:
+        result = get_weather({"lat": 41.8781, "lon": -87.6298}, "2025-11-01", "2025-11-01")
+
+    assert len(result) == 1
+    assert mock_get.call_count == 1
+    assert mock_get.call_args[0][0] == "http://backend:8080/weather"
+    assert mock_get.call_args[1]["params"] == {"lat": 41.8781, "lon": -87.6298, "start": "2025-11-01", "end": "2025-11-01"}
+    assert mock_get.call_args[1]["headers"] == {"X-API-Key": "worker-key"}
+
+@pytest.mark.unit
+def test_get_weather_cache_falls_back_to_upstream():
+    """An unreachable cache falls back to Open-Meteo."""
+    mock_response = Mock()
+    mock_response.json.return_value = {
+        "hourly": {
+            "time": ["2025-11-01T00:00"],
+            "temperature_2m": [10.0],
+            "shortwave_radiation": [0.0],
+            "relativehumidity_2m": [50.0]
+        }
+    }
+    mock_response.raise_for_status = Mock()
+
+    with patch('simulation.weather.WEATHER_CACHE_URL', "http://backend:8080/weather"), \
+         patch('simulation.weather.requests.get', side_effect=[Exception("connection refused"), mock_response]) as mock_get:
+        result = get_weather({"lat": 41.8781, "lon": -87.6298}, "2025-11-01", "2025-11-01")
+
+    assert len(result) == 1
+    assert mock_get.call_count == 2
+    assert "api.open-meteo.com" in mock_get.call_args[0][0]
+
+@pytest.mark.unit
+def test_resample_weather():
+    """Finer steps interpolate between hours; coarser steps average them."""
+    df = pd.DataFrame({
+        "datetime": pd.date_range("2025-11-01", periods=4, freq="h"),
+        "Tout": [0.0, 10.0, 20.0, 30.0],
+        "G": [0.0, 100.0, 200.0, 300.0],
+        "RH": [0.5, 0.5, 0.5, 0.5],
+    })
+
+    assert resample_weather(df, 3600) is df
+
+    fine = resample_weather(df, 900)
+    assert len(fine) == 13
+    assert fine["Tout"].iloc[1] == pytest.approx(2.5)
+
+    coarse = resample_weather(df, 7200)
+    assert list(coarse["Tout"]) == pytest.approx([5.0, 25.0])
+
+@pytest.mark.unit
+def test_apply_outdoor_temp():
+    """Synthetic modes replace Tout only; weather mode keeps the fetched values."""
+    df = pd.DataFrame({
+        "datetime": pd.date_range("2025-11-01", periods=24, freq="h"),
+        "Tout": [7.0] * 24,
+        "G": [100.0] * 24,
+        "RH": [0.5] * 24,
+    })
+
+    assert apply_outdoor_temp(df, {}) is df
+    assert apply_outdoor_temp(df, {"outdoor_temp_mode": "weather"}) is df
+
+    const = apply_outdoor_temp(df, {"outdoor_temp_mode": "constant", "outdoor_temp_const": -2})
+    assert list(const["Tout"]) == [-2.0] * 24
+    assert list(const["G"]) == list(df["G"])
+    assert df["Tout"].iloc[0] == 7.0  # the input is left alone
+
+    sine = apply_outdoor_temp(df, {"outdoor_temp_mode": "sine", "outdoor_temp_mean": 10, "outdoor_temp_amplitude": 5})
+    assert sine["Tout"].iloc[3] == pytest.approx(5.0)
+    assert sine["Tout"].iloc[15] == pytest.approx(15.0)
+    assert sine["Tout"].iloc[9] == pytest.approx(10.0)
+
+@pytest.mark.unit
+def test_synthetic_weather():
+    """The fallback frame covers the dates hourly, end date included, without sun."""
+    df = synthetic_weather("2025-11-01", "2025-11-02")
+    assert len(df) == 48
+    assert df["datetime"].iloc[0] == pd.Timestamp("2025-11-01T00:00:00")
+    assert df["datetime"].iloc[-1] == pd.Timestamp("2025-11-02T23:00:00")
+    assert (df["G"] == 0.0).all()
+    assert (df["RH"] == DEFAULT_RH).all()
+
+    const = apply_outdoor_temp(df, {"outdoor_temp_mode": "constant", "outdoor_temp_const": 4})
+    assert list(const["Tout"]) == [4.0] * 48
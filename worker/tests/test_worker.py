@@ -28,6 +28,15 @@ spec.loader.exec_module(worker_module)
 process_job = worker_module.process_job
 update_job_status = worker_module.update_job_status
 connect_redis = worker_module.connect_redis
+encode_result = worker_module.encode_result
+decode_result = worker_module.decode_result
+touch_job = worker_module.touch_job
+Heartbeat = worker_module.Heartbeat
+beat_worker = worker_module.beat_worker
+start_liveness = worker_module.start_liveness
+job_log = worker_module.job_log
+trace_id = worker_module.trace_id
+job_span = worker_module.job_span
 
 @pytest.fixture
 def rdb():
@@ -57,6 +66,9 @@ def test_update_job_status(rdb):
     meta = json.loads(rdb.get(meta_key))
     assert meta["status"] == "running"
     assert "updated_at" in meta
+    # the write renews the TTL, so expires_at moves and the expiry marker follows
+    assert meta["expires_at"] > meta["updated_at"]
+    assert rdb.get(f"job_expiry:{job_id}") == meta["expires_at"]
 
 @pytest.mark.unit
 def test_update_job_status_with_error(rdb):
@@ -73,6 +85,94 @@ def test_update_job_status_with_error(rdb):
     assert meta["status"] == "error"
     assert meta["error"] == "Test error message"
 
+@pytest.mark.unit
+def test_touch_job_refreshes_running_only(rdb):
+    """The heartbeat bumps updated_at of a running job and leaves other states alone."""
+    old = "2025-11-01T00:00:00+00:00"
+    rdb.set("job_meta:hb_running", json.dumps({"status": "running", "updated_at": old}))
+    rdb.set("job_meta:hb_error", json.dumps({"status": "error", "updated_at": old, "error": "worker timeout"}))
+
+    assert touch_job(rdb, "hb_running")
+    assert json.loads(rdb.get("job_meta:hb_running"))["updated_at"] > old
+
+    assert not touch_job(rdb, "hb_error")
+    assert json.loads(rdb.get("job_meta:hb_error")) == {"status": "error", "updated_at": old, "error": "worker timeout"}
+    assert not touch_job(rdb, "hb_missing")
+
+@pytest.mark.unit
+def test_heartbeat_thread(rdb):
+    """Heartbeat refreshes the meta while active and stops on exit."""
+    old = "2025-11-01T00:00:00+00:00"
+    rdb.set("job_meta:hb_thread", json.dumps({"status": "running", "updated_at": old}))
+
+    with Heartbeat(rdb, "hb_thread", interval=0.01):
+        time.sleep(0.1)
+    beat = json.loads(rdb.get("job_meta:hb_thread"))["updated_at"]
+    assert beat > old
+
+    time.sleep(0.05)
+    assert json.loads(rdb.get("job_meta:hb_thread"))["updated_at"] == beat
+
+@pytest.mark.unit
+def test_worker_liveness(rdb):
+    """The worker heartbeat key is written at once, refreshed, and expires."""
+    beat_worker(rdb, "w-ttl", ttl=20)
+    assert 0 < rdb.ttl("worker_heartbeat:w-ttl") <= 20
+
+    stop = start_liveness(rdb, "w-live", interval=0.01)
+    first = rdb.get("worker_heartbeat:w-live")
+    assert first
+    time.sleep(0.1)
+    stop.set()
+    assert rdb.get("worker_heartbeat:w-live") > first
+
+@pytest.mark.unit
+def test_job_log_order_and_cap(rdb, monkeypatch):
+    """Log lines are appended in order and only the newest JOB_LOG_MAX_LINES are kept."""
+    monkeypatch.setattr(worker_module, "JOB_LOG_MAX_LINES", 3)
+    for i in range(5):
+        job_log(rdb, "log_cap", f"step {i}", ttl=60)
+
+    lines = rdb.lrange("job_logs:log_cap", 0, -1)
+    assert [l.split(" ", 1)[1] for l in lines] == ["INFO step 2", "INFO step 3", "INFO step 4"]
+    assert 0 < rdb.ttl("job_logs:log_cap") <= 60
+
+@pytest.mark.unit
+def test_trace_context_from_payload():
+    """The backend's trace context is read from the payload; jobs without one still run."""
+    traced = {"job_id": "t", "trace_context": {"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+    assert trace_id(traced) == "4bf92f3577b34da6a3ce929d0e0e4736"
+    assert trace_id({"job_id": "t"}) == ""
+    assert trace_id({"job_id": "t", "trace_context": {"traceparent": "garbage"}}) == ""
+    with job_span(traced), job_span({"job_id": "t"}):
+        pass
+
+@pytest.mark.unit
+def test_update_job_status_releases_greenhouse_lock(rdb):
+    """Finishing a job frees active_job:<greenhouse_id> only if that job holds it."""
+    for job_id in ("gh_holder", "gh_other"):
+        rdb.set(f"job_meta:{job_id}", json.dumps({"status": "running", "params": {"greenhouse_id": "gh-7"}}))
+    rdb.set("active_job:gh-7", "gh_holder")
+
+    update_job_status(rdb, "gh_other", "done")
+    assert rdb.get("active_job:gh-7") == "gh_holder"
+
+    update_job_status(rdb, "gh_holder", "running")
+    assert rdb.get("active_job:gh-7") == "gh_holder"
+
+    update_job_status(rdb, "gh_holder", "error", "boom")
+    assert rdb.get("active_job:gh-7") is None
+
+@pytest.mark.unit
+def test_result_encoding_round_trip():
+    """Compressed results decode back to the same document; legacy JSON still decodes."""
+    result = {"job_id": "gz", "data": [{"Tin": 12.5}]}
+    encoded = encode_result(result)
+    assert encoded[:2] == b"\x1f\x8b"
+    assert decode_result(encoded) == result
+    assert decode_result(json.dumps(result)) == result
+    assert decode_result(json.dumps(result).encode("utf-8")) == result
+
 @pytest.mark.integration
 def test_worker_job_success(rdb):
     """Test successful job processing."""
@@ -100,12 +200,27 @@ def test_worker_job_success(rdb):
     meta_after = json.loads(rdb.get(f"job_meta:{job['job_id']}"))
     assert meta_after["status"] == "done"
     
-    # Check result exists
-    result = json.loads(rdb.get(f"job_result:{job['job_id']}"))
+    # Check result exists (read as bytes: it is gzip-compressed)
+    raw = redis.Redis(host="localhost", port=6379, db=0)
+    result = decode_result(raw.get(f"job_result:{job['job_id']}"))
     assert result["job_id"] == "test123"
     assert "data" in result
     assert "summary" in result
 
+    # rows were also streamed as partial chunks while the job ran
+    chunks = [json.loads(c) for c in rdb.lrange(f"job_result_partial:{job['job_id']}", 0, -1)]
+    assert chunks
+    assert sum(len(c) for c in chunks) == len(result["data"])
+    # ...and the last chunk brought progress to 100%
+    assert float(rdb.get(f"job_progress:{job['job_id']}")) == 100.0
+
+    # named artifacts sit next to the result
+    assert rdb.smembers(f"job_artifacts:{job['job_id']}") == {"summary", "temperature", "energy"}
+    temperature = decode_result(raw.get(f"job_artifact:{job['job_id']}:temperature"))
+    assert len(temperature["data"]) == len(result["data"])
+    assert set(temperature["data"][0]) >= {"datetime", "Tin"}
+    assert decode_result(raw.get(f"job_artifact:{job['job_id']}:summary")) == result["summary"]
+
 @pytest.mark.integration
 def test_worker_job_error_handling(rdb):
     """Test job processing error handling."""
@@ -140,6 +255,40 @@ def test_worker_job_error_handling(rdb):
     assert meta_after["status"] == "error"
     assert "error" in meta_after
 
+    # the failure and its traceback are kept for GET /jobs/<id>/logs
+    lines = rdb.lrange(f"job_logs:{job['job_id']}", 0, -1)
+    assert "INFO Processing job test_error" in lines[0]
+    assert any("ERROR Error processing job test_error: Test error: Weather API failed" in l for l in lines)
+    assert any("Traceback" in l for l in lines)
+
+@pytest.mark.integration
+def test_worker_synthetic_mode_without_weather(rdb):
+    """Constant and sine modes still run when the weather fetch fails."""
+    job = {
+        "job_id": "test_no_weather",
+        "params": {
+            "A_glass": 50.0,
+            "outdoor_temp_mode": "constant",
+            "outdoor_temp_const": 3.0,
+            "start_date": "2025-11-01",
+            "end_date": "2025-11-01"
+        },
+        "created_at": "2025-10-05T00:00:00"
+    }
+    rdb.set(f"job_meta:{job['job_id']}", json.dumps({"status": "queued", "created_at": job["created_at"]}))
+
+    with patch("simulation.weather.fetch_hourly", side_effect=Exception("Open-Meteo down")):
+        process_job(job, rdb)
+
+    meta_after = json.loads(rdb.get(f"job_meta:{job['job_id']}"))
+    assert meta_after["status"] == "done"
+    raw = redis.Redis(host="localhost", port=6379, db=0)
+    result = decode_result(raw.get(f"job_result:{job['job_id']}"))
+    assert len(result["data"]) == 24
+    assert all(row["Tout"] == 3.0 for row in result["data"])
+    lines = rdb.lrange(f"job_logs:{job['job_id']}", 0, -1)
+    assert any("WARNING No weather data; running outdoor_temp_mode=constant" in l for l in lines)
+
 @pytest.mark.unit
 def test_connect_redis():
     """Test Redis connection function."""
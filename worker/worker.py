@@ -1,14 +1,26 @@
+import gzip
 import json
+import threading
 import time
 import traceback
 import pandas as pd
 import numpy as np
 import redis
-from datetime import datetime, timezone
-from simulation.model import simulate_greenhouse
-from simulation.weather import get_weather
+import socket
+from contextlib import nullcontext
+from datetime import datetime, timedelta, timezone
+from simulation.model import get_model
+from simulation.weather import get_weather, synthetic_weather, resample_weather, apply_outdoor_temp, HOURLY_SECONDS
 import os
 
+try:
+    # optional: with the OpenTelemetry SDK installed (configured through the
+    # standard OTEL_* variables) each job continues its submitter's trace
+    from opentelemetry import trace as otel_trace
+    from opentelemetry.propagate import extract as otel_extract
+except ImportError:
+    otel_trace = None
+
 # Redis configuration
 REDIS_HOST = os.getenv("REDIS_HOST", "redis")
 REDIS_PORT = int(os.getenv("REDIS_PORT", 6379))
@@ -18,9 +30,55 @@ rdb = redis.from_url(REDIS_ADDR, decode_responses=True)
 print(f"[{datetime.now(timezone.utc).isoformat()}] Connected to Redis at {REDIS_ADDR}")
 
 RESULT_TTL = int(os.getenv("RESULT_TTL", 86400))  # 24h
-QUEUE_NAME = "simulation_jobs"
-META_PREFIX = "job_meta:"
-RESULT_PREFIX = "job_result:"
+# prepended to every key and channel; must match the backend's REDIS_KEY_PREFIX
+KEY_PREFIX = os.getenv("REDIS_KEY_PREFIX", "")
+QUEUE_NAME = KEY_PREFIX + "simulation_jobs"
+# named queue (worker pool) to drain, one of the backend's JOB_QUEUES; unset
+# drains the default lists. Named lists are the default ones suffixed with :<queue>.
+WORKER_QUEUE = os.getenv("WORKER_QUEUE", "")
+_QUEUE_SUFFIX = f":{WORKER_QUEUE}" if WORKER_QUEUE else ""
+# BLPOP checks keys in order, so high-priority jobs are always taken first
+QUEUE_NAMES = [QUEUE_NAME + "_high" + _QUEUE_SUFFIX, QUEUE_NAME + _QUEUE_SUFFIX, QUEUE_NAME + "_low" + _QUEUE_SUFFIX]
+META_PREFIX = KEY_PREFIX + "job_meta:"
+RESULT_PREFIX = KEY_PREFIX + "job_result:"
+# job_expiry:<id> holds the meta's expires_at and outlives it by the grace period,
+# so the backend can report the job as expired (see backend/expiry.go)
+EXPIRY_PREFIX = KEY_PREFIX + "job_expiry:"
+EXPIRED_GRACE_SECONDS = int(os.getenv("EXPIRED_GRACE_SECONDS", 7 * 86400))
+# job_result_partial:<id> is a list of JSON arrays of rows, appended while the job runs
+PARTIAL_PREFIX = KEY_PREFIX + "job_result_partial:"
+# job_artifact:<id>:<name> holds a named output besides the result; the names are
+# indexed in the set job_artifacts:<id> (served by GET /jobs/<id>/artifacts)
+ARTIFACT_PREFIX = KEY_PREFIX + "job_artifact:"
+ARTIFACT_INDEX_PREFIX = KEY_PREFIX + "job_artifacts:"
+PARTIAL_CHUNK_ROWS = int(os.getenv("PARTIAL_CHUNK_ROWS", 24))  # 0 disables partial results
+EVENTS_PREFIX = KEY_PREFIX + "job_events:"
+# job_progress:<id> is the percentage of rows simulated, updated with each partial chunk
+PROGRESS_PREFIX = KEY_PREFIX + "job_progress:"
+# job_logs:<id> holds "<timestamp> <LEVEL> <message>" lines, served by GET /jobs/<id>/logs
+LOGS_PREFIX = KEY_PREFIX + "job_logs:"
+# active_job:<greenhouse_id> is held by a job submitted with ?unique_active=true
+ACTIVE_JOB_PREFIX = KEY_PREFIX + "active_job:"
+# delete the lock only if this job still holds it
+RELEASE_ACTIVE_SCRIPT = """
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+"""
+JOB_LOG_MAX_LINES = int(os.getenv("JOB_LOG_MAX_LINES", 1000))
+# "gzip" (default) or "none"; the backend detects gzip by its magic bytes
+RESULT_COMPRESSION = os.getenv("RESULT_COMPRESSION", "gzip")
+# how often a running job's updated_at is refreshed; the backend fails running
+# jobs silent for longer than its JOB_TIMEOUT_SECONDS (default 600)
+HEARTBEAT_SECONDS = float(os.getenv("WORKER_HEARTBEAT_SECONDS", 30))
+# worker_heartbeat:<worker_id> holds the time of this process's last beat. It is
+# refreshed every LIVENESS_SECONDS whether or not a job is running and expires
+# after LIVENESS_TTL_SECONDS, so GET /health/workers counts only live workers.
+WORKER_HEARTBEAT_PREFIX = KEY_PREFIX + "worker_heartbeat:"
+LIVENESS_SECONDS = float(os.getenv("WORKER_LIVENESS_SECONDS", 10))
+LIVENESS_TTL_SECONDS = int(os.getenv("WORKER_LIVENESS_TTL_SECONDS", 30))
+WORKER_ID = os.getenv("WORKER_ID") or f"{socket.gethostname()}-{os.getpid()}"
 
 def connect_redis():
     return redis.from_url(REDIS_ADDR, decode_responses=True)
@@ -28,42 +86,233 @@ def connect_redis():
 def log(msg: str):
     print(f"[{datetime.now(timezone.utc).isoformat()}] {msg}", flush=True)
 
-def update_job_status(rdb, job_id: str, status: str, error: str = None):
+def job_log(rdb, job_id: str, msg: str, level: str = "INFO", ttl: int = None):
+    """log() msg and append it to the job's log list (capped at JOB_LOG_MAX_LINES)."""
+    log(msg)
+    key = f"{LOGS_PREFIX}{job_id}"
+    try:
+        pipe = rdb.pipeline()
+        pipe.rpush(key, f"{datetime.now(timezone.utc).isoformat()} {level} {msg}")
+        pipe.ltrim(key, -JOB_LOG_MAX_LINES, -1)
+        pipe.expire(key, ttl or RESULT_TTL)
+        pipe.execute()
+    except Exception as e:
+        # diagnostics must never fail the job itself
+        log(f"Failed to store log line for job {job_id}: {e}")
+
+def encode_result(result_json: dict):
+    raw = json.dumps(result_json)
+    if RESULT_COMPRESSION == "gzip":
+        return gzip.compress(raw.encode("utf-8"))
+    return raw
+
+def decode_result(stored):
+    """Inverse of encode_result; also accepts legacy uncompressed results."""
+    if isinstance(stored, bytes) and stored[:2] == b"\x1f\x8b":
+        stored = gzip.decompress(stored)
+    return json.loads(stored)
+
+def store_artifacts(rdb, job_id: str, artifacts: dict, ttl: int):
+    """Store each named artifact (a JSON document) and index its name."""
+    index_key = f"{ARTIFACT_INDEX_PREFIX}{job_id}"
+    pipe = rdb.pipeline()
+    for name, doc in artifacts.items():
+        pipe.set(f"{ARTIFACT_PREFIX}{job_id}:{name}", encode_result(doc), ex=ttl)
+        pipe.sadd(index_key, name)
+    pipe.expire(index_key, ttl)
+    pipe.execute()
+
+def series_artifact(records: list, columns: list) -> dict:
+    """The datetime and the given columns of each result row."""
+    return {"data": [{k: r.get(k) for k in ["datetime"] + columns} for r in records]}
+
+def to_record(row: dict) -> dict:
+    """One result row as JSON-ready data (timestamps in ISO format)."""
+    record = row.copy()
+    if isinstance(record.get("datetime"), pd.Timestamp):
+        record["datetime"] = record["datetime"].isoformat()
+    return record
+
+def renew_expiry(pipe, job_id: str, meta_obj: dict, now: datetime, ttl: int):
+    """Record in meta_obj when a meta written now with ttl expires, and queue
+    the matching job_expiry marker on pipe."""
+    expires_at = (now + timedelta(seconds=ttl)).isoformat()
+    meta_obj["expires_at"] = expires_at
+    pipe.set(f"{EXPIRY_PREFIX}{job_id}", expires_at, ex=ttl + EXPIRED_GRACE_SECONDS)
+
+def update_job_status(rdb, job_id: str, status: str, error: str = None, ttl: int = None):
     meta_key = f"{META_PREFIX}{job_id}"
     meta = rdb.get(meta_key)
     if not meta:
         return
     meta_obj = json.loads(meta)
+    now = datetime.now(timezone.utc)
     meta_obj["status"] = status
-    meta_obj["updated_at"] = datetime.now(timezone.utc).isoformat()
+    meta_obj["updated_at"] = now.isoformat()
+    if status == "running":
+        # backend derives queue wait time from created_at -> started_at
+        meta_obj["started_at"] = meta_obj["updated_at"]
     if error:
         meta_obj["error"] = error
-    rdb.set(meta_key, json.dumps(meta_obj), ex=RESULT_TTL)
+    with rdb.pipeline() as pipe:
+        renew_expiry(pipe, job_id, meta_obj, now, ttl or RESULT_TTL)
+        pipe.set(meta_key, json.dumps(meta_obj), ex=ttl or RESULT_TTL)
+        pipe.execute()
+    # notify /jobs/<id>/events subscribers (the backend polls if this is missed)
+    rdb.publish(f"{EVENTS_PREFIX}{job_id}", json.dumps(meta_obj))
+    greenhouse_id = (meta_obj.get("params") or {}).get("greenhouse_id")
+    if status in ("done", "error") and greenhouse_id:
+        rdb.eval(RELEASE_ACTIVE_SCRIPT, 1, f"{ACTIVE_JOB_PREFIX}{greenhouse_id}", job_id)
+
+def touch_job(rdb, job_id: str, ttl: int = None) -> bool:
+    """Refresh updated_at of a running job. The meta is watched so a status
+    written meanwhile (e.g. the backend's timeout) is never overwritten."""
+    meta_key = f"{META_PREFIX}{job_id}"
+    with rdb.pipeline() as pipe:
+        try:
+            pipe.watch(meta_key)
+            meta = pipe.get(meta_key)
+            if not meta:
+                return False
+            meta_obj = json.loads(meta)
+            if meta_obj.get("status") != "running":
+                return False
+            now = datetime.now(timezone.utc)
+            meta_obj["updated_at"] = now.isoformat()
+            pipe.multi()
+            renew_expiry(pipe, job_id, meta_obj, now, ttl or RESULT_TTL)
+            pipe.set(meta_key, json.dumps(meta_obj), ex=ttl or RESULT_TTL)
+            pipe.execute()
+            return True
+        except redis.WatchError:
+            return False
+
+def beat_worker(rdb, worker_id: str = WORKER_ID, ttl: int = LIVENESS_TTL_SECONDS):
+    """Record that worker_id is alive for the next ttl seconds."""
+    rdb.set(f"{WORKER_HEARTBEAT_PREFIX}{worker_id}", datetime.now(timezone.utc).isoformat(), ex=ttl)
+
+def start_liveness(rdb, worker_id: str = WORKER_ID, interval: float = LIVENESS_SECONDS) -> threading.Event:
+    """Beat now, then every interval seconds on a daemon thread (BLPOP blocks
+    the main loop while idle) until the returned event is set."""
+    beat_worker(rdb, worker_id)
+    stop = threading.Event()
+
+    def run():
+        while not stop.wait(interval):
+            try:
+                beat_worker(rdb, worker_id)
+            except Exception as e:
+                log(f"Worker heartbeat failed: {e}")
+
+    threading.Thread(target=run, daemon=True).start()
+    return stop
+
+class Heartbeat:
+    """Calls touch_job every interval seconds on a background thread while the
+    job runs. stop() waits for the thread so no heartbeat lands after the final
+    status update."""
+
+    def __init__(self, rdb, job_id: str, ttl: int = None, interval: float = None):
+        self._interval = HEARTBEAT_SECONDS if interval is None else interval
+        self._stop = threading.Event()
+        self._thread = threading.Thread(target=self._run, args=(rdb, job_id, ttl), daemon=True)
+
+    def _run(self, rdb, job_id, ttl):
+        while not self._stop.wait(self._interval):
+            try:
+                touch_job(rdb, job_id, ttl)
+            except Exception as e:
+                log(f"Heartbeat for job {job_id} failed: {e}")
+
+    def __enter__(self):
+        if self._interval > 0:
+            self._thread.start()
+        return self
+
+    def __exit__(self, *exc):
+        self._stop.set()
+        if self._thread.is_alive():
+            self._thread.join()
+        return False
+
+def trace_id(job: dict) -> str:
+    """The trace id in the job's W3C traceparent (see backend/tracing.go), or ""."""
+    parts = (job.get("trace_context") or {}).get("traceparent", "").split("-")
+    return parts[1] if len(parts) == 4 else ""
+
+def job_span(job: dict):
+    """A span around processing job, child of the submitting request's span;
+    a no-op without OpenTelemetry."""
+    if otel_trace is None:
+        return nullcontext()
+    return otel_trace.get_tracer("greensim-worker").start_as_current_span(
+        "process_job",
+        context=otel_extract(job.get("trace_context") or {}),
+        kind=otel_trace.SpanKind.CONSUMER,
+        attributes={"job.id": job.get("job_id", "")},
+    )
 
 def process_job(job: dict, rdb):
     job_id = job["job_id"]
     params = job["params"]
     created_at = job.get("created_at", datetime.now(timezone.utc).isoformat())
+    # per-job TTL chosen at submit time (falls back to the worker default)
+    ttl = int(job.get("result_ttl_seconds") or RESULT_TTL)
 
-    log(f"Processing job {job_id} with params: {params}")
+    job_log(rdb, job_id, f"Processing job {job_id} with params: {params}", ttl=ttl)
+    if trace_id(job):
+        job_log(rdb, job_id, f"Trace {trace_id(job)}", ttl=ttl)
 
     try:
-        update_job_status(rdb, job_id, "running")
+        update_job_status(rdb, job_id, "running", ttl=ttl)
+
+        # the backend records a seed for every job; seeding here makes any
+        # stochastic input (e.g. generated weather) reproducible from the meta
+        if params.get("seed") is not None:
+            np.random.seed(int(params["seed"]))
 
         lat, lon = params.get("lat", 39.9), params.get("lon", 116.4)
         start_date = params.get("start_date", "2025-10-01")
         end_date = params.get("end_date", "2025-10-02")
 
-        weather_df = get_weather({"lat": lat, "lon": lon}, start_date, end_date)
+        partial_key = f"{PARTIAL_PREFIX}{job_id}"
+        progress_key = f"{PROGRESS_PREFIX}{job_id}"
+        rows_done = 0
 
-        result_df = simulate_greenhouse(weather_df, params)
+        def publish_partial(rows):
+            nonlocal rows_done
+            rows_done += len(rows)
+            pipe = rdb.pipeline()
+            pipe.rpush(partial_key, json.dumps([to_record(r) for r in rows]))
+            pipe.expire(partial_key, ttl)
+            # one output row per weather row
+            pipe.set(progress_key, f"{min(100.0, 100.0 * rows_done / max(total_rows, 1)):.1f}", ex=ttl)
+            pipe.execute()
+
+        # heartbeats cover the slow part: the weather fetch and the simulation
+        with Heartbeat(rdb, job_id, ttl):
+            weather_df = get_weather({"lat": lat, "lon": lon}, start_date, end_date)
+            job_log(rdb, job_id, f"Fetched {len(weather_df)} weather rows for ({lat}, {lon}) {start_date}..{end_date}", ttl=ttl)
+            # synthetic temperature modes do not need the fetch; run them without sun
+            mode = params.get("outdoor_temp_mode") or "weather"
+            if weather_df.empty and mode != "weather":
+                weather_df = synthetic_weather(start_date, end_date)
+                job_log(rdb, job_id, f"No weather data; running outdoor_temp_mode={mode} with G=0 and default humidity",
+                        level="WARNING", ttl=ttl)
+            # the backend bounds timestep_seconds; hourly when omitted
+            dt = float(params.get("timestep_seconds") or HOURLY_SECONDS)
+            weather_df = resample_weather(weather_df, dt)
+            weather_df = apply_outdoor_temp(weather_df, params)
+            total_rows = len(weather_df)
+            simulate = get_model(params.get("model"))
+            result_df = simulate(weather_df, params, dt=dt, on_chunk=publish_partial, chunk_rows=PARTIAL_CHUNK_ROWS)
 
         # Debug: Check if Tout is in the dataframe
         log(f"Result dataframe columns: {list(result_df.columns)}")
         if "Tout" in result_df.columns:
             log(f"Tout sample values: {result_df['Tout'].head(5).tolist()}")
         else:
-            log("WARNING: Tout column not found in result dataframe!")
+            job_log(rdb, job_id, "Tout column not found in result dataframe", level="WARNING", ttl=ttl)
 
         summary = {
             "Tin_min": float(result_df["Tin"].min()) if "Tin" in result_df.columns else None,
@@ -78,9 +327,7 @@ def process_job(job: dict, rdb):
         data_records = []
         for row in result_df.to_dict(orient="records"):
             # Ensure datetime is in ISO format
-            row_dict = row.copy()
-            if isinstance(row_dict.get("datetime"), pd.Timestamp):
-                row_dict["datetime"] = row_dict["datetime"].isoformat()
+            row_dict = to_record(row)
             # Explicitly ensure Tout is included
             if "Tout" not in row_dict:
                 log(f"WARNING: Tout missing in row: {row_dict}")
@@ -94,29 +341,41 @@ def process_job(job: dict, rdb):
             "data": data_records,
         }
 
-        rdb.set(f"{RESULT_PREFIX}{job_id}", json.dumps(result_json), ex=RESULT_TTL)
-        update_job_status(rdb, job_id, "done")
+        rdb.set(f"{RESULT_PREFIX}{job_id}", encode_result(result_json), ex=ttl)
+        # the result is also the "default" artifact; these are slices of it for reports
+        artifacts = {
+            "summary": summary,
+            "temperature": series_artifact(data_records, [c for c in ("Tout", "Tin", "T_mass", "T_soil") if c in result_df.columns]),
+        }
+        if "Q_heater" in result_df.columns:
+            artifacts["energy"] = series_artifact(data_records, [c for c in ("Q_heater", "Q_latent") if c in result_df.columns])
+        store_artifacts(rdb, job_id, artifacts, ttl)
+        update_job_status(rdb, job_id, "done", ttl=ttl)
 
-        log(f"Job {job_id} complete. {len(result_df)} rows simulated.")
+        job_log(rdb, job_id, f"Job {job_id} complete. {len(result_df)} rows simulated.", ttl=ttl)
 
     except Exception as e:
-        log(f"Error processing job {job_id}: {e}")
-        traceback.print_exc()
-        update_job_status(rdb, job_id, "error", str(e))
+        job_log(rdb, job_id, f"Error processing job {job_id}: {e}", level="ERROR", ttl=ttl)
+        for line in traceback.format_exc().rstrip().splitlines():
+            job_log(rdb, job_id, line, level="ERROR", ttl=ttl)
+        update_job_status(rdb, job_id, "error", str(e), ttl=ttl)
 
 def main():
     rdb = connect_redis()
     log(f"Connected to Redis at {REDIS_ADDR}")
-    log(f"Listening for jobs on queue: {QUEUE_NAME}")
+    log(f"Listening for jobs on queues: {', '.join(QUEUE_NAMES)}")
+    start_liveness(rdb)
+    log(f"Worker {WORKER_ID} reporting liveness every {LIVENESS_SECONDS:g}s")
 
     while True:
         try:
-            job_data = rdb.blpop(QUEUE_NAME, timeout=0)
+            job_data = rdb.blpop(QUEUE_NAMES, timeout=0)
             if not job_data:
                 continue
             _, raw = job_data
             job = json.loads(raw)
-            process_job(job, rdb)
+            with job_span(job):
+                process_job(job, rdb)
         except Exception as e:
             log(f"Redis or parsing error: {e}")
             time.sleep(3)